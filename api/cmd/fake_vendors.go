@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/doopush/doopush/api/internal/config"
+	"github.com/doopush/doopush/api/internal/push/fakevendor"
+	"github.com/doopush/doopush/api/pkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+var fakeVendorsCmd = &cobra.Command{
+	Use:   "fake-vendors",
+	Short: "启动模拟推送厂商服务器",
+	Long: `启动模拟 APNs、FCM、华为、荣耀、小米、OPPO、VIVO、魅族鉴权与推送接口的服务器，用于 CI 和本地开发。
+API 服务配置 VENDOR_URL_BASE=http://<地址> 后，所有推送都会发往该服务器。
+
+失败场景：凭据以 invalid 开头时鉴权失败；设备 Token 以 invalid_token、rate_limit、server_error、token_expired 开头时
+返回厂商对应的错误。控制接口：
+  GET    /_fake/messages?vendor=     查看收到的推送
+  DELETE /_fake/messages             清空收到的推送
+  POST   /_fake/expire-tokens        使已签发的鉴权令牌全部失效
+  POST   /_fake/fail                 排队失败 {"vendor":"huawei","scenario":"rate_limit","count":1}
+  POST   /_fake/reset                重置全部状态`,
+	Run: func(cmd *cobra.Command, args []string) {
+		envFile, _ := cmd.Flags().GetString("env-file")
+		if envFile != "" {
+			config.LoadConfig(envFile)
+		}
+		logger.Init("fake-vendors", config.GetString("LOG_LEVEL", "info"), config.GetString("LOG_FORMAT", "text"))
+
+		addr, _ := cmd.Flags().GetString("addr")
+		tokenTTL, _ := cmd.Flags().GetDuration("token-ttl")
+
+		server := &http.Server{
+			Addr:              addr,
+			Handler:           fakevendor.New(tokenTTL).Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		logger.Info("模拟推送厂商服务器已启动，API 服务配置 VENDOR_URL_BASE 指向该地址即可使用", "addr", addr, "token_ttl", tokenTTL)
+		if err := server.ListenAndServe(); err != nil {
+			logger.Fatal("模拟推送厂商服务器启动失败", "error", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fakeVendorsCmd)
+	fakeVendorsCmd.Flags().StringP("env-file", "e", "", "环境变量文件路径 (可选)")
+	fakeVendorsCmd.Flags().String("addr", ":9900", "监听地址")
+	fakeVendorsCmd.Flags().Duration("token-ttl", time.Hour, "签发的鉴权令牌有效期")
+}
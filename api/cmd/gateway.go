@@ -1,10 +1,9 @@
 package cmd
 
 import (
-	"log"
-
 	"github.com/doopush/doopush/api/internal/config"
 	"github.com/doopush/doopush/api/internal/gateway"
+	"github.com/doopush/doopush/api/pkg/logger"
 	"github.com/doopush/doopush/api/pkg/utils"
 
 	"github.com/spf13/cobra"
@@ -20,6 +19,7 @@ var gatewayCmd = &cobra.Command{
 		if envFile != "" {
 			config.LoadConfig(envFile)
 		}
+		logger.Init("gateway", config.GetString("LOG_LEVEL", "info"), config.GetString("LOG_FORMAT", "json"))
 
 		// 启动网关服务器
 		startGateway()
@@ -32,12 +32,12 @@ func init() {
 }
 
 func startGateway() {
-	log.Println("正在启动网关服务器...")
+	logger.Info("正在启动网关服务器")
 
 	// 创建并启动网关服务器
 	server, err := gateway.NewGatewayServer()
 	if err != nil {
-		log.Fatal("网关服务器创建失败:", err)
+		logger.Fatal("网关服务器创建失败", "error", err)
 	}
 
 	// 开发环境启动时根据端口结束进程
@@ -46,6 +46,6 @@ func startGateway() {
 	}
 
 	if err := server.Start(); err != nil {
-		log.Fatal("网关服务器启动失败:", err)
+		logger.Fatal("网关服务器启动失败", "error", err)
 	}
 }
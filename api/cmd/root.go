@@ -1,7 +1,7 @@
 package cmd
 
 import (
-	"log"
+	"github.com/doopush/doopush/api/pkg/logger"
 
 	"github.com/spf13/cobra"
 )
@@ -14,6 +14,6 @@ var rootCmd = &cobra.Command{
 
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		log.Fatal(err)
+		logger.Fatal("命令执行失败", "error", err)
 	}
 }
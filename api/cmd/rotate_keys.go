@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/doopush/doopush/api/internal/config"
+	"github.com/doopush/doopush/api/internal/database"
+	"github.com/doopush/doopush/api/internal/secrets"
+	"github.com/doopush/doopush/api/pkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+var rotateKeysCmd = &cobra.Command{
+	Use:   "rotate-keys",
+	Short: "使用当前主密钥重新加密推送配置",
+	Long: `使用 CONFIG_MASTER_KEY 重新加密所有推送配置（包括已删除的配置），每条配置同时更换数据密钥；
+明文保存的历史配置会被加密。由 CONFIG_MASTER_KEY_PREVIOUS 中的历史主密钥加密的配置在此过程中解密，
+全部完成后即可从配置中移除历史主密钥。--generate-key 仅生成一个新的主密钥并退出。`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if generate, _ := cmd.Flags().GetBool("generate-key"); generate {
+			fmt.Fprintln(cmd.OutOrStdout(), secrets.GenerateMasterKey())
+			return
+		}
+
+		envFile, _ := cmd.Flags().GetString("env-file")
+		if envFile != "" {
+			config.LoadConfig(envFile)
+		}
+		logger.Init("rotate-keys", config.GetString("LOG_LEVEL", "info"), config.GetString("LOG_FORMAT", "text"))
+
+		if err := secrets.Init(); err != nil {
+			logger.Fatal("主密钥加载失败", "error", err)
+		}
+		if !secrets.Enabled() {
+			logger.Fatal("未配置 CONFIG_MASTER_KEY 或 CONFIG_MASTER_KEY_FILE")
+		}
+
+		database.Connect()
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		rotateAppConfigs(dryRun)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rotateKeysCmd)
+	rotateKeysCmd.Flags().StringP("env-file", "e", "", "环境变量文件路径 (可选)")
+	rotateKeysCmd.Flags().Bool("dry-run", false, "只统计需要重新加密的配置，不写入")
+	rotateKeysCmd.Flags().Bool("generate-key", false, "生成新的主密钥并退出")
+}
+
+// rotateAppConfigs 逐条重新加密推送配置。直接读写原始列，不经过模型的加解密钩子；
+// 以 updated_at 作为乐观锁，期间被 API 修改的配置跳过，重新执行即可
+func rotateAppConfigs(dryRun bool) {
+	var rows []struct {
+		ID        uint
+		Config    string
+		UpdatedAt time.Time
+	}
+	if err := database.DB.Table("app_configs").Select("id, config, updated_at").Order("id ASC").Scan(&rows).Error; err != nil {
+		logger.Fatal("读取推送配置失败", "error", err)
+	}
+
+	var rotated, current, skipped, failed int
+	for _, row := range rows {
+		if !secrets.NeedsRotation(row.Config) {
+			current++
+			continue
+		}
+		ciphertext, err := secrets.Rotate(row.Config)
+		if err != nil {
+			failed++
+			logger.Error("推送配置重新加密失败", "config_id", row.ID, "error", err)
+			continue
+		}
+		if dryRun {
+			rotated++
+			continue
+		}
+
+		result := database.DB.Table("app_configs").
+			Where("id = ? AND updated_at = ?", row.ID, row.UpdatedAt).
+			UpdateColumn("config", ciphertext)
+		switch {
+		case result.Error != nil:
+			failed++
+			logger.Error("推送配置写入失败", "config_id", row.ID, "error", result.Error)
+		case result.RowsAffected == 0:
+			skipped++
+			logger.Warn("推送配置在重新加密期间被修改，已跳过", "config_id", row.ID)
+		default:
+			rotated++
+		}
+	}
+
+	logger.Info("推送配置重新加密完成",
+		"key_id", secrets.KeyID(), "dry_run", dryRun, "total", len(rows),
+		"rotated", rotated, "already_current", current, "skipped", skipped, "failed", failed)
+	if failed > 0 || skipped > 0 {
+		logger.Fatal("部分推送配置未完成重新加密，请处理后重新执行", "skipped", skipped, "failed", failed)
+	}
+}
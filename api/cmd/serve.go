@@ -1,15 +1,22 @@
 package cmd
 
 import (
-	"log"
+	"context"
 	"strconv"
+	"strings"
 
 	"github.com/doopush/doopush/api/internal/config"
 	"github.com/doopush/doopush/api/internal/controllers"
 	"github.com/doopush/doopush/api/internal/database"
+	"github.com/doopush/doopush/api/internal/metrics"
 	"github.com/doopush/doopush/api/internal/middleware"
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/internal/push"
 	"github.com/doopush/doopush/api/internal/redisclient"
+	"github.com/doopush/doopush/api/internal/secrets"
 	"github.com/doopush/doopush/api/internal/services"
+	"github.com/doopush/doopush/api/internal/tracing"
+	"github.com/doopush/doopush/api/pkg/logger"
 	"github.com/doopush/doopush/api/pkg/utils"
 
 	_ "github.com/doopush/doopush/api/docs"
@@ -32,6 +39,27 @@ var serveCmd = &cobra.Command{
 		if envFile != "" {
 			config.LoadConfig(envFile)
 		}
+		logger.Init("api", config.GetString("LOG_LEVEL", "info"), config.GetString("LOG_FORMAT", "json"))
+
+		// 初始化链路追踪
+		shutdownTracing, err := tracing.Init("doopush-api")
+		if err != nil {
+			logger.Fatal("链路追踪初始化失败", "error", err)
+		}
+		defer shutdownTracing(context.Background())
+
+		// 加载推送配置加密主密钥
+		if err := secrets.Init(); err != nil {
+			logger.Fatal("主密钥加载失败", "error", err)
+		}
+		if !secrets.Enabled() {
+			logger.Warn("未配置 CONFIG_MASTER_KEY，推送配置中的凭据将以明文保存")
+		}
+
+		// 加载厂商接口地址，被覆盖时逐个提示，避免生产环境误将推送发往模拟服务器
+		for name, url := range push.InitEndpoints() {
+			logger.Warn("厂商接口地址已覆盖", "endpoint", name, "url", url)
+		}
 
 		// 连接数据库
 		database.Connect()
@@ -58,11 +86,22 @@ func startServer() {
 	}
 
 	// 创建Gin引擎
-	r := gin.Default()
+	r := gin.New()
 	r.UseRawPath = true
 	r.UnescapePathValues = true
 
+	// 只信任反向代理传入的 X-Forwarded-For，避免客户端伪造来源 IP 绕过API密钥的IP白名单。
+	// 默认只信任本机的 nginx，代理部署在其他主机时需显式配置其地址
+	trustedProxies := strings.Split(config.GetString("TRUSTED_PROXIES", "127.0.0.1/8,::1/128"), ",")
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		logger.Fatal("TRUSTED_PROXIES 配置无效", "error", err)
+	}
+
 	// 全局中间件
+	r.Use(middleware.Metrics())
+	r.Use(middleware.Tracing())
+	r.Use(middleware.RequestLogger())
+	r.Use(gin.Recovery())
 	r.Use(middleware.CORS())
 
 	// 静态文件服务
@@ -71,7 +110,13 @@ func startServer() {
 	// Redis 不可用时设备列表回退到 DB 的 is_online，与请求路径的容错策略一致；不阻断启动
 	rdb, err := redisclient.New()
 	if err != nil {
-		log.Printf("Redis 初始化失败，设备在线态将回退到 DB stale 值: %v", err)
+		logger.Warn("Redis 初始化失败，设备在线态将回退到 DB stale 值", "error", err)
+	} else {
+		// 频控和API密钥频率限制的计数依赖 Redis，不可用时不做限制
+		services.SetFrequencyRedis(rdb)
+		services.SetAPIKeyRedis(rdb)
+		// 分批发送任务的租约依赖 Redis，不可用时只在本进程内去重
+		services.SetCampaignRedis(rdb)
 	}
 
 	// 控制器
@@ -84,6 +129,12 @@ func startServer() {
 	templateCtrl := controllers.NewTemplateController()
 	tagCtrl := controllers.NewTagController()
 	groupCtrl := controllers.NewGroupController()
+	topicCtrl := controllers.NewTopicController()
+	frequencyCapCtrl := controllers.NewFrequencyCapController()
+	campaignCtrl := controllers.NewCampaignController()
+	approvalCtrl := controllers.NewPushApprovalController()
+	webhookCtrl := controllers.NewWebhookController()
+	alertCtrl := controllers.NewAlertController()
 	schedulerCtrl := controllers.NewSchedulerController()
 	auditCtrl := controllers.NewAuditController()
 	uploadCtrl := controllers.NewUploadController()
@@ -146,11 +197,10 @@ func startServer() {
 			// API密钥管理
 			authenticated.GET("/apps/:appId/api-keys", middleware.RequireAppRole("developer"), appCtrl.GetAppAPIKeys)
 			authenticated.POST("/apps/:appId/api-keys", middleware.RequireAppRole("developer"), appCtrl.CreateAPIKey)
+			authenticated.PUT("/apps/:appId/api-keys/:keyId", middleware.RequireAppRole("developer"), appCtrl.UpdateAPIKey)
 			authenticated.DELETE("/apps/:appId/api-keys/:keyId", middleware.RequireAppRole("developer"), appCtrl.DeleteAPIKey)
 
-			// 设备管理
-			authenticated.GET("/apps/:appId/devices", middleware.RequireAppRole("viewer"), deviceCtrl.GetDevices)
-			authenticated.GET("/apps/:appId/devices/:deviceId", middleware.RequireAppRole("viewer"), deviceCtrl.GetDevice)
+			// 设备管理（设备查询接口同时支持 API Key，见下方双重认证路由）
 			authenticated.PUT("/apps/:appId/devices/:deviceId/status", middleware.RequireAppRole("developer"), deviceCtrl.UpdateDeviceStatus)
 			authenticated.DELETE("/apps/:appId/devices/:deviceId", middleware.RequireAppRole("developer"), deviceCtrl.DeleteDevice)
 
@@ -164,6 +214,9 @@ func startServer() {
 			authenticated.POST("/apps/:appId/config", middleware.RequireAppRole("developer"), configCtrl.SetAppConfig)
 			authenticated.PUT("/apps/:appId/config/:configId", middleware.RequireAppRole("developer"), configCtrl.UpdateAppConfig)
 			authenticated.DELETE("/apps/:appId/config/:configId", middleware.RequireAppRole("developer"), configCtrl.DeleteAppConfig)
+			authenticated.POST("/apps/:appId/config/:configId/reveal", middleware.RequireAppRole("owner"), configCtrl.RevealAppConfig)
+			authenticated.POST("/apps/:appId/config/:configId/health-check", middleware.RequireAppRole("developer"), configCtrl.CheckAppConfigHealth)
+			authenticated.PUT("/apps/:appId/config/:configId/categories", middleware.RequireAppRole("developer"), configCtrl.UpdateCategoryMap)
 			authenticated.POST("/apps/:appId/config/test", middleware.RequireAppRole("developer"), configCtrl.TestAppConfig)
 
 			// 消息模板管理
@@ -190,6 +243,45 @@ func startServer() {
 			authenticated.PUT("/apps/:appId/device-groups/:id", middleware.RequireAppRole("developer"), groupCtrl.UpdateGroup)
 			authenticated.DELETE("/apps/:appId/device-groups/:id", middleware.RequireAppRole("developer"), groupCtrl.DeleteGroup)
 
+			// 订阅主题管理
+			authenticated.GET("/apps/:appId/topics", middleware.RequireAppRole("viewer"), topicCtrl.GetTopics)
+			authenticated.POST("/apps/:appId/topics", middleware.RequireAppRole("developer"), topicCtrl.CreateTopic)
+			authenticated.PUT("/apps/:appId/topics/:id", middleware.RequireAppRole("developer"), topicCtrl.UpdateTopic)
+			authenticated.DELETE("/apps/:appId/topics/:id", middleware.RequireAppRole("developer"), topicCtrl.DeleteTopic)
+			authenticated.GET("/apps/:appId/devices/:deviceId/topics", middleware.RequireAppRole("viewer"), topicCtrl.GetDeviceTopics)
+			authenticated.PUT("/apps/:appId/devices/:deviceId/topics", middleware.RequireAppRole("developer"), topicCtrl.UpdateDeviceTopics)
+
+			// 推送频控
+			authenticated.GET("/apps/:appId/frequency-caps", middleware.RequireAppRole("viewer"), frequencyCapCtrl.GetFrequencyCap)
+			authenticated.PUT("/apps/:appId/frequency-caps", middleware.RequireAppRole("developer"), frequencyCapCtrl.UpdateFrequencyCap)
+
+			// 事件回调
+			authenticated.GET("/apps/:appId/webhooks", middleware.RequireAppRole("developer"), webhookCtrl.GetWebhooks)
+			authenticated.POST("/apps/:appId/webhooks", middleware.RequireAppRole("developer"), webhookCtrl.CreateWebhook)
+			authenticated.GET("/apps/:appId/webhooks/:id", middleware.RequireAppRole("developer"), webhookCtrl.GetWebhook)
+			authenticated.PUT("/apps/:appId/webhooks/:id", middleware.RequireAppRole("developer"), webhookCtrl.UpdateWebhook)
+			authenticated.DELETE("/apps/:appId/webhooks/:id", middleware.RequireAppRole("developer"), webhookCtrl.DeleteWebhook)
+			authenticated.POST("/apps/:appId/webhooks/:id/rotate-secret", middleware.RequireAppRole("developer"), webhookCtrl.RotateWebhookSecret)
+			authenticated.POST("/apps/:appId/webhooks/:id/test", middleware.RequireAppRole("developer"), webhookCtrl.TestWebhook)
+			authenticated.GET("/apps/:appId/webhooks/:id/deliveries", middleware.RequireAppRole("developer"), webhookCtrl.GetWebhookDeliveries)
+			authenticated.POST("/apps/:appId/webhooks/:id/deliveries/:deliveryId/replay", middleware.RequireAppRole("developer"), webhookCtrl.ReplayWebhookDelivery)
+
+			// 告警
+			authenticated.GET("/apps/:appId/alert-rules", middleware.RequireAppRole("viewer"), alertCtrl.GetAlertRules)
+			authenticated.POST("/apps/:appId/alert-rules", middleware.RequireAppRole("developer"), alertCtrl.CreateAlertRule)
+			authenticated.GET("/apps/:appId/alert-rules/:id", middleware.RequireAppRole("viewer"), alertCtrl.GetAlertRule)
+			authenticated.PUT("/apps/:appId/alert-rules/:id", middleware.RequireAppRole("developer"), alertCtrl.UpdateAlertRule)
+			authenticated.DELETE("/apps/:appId/alert-rules/:id", middleware.RequireAppRole("developer"), alertCtrl.DeleteAlertRule)
+			authenticated.GET("/apps/:appId/alerts", middleware.RequireAppRole("viewer"), alertCtrl.GetAlerts)
+
+			// 推送审批
+			authenticated.GET("/apps/:appId/approval-policy", middleware.RequireAppRole("viewer"), approvalCtrl.GetApprovalPolicy)
+			authenticated.PUT("/apps/:appId/approval-policy", middleware.RequireAppRole("owner"), approvalCtrl.UpdateApprovalPolicy)
+			authenticated.GET("/apps/:appId/push/approvals", middleware.RequireAppRole("viewer"), approvalCtrl.GetApprovals)
+			authenticated.GET("/apps/:appId/push/approvals/:id", middleware.RequireAppRole("viewer"), approvalCtrl.GetApproval)
+			authenticated.POST("/apps/:appId/push/approvals/:id/approve", middleware.RequireAppRole("developer"), approvalCtrl.ApprovePush)
+			authenticated.POST("/apps/:appId/push/approvals/:id/reject", middleware.RequireAppRole("developer"), approvalCtrl.RejectPush)
+
 			// 定时推送管理
 			authenticated.GET("/apps/:appId/scheduled-pushes", middleware.RequireAppRole("viewer"), schedulerCtrl.GetScheduledPushes)
 			authenticated.GET("/apps/:appId/scheduled-pushes/stats", middleware.RequireAppRole("viewer"), schedulerCtrl.GetScheduledPushStats)
@@ -228,40 +320,71 @@ func startServer() {
 			receipt.POST("", callbackCtrl.ReceiveGenericCallback) // 通用回执接口
 		}
 
-		// API Key认证的路由 (供客户端SDK使用)
+		// API Key认证的路由 (供客户端SDK使用，按接口检查密钥权限范围)
 		apiKeyRoutes := api.Group("")
-		apiKeyRoutes.Use(middleware.APIKeyAuth())
 		{
-			apiKeyRoutes.POST("/apps/:appId/devices", deviceCtrl.RegisterDevice)
-			apiKeyRoutes.POST("/apps/:appId/push/statistics/report", pushCtrl.ReportPushStatistics)
+			apiKeyRoutes.POST("/apps/:appId/devices", middleware.APIKeyAuth(models.APIKeyScopeDeviceRegister), deviceCtrl.RegisterDevice)
+			apiKeyRoutes.POST("/apps/:appId/push/statistics/report", middleware.APIKeyAuth(models.APIKeyScopeStatsReport), pushCtrl.ReportPushStatistics)
 		}
 
-		// 双重认证的路由 (支持JWT和API Key认证)
+		// 客户端SDK路由 (API Key + X-Device-Token，只能操作调用方设备自身)
+		sdkRoutes := api.Group("/apps/:appId/device")
+		sdkRoutes.Use(middleware.APIKeyAuth(models.APIKeyScopeDeviceRegister), middleware.DeviceTokenAuth())
+		{
+			sdkRoutes.DELETE("", deviceCtrl.UnregisterDevice)
+			sdkRoutes.GET("/tags", deviceCtrl.GetOwnTags)
+			sdkRoutes.POST("/tags", deviceCtrl.AddOwnTags)
+			sdkRoutes.DELETE("/tags/:tagName", deviceCtrl.DeleteOwnTag)
+			sdkRoutes.PUT("/alias", deviceCtrl.BindAlias)
+			sdkRoutes.DELETE("/alias", deviceCtrl.UnbindAlias)
+			sdkRoutes.PUT("/preferences", deviceCtrl.UpdatePreferences)
+			sdkRoutes.PUT("/categories", deviceCtrl.UpdateCategoryPreferences)
+			sdkRoutes.GET("/topics", topicCtrl.GetOwnTopics)
+			sdkRoutes.PUT("/topics", topicCtrl.UpdateOwnTopics)
+			sdkRoutes.POST("/badge", deviceCtrl.UpdateBadge)
+		}
+
+		// 双重认证的路由 (支持JWT和API Key认证，API Key 按接口检查权限范围)
 		dualAuthRoutes := api.Group("")
-		dualAuthRoutes.Use(middleware.DualAuth())
 		dualAuthRoutes.Use(middleware.AuditLogger())
 		{
 			// 推送管理 - 发送类接口（支持JWT和API Key双重认证）
-			dualAuthRoutes.POST("/apps/:appId/push", pushCtrl.SendPush)
-			dualAuthRoutes.POST("/apps/:appId/push/single", pushCtrl.SendSingle)
-			dualAuthRoutes.POST("/apps/:appId/push/batch", pushCtrl.SendBatch)
-			dualAuthRoutes.POST("/apps/:appId/push/broadcast", pushCtrl.SendBroadcast)
+			pushSend := middleware.DualAuth(models.APIKeyScopePushSend)
+			dualAuthRoutes.POST("/apps/:appId/push", pushSend, pushCtrl.SendPush)
+			dualAuthRoutes.POST("/apps/:appId/push/single", pushSend, pushCtrl.SendSingle)
+			dualAuthRoutes.POST("/apps/:appId/push/batch", pushSend, pushCtrl.SendBatch)
+			dualAuthRoutes.POST("/apps/:appId/push/broadcast", middleware.DualAuth(models.APIKeyScopePushBroadcast), pushCtrl.SendBroadcast)
+			dualAuthRoutes.POST("/apps/:appId/push/batches/:batchId/cancel", pushSend, pushCtrl.CancelPush)
+
+			// 分批发送任务控制
+			dualAuthRoutes.GET("/apps/:appId/push/campaigns/:id", pushSend, campaignCtrl.GetCampaign)
+			dualAuthRoutes.POST("/apps/:appId/push/campaigns/:id/pause", pushSend, campaignCtrl.PauseCampaign)
+			dualAuthRoutes.POST("/apps/:appId/push/campaigns/:id/resume", pushSend, campaignCtrl.ResumeCampaign)
+			dualAuthRoutes.POST("/apps/:appId/push/campaigns/:id/promote", pushSend, campaignCtrl.PromoteCampaign)
+			dualAuthRoutes.POST("/apps/:appId/push/campaigns/:id/cancel", pushSend, campaignCtrl.CancelCampaign)
+
+			// 设备查询
+			devicesRead := middleware.DualAuth(models.APIKeyScopeDevicesRead)
+			dualAuthRoutes.GET("/apps/:appId/devices", devicesRead, middleware.RequireAppRole("viewer"), deviceCtrl.GetDevices)
+			dualAuthRoutes.GET("/apps/:appId/devices/:deviceId", devicesRead, middleware.RequireAppRole("viewer"), deviceCtrl.GetDevice)
 		}
 	}
 
 	// Swagger文档
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// Prometheus 指标在独立的内部地址输出，不挂在对外的 API 端口上；默认只监听本机
+	metrics.Serve(config.GetString("METRICS_ADDR", "127.0.0.1:9091"))
+
 	// 启动服务器
 	if config.GetString("APP_ENV", "development") == "development" {
 		utils.KillProcessByPort(ListenPort)
 	}
 
 	addr := ":" + strconv.Itoa(ListenPort)
-	log.Printf("服务器启动在 %s", addr)
-	log.Printf("Swagger文档: http://localhost%s/swagger/index.html", addr)
+	logger.Info("服务器启动", "addr", addr, "swagger", "http://localhost"+addr+"/swagger/index.html")
 
 	if err := r.Run(addr); err != nil {
-		log.Fatal("服务器启动失败:", err)
+		logger.Fatal("服务器启动失败", "error", err)
 	}
 }
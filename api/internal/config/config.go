@@ -1,10 +1,11 @@
 package config
 
 import (
-	"log"
 	"os"
 	"strconv"
 
+	"github.com/doopush/doopush/api/pkg/logger"
+
 	"github.com/spf13/viper"
 )
 
@@ -14,10 +15,10 @@ func LoadConfig(envFile string) {
 	viper.SetConfigType("env")
 
 	if err := viper.ReadInConfig(); err != nil {
-		log.Printf("配置文件读取失败: %v", err)
+		logger.Warn("配置文件读取失败", "file", envFile, "error", err)
 	}
 
-	log.Printf("配置文件已加载: %s", envFile)
+	logger.Info("配置文件已加载", "file", envFile)
 
 	// 检查是否存在本地覆盖配置文件
 	localConfigFile := envFile + ".local"
@@ -25,12 +26,12 @@ func LoadConfig(envFile string) {
 		// 本地配置文件存在，合并配置
 		viper.SetConfigFile(localConfigFile)
 		if err := viper.MergeInConfig(); err != nil {
-			log.Printf("本地配置文件合并失败: %v", err)
+			logger.Warn("本地配置文件合并失败", "file", localConfigFile, "error", err)
 		} else {
-			log.Printf("本地配置文件已合并: %s", localConfigFile)
+			logger.Info("本地配置文件已合并", "file", localConfigFile)
 		}
 	} else if !os.IsNotExist(err) {
-		log.Printf("检查本地配置文件失败: %v", err)
+		logger.Warn("检查本地配置文件失败", "file", localConfigFile, "error", err)
 	}
 }
 
@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/internal/services"
+	"github.com/doopush/doopush/api/pkg/response"
+	"github.com/doopush/doopush/api/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AlertController 告警控制器
+type AlertController struct {
+	alertService *services.AlertService
+}
+
+// NewAlertController 创建告警控制器
+func NewAlertController() *AlertController {
+	return &AlertController{
+		alertService: services.NewAlertService(),
+	}
+}
+
+// AlertRuleRequest 创建或更新告警规则请求
+type AlertRuleRequest struct {
+	Name          string  `json:"name" binding:"required,max=100" example:"华为失败率过高"`
+	Type          string  `json:"type" binding:"required,oneof=failure_rate error_code callback_failure_rate cert_expiry" example:"failure_rate"`
+	Channel       string  `json:"channel,omitempty" binding:"omitempty,max=20" example:"huawei"`                   // 推送通道或厂商，为空表示分别评估每个通道
+	ErrorCode     string  `json:"error_code,omitempty" binding:"omitempty,max=200" example:"AUTHENTICATION_ERROR"` // 仅 error_code 类型，逗号分隔，默认检测鉴权失败
+	Threshold     float64 `json:"threshold" example:"20"`                                                          // 失败率百分比、出现次数或证书剩余天数
+	WindowMinutes int     `json:"window_minutes,omitempty" example:"15"`                                           // 默认 15 分钟
+	MinSamples    int     `json:"min_samples,omitempty" example:"20"`                                              // 失败率规则的最少样本数，默认 20
+	Status        *int    `json:"status,omitempty" binding:"omitempty,oneof=0 1" example:"1"`                      // 仅更新时生效
+}
+
+// AlertsResponse 告警记录列表响应
+type AlertsResponse struct {
+	Items []models.Alert `json:"items"`
+}
+
+// GetAlertRules 获取告警规则列表
+// @Summary 获取告警规则列表
+// @Description 获取应用的告警规则
+// @Tags 告警
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Success 200 {object} response.APIResponse{data=[]models.AlertRule} "告警规则列表"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Router /apps/{appId}/alert-rules [get]
+func (ctrl *AlertController) GetAlertRules(ctx *gin.Context) {
+	appID, ok := parseAppID(ctx)
+	if !ok {
+		return
+	}
+
+	rules, err := ctrl.alertService.GetRules(appID)
+	if err != nil {
+		response.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, rules)
+}
+
+// CreateAlertRule 创建告警规则
+// @Summary 创建告警规则
+// @Description 创建告警规则，调度器每分钟评估一次，触发和恢复时通过事件回调和收件箱通知应用所有者与开发者
+// @Tags 告警
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param request body AlertRuleRequest true "告警规则"
+// @Success 201 {object} response.APIResponse{data=models.AlertRule} "创建成功"
+// @Failure 400 {object} response.APIResponse "请求参数错误"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Router /apps/{appId}/alert-rules [post]
+func (ctrl *AlertController) CreateAlertRule(ctx *gin.Context) {
+	appID, ok := parseAppID(ctx)
+	if !ok {
+		return
+	}
+
+	var req AlertRuleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	rule, err := ctrl.alertService.CreateRule(appID, req.params())
+	if err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, response.APIResponse{
+		Code:    201,
+		Message: "告警规则创建成功",
+		Data:    rule,
+	})
+}
+
+// GetAlertRule 获取告警规则
+// @Summary 获取告警规则
+// @Description 获取告警规则详情
+// @Tags 告警
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param id path int true "告警规则ID"
+// @Success 200 {object} response.APIResponse{data=models.AlertRule} "告警规则"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Failure 404 {object} response.APIResponse "告警规则不存在"
+// @Router /apps/{appId}/alert-rules/{id} [get]
+func (ctrl *AlertController) GetAlertRule(ctx *gin.Context) {
+	appID, ruleID, ok := parseAlertRuleParams(ctx)
+	if !ok {
+		return
+	}
+
+	rule, err := ctrl.alertService.GetRule(appID, ruleID)
+	respondAlert(ctx, rule, err)
+}
+
+// UpdateAlertRule 更新告警规则
+// @Summary 更新告警规则
+// @Description 更新告警规则，status 为 0 时停用规则并恢复其正在触发的告警
+// @Tags 告警
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param id path int true "告警规则ID"
+// @Param request body AlertRuleRequest true "告警规则"
+// @Success 200 {object} response.APIResponse{data=models.AlertRule} "更新成功"
+// @Failure 400 {object} response.APIResponse "请求参数错误"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Failure 404 {object} response.APIResponse "告警规则不存在"
+// @Router /apps/{appId}/alert-rules/{id} [put]
+func (ctrl *AlertController) UpdateAlertRule(ctx *gin.Context) {
+	appID, ruleID, ok := parseAlertRuleParams(ctx)
+	if !ok {
+		return
+	}
+
+	var req AlertRuleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	rule, err := ctrl.alertService.UpdateRule(appID, ruleID, req.params(), req.Status)
+	respondAlert(ctx, rule, err)
+}
+
+// DeleteAlertRule 删除告警规则
+// @Summary 删除告警规则
+// @Description 删除告警规则，并恢复其正在触发的告警
+// @Tags 告警
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param id path int true "告警规则ID"
+// @Success 200 {object} response.APIResponse "删除成功"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Failure 404 {object} response.APIResponse "告警规则不存在"
+// @Router /apps/{appId}/alert-rules/{id} [delete]
+func (ctrl *AlertController) DeleteAlertRule(ctx *gin.Context) {
+	appID, ruleID, ok := parseAlertRuleParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.alertService.DeleteRule(appID, ruleID); err != nil {
+		respondAlert(ctx, nil, err)
+		return
+	}
+
+	response.Success(ctx, nil)
+}
+
+// GetAlerts 获取告警记录
+// @Summary 获取告警记录
+// @Description 获取应用的告警记录，可按状态和规则过滤
+// @Tags 告警
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param status query string false "状态 firing/resolved"
+// @Param rule_id query int false "告警规则ID"
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(20)
+// @Success 200 {object} response.APIResponse{data=AlertsResponse} "告警记录"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Router /apps/{appId}/alerts [get]
+func (ctrl *AlertController) GetAlerts(ctx *gin.Context) {
+	appID, ok := parseAppID(ctx)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	ruleID, _ := strconv.ParseUint(ctx.Query("rule_id"), 10, 32)
+
+	alerts, total, err := ctrl.alertService.GetAlerts(appID, ctx.Query("status"), uint(ruleID), page, pageSize)
+	if err != nil {
+		response.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, utils.NewPaginationResponse(page, pageSize, total, AlertsResponse{Items: alerts}))
+}
+
+// params 转换为服务层参数
+func (req AlertRuleRequest) params() services.AlertRuleParams {
+	return services.AlertRuleParams{
+		Name:          req.Name,
+		Type:          req.Type,
+		Channel:       req.Channel,
+		ErrorCode:     req.ErrorCode,
+		Threshold:     req.Threshold,
+		WindowMinutes: req.WindowMinutes,
+		MinSamples:    req.MinSamples,
+	}
+}
+
+// parseAlertRuleParams 解析应用ID和告警规则ID
+func parseAlertRuleParams(ctx *gin.Context) (uint, uint, bool) {
+	appID, ok := parseAppID(ctx)
+	if !ok {
+		return 0, 0, false
+	}
+	ruleID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(ctx, "无效的告警规则ID")
+		return 0, 0, false
+	}
+	return appID, uint(ruleID), true
+}
+
+// respondAlert 返回告警规则，按错误类型映射状态码
+func respondAlert(ctx *gin.Context, data interface{}, err error) {
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAlertRuleNotFound):
+			response.NotFound(ctx, err.Error())
+		default:
+			response.BadRequest(ctx, err.Error())
+		}
+		return
+	}
+	response.Success(ctx, data)
+}
@@ -41,6 +41,10 @@ type UpdateAppRequest struct {
 	Platform    string `json:"platform" binding:"omitempty,oneof=ios android both" example:"both"`
 	AppIcon     string `json:"app_icon" example:"/uploads/icons/app_icon_123456.png"`
 	Status      *int   `json:"status" binding:"omitempty,oneof=0 1" example:"1"`
+
+	// 静默时段（设备本地时间 HH:MM），两者同时传空字符串表示关闭
+	QuietStart *string `json:"quiet_hours_start" example:"22:00"`
+	QuietEnd   *string `json:"quiet_hours_end" example:"08:00"`
 }
 
 type UpdateAppMemberRequest struct {
@@ -50,6 +54,19 @@ type UpdateAppMemberRequest struct {
 // CreateAPIKeyRequest 创建API密钥请求
 type CreateAPIKeyRequest struct {
 	Name string `json:"name" binding:"required,max=100" example:"生产环境密钥"`
+	Mode string `json:"mode" binding:"omitempty,oneof=live test" example:"live"` // live=正式密钥（默认），test=测试密钥，推送进入沙箱不发往厂商
+	APIKeyPolicyRequest
+}
+
+// APIKeyPolicyRequest API密钥的权限范围、IP白名单和频率限制
+type APIKeyPolicyRequest struct {
+	Scopes       []string `json:"scopes" example:"device:register,stats:report"`        // 为空时为客户端 SDK 权限：device:register、stats:report
+	AllowedCIDRs []string `json:"allowed_cidrs" example:"203.0.113.0/24"`               // CIDR 或单个 IP，为空不限制
+	RateLimit    int      `json:"rate_limit" binding:"min=0,max=1000000" example:"600"` // 每分钟请求上限，0=不限制
+}
+
+func (r APIKeyPolicyRequest) options() services.APIKeyOptions {
+	return services.APIKeyOptions{Scopes: r.Scopes, AllowedCIDRs: r.AllowedCIDRs, RateLimit: r.RateLimit}
 }
 
 // CreateAPIKeyResponse 创建API密钥响应
@@ -305,6 +322,18 @@ func (a *AppController) UpdateApp(c *gin.Context) {
 	if req.Status != nil {
 		updates["status"] = *req.Status
 	}
+	if req.QuietStart != nil || req.QuietEnd != nil {
+		if req.QuietStart == nil || req.QuietEnd == nil {
+			response.BadRequest(c, "静默时段的开始和结束时间需同时设置")
+			return
+		}
+		if err := services.ValidateQuietHours(*req.QuietStart, *req.QuietEnd); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		updates["quiet_hours_start"] = *req.QuietStart
+		updates["quiet_hours_end"] = *req.QuietEnd
+	}
 
 	userID := c.GetUint("user_id")
 
@@ -436,7 +465,8 @@ func (a *AppController) GetAppAPIKeys(c *gin.Context) {
 
 // CreateAPIKey 创建API密钥
 // @Summary 创建API密钥
-// @Description 为应用创建新的API密钥
+// @Description 为应用创建新的API密钥。mode=test 创建 dp_test_ 测试密钥：用它注册的设备与正式设备隔离，发起的推送由沙箱记录而不发往厂商。
+// @Description scopes 可选 device:register、stats:report、push:send、push:broadcast、devices:read，未指定时为客户端 SDK 权限（不能发送推送）
 // @Tags 应用管理
 // @Accept json
 // @Produce json
@@ -462,10 +492,12 @@ func (a *AppController) CreateAPIKey(c *gin.Context) {
 	}
 
 	userID := c.GetUint("user_id")
-	keyInfo, apiKey, err := a.appService.CreateAPIKey(uint(appID), userID, req.Name)
+	keyInfo, apiKey, err := a.appService.CreateAPIKey(uint(appID), userID, req.Name, req.Mode == "test", req.options())
 	if err != nil {
 		if err.Error() == "无权限创建API密钥" {
 			response.Forbidden(c, err.Error())
+		} else if errors.Is(err, services.ErrInvalidAPIKeyOpts) {
+			response.BadRequest(c, err.Error())
 		} else {
 			response.InternalServerError(c, err.Error())
 		}
@@ -488,6 +520,9 @@ func (a *AppController) CreateAPIKey(c *gin.Context) {
 				"name":            req.Name,
 				"key_prefix":      keyInfo.KeyPrefix,
 				"key_suffix":      keyInfo.KeySuffix,
+				"scopes":          keyInfo.Scopes,
+				"allowed_cidrs":   keyInfo.AllowedCIDRs,
+				"rate_limit":      keyInfo.RateLimit,
 				"created_for_app": appID,
 			}, // API密钥创建信息（不包含完整密钥）
 			ipAddress,
@@ -509,6 +544,75 @@ func (a *AppController) CreateAPIKey(c *gin.Context) {
 	})
 }
 
+// UpdateAPIKey 修改API密钥
+// @Summary 修改API密钥
+// @Description 修改API密钥的权限范围、IP白名单和每分钟请求上限，立即生效
+// @Tags 应用管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param keyId path int true "密钥ID"
+// @Param request body APIKeyPolicyRequest true "密钥权限"
+// @Success 200 {object} response.APIResponse{data=models.AppAPIKey}
+// @Failure 400 {object} response.APIResponse
+// @Failure 401 {object} response.APIResponse
+// @Failure 403 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Router /apps/{appId}/api-keys/{keyId} [put]
+func (a *AppController) UpdateAPIKey(c *gin.Context) {
+	appID, err := strconv.ParseUint(c.Param("appId"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的应用ID")
+		return
+	}
+
+	keyID, err := strconv.ParseUint(c.Param("keyId"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的密钥ID")
+		return
+	}
+
+	var req APIKeyPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	key, err := a.appService.UpdateAPIKey(uint(appID), uint(keyID), userID, req.options())
+	if err != nil {
+		if err.Error() == "无权限修改API密钥" {
+			response.Forbidden(c, err.Error())
+		} else if err.Error() == "API密钥不存在" {
+			response.NotFound(c, err.Error())
+		} else if errors.Is(err, services.ErrInvalidAPIKeyOpts) {
+			response.BadRequest(c, err.Error())
+		} else {
+			response.InternalServerError(c, err.Error())
+		}
+		return
+	}
+
+	// 记录审计日志
+	go func() {
+		appIDUint := uint(appID)
+		a.auditService.LogActionWithBeforeAfter(
+			userID,
+			&appIDUint,
+			"update",
+			"api_key",
+			strconv.FormatUint(uint64(keyID), 10),
+			gin.H{"app_id": appID, "key_id": keyID},
+			gin.H{"scopes": key.Scopes, "allowed_cidrs": key.AllowedCIDRs, "rate_limit": key.RateLimit},
+			c.ClientIP(),
+			c.GetHeader("User-Agent"),
+		)
+	}()
+
+	response.Success(c, key)
+}
+
 // DeleteAPIKey 删除API密钥
 // @Summary 删除API密钥
 // @Description 删除应用的API密钥
@@ -78,6 +78,7 @@ func getActionLabel(action string) string {
 		"push":   "推送",
 		"login":  "登录",
 		"logout": "登出",
+		"reveal": "查看明文",
 	}
 	if label, exists := labels[action]; exists {
 		return label
@@ -83,12 +83,12 @@ func (r *CallbackController) processVendorCallback(c *gin.Context, vendor string
 	}
 	var raw json.RawMessage
 	if err := c.ShouldBindJSON(&raw); err != nil {
-		logger.Error("回执 body 解析失败", vendor, err)
+		logger.WarnContext(c.Request.Context(), "回执 body 解析失败", "channel", vendor, "error", err)
 		response.Csuccess(c, "0")
 		return
 	}
 	if err := handler(r.callbackService, raw); err != nil {
-		logger.Error("处理回执失败", vendor, err)
+		logger.ErrorContext(c.Request.Context(), "处理回执失败", "channel", vendor, "error", err)
 		response.Csuccess(c, "0")
 		return
 	}
@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/doopush/doopush/api/internal/middleware"
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/internal/services"
+	"github.com/doopush/doopush/api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// CampaignController 分批发送任务控制器
+type CampaignController struct {
+	campaignService *services.CampaignService
+}
+
+// NewCampaignController 创建分批发送任务控制器
+func NewCampaignController() *CampaignController {
+	return &CampaignController{
+		campaignService: services.NewCampaignService(),
+	}
+}
+
+// PromoteCampaignRequest 全量发送请求
+type PromoteCampaignRequest struct {
+	Variant string `json:"variant,omitempty" binding:"omitempty,max=16" example:"B"` // A/B 测试的胜出变体，不指定时按胜出指标选出
+}
+
+// parseCampaignParams 解析应用ID、任务ID和当前用户
+func parseCampaignParams(ctx *gin.Context) (appID, campaignID, userID uint, ok bool) {
+	app, err := strconv.ParseUint(ctx.Param("appId"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "无效的应用ID")
+		return 0, 0, 0, false
+	}
+	campaign, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "无效的任务ID")
+		return 0, 0, 0, false
+	}
+	userID = ctx.GetUint("user_id")
+	if userID == 0 {
+		response.Unauthorized(ctx, "用户信息获取失败")
+		return 0, 0, 0, false
+	}
+	return uint(app), uint(campaign), userID, true
+}
+
+// respondCampaign 输出任务操作结果，权限错误返回 403
+func respondCampaign(ctx *gin.Context, campaign interface{}, err error) {
+	if errors.Is(err, services.ErrCampaignBroadcastScope) {
+		response.Forbidden(ctx, err.Error())
+		return
+	}
+	if err != nil {
+		switch err.Error() {
+		case "无权限操作分批发送任务":
+			response.Forbidden(ctx, err.Error())
+		case "分批发送任务不存在":
+			response.NotFound(ctx, err.Error())
+		default:
+			response.BadRequest(ctx, err.Error())
+		}
+		return
+	}
+	response.Success(ctx, campaign)
+}
+
+// GetCampaign 获取分批发送任务
+// @Summary 获取分批发送任务
+// @Description 获取分批发送任务的状态、灰度阶段和各状态推送数量
+// @Tags 分批发送
+// @Accept json
+// @Produce json
+// @Security BearerAuth || ApiKeyAuth
+// @Param appId path int true "应用ID"
+// @Param id path int true "任务ID"
+// @Success 200 {object} response.APIResponse{data=services.CampaignProgress} "任务进度"
+// @Failure 400 {object} response.APIResponse "请求参数错误"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Failure 404 {object} response.APIResponse "任务不存在"
+// @Router /apps/{appId}/push/campaigns/{id} [get]
+func (ctrl *CampaignController) GetCampaign(ctx *gin.Context) {
+	appID, campaignID, userID, ok := parseCampaignParams(ctx)
+	if !ok {
+		return
+	}
+	progress, err := ctrl.campaignService.GetCampaign(appID, userID, campaignID)
+	respondCampaign(ctx, progress, err)
+}
+
+// PauseCampaign 暂停分批发送任务
+// @Summary 暂停分批发送任务
+// @Description 暂停进行中的任务，尚未发出的推送保留到恢复或取消
+// @Tags 分批发送
+// @Accept json
+// @Produce json
+// @Security BearerAuth || ApiKeyAuth
+// @Param appId path int true "应用ID"
+// @Param id path int true "任务ID"
+// @Success 200 {object} response.APIResponse{data=models.PushCampaign} "暂停成功"
+// @Failure 400 {object} response.APIResponse "任务不在进行中"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Failure 404 {object} response.APIResponse "任务不存在"
+// @Router /apps/{appId}/push/campaigns/{id}/pause [post]
+func (ctrl *CampaignController) PauseCampaign(ctx *gin.Context) {
+	ctrl.changeState(ctx, ctrl.campaignService.PauseCampaign)
+}
+
+// ResumeCampaign 恢复分批发送任务
+// @Summary 恢复分批发送任务
+// @Description 恢复已暂停的任务。因灰度指标未达标而暂停的任务恢复后不再自动全量，需调用全量接口。面向全部设备的任务使用 API 密钥时需要 push:broadcast 权限
+// @Tags 分批发送
+// @Accept json
+// @Produce json
+// @Security BearerAuth || ApiKeyAuth
+// @Param appId path int true "应用ID"
+// @Param id path int true "任务ID"
+// @Success 200 {object} response.APIResponse{data=models.PushCampaign} "恢复成功"
+// @Failure 400 {object} response.APIResponse "任务未暂停"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Failure 404 {object} response.APIResponse "任务不存在"
+// @Router /apps/{appId}/push/campaigns/{id}/resume [post]
+func (ctrl *CampaignController) ResumeCampaign(ctx *gin.Context) {
+	allowBroadcast := middleware.APIKeyAllows(ctx, models.APIKeyScopePushBroadcast)
+	ctrl.changeState(ctx, func(appID, userID, campaignID uint) (*models.PushCampaign, error) {
+		return ctrl.campaignService.ResumeCampaign(appID, userID, campaignID, allowBroadcast)
+	})
+}
+
+// PromoteCampaign 灰度任务全量发送
+// @Summary 灰度任务全量发送
+// @Description 将灰度阶段的任务切换为全量，发送剩余设备。A/B 测试发送胜出变体，可在请求中指定。面向全部设备的任务使用 API 密钥时需要 push:broadcast 权限
+// @Tags 分批发送
+// @Accept json
+// @Produce json
+// @Security BearerAuth || ApiKeyAuth
+// @Param appId path int true "应用ID"
+// @Param id path int true "任务ID"
+// @Param request body PromoteCampaignRequest false "胜出变体"
+// @Success 200 {object} response.APIResponse{data=models.PushCampaign} "已全量"
+// @Failure 400 {object} response.APIResponse "任务不在灰度阶段"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Failure 404 {object} response.APIResponse "任务不存在"
+// @Router /apps/{appId}/push/campaigns/{id}/promote [post]
+func (ctrl *CampaignController) PromoteCampaign(ctx *gin.Context) {
+	appID, campaignID, userID, ok := parseCampaignParams(ctx)
+	if !ok {
+		return
+	}
+
+	var req PromoteCampaignRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(ctx, "请求参数错误: "+err.Error())
+			return
+		}
+	}
+
+	campaign, err := ctrl.campaignService.PromoteCampaign(appID, userID, campaignID, req.Variant,
+		middleware.APIKeyAllows(ctx, models.APIKeyScopePushBroadcast))
+	respondCampaign(ctx, campaign, err)
+}
+
+// CancelCampaign 取消分批发送任务
+// @Summary 取消分批发送任务
+// @Description 取消进行中或已暂停的任务，尚未发出的推送标记为 cancelled
+// @Tags 分批发送
+// @Accept json
+// @Produce json
+// @Security BearerAuth || ApiKeyAuth
+// @Param appId path int true "应用ID"
+// @Param id path int true "任务ID"
+// @Success 200 {object} response.APIResponse{data=models.PushCampaign} "取消成功"
+// @Failure 400 {object} response.APIResponse "任务已结束"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Failure 404 {object} response.APIResponse "任务不存在"
+// @Router /apps/{appId}/push/campaigns/{id}/cancel [post]
+func (ctrl *CampaignController) CancelCampaign(ctx *gin.Context) {
+	ctrl.changeState(ctx, ctrl.campaignService.CancelCampaign)
+}
+
+// changeState 执行任务状态变更
+func (ctrl *CampaignController) changeState(ctx *gin.Context, action func(appID, userID, campaignID uint) (*models.PushCampaign, error)) {
+	appID, campaignID, userID, ok := parseCampaignParams(ctx)
+	if !ok {
+		return
+	}
+	campaign, err := action(appID, userID, campaignID)
+	respondCampaign(ctx, campaign, err)
+}
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/doopush/doopush/api/internal/database"
+	"github.com/doopush/doopush/api/internal/middleware"
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/internal/testutil"
+	"github.com/gin-gonic/gin"
+)
+
+func TestCampaignBroadcastScope(t *testing.T) {
+	testutil.SetupDB(t)
+	app := testutil.CreateApp(t, 1, "a")
+	testutil.CreateAPIKey(t, app.ID, "dp_live_send_key", models.APIKeyScopePushSend)
+	testutil.CreateAPIKey(t, app.ID, "dp_live_broadcast_key", models.APIKeyScopePushSend, models.APIKeyScopePushBroadcast)
+	// 任务处于全量阶段且正在发送，通过权限检查后恢复和全量都会因状态不符返回 400，不会启动发送协程
+	broadcast := &models.PushCampaign{AppID: app.ID, UserID: 1, Status: "running", Stage: "full", TargetType: "all"}
+	targeted := &models.PushCampaign{AppID: app.ID, UserID: 1, Status: "running", Stage: "full", TargetType: "devices"}
+	for _, campaign := range []*models.PushCampaign{broadcast, targeted} {
+		if err := database.DB.Create(campaign).Error; err != nil {
+			t.Fatalf("create campaign: %v", err)
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ctrl := NewCampaignController()
+	pushSend := middleware.DualAuth(models.APIKeyScopePushSend)
+	r.POST("/apps/:appId/push/campaigns/:id/resume", pushSend, ctrl.ResumeCampaign)
+	r.POST("/apps/:appId/push/campaigns/:id/promote", pushSend, ctrl.PromoteCampaign)
+
+	cases := []struct {
+		key      string
+		campaign *models.PushCampaign
+		want     int
+	}{
+		{"dp_live_send_key", broadcast, http.StatusForbidden},
+		{"dp_live_send_key", targeted, http.StatusBadRequest},
+		{"dp_live_broadcast_key", broadcast, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		for _, action := range []string{"resume", "promote"} {
+			path := fmt.Sprintf("/apps/%d/push/campaigns/%d/%s", app.ID, tc.campaign.ID, action)
+			w := doAPIKeyRequest(r, http.MethodPost, path, tc.key)
+			if w.Code != tc.want {
+				t.Errorf("%s %s target=%s: status = %d, want %d, body = %s", tc.key, action, tc.campaign.TargetType, w.Code, tc.want, w.Body)
+			}
+		}
+	}
+}
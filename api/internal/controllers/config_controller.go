@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"strconv"
 	"time"
 
@@ -114,8 +115,10 @@ func (c *ConfigController) SetAppConfig(ctx *gin.Context) {
 			response.InternalServerError(ctx, "配置更新失败")
 			return
 		}
+		services.NewConfigHealthService().Reset(appConfig.ID)
 	}
 
+	appConfig.Config = maskConfigSecrets(appConfig.Config)
 	response.Success(ctx, appConfig)
 }
 
@@ -152,56 +155,14 @@ func (c *ConfigController) GetAppConfigs(ctx *gin.Context) {
 	}
 
 	var configs []models.AppConfig
-	if err := database.DB.Where("app_id = ?", appID).Find(&configs).Error; err != nil {
+	if err := database.DB.Preload("Health").Where("app_id = ?", appID).Find(&configs).Error; err != nil {
 		response.InternalServerError(ctx, "获取配置失败")
 		return
 	}
 
-	// 对于敏感配置，隐藏相关的密钥字段
+	// 隐藏密钥字段，所有者可通过 reveal 接口查看
 	for i := range configs {
-		var configMap map[string]interface{}
-		if err := json.Unmarshal([]byte(configs[i].Config), &configMap); err == nil {
-			modified := false
-
-			// iOS配置：隐藏private_key
-			if configs[i].Platform == "ios" {
-				if _, exists := configMap["private_key"]; exists {
-					configMap["private_key"] = "[REDACTED]"
-					modified = true
-				}
-			}
-
-			// Android配置：隐藏各种secret字段
-			if configs[i].Platform == "android" {
-				// FCM: 隐藏service_account_key
-				if _, exists := configMap["service_account_key"]; exists {
-					configMap["service_account_key"] = "[REDACTED]"
-					modified = true
-				}
-				// 华为、小米、VIVO等：隐藏app_secret
-				if _, exists := configMap["app_secret"]; exists {
-					configMap["app_secret"] = "[REDACTED]"
-					modified = true
-				}
-				// 荣耀等：隐藏client_secret
-				if _, exists := configMap["client_secret"]; exists {
-					configMap["client_secret"] = "[REDACTED]"
-					modified = true
-				}
-				// OPPO：隐藏master_secret
-				if _, exists := configMap["master_secret"]; exists {
-					configMap["master_secret"] = "[REDACTED]"
-					modified = true
-				}
-			}
-
-			// 如果有修改，更新配置字符串
-			if modified {
-				if configBytes, err := json.Marshal(configMap); err == nil {
-					configs[i].Config = string(configBytes)
-				}
-			}
-		}
+		configs[i].Config = maskConfigSecrets(configs[i].Config)
 	}
 
 	response.Success(ctx, configs)
@@ -406,7 +367,7 @@ func (c *ConfigController) UpdateAppConfig(ctx *gin.Context) {
 
 	// 过滤隐藏字段标记，保持原有值
 	for key, value := range newConfigMap {
-		if strVal, ok := value.(string); ok && strVal == "[REDACTED]" {
+		if strVal, ok := value.(string); ok && strVal == redactedMarker {
 			// 如果新值是隐藏标记，使用原始值
 			if originalVal, exists := originalConfigMap[key]; exists {
 				newConfigMap[key] = originalVal
@@ -427,7 +388,77 @@ func (c *ConfigController) UpdateAppConfig(ctx *gin.Context) {
 		response.InternalServerError(ctx, "配置更新失败")
 		return
 	}
+	services.NewConfigHealthService().Reset(appConfig.ID)
 
+	appConfig.Config = maskConfigSecrets(appConfig.Config)
+	response.Success(ctx, appConfig)
+}
+
+// UpdateCategoryMapRequest 更新消息分类映射请求
+type UpdateCategoryMapRequest struct {
+	CategoryMap push.CategoryMapping `json:"category_map" binding:"required"`
+}
+
+// UpdateCategoryMap 更新消息分类映射
+// @Summary 更新消息分类映射
+// @Description 配置统一消息分类（transactional/marketing/im/account）到该通道厂商字段的映射，如华为 category、vivo classification、OPPO/小米 channel_id
+// @Tags 应用配置
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param configId path int true "配置ID"
+// @Param request body UpdateCategoryMapRequest true "分类映射"
+// @Success 200 {object} response.APIResponse{data=models.AppConfig}
+// @Failure 400 {object} response.APIResponse
+// @Failure 401 {object} response.APIResponse
+// @Failure 403 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Router /apps/{appId}/config/{configId}/categories [put]
+func (c *ConfigController) UpdateCategoryMap(ctx *gin.Context) {
+	appID, err := strconv.ParseUint(ctx.Param("appId"), 10, 32)
+	if err != nil {
+		response.BadRequest(ctx, "无效的应用ID")
+		return
+	}
+
+	configID, err := strconv.ParseUint(ctx.Param("configId"), 10, 32)
+	if err != nil {
+		response.BadRequest(ctx, "无效的配置ID")
+		return
+	}
+
+	var req UpdateCategoryMapRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+	for category := range req.CategoryMap {
+		if !push.IsValidCategory(category) {
+			response.BadRequest(ctx, "不支持的消息分类: "+category)
+			return
+		}
+	}
+
+	var appConfig models.AppConfig
+	if err := database.DB.Where("id = ? AND app_id = ?", configID, appID).First(&appConfig).Error; err != nil {
+		response.NotFound(ctx, "配置不存在")
+		return
+	}
+
+	mapBytes, err := json.Marshal(req.CategoryMap)
+	if err != nil {
+		response.InternalServerError(ctx, "分类映射序列化失败")
+		return
+	}
+	appConfig.CategoryMap = string(mapBytes)
+
+	if err := database.DB.Model(&appConfig).Update("category_map", appConfig.CategoryMap).Error; err != nil {
+		response.InternalServerError(ctx, "分类映射更新失败")
+		return
+	}
+
+	appConfig.Config = maskConfigSecrets(appConfig.Config)
 	response.Success(ctx, appConfig)
 }
 
@@ -485,6 +516,118 @@ func (c *ConfigController) DeleteAppConfig(ctx *gin.Context) {
 		response.InternalServerError(ctx, "删除配置失败")
 		return
 	}
+	services.NewConfigHealthService().Reset(appConfig.ID)
 
 	response.Success(ctx, gin.H{"message": "配置删除成功"})
 }
+
+// RevealAppConfig 查看应用配置明文
+// @Summary 查看应用配置明文
+// @Description 返回未隐藏密钥字段的推送配置，仅应用所有者可调用，每次调用都会记录审计日志
+// @Tags 应用配置
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param configId path int true "配置ID"
+// @Success 200 {object} response.APIResponse{data=models.AppConfig}
+// @Failure 400 {object} response.APIResponse
+// @Failure 401 {object} response.APIResponse
+// @Failure 403 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Router /apps/{appId}/config/{configId}/reveal [post]
+func (c *ConfigController) RevealAppConfig(ctx *gin.Context) {
+	appID, err := strconv.ParseUint(ctx.Param("appId"), 10, 32)
+	if err != nil {
+		response.BadRequest(ctx, "无效的应用ID")
+		return
+	}
+
+	configID, err := strconv.ParseUint(ctx.Param("configId"), 10, 32)
+	if err != nil {
+		response.BadRequest(ctx, "无效的配置ID")
+		return
+	}
+
+	var appConfig models.AppConfig
+	if err := database.DB.Where("id = ? AND app_id = ?", configID, appID).First(&appConfig).Error; err != nil {
+		response.NotFound(ctx, "配置不存在")
+		return
+	}
+
+	response.Success(ctx, appConfig)
+}
+
+// CheckAppConfigHealth 检查推送配置凭据
+// @Summary 检查推送配置凭据
+// @Description 立即向厂商鉴权接口检查推送配置中的凭据（获取 access token、APNs 鉴权、证书有效期），不需要设备Token。后台任务也会定期检查，结果随配置列表的 health 字段返回
+// @Tags 应用配置
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param configId path int true "配置ID"
+// @Success 200 {object} response.APIResponse{data=models.AppConfigHealth}
+// @Failure 400 {object} response.APIResponse
+// @Failure 401 {object} response.APIResponse
+// @Failure 403 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Router /apps/{appId}/config/{configId}/health-check [post]
+func (c *ConfigController) CheckAppConfigHealth(ctx *gin.Context) {
+	appID, err := strconv.ParseUint(ctx.Param("appId"), 10, 32)
+	if err != nil {
+		response.BadRequest(ctx, "无效的应用ID")
+		return
+	}
+
+	configID, err := strconv.ParseUint(ctx.Param("configId"), 10, 32)
+	if err != nil {
+		response.BadRequest(ctx, "无效的配置ID")
+		return
+	}
+
+	health, err := services.NewConfigHealthService().CheckConfig(uint(appID), uint(configID))
+	if err != nil {
+		if errors.Is(err, services.ErrAppConfigNotFound) {
+			response.NotFound(ctx, err.Error())
+			return
+		}
+		response.InternalServerError(ctx, "凭据检查失败")
+		return
+	}
+
+	response.Success(ctx, health)
+}
+
+// redactedMarker 隐藏的密钥字段值，更新配置时传回该值表示保持原值
+const redactedMarker = "[REDACTED]"
+
+// secretConfigFields 推送配置中需要隐藏的密钥字段
+var secretConfigFields = []string{
+	"private_key", "auth_key_p8", "key_pem", "cert_p12", "cert_passwd", // APNs
+	"service_account_key", // FCM
+	"app_secret",          // 华为、小米、vivo 等
+	"client_secret",       // 荣耀
+	"master_secret",       // OPPO
+}
+
+// maskConfigSecrets 将推送配置中的密钥字段替换为隐藏标记，配置无法解析时整体隐藏
+func maskConfigSecrets(config string) string {
+	if config == "" {
+		return config
+	}
+	var configMap map[string]interface{}
+	if err := json.Unmarshal([]byte(config), &configMap); err != nil {
+		return "{}"
+	}
+	for _, field := range secretConfigFields {
+		if value, exists := configMap[field]; exists && value != "" {
+			configMap[field] = redactedMarker
+		}
+	}
+	masked, err := json.Marshal(configMap)
+	if err != nil {
+		return "{}"
+	}
+	return string(masked)
+}
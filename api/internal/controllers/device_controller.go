@@ -1,11 +1,14 @@
 package controllers
 
 import (
-	"log"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/doopush/doopush/api/internal/models"
 	"github.com/doopush/doopush/api/internal/services"
+	"github.com/doopush/doopush/api/pkg/logger"
 	"github.com/doopush/doopush/api/pkg/response"
 	"github.com/doopush/doopush/api/pkg/utils"
 	"github.com/gin-gonic/gin"
@@ -38,6 +41,8 @@ type RegisterDeviceRequest struct {
 	SystemVer  string                   `json:"system_version" example:"17.0"`
 	AppVersion string                   `json:"app_version" example:"1.0.0"`
 	UserAgent  string                   `json:"user_agent" example:"MyApp/1.0.0 (iOS 17.0)"`
+	Locale     string                   `json:"locale" binding:"max=20" example:"zh-CN"`
+	Timezone   string                   `json:"timezone" binding:"max=64" example:"Asia/Shanghai"` // IANA 时区，用于本地时间投递和静默时段
 	Tags       []services.DeviceTagItem `json:"tags" example:"[{\"tag_name\":\"user_type\",\"tag_value\":\"vip\"},{\"tag_name\":\"version\",\"tag_value\":\"1.0\"}]"`
 }
 
@@ -51,7 +56,7 @@ type DeviceListResponse struct {
 
 // RegisterDevice 注册设备
 // @Summary 注册设备
-// @Description 注册设备以接收推送通知。需要验证API Key属于指定应用且bundle_id与应用包名匹配。可以在注册时同时设置设备标签
+// @Description 注册设备以接收推送通知。需要验证API Key属于指定应用且bundle_id与应用包名匹配。可以在注册时同时设置设备标签。使用测试密钥（dp_test_）注册的设备为沙箱设备，只接收测试密钥发起的推送
 // @Tags 设备管理
 // @Accept json
 // @Produce json
@@ -61,6 +66,7 @@ type DeviceListResponse struct {
 // @Success 201 {object} response.APIResponse "注册成功，返回设备信息"
 // @Failure 400 {object} response.APIResponse "请求参数错误"
 // @Failure 401 {object} response.APIResponse "API密钥无效或与应用不匹配"
+// @Failure 409 {object} response.APIResponse "设备已用另一种模式（测试/正式）的密钥注册"
 // @Failure 422 {object} response.APIResponse "Bundle ID与应用包名不匹配"
 // @Router /apps/{appId}/devices [post]
 func (d *DeviceController) RegisterDevice(c *gin.Context) {
@@ -75,6 +81,12 @@ func (d *DeviceController) RegisterDevice(c *gin.Context) {
 		response.BadRequest(c, "请求参数错误: "+err.Error())
 		return
 	}
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			response.BadRequest(c, "无效的时区")
+			return
+		}
+	}
 
 	device, err := d.deviceService.RegisterDevice(
 		uint(appID),
@@ -88,18 +100,28 @@ func (d *DeviceController) RegisterDevice(c *gin.Context) {
 		req.SystemVer,
 		req.AppVersion,
 		req.UserAgent,
+		c.GetBool("sandbox"),
 	)
 	if err != nil {
+		if errors.Is(err, services.ErrDeviceModeMismatch) {
+			response.Error(c, http.StatusConflict, err.Error())
+			return
+		}
 		response.Error(c, http.StatusUnprocessableEntity, err.Error())
 		return
 	}
 
+	// 记录设备语言和时区
+	if err := d.deviceService.UpdatePreferences(device, req.Locale, req.Timezone); err != nil {
+		logger.WarnContext(c.Request.Context(), "设备注册成功但语言和时区更新失败", "app_id", appID, "device_id", device.ID, "error", err)
+	}
+
 	// 处理设备标签
 	if len(req.Tags) > 0 {
 		err := d.deviceService.UpdateDeviceTags(uint(appID), req.Token, req.Tags)
 		if err != nil {
 			// 标签处理失败不应该影响设备注册，只记录日志
-			log.Printf("设备注册成功但标签处理失败: %v", err)
+			logger.WarnContext(c.Request.Context(), "设备注册成功但标签处理失败", "app_id", appID, "device_id", device.ID, "error", err)
 		}
 	}
 
@@ -110,9 +132,316 @@ func (d *DeviceController) RegisterDevice(c *gin.Context) {
 	})
 }
 
+// sdkDevice 获取 DeviceTokenAuth 中间件识别出的调用方设备
+func sdkDevice(c *gin.Context) *models.Device {
+	device, _ := c.Get("device")
+	return device.(*models.Device)
+}
+
+// UnregisterDevice 注销当前设备
+// @Summary 注销当前设备
+// @Description 客户端SDK在用户退出登录或关闭推送时注销当前设备：停用设备并解除用户绑定，重新注册后恢复
+// @Tags 客户端SDK
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param appId path int true "应用ID"
+// @Param X-Device-Token header string true "当前设备推送Token"
+// @Success 200 {object} response.APIResponse "注销成功"
+// @Failure 401 {object} response.APIResponse "API密钥无效或缺少设备Token"
+// @Failure 404 {object} response.APIResponse "设备不存在"
+// @Router /apps/{appId}/device [delete]
+func (d *DeviceController) UnregisterDevice(c *gin.Context) {
+	if err := d.deviceService.UnregisterDevice(sdkDevice(c)); err != nil {
+		response.InternalServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// SDKDeviceTagsRequest 客户端设置标签请求
+type SDKDeviceTagsRequest struct {
+	Tags []AddDeviceTagRequest `json:"tags" binding:"required,min=1,max=50,dive"`
+}
+
+// GetOwnTags 获取当前设备标签
+// @Summary 获取当前设备标签
+// @Description 客户端SDK获取当前设备的标签列表
+// @Tags 客户端SDK
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param appId path int true "应用ID"
+// @Param X-Device-Token header string true "当前设备推送Token"
+// @Success 200 {object} response.APIResponse{data=[]models.DeviceTag} "设备标签列表"
+// @Failure 401 {object} response.APIResponse "API密钥无效或缺少设备Token"
+// @Failure 404 {object} response.APIResponse "设备不存在"
+// @Router /apps/{appId}/device/tags [get]
+func (d *DeviceController) GetOwnTags(c *gin.Context) {
+	device := sdkDevice(c)
+	tags, err := services.NewTagService().GetDeviceTags(device.AppID, device.Token)
+	if err != nil {
+		response.InternalServerError(c, "获取设备标签失败")
+		return
+	}
+
+	response.Success(c, tags)
+}
+
+// AddOwnTags 为当前设备添加标签
+// @Summary 为当前设备添加标签
+// @Description 客户端SDK为当前设备添加标签，已存在的标签不会重复添加
+// @Tags 客户端SDK
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param appId path int true "应用ID"
+// @Param X-Device-Token header string true "当前设备推送Token"
+// @Param request body SDKDeviceTagsRequest true "标签列表"
+// @Success 200 {object} response.APIResponse{data=[]models.DeviceTag} "添加后的设备标签列表"
+// @Failure 400 {object} response.APIResponse "请求参数错误"
+// @Failure 401 {object} response.APIResponse "API密钥无效或缺少设备Token"
+// @Failure 404 {object} response.APIResponse "设备不存在"
+// @Router /apps/{appId}/device/tags [post]
+func (d *DeviceController) AddOwnTags(c *gin.Context) {
+	var req SDKDeviceTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	device := sdkDevice(c)
+	tagService := services.NewTagService()
+	for _, tag := range req.Tags {
+		if _, err := tagService.AddDeviceTag(device.AppID, device.Token, tag.TagName, tag.TagValue); err != nil {
+			response.InternalServerError(c, err.Error())
+			return
+		}
+	}
+
+	tags, err := tagService.GetDeviceTags(device.AppID, device.Token)
+	if err != nil {
+		response.InternalServerError(c, "获取设备标签失败")
+		return
+	}
+	response.Success(c, tags)
+}
+
+// DeleteOwnTag 删除当前设备标签
+// @Summary 删除当前设备标签
+// @Description 客户端SDK删除当前设备指定名称的标签
+// @Tags 客户端SDK
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param appId path int true "应用ID"
+// @Param tagName path string true "标签名称"
+// @Param X-Device-Token header string true "当前设备推送Token"
+// @Success 200 {object} response.APIResponse "删除成功"
+// @Failure 400 {object} response.APIResponse "标签不存在"
+// @Failure 401 {object} response.APIResponse "API密钥无效或缺少设备Token"
+// @Failure 404 {object} response.APIResponse "设备不存在"
+// @Router /apps/{appId}/device/tags/{tagName} [delete]
+func (d *DeviceController) DeleteOwnTag(c *gin.Context) {
+	device := sdkDevice(c)
+	if err := services.NewTagService().DeleteDeviceTag(device.AppID, device.Token, c.Param("tagName")); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// BindAliasRequest 绑定外部用户ID请求
+type BindAliasRequest struct {
+	ExternalUserID string `json:"external_user_id" binding:"required,max=128" example:"12345"`
+}
+
+// BindAlias 绑定外部用户ID
+// @Summary 绑定外部用户ID
+// @Description 客户端SDK在用户登录后将业务系统的用户ID绑定到当前设备，一个用户可绑定多台设备，设备已绑定其他用户时直接改绑
+// @Tags 客户端SDK
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param appId path int true "应用ID"
+// @Param X-Device-Token header string true "当前设备推送Token"
+// @Param request body BindAliasRequest true "绑定信息"
+// @Success 200 {object} response.APIResponse "绑定成功"
+// @Failure 400 {object} response.APIResponse "请求参数错误"
+// @Failure 401 {object} response.APIResponse "API密钥无效或缺少设备Token"
+// @Failure 404 {object} response.APIResponse "设备不存在"
+// @Router /apps/{appId}/device/alias [put]
+func (d *DeviceController) BindAlias(c *gin.Context) {
+	var req BindAliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if err := d.deviceService.BindAlias(sdkDevice(c), req.ExternalUserID); err != nil {
+		response.InternalServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"external_user_id": req.ExternalUserID})
+}
+
+// UnbindAlias 解除外部用户ID绑定
+// @Summary 解除外部用户ID绑定
+// @Description 客户端SDK在用户退出登录时解除当前设备与用户的绑定，之后按用户推送不再送达该设备
+// @Tags 客户端SDK
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param appId path int true "应用ID"
+// @Param X-Device-Token header string true "当前设备推送Token"
+// @Success 200 {object} response.APIResponse "解除成功"
+// @Failure 401 {object} response.APIResponse "API密钥无效或缺少设备Token"
+// @Failure 404 {object} response.APIResponse "设备不存在"
+// @Router /apps/{appId}/device/alias [delete]
+func (d *DeviceController) UnbindAlias(c *gin.Context) {
+	if err := d.deviceService.UnbindAlias(sdkDevice(c)); err != nil {
+		response.InternalServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// UpdatePreferencesRequest 更新设备偏好请求
+type UpdatePreferencesRequest struct {
+	Locale   string `json:"locale,omitempty" binding:"omitempty,max=20" example:"zh-CN"`
+	Timezone string `json:"timezone,omitempty" binding:"omitempty,max=64" example:"Asia/Shanghai"` // IANA 时区名
+}
+
+// UpdatePreferences 更新当前设备语言和时区
+// @Summary 更新当前设备语言和时区
+// @Description 客户端SDK上报当前设备的语言和时区，未提供的字段保持不变
+// @Tags 客户端SDK
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param appId path int true "应用ID"
+// @Param X-Device-Token header string true "当前设备推送Token"
+// @Param request body UpdatePreferencesRequest true "语言和时区"
+// @Success 200 {object} response.APIResponse "更新成功"
+// @Failure 400 {object} response.APIResponse "请求参数错误或时区无效"
+// @Failure 401 {object} response.APIResponse "API密钥无效或缺少设备Token"
+// @Failure 404 {object} response.APIResponse "设备不存在"
+// @Router /apps/{appId}/device/preferences [put]
+func (d *DeviceController) UpdatePreferences(c *gin.Context) {
+	var req UpdatePreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if err := d.deviceService.UpdatePreferences(sdkDevice(c), req.Locale, req.Timezone); err != nil {
+		if err.Error() == "无效的时区" {
+			response.BadRequest(c, err.Error())
+		} else {
+			response.InternalServerError(c, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// UpdateCategoryPreferencesRequest 更新消息分类订阅请求
+type UpdateCategoryPreferencesRequest struct {
+	Categories map[string]bool `json:"categories" binding:"required" example:"marketing:false"` // 分类 -> 是否接收
+}
+
+// UpdateCategoryPreferences 更新当前设备的消息分类订阅
+// @Summary 更新当前设备的消息分类订阅
+// @Description 客户端SDK按消息分类（transactional/marketing/im/account）订阅或退订推送，指定分类的推送将不再发往已退订的设备
+// @Tags 客户端SDK
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param appId path int true "应用ID"
+// @Param X-Device-Token header string true "当前设备推送Token"
+// @Param request body UpdateCategoryPreferencesRequest true "分类订阅状态"
+// @Success 200 {object} response.APIResponse{data=object} "更新成功，返回退订的分类列表"
+// @Failure 400 {object} response.APIResponse "请求参数错误或分类不支持"
+// @Failure 401 {object} response.APIResponse "API密钥无效或缺少设备Token"
+// @Failure 404 {object} response.APIResponse "设备不存在"
+// @Router /apps/{appId}/device/categories [put]
+func (d *DeviceController) UpdateCategoryPreferences(c *gin.Context) {
+	var req UpdateCategoryPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	optOut, err := d.deviceService.UpdateCategoryPreferences(sdkDevice(c), req.Categories)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"opt_out_categories": optOut})
+}
+
+// UpdateBadgeRequest 客户端更新角标请求
+type UpdateBadgeRequest struct {
+	Action string `json:"action" binding:"required,oneof=reset decrement set" example:"decrement"` // reset=清零，decrement=减少，set=设置
+	Count  int    `json:"count,omitempty" binding:"omitempty,min=0" example:"1"`                   // decrement 的减少数量（默认1）或 set 的目标值
+}
+
+// UpdateBadge 更新当前设备角标计数
+// @Summary 更新当前设备角标计数
+// @Description 客户端SDK在用户阅读消息后清零或减少服务端角标计数，后续推送将基于该计数计算角标
+// @Tags 客户端SDK
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param appId path int true "应用ID"
+// @Param X-Device-Token header string true "当前设备推送Token"
+// @Param request body UpdateBadgeRequest true "角标操作"
+// @Success 200 {object} response.APIResponse{data=object} "更新成功，返回最新角标计数"
+// @Failure 400 {object} response.APIResponse "请求参数错误"
+// @Failure 401 {object} response.APIResponse "API密钥无效或缺少设备Token"
+// @Failure 404 {object} response.APIResponse "设备不存在"
+// @Router /apps/{appId}/device/badge [post]
+func (d *DeviceController) UpdateBadge(c *gin.Context) {
+	var req UpdateBadgeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+	if req.Action == "decrement" && req.Count == 0 {
+		req.Count = 1
+	}
+
+	badge, err := d.deviceService.UpdateBadge(sdkDevice(c), req.Action, req.Count)
+	if err != nil {
+		response.InternalServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"badge_count": badge})
+}
+
+// apiKeySandboxFilter API密钥请求只能列出与密钥模式相同的设备：测试密钥只看沙箱设备，正式密钥只看正式设备
+func apiKeySandboxFilter(c *gin.Context) string {
+	if c.GetString("auth_type") != "api_key" {
+		return ""
+	}
+	return strconv.FormatBool(c.GetBool("sandbox"))
+}
+
+// apiKeyCanSee API密钥请求是否可以访问该设备，JWT 请求总是可以
+func apiKeyCanSee(c *gin.Context, device *models.Device) bool {
+	return c.GetString("auth_type") != "api_key" || device.Sandbox == c.GetBool("sandbox")
+}
+
 // GetDevices 获取设备列表
 // @Summary 获取设备列表
-// @Description 获取应用的设备列表
+// @Description 获取应用的设备列表。API Key 认证时测试密钥只返回沙箱设备，正式密钥只返回正式设备
 // @Tags 设备管理
 // @Accept json
 // @Produce json
@@ -148,7 +477,7 @@ func (d *DeviceController) GetDevices(c *gin.Context) {
 	}
 
 	userID := c.GetUint("user_id")
-	devices, total, err := d.deviceService.GetDevices(uint(appID), userID, page, pageSize, platform, status, pushEnv)
+	devices, total, err := d.deviceService.GetDevices(uint(appID), userID, page, pageSize, platform, status, pushEnv, apiKeySandboxFilter(c))
 	if err != nil {
 		if err.Error() == "无权限访问该应用" {
 			response.Forbidden(c, err.Error())
@@ -160,7 +489,7 @@ func (d *DeviceController) GetDevices(c *gin.Context) {
 
 	// 失败仅记录：列表回退到 DB stale 值
 	if err := services.EnrichOnlineStatus(c.Request.Context(), d.rdb, devices); err != nil {
-		log.Printf("EnrichOnlineStatus failed: %v", err)
+		logger.WarnContext(c.Request.Context(), "查询设备在线态失败", "app_id", appID, "error", err)
 	}
 
 	response.Success(c, utils.NewPaginationResponse(page, pageSize, total, gin.H{
@@ -194,7 +523,7 @@ func (d *DeviceController) GetDevice(c *gin.Context) {
 
 	// 尝试解析为数字ID
 	if deviceID, err := strconv.ParseUint(deviceParam, 10, 32); err == nil {
-		device, err := d.deviceService.GetDeviceByID(uint(deviceID), userID)
+		device, err := d.deviceService.GetDeviceByID(uint(appID), uint(deviceID), userID)
 		if err != nil {
 			if err.Error() == "无权限访问该设备" {
 				response.Forbidden(c, err.Error())
@@ -203,8 +532,12 @@ func (d *DeviceController) GetDevice(c *gin.Context) {
 			}
 			return
 		}
+		if !apiKeyCanSee(c, device) {
+			response.NotFound(c, "设备不存在")
+			return
+		}
 		if err := services.EnrichOnlineStatusOne(c.Request.Context(), d.rdb, device); err != nil {
-			log.Printf("EnrichOnlineStatusOne failed: %v", err)
+			logger.WarnContext(c.Request.Context(), "查询设备在线态失败", "app_id", appID, "device_id", device.ID, "error", err)
 		}
 		response.Success(c, device)
 		return
@@ -220,8 +553,12 @@ func (d *DeviceController) GetDevice(c *gin.Context) {
 		}
 		return
 	}
+	if !apiKeyCanSee(c, device) {
+		response.NotFound(c, "设备不存在")
+		return
+	}
 	if err := services.EnrichOnlineStatusOne(c.Request.Context(), d.rdb, device); err != nil {
-		log.Printf("EnrichOnlineStatusOne failed: %v", err)
+		logger.WarnContext(c.Request.Context(), "查询设备在线态失败", "app_id", appID, "device_id", device.ID, "error", err)
 	}
 	response.Success(c, device)
 }
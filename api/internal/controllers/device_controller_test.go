@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/doopush/doopush/api/internal/middleware"
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/internal/testutil"
+	"github.com/gin-gonic/gin"
+)
+
+// newDeviceQueryRouter 与 serve.go 中 devices:read 路由相同的中间件组合
+func newDeviceQueryRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ctrl := NewDeviceController(nil)
+	devicesRead := middleware.DualAuth(models.APIKeyScopeDevicesRead)
+	r.GET("/apps/:appId/devices", devicesRead, middleware.RequireAppRole("viewer"), ctrl.GetDevices)
+	r.GET("/apps/:appId/devices/:deviceId", devicesRead, middleware.RequireAppRole("viewer"), ctrl.GetDevice)
+	return r
+}
+
+func doAPIKeyRequest(r http.Handler, method, path, apiKey string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("X-API-Key", apiKey)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetDeviceRejectsSiblingAppDevice(t *testing.T) {
+	testutil.SetupDB(t)
+	appA := testutil.CreateApp(t, 1, "a")
+	appB := testutil.CreateApp(t, 1, "b")
+	testutil.CreateAPIKey(t, appA.ID, "dp_live_app_a_key", models.APIKeyScopeDevicesRead)
+	own := testutil.CreateDevice(t, appA.ID, "token-a", false)
+	sibling := testutil.CreateDevice(t, appB.ID, "token-b", false)
+	r := newDeviceQueryRouter()
+
+	w := doAPIKeyRequest(r, http.MethodGet, fmt.Sprintf("/apps/%d/devices/%d", appA.ID, own.ID), "dp_live_app_a_key")
+	if w.Code != http.StatusOK {
+		t.Fatalf("own device: status = %d, body = %s", w.Code, w.Body)
+	}
+
+	// 两个应用属于同一所有者，密钥仍然只能读到自己应用的设备
+	w = doAPIKeyRequest(r, http.MethodGet, fmt.Sprintf("/apps/%d/devices/%d", appA.ID, sibling.ID), "dp_live_app_a_key")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("sibling device: status = %d, want 404, body = %s", w.Code, w.Body)
+	}
+}
+
+func TestDeviceQuerySandboxIsolation(t *testing.T) {
+	testutil.SetupDB(t)
+	app := testutil.CreateApp(t, 1, "a")
+	testutil.CreateAPIKey(t, app.ID, "dp_live_read_key", models.APIKeyScopeDevicesRead)
+	testutil.CreateAPIKey(t, app.ID, "dp_test_read_key", models.APIKeyScopeDevicesRead)
+	live := testutil.CreateDevice(t, app.ID, "live-token", false)
+	sandbox := testutil.CreateDevice(t, app.ID, "sandbox-token", true)
+	r := newDeviceQueryRouter()
+
+	cases := []struct {
+		key     string
+		visible *models.Device
+		hidden  *models.Device
+	}{
+		{"dp_live_read_key", live, sandbox},
+		{"dp_test_read_key", sandbox, live},
+	}
+	for _, tc := range cases {
+		w := doAPIKeyRequest(r, http.MethodGet, fmt.Sprintf("/apps/%d/devices", app.ID), tc.key)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s list: status = %d, body = %s", tc.key, w.Code, w.Body)
+		}
+		var list struct {
+			Data struct {
+				Total int64 `json:"total_items"`
+				Data  struct {
+					Items []models.Device `json:"items"`
+				} `json:"data"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+			t.Fatal(err)
+		}
+		if items := list.Data.Data.Items; list.Data.Total != 1 || len(items) != 1 || items[0].ID != tc.visible.ID {
+			t.Errorf("%s list: got %s, want only device %d", tc.key, w.Body, tc.visible.ID)
+		}
+
+		if w := doAPIKeyRequest(r, http.MethodGet, fmt.Sprintf("/apps/%d/devices/%d", app.ID, tc.visible.ID), tc.key); w.Code != http.StatusOK {
+			t.Errorf("%s detail of same-mode device: status = %d", tc.key, w.Code)
+		}
+		if w := doAPIKeyRequest(r, http.MethodGet, fmt.Sprintf("/apps/%d/devices/%d", app.ID, tc.hidden.ID), tc.key); w.Code != http.StatusNotFound {
+			t.Errorf("%s detail of other-mode device: status = %d, want 404", tc.key, w.Code)
+		}
+		if w := doAPIKeyRequest(r, http.MethodGet, fmt.Sprintf("/apps/%d/devices/%s", app.ID, tc.hidden.Token), tc.key); w.Code != http.StatusNotFound {
+			t.Errorf("%s detail by token of other-mode device: status = %d, want 404", tc.key, w.Code)
+		}
+	}
+}
+
+// newSDKDeviceRouter 与 serve.go 中客户端SDK设备路由相同的中间件组合
+func newSDKDeviceRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ctrl := NewDeviceController(nil)
+	sdk := r.Group("/apps/:appId/device", middleware.APIKeyAuth(models.APIKeyScopeDeviceRegister), middleware.DeviceTokenAuth())
+	sdk.DELETE("", ctrl.UnregisterDevice)
+	sdk.GET("/tags", ctrl.GetOwnTags)
+	sdk.POST("/tags", ctrl.AddOwnTags)
+	sdk.DELETE("/tags/:tagName", ctrl.DeleteOwnTag)
+	sdk.PUT("/alias", ctrl.BindAlias)
+	sdk.DELETE("/alias", ctrl.UnbindAlias)
+	sdk.PUT("/preferences", ctrl.UpdatePreferences)
+	return r
+}
+
+func doSDKRequest(r http.Handler, method, path, apiKey, deviceToken, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", apiKey)
+	if deviceToken != "" {
+		req.Header.Set("X-Device-Token", deviceToken)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestSDKDeviceRoutes(t *testing.T) {
+	db := testutil.SetupDB(t)
+	app := testutil.CreateApp(t, 1, "a")
+	other := testutil.CreateApp(t, 1, "b")
+	testutil.CreateAPIKey(t, app.ID, "dp_live_sdk_key", models.APIKeyScopeDeviceRegister)
+	testutil.CreateAPIKey(t, app.ID, "dp_test_sdk_key", models.APIKeyScopeDeviceRegister)
+	testutil.CreateAPIKey(t, other.ID, "dp_live_other_key", models.APIKeyScopeDeviceRegister)
+	own := testutil.CreateDevice(t, app.ID, "own-token", false)
+	sandbox := testutil.CreateDevice(t, app.ID, "sandbox-token", true)
+	foreign := testutil.CreateDevice(t, other.ID, "foreign-token", false)
+	r := newSDKDeviceRouter()
+	base := fmt.Sprintf("/apps/%d/device", app.ID)
+
+	routes := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodGet, "/tags", ""},
+		{http.MethodPost, "/tags", `{"tags":[{"tag_name":"vip","tag_value":"gold"}]}`},
+		{http.MethodDelete, "/tags/vip", ""},
+		{http.MethodPut, "/alias", `{"external_user_id":"user-1"}`},
+		{http.MethodDelete, "/alias", ""},
+		{http.MethodPut, "/preferences", `{"locale":"en-US","timezone":"Europe/Berlin"}`},
+		{http.MethodDelete, "", ""},
+	}
+
+	// 其他应用的设备、另一模式的设备和缺少设备Token的请求都被拒绝，且不产生任何修改
+	for _, route := range routes {
+		for _, tc := range []struct {
+			name   string
+			key    string
+			token  string
+			status int
+		}{
+			{"其他应用的设备", "dp_live_sdk_key", foreign.Token, http.StatusNotFound},
+			{"正式密钥操作沙箱设备", "dp_live_sdk_key", sandbox.Token, http.StatusNotFound},
+			{"测试密钥操作正式设备", "dp_test_sdk_key", own.Token, http.StatusNotFound},
+			{"缺少设备Token", "dp_live_sdk_key", "", http.StatusUnauthorized},
+		} {
+			if w := doSDKRequest(r, route.method, base+route.path, tc.key, tc.token, route.body); w.Code != tc.status {
+				t.Errorf("%s %s (%s): status = %d, want %d, body = %s", route.method, route.path, tc.name, w.Code, tc.status, w.Body)
+			}
+		}
+		// 其他应用的密钥不能访问本应用路径
+		if w := doSDKRequest(r, route.method, base+route.path, "dp_live_other_key", foreign.Token, route.body); w.Code == http.StatusOK {
+			t.Errorf("%s %s with other app's key: status = %d", route.method, route.path, w.Code)
+		}
+	}
+	var tagCount int64
+	db.Model(&models.DeviceTag{}).Count(&tagCount)
+	for _, device := range []*models.Device{sandbox, foreign} {
+		var got models.Device
+		db.First(&got, device.ID)
+		if got.Status != 1 || got.ExternalUID != "" || got.Locale != "" || got.Timezone != "" {
+			t.Errorf("rejected device %s was modified: %+v", device.Token, got)
+		}
+	}
+	if tagCount != 0 {
+		t.Fatalf("rejected requests created %d tags", tagCount)
+	}
+
+	// 调用方设备按顺序完成标签、别名、偏好和注销操作
+	for _, route := range routes {
+		w := doSDKRequest(r, route.method, base+route.path, "dp_live_sdk_key", own.Token, route.body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s %s: status = %d, body = %s", route.method, route.path, w.Code, w.Body)
+		}
+		var got models.Device
+		db.First(&got, own.ID)
+		switch route.method + " " + route.path {
+		case "POST /tags":
+			var tags []models.DeviceTag
+			db.Where("app_id = ? AND device_token = ?", app.ID, own.Token).Find(&tags)
+			if len(tags) != 1 || tags[0].TagName != "vip" || tags[0].TagValue != "gold" {
+				t.Fatalf("tags after add = %+v", tags)
+			}
+		case "DELETE /tags/vip":
+			db.Model(&models.DeviceTag{}).Where("device_token = ?", own.Token).Count(&tagCount)
+			if tagCount != 0 {
+				t.Fatalf("tags after delete = %d", tagCount)
+			}
+		case "PUT /alias":
+			if got.ExternalUID != "user-1" {
+				t.Fatalf("alias = %q, want user-1", got.ExternalUID)
+			}
+		case "DELETE /alias":
+			if got.ExternalUID != "" {
+				t.Fatalf("alias after unbind = %q", got.ExternalUID)
+			}
+		case "PUT /preferences":
+			if got.Locale != "en-US" || got.Timezone != "Europe/Berlin" {
+				t.Fatalf("preferences = %s %s", got.Locale, got.Timezone)
+			}
+		case "DELETE ":
+			if got.Status != 0 {
+				t.Fatalf("status after unregister = %d, want 0", got.Status)
+			}
+		}
+	}
+
+	// 无效时区返回 400
+	if w := doSDKRequest(r, http.MethodPut, base+"/preferences", "dp_test_sdk_key", sandbox.Token, `{"timezone":"Mars/Base"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid timezone: status = %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/doopush/doopush/api/internal/services"
+	"github.com/doopush/doopush/api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// FrequencyCapController 推送频控控制器
+type FrequencyCapController struct {
+	frequencyCapService *services.FrequencyCapService
+}
+
+// NewFrequencyCapController 创建推送频控控制器
+func NewFrequencyCapController() *FrequencyCapController {
+	return &FrequencyCapController{
+		frequencyCapService: services.NewFrequencyCapService(),
+	}
+}
+
+// UpdateFrequencyCapRequest 更新频控配置请求，0 表示不限制
+type UpdateFrequencyCapRequest struct {
+	MaxPushPerDay   int `json:"max_push_per_day" binding:"min=0" example:"100000"` // 应用每日推送配额，0 时取系统配置 max_push_per_day
+	DeviceDailyCap  int `json:"device_daily_cap" binding:"min=0" example:"3"`      // 单设备每日营销类推送上限
+	DeviceWeeklyCap int `json:"device_weekly_cap" binding:"min=0" example:"10"`    // 单设备每周营销类推送上限
+}
+
+// GetFrequencyCap 获取频控配置
+// @Summary 获取频控配置
+// @Description 获取应用的推送频控配置及当天配额用量
+// @Tags 推送频控
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Success 200 {object} response.APIResponse{data=services.FrequencyCapUsage} "频控配置"
+// @Failure 400 {object} response.APIResponse "请求参数错误"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Router /apps/{appId}/frequency-caps [get]
+func (ctrl *FrequencyCapController) GetFrequencyCap(ctx *gin.Context) {
+	appID, err := strconv.ParseUint(ctx.Param("appId"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "无效的应用ID")
+		return
+	}
+
+	usage, err := ctrl.frequencyCapService.GetUsage(uint(appID))
+	if err != nil {
+		response.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, usage)
+}
+
+// UpdateFrequencyCap 更新频控配置
+// @Summary 更新频控配置
+// @Description 设置应用每日推送配额和单设备日/周推送上限，交易类、即时通讯和账号类消息不受限制
+// @Tags 推送频控
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param request body UpdateFrequencyCapRequest true "频控配置"
+// @Success 200 {object} response.APIResponse{data=models.AppFrequencyCap} "更新成功"
+// @Failure 400 {object} response.APIResponse "请求参数错误"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Router /apps/{appId}/frequency-caps [put]
+func (ctrl *FrequencyCapController) UpdateFrequencyCap(ctx *gin.Context) {
+	appID, err := strconv.ParseUint(ctx.Param("appId"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "无效的应用ID")
+		return
+	}
+
+	var req UpdateFrequencyCapRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	limits, err := ctrl.frequencyCapService.UpdateFrequencyCap(uint(appID), req.MaxPushPerDay, req.DeviceDailyCap, req.DeviceWeeklyCap)
+	if err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, limits)
+}
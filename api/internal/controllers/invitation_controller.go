@@ -162,13 +162,16 @@ func parseInvitationID(c *gin.Context) (uint, bool) {
 
 func handleInvitationError(c *gin.Context, err error) {
 	switch {
-	case errors.Is(err, services.ErrEmailUserNotFound), errors.Is(err, services.ErrInvitationNotFound):
+	case errors.Is(err, services.ErrEmailUserNotFound), errors.Is(err, services.ErrInvitationNotFound),
+		errors.Is(err, services.ErrApprovalNotFound):
 		response.NotFound(c, err.Error())
 	case errors.Is(err, services.ErrPendingInvitationExists), errors.Is(err, services.ErrCannotInviteMember):
 		response.Error(c, http.StatusConflict, err.Error())
-	case errors.Is(err, services.ErrInvitationNotPending), errors.Is(err, services.ErrInvalidAppRole):
+	case errors.Is(err, services.ErrInvitationNotPending), errors.Is(err, services.ErrInvalidAppRole),
+		errors.Is(err, services.ErrApprovalNotPending):
 		response.BadRequest(c, err.Error())
-	case err.Error() == "无权限管理应用成员":
+	case err.Error() == "无权限管理应用成员", errors.Is(err, services.ErrCannotReviewOwnPush),
+		errors.Is(err, services.ErrNoApprovalPermission):
 		response.Forbidden(c, err.Error())
 	default:
 		response.InternalServerError(c, err.Error())
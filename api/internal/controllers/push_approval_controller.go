@@ -0,0 +1,258 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/internal/services"
+	"github.com/doopush/doopush/api/pkg/response"
+	"github.com/doopush/doopush/api/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// PushApprovalController 推送审批控制器
+type PushApprovalController struct {
+	approvalService *services.PushApprovalService
+}
+
+// NewPushApprovalController 创建推送审批控制器
+func NewPushApprovalController() *PushApprovalController {
+	return &PushApprovalController{
+		approvalService: services.NewPushApprovalService(),
+	}
+}
+
+// UpdateApprovalPolicyRequest 更新审批策略请求
+type UpdateApprovalPolicyRequest struct {
+	DeviceThreshold        int  `json:"device_threshold" binding:"min=0" example:"10000"` // 目标设备数超过该值时需要审批，0 表示不按设备数审批
+	RequireForBroadcast    bool `json:"require_for_broadcast" example:"true"`             // 广播推送需要审批
+	AllowDeveloperApproval bool `json:"allow_developer_approval" example:"false"`         // 允许发起人以外的开发者审批，否则仅 owner 可审批
+}
+
+// ReviewPushRequest 审批推送请求
+type ReviewPushRequest struct {
+	Note string `json:"note,omitempty" binding:"max=255" example:"内容已确认"` // 审批意见
+}
+
+// PushApprovalsResponse 推送审批列表响应
+type PushApprovalsResponse struct {
+	Items []models.PushApproval `json:"items"`
+}
+
+// GetApprovalPolicy 获取审批策略
+// @Summary 获取审批策略
+// @Description 获取应用的推送审批策略
+// @Tags 推送审批
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Success 200 {object} response.APIResponse{data=models.AppApprovalPolicy} "审批策略"
+// @Failure 400 {object} response.APIResponse "请求参数错误"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Router /apps/{appId}/approval-policy [get]
+func (ctrl *PushApprovalController) GetApprovalPolicy(ctx *gin.Context) {
+	appID, ok := parseAppID(ctx)
+	if !ok {
+		return
+	}
+
+	policy, err := ctrl.approvalService.GetPolicy(appID)
+	if err != nil {
+		response.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, policy)
+}
+
+// UpdateApprovalPolicy 更新审批策略
+// @Summary 更新审批策略
+// @Description 设置需要审批的推送：目标设备数超过阈值或广播推送。owner 发起的推送不需要审批
+// @Tags 推送审批
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param request body UpdateApprovalPolicyRequest true "审批策略"
+// @Success 200 {object} response.APIResponse{data=models.AppApprovalPolicy} "更新成功"
+// @Failure 400 {object} response.APIResponse "请求参数错误"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Router /apps/{appId}/approval-policy [put]
+func (ctrl *PushApprovalController) UpdateApprovalPolicy(ctx *gin.Context) {
+	appID, ok := parseAppID(ctx)
+	if !ok {
+		return
+	}
+
+	var req UpdateApprovalPolicyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	policy, err := ctrl.approvalService.UpdatePolicy(appID, req.DeviceThreshold, req.RequireForBroadcast, req.AllowDeveloperApproval)
+	if err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, policy)
+}
+
+// GetApprovals 获取推送审批列表
+// @Summary 获取推送审批列表
+// @Description 获取应用的推送审批记录
+// @Tags 推送审批
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param status query string false "审批状态筛选" Enums(pending, approved, rejected, cancelled)
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(20)
+// @Success 200 {object} response.APIResponse{data=PushApprovalsResponse} "审批列表"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Router /apps/{appId}/push/approvals [get]
+func (ctrl *PushApprovalController) GetApprovals(ctx *gin.Context) {
+	appID, ok := parseAppID(ctx)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	approvals, total, err := ctrl.approvalService.GetApprovals(appID, ctx.Query("status"), page, pageSize)
+	if err != nil {
+		response.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, utils.NewPaginationResponse(page, pageSize, total, PushApprovalsResponse{Items: approvals}))
+}
+
+// GetApproval 获取推送审批
+// @Summary 获取推送审批
+// @Description 获取推送审批详情，request 字段为发起时的推送请求
+// @Tags 推送审批
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param id path int true "审批ID"
+// @Success 200 {object} response.APIResponse{data=models.PushApproval} "审批详情"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Failure 404 {object} response.APIResponse "审批不存在"
+// @Router /apps/{appId}/push/approvals/{id} [get]
+func (ctrl *PushApprovalController) GetApproval(ctx *gin.Context) {
+	appID, approvalID, ok := parseApprovalParams(ctx)
+	if !ok {
+		return
+	}
+
+	approval, err := ctrl.approvalService.GetApproval(appID, approvalID)
+	respondApproval(ctx, approval, err)
+}
+
+// ApprovePush 批准推送
+// @Summary 批准推送
+// @Description 批准等待审批的推送，推送按原计划开始投递。发起人不能审批自己的推送
+// @Tags 推送审批
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param id path int true "审批ID"
+// @Param request body ReviewPushRequest false "审批意见"
+// @Success 200 {object} response.APIResponse{data=models.PushApproval} "已批准"
+// @Failure 400 {object} response.APIResponse "审批已处理"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Failure 404 {object} response.APIResponse "审批不存在"
+// @Router /apps/{appId}/push/approvals/{id}/approve [post]
+func (ctrl *PushApprovalController) ApprovePush(ctx *gin.Context) {
+	ctrl.review(ctx, true)
+}
+
+// RejectPush 拒绝推送
+// @Summary 拒绝推送
+// @Description 拒绝等待审批的推送，尚未投递的推送标记为 rejected
+// @Tags 推送审批
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param id path int true "审批ID"
+// @Param request body ReviewPushRequest false "审批意见"
+// @Success 200 {object} response.APIResponse{data=models.PushApproval} "已拒绝"
+// @Failure 400 {object} response.APIResponse "审批已处理"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Failure 404 {object} response.APIResponse "审批不存在"
+// @Router /apps/{appId}/push/approvals/{id}/reject [post]
+func (ctrl *PushApprovalController) RejectPush(ctx *gin.Context) {
+	ctrl.review(ctx, false)
+}
+
+// review 批准或拒绝推送
+func (ctrl *PushApprovalController) review(ctx *gin.Context, approve bool) {
+	appID, approvalID, ok := parseApprovalParams(ctx)
+	if !ok {
+		return
+	}
+
+	var req ReviewPushRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(ctx, "请求参数错误: "+err.Error())
+			return
+		}
+	}
+
+	approval, err := ctrl.approvalService.Review(appID, ctx.GetUint("user_id"), approvalID, approve, req.Note)
+	respondApproval(ctx, approval, err)
+}
+
+// parseApprovalParams 解析应用ID和审批ID
+func parseApprovalParams(ctx *gin.Context) (uint, uint, bool) {
+	appID, ok := parseAppID(ctx)
+	if !ok {
+		return 0, 0, false
+	}
+	approvalID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(ctx, "无效的审批ID")
+		return 0, 0, false
+	}
+	return appID, uint(approvalID), true
+}
+
+// respondApproval 返回审批结果，按错误类型映射状态码
+func respondApproval(ctx *gin.Context, approval *models.PushApproval, err error) {
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrApprovalNotFound):
+			response.NotFound(ctx, err.Error())
+		case errors.Is(err, services.ErrCannotReviewOwnPush), errors.Is(err, services.ErrNoApprovalPermission):
+			response.Forbidden(ctx, err.Error())
+		case errors.Is(err, services.ErrApprovalNotPending):
+			response.BadRequest(ctx, err.Error())
+		default:
+			response.InternalServerError(ctx, err.Error())
+		}
+		return
+	}
+	response.Success(ctx, approval)
+}
@@ -6,7 +6,9 @@ import (
 	"time"
 
 	"github.com/doopush/doopush/api/internal/database"
+	"github.com/doopush/doopush/api/internal/middleware"
 	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/internal/push"
 	"github.com/doopush/doopush/api/internal/services"
 	"github.com/doopush/doopush/api/pkg/response"
 	"github.com/doopush/doopush/api/pkg/utils"
@@ -29,15 +31,23 @@ func NewPushController() *PushController {
 
 // PushPayload 推送负载数据
 type PushPayload struct {
-	Action string                 `json:"action,omitempty" example:"open_page"`        // 动作类型
-	URL    string                 `json:"url,omitempty" example:"https://example.com"` // 链接地址
-	Data   string                 `json:"data,omitempty" example:"extra_data"`         // 额外数据，JSON字符串
-	Huawei map[string]interface{} `json:"huawei,omitempty"`                            // 华为厂商参数
-	Honor  map[string]interface{} `json:"honor,omitempty"`                             // 荣耀厂商参数
-	Xiaomi map[string]interface{} `json:"xiaomi,omitempty"`                            // 小米厂商参数
-	Oppo   map[string]interface{} `json:"oppo,omitempty"`                              // OPPO厂商参数
-	Vivo   map[string]interface{} `json:"vivo,omitempty"`                              // vivo厂商参数
-	Meizu  map[string]interface{} `json:"meizu,omitempty"`                             // 魅族厂商参数
+	Action string `json:"action,omitempty" example:"open_page"`        // 动作类型
+	URL    string `json:"url,omitempty" example:"https://example.com"` // 链接地址
+	Data   string `json:"data,omitempty" example:"extra_data"`         // 额外数据，JSON字符串
+
+	// 富媒体字段（厂商无关），由各通道映射为原生样式
+	ImageURL      string            `json:"image_url,omitempty" binding:"omitempty,url" example:"https://example.com/banner.png"`     // 大图
+	BigText       string            `json:"big_text,omitempty" binding:"omitempty,max=1000" example:"展开后显示的长文本"`                      // 长文本
+	Buttons       []push.RichButton `json:"buttons,omitempty" binding:"omitempty,max=3,dive"`                                         // 通知按钮，最多3个
+	AttachmentURL string            `json:"attachment_url,omitempty" binding:"omitempty,url" example:"https://example.com/video.mp4"` // iOS 通知附件，自动开启 mutable-content
+
+	Huawei map[string]interface{} `json:"huawei,omitempty"` // 华为厂商参数
+	Honor  map[string]interface{} `json:"honor,omitempty"`  // 荣耀厂商参数
+	Xiaomi map[string]interface{} `json:"xiaomi,omitempty"` // 小米厂商参数
+	Oppo   map[string]interface{} `json:"oppo,omitempty"`   // OPPO厂商参数
+	Vivo   map[string]interface{} `json:"vivo,omitempty"`   // vivo厂商参数
+	Meizu  map[string]interface{} `json:"meizu,omitempty"`  // 魅族厂商参数
+	APNs   *push.APNsOptions      `json:"apns,omitempty"`   // APNs推送选项
 }
 
 func (p PushPayload) toMap() map[string]interface{} {
@@ -51,6 +61,18 @@ func (p PushPayload) toMap() map[string]interface{} {
 	if p.Data != "" {
 		payload["data"] = p.Data
 	}
+	if p.ImageURL != "" {
+		payload["image_url"] = p.ImageURL
+	}
+	if p.BigText != "" {
+		payload["big_text"] = p.BigText
+	}
+	if len(p.Buttons) > 0 {
+		payload["buttons"] = p.Buttons
+	}
+	if p.AttachmentURL != "" {
+		payload["attachment_url"] = p.AttachmentURL
+	}
 	if p.Huawei != nil {
 		payload["huawei"] = p.Huawei
 	}
@@ -69,17 +91,26 @@ func (p PushPayload) toMap() map[string]interface{} {
 	if p.Meizu != nil {
 		payload["meizu"] = p.Meizu
 	}
+	if p.APNs != nil {
+		payload["apns"] = p.APNs
+	}
 	return payload
 }
 
 // SendPushRequest 发送推送请求
 type SendPushRequest struct {
-	Title    string              `json:"title" binding:"required,max=200" example:"新消息"`
-	Content  string              `json:"content" binding:"required" example:"您有一条新消息"`
-	Payload  PushPayload         `json:"payload,omitempty"`
-	Target   services.PushTarget `json:"target" binding:"required"`
-	Schedule *string             `json:"schedule_time,omitempty" example:"2024-12-31T10:00:00Z"`
-	Badge    *int                `json:"badge,omitempty" example:"1"`
+	Title       string               `json:"title" binding:"required_unless=MessageType data,max=200" example:"新消息"`
+	Content     string               `json:"content" binding:"required_unless=MessageType data" example:"您有一条新消息"`
+	Payload     PushPayload          `json:"payload,omitempty"`
+	Target      services.PushTarget  `json:"target" binding:"required"`
+	Schedule    *string              `json:"schedule_time,omitempty" example:"2024-12-31T10:00:00Z"`
+	Badge       *services.BadgeValue `json:"badge,omitempty" swaggertype:"string" example:"+1"`
+	MessageType string               `json:"message_type,omitempty" binding:"omitempty,oneof=notification data" example:"notification"`               // notification=通知栏消息，data=静默/透传消息
+	Category    string               `json:"category,omitempty" binding:"omitempty,oneof=transactional marketing im account" example:"transactional"` // 统一消息分类，按应用配置翻译为各厂商分类字段
+	TTLSeconds  int                  `json:"ttl_seconds,omitempty" binding:"omitempty,min=1,max=2419200" example:"300"`                               // 消息存活时长（秒），超时未送达则丢弃
+	Priority    string               `json:"priority,omitempty" binding:"omitempty,oneof=high normal" example:"high"`                                 // 投递优先级
+	CollapseKey string               `json:"collapse_key,omitempty" binding:"omitempty,max=64" example:"score_update"`                                // 合并键
+	Topic       string               `json:"topic,omitempty" binding:"omitempty,max=64" example:"order_updates"`                                      // 订阅主题，未订阅的设备将被排除
 }
 
 // PushLogsResponse 推送日志列表响应
@@ -130,6 +161,7 @@ type PlatformStat struct {
 // SendPush 发送推送
 // @Summary 发送推送
 // @Description 向指定目标发送推送通知。支持JWT Token和API Key双重认证方式
+// @Description API Key 需要 push:send 权限，目标为 all 时还需要 push:broadcast 权限
 // @Tags 推送管理
 // @Accept json
 // @Produce json
@@ -155,15 +187,29 @@ func (p *PushController) SendPush(c *gin.Context) {
 		return
 	}
 
+	// 向全部设备发送等同广播，API 密钥需要 push:broadcast 权限
+	if req.Target.Type == "all" && !middleware.APIKeyAllows(c, models.APIKeyScopePushBroadcast) {
+		response.Forbidden(c, "API密钥缺少权限: "+models.APIKeyScopePushBroadcast)
+		return
+	}
+
 	payload := req.Payload.toMap()
 
 	// 构建推送请求
 	pushReq := services.PushRequest{
-		Title:   req.Title,
-		Content: req.Content,
-		Badge:   req.Badge,
-		Payload: payload,
-		Target:  req.Target,
+		Title:       req.Title,
+		Content:     req.Content,
+		Badge:       req.Badge,
+		Payload:     payload,
+		Target:      req.Target,
+		MessageType: req.MessageType,
+		Category:    req.Category,
+		TTLSeconds:  req.TTLSeconds,
+		Priority:    req.Priority,
+		CollapseKey: req.CollapseKey,
+		Topic:       req.Topic,
+		ViaAPIKey:   c.GetString("auth_type") == "api_key",
+		Sandbox:     c.GetBool("sandbox"),
 	}
 
 	// 处理定时推送
@@ -177,7 +223,7 @@ func (p *PushController) SendPush(c *gin.Context) {
 	}
 
 	userID := c.GetUint("user_id")
-	pushLogs, err := p.pushService.SendPush(uint(appID), userID, pushReq)
+	pushLogs, err := p.pushService.SendPush(c.Request.Context(), uint(appID), userID, pushReq)
 	if err != nil {
 		if err.Error() == "无权限发送推送" {
 			response.Forbidden(c, err.Error())
@@ -233,10 +279,34 @@ func (p *PushController) SendPush(c *gin.Context) {
 		message = "推送已加入定时队列"
 	}
 
+	excluded, suppressed, pendingApproval := 0, 0, 0
+	for _, pushLog := range pushLogs {
+		switch pushLog.Status {
+		case "excluded":
+			excluded++
+		case "suppressed":
+			suppressed++
+		case "pending_approval":
+			pendingApproval++
+		}
+	}
+	if pendingApproval > 0 {
+		message = "推送已提交审批，批准后开始发送"
+	}
+
+	batchID := ""
+	if len(pushLogs) > 0 {
+		batchID = pushLogs[0].BatchID
+	}
+
 	response.Success(c, gin.H{
-		"message":   message,
-		"push_logs": pushLogs,
-		"count":     len(pushLogs),
+		"message":          message,
+		"batch_id":         batchID,
+		"push_logs":        pushLogs,
+		"count":            len(pushLogs) - excluded - suppressed,
+		"excluded":         excluded,
+		"suppressed":       suppressed,
+		"pending_approval": pendingApproval,
 	})
 }
 
@@ -252,6 +322,7 @@ func (p *PushController) SendPush(c *gin.Context) {
 // @Param page_size query int false "每页数量" default(20)
 // @Param status query string false "推送状态筛选" Enums(pending, sent, failed)
 // @Param platform query string false "设备平台筛选" Enums(ios, android)
+// @Param sandbox query string false "沙箱推送筛选：true=仅测试密钥发起的沙箱推送，false=仅正式推送" Enums(true, false)
 // @Success 200 {object} response.APIResponse{data=PushLogsResponse}
 // @Failure 401 {object} response.APIResponse
 // @Failure 403 {object} response.APIResponse
@@ -268,6 +339,7 @@ func (p *PushController) GetPushLogs(c *gin.Context) {
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
 	status := c.Query("status")
 	platform := c.Query("platform")
+	sandbox := c.Query("sandbox")
 
 	if page < 1 {
 		page = 1
@@ -277,7 +349,7 @@ func (p *PushController) GetPushLogs(c *gin.Context) {
 	}
 
 	userID := c.GetUint("user_id")
-	pushLogs, total, err := p.pushService.GetPushLogsWithFilters(uint(appID), userID, page, pageSize, status, platform)
+	pushLogs, total, err := p.pushService.GetPushLogsWithFilters(uint(appID), userID, page, pageSize, status, platform, sandbox)
 	if err != nil {
 		if err.Error() == "无权限访问该应用" {
 			response.Forbidden(c, err.Error())
@@ -302,6 +374,7 @@ func (p *PushController) GetPushLogs(c *gin.Context) {
 			"dedup_key":  log.DedupKey,
 			"send_at":    log.SendAt,
 			"badge":      log.Badge,
+			"sandbox":    log.Sandbox,
 			"created_at": log.CreatedAt,
 			"updated_at": log.UpdatedAt,
 		}
@@ -437,6 +510,7 @@ func (p *PushController) GetPushLogDetails(c *gin.Context) {
 	if err := database.DB.Where("id = ? AND app_id = ?", logID, appID).
 		Preload("Device").
 		Preload("PushResult").
+		Preload("Recall").
 		First(&pushLog).Error; err != nil {
 		response.NotFound(c, "推送日志不存在")
 		return
@@ -478,31 +552,52 @@ func (p *PushController) GetPushLogDetails(c *gin.Context) {
 
 // SendSingleRequest 单设备推送请求
 type SendSingleRequest struct {
-	DeviceID string      `json:"device_id" binding:"required" example:"device123"`
-	Title    string      `json:"title" binding:"required,max=200" example:"个人消息"`
-	Content  string      `json:"content" binding:"required" example:"您有一条个人消息"`
-	Payload  PushPayload `json:"payload,omitempty"`
-	Badge    *int        `json:"badge,omitempty" example:"1"`
+	DeviceID    string               `json:"device_id" binding:"required" example:"device123"`
+	Title       string               `json:"title" binding:"required_unless=MessageType data,max=200" example:"个人消息"`
+	Content     string               `json:"content" binding:"required_unless=MessageType data" example:"您有一条个人消息"`
+	Payload     PushPayload          `json:"payload,omitempty"`
+	Badge       *services.BadgeValue `json:"badge,omitempty" swaggertype:"string" example:"+1"`
+	MessageType string               `json:"message_type,omitempty" binding:"omitempty,oneof=notification data" example:"notification"`
+	Category    string               `json:"category,omitempty" binding:"omitempty,oneof=transactional marketing im account" example:"transactional"`
+	TTLSeconds  int                  `json:"ttl_seconds,omitempty" binding:"omitempty,min=1,max=2419200" example:"300"` // 可选：消息存活时长（秒），超时未送达则丢弃
+	Priority    string               `json:"priority,omitempty" binding:"omitempty,oneof=high normal" example:"high"`   // 可选：投递优先级
+	CollapseKey string               `json:"collapse_key,omitempty" binding:"omitempty,max=64" example:"score_update"`  // 可选：合并键
+	Topic       string               `json:"topic,omitempty" binding:"omitempty,max=64" example:"order_updates"`        // 可选：订阅主题，未订阅的设备将被排除
 }
 
 // SendBatchRequest 批量推送请求
 type SendBatchRequest struct {
-	DeviceIDs []string    `json:"device_ids" binding:"required,min=1,max=1000" example:"[\"device1\",\"device2\"]"`
-	Title     string      `json:"title" binding:"required,max=200" example:"批量消息"`
-	Content   string      `json:"content" binding:"required" example:"批量推送消息内容"`
-	Payload   PushPayload `json:"payload,omitempty"`
-	Badge     *int        `json:"badge,omitempty" example:"1"`
+	DeviceIDs   []string             `json:"device_ids" binding:"required,min=1,max=1000" example:"[\"device1\",\"device2\"]"`
+	Title       string               `json:"title" binding:"required_unless=MessageType data,max=200" example:"批量消息"`
+	Content     string               `json:"content" binding:"required_unless=MessageType data" example:"批量推送消息内容"`
+	Payload     PushPayload          `json:"payload,omitempty"`
+	Badge       *services.BadgeValue `json:"badge,omitempty" swaggertype:"string" example:"+1"`
+	MessageType string               `json:"message_type,omitempty" binding:"omitempty,oneof=notification data" example:"notification"`
+	Category    string               `json:"category,omitempty" binding:"omitempty,oneof=transactional marketing im account" example:"transactional"`
+	TTLSeconds  int                  `json:"ttl_seconds,omitempty" binding:"omitempty,min=1,max=2419200" example:"300"` // 可选：消息存活时长（秒），超时未送达则丢弃
+	Priority    string               `json:"priority,omitempty" binding:"omitempty,oneof=high normal" example:"high"`   // 可选：投递优先级
+	CollapseKey string               `json:"collapse_key,omitempty" binding:"omitempty,max=64" example:"score_update"`  // 可选：合并键
+	Topic       string               `json:"topic,omitempty" binding:"omitempty,max=64" example:"order_updates"`        // 可选：订阅主题，未订阅的设备将被排除
 }
 
 // SendBroadcastRequest 广播推送请求
 type SendBroadcastRequest struct {
-	Title    string      `json:"title" binding:"required,max=200" example:"系统公告"`
-	Content  string      `json:"content" binding:"required" example:"系统维护通知"`
-	Payload  PushPayload `json:"payload,omitempty"`
-	Badge    *int        `json:"badge,omitempty" example:"1"`
-	Platform string      `json:"platform,omitempty" example:"ios"`                                                                 // 可选：指定平台
-	Vendor   string      `json:"vendor,omitempty" example:"huawei"`                                                                // 可选：指定厂商
-	PushEnv  string      `json:"push_environment,omitempty" binding:"omitempty,oneof=development production" example:"production"` // 可选：APNs环境
+	Title       string                      `json:"title" binding:"required_unless=MessageType data,max=200" example:"系统公告"`
+	Content     string                      `json:"content" binding:"required_unless=MessageType data" example:"系统维护通知"`
+	Payload     PushPayload                 `json:"payload,omitempty"`
+	Badge       *services.BadgeValue        `json:"badge,omitempty" swaggertype:"string" example:"+1"`
+	Platform    string                      `json:"platform,omitempty" example:"ios"`                                                                        // 可选：指定平台
+	Vendor      string                      `json:"vendor,omitempty" example:"huawei"`                                                                       // 可选：指定厂商
+	PushEnv     string                      `json:"push_environment,omitempty" binding:"omitempty,oneof=development production" example:"production"`        // 可选：APNs环境
+	MessageType string                      `json:"message_type,omitempty" binding:"omitempty,oneof=notification data" example:"notification"`               // 可选：消息类型
+	Category    string                      `json:"category,omitempty" binding:"omitempty,oneof=transactional marketing im account" example:"transactional"` // 可选：消息分类
+	TTLSeconds  int                         `json:"ttl_seconds,omitempty" binding:"omitempty,min=1,max=2419200" example:"300"`                               // 可选：消息存活时长（秒），超时未送达则丢弃
+	Priority    string                      `json:"priority,omitempty" binding:"omitempty,oneof=high normal" example:"high"`                                 // 可选：投递优先级
+	CollapseKey string                      `json:"collapse_key,omitempty" binding:"omitempty,max=64" example:"score_update"`                                // 可选：合并键
+	Topic       string                      `json:"topic,omitempty" binding:"omitempty,max=64" example:"order_updates"`                                      // 可选：订阅主题，未订阅的设备将被排除
+	Rollout     *services.RolloutOptions    `json:"rollout,omitempty"`                                                                                       // 可选：分批发送，按速率节流或按比例灰度
+	Variants    []services.PushVariantInput `json:"variants,omitempty" binding:"omitempty,min=2,max=3,dive"`                                                 // 可选：A/B 测试内容变体
+	ABTest      *services.ABTestOptions     `json:"ab_test,omitempty"`                                                                                       // 可选：A/B 测试参数
 }
 
 // SendSingle 单设备推送
@@ -548,10 +643,18 @@ func (ctrl *PushController) SendSingle(ctx *gin.Context) {
 	}
 
 	pushReq := services.PushRequest{
-		Title:   req.Title,
-		Content: req.Content,
-		Badge:   req.Badge,
-		Payload: payload,
+		Title:       req.Title,
+		Content:     req.Content,
+		Badge:       req.Badge,
+		Payload:     payload,
+		MessageType: req.MessageType,
+		Category:    req.Category,
+		TTLSeconds:  req.TTLSeconds,
+		Priority:    req.Priority,
+		CollapseKey: req.CollapseKey,
+		Topic:       req.Topic,
+		ViaAPIKey:   ctx.GetString("auth_type") == "api_key",
+		Sandbox:     ctx.GetBool("sandbox"),
 		Target: services.PushTarget{
 			Type:      "devices",
 			DeviceIDs: []uint{device.ID},
@@ -559,7 +662,7 @@ func (ctrl *PushController) SendSingle(ctx *gin.Context) {
 	}
 
 	// 执行推送
-	result, err := ctrl.pushService.SendPush(uint(appID), userID, pushReq)
+	result, err := ctrl.pushService.SendPush(ctx.Request.Context(), uint(appID), userID, pushReq)
 	if err != nil {
 		response.BadRequest(ctx, err.Error())
 		return
@@ -622,10 +725,18 @@ func (ctrl *PushController) SendBatch(ctx *gin.Context) {
 	}
 
 	pushReq := services.PushRequest{
-		Title:   req.Title,
-		Content: req.Content,
-		Badge:   req.Badge,
-		Payload: payload,
+		Title:       req.Title,
+		Content:     req.Content,
+		Badge:       req.Badge,
+		Payload:     payload,
+		MessageType: req.MessageType,
+		Category:    req.Category,
+		TTLSeconds:  req.TTLSeconds,
+		Priority:    req.Priority,
+		CollapseKey: req.CollapseKey,
+		Topic:       req.Topic,
+		ViaAPIKey:   ctx.GetString("auth_type") == "api_key",
+		Sandbox:     ctx.GetBool("sandbox"),
 		Target: services.PushTarget{
 			Type:      "devices",
 			DeviceIDs: deviceIDs,
@@ -633,7 +744,7 @@ func (ctrl *PushController) SendBatch(ctx *gin.Context) {
 	}
 
 	// 执行推送
-	result, err := ctrl.pushService.SendPush(uint(appID), userID, pushReq)
+	result, err := ctrl.pushService.SendPush(ctx.Request.Context(), uint(appID), userID, pushReq)
 	if err != nil {
 		response.BadRequest(ctx, err.Error())
 		return
@@ -645,6 +756,7 @@ func (ctrl *PushController) SendBatch(ctx *gin.Context) {
 // SendBroadcast 广播推送
 // @Summary 广播推送
 // @Description 向应用的所有设备发送推送通知。支持JWT Token和API Key双重认证方式
+// @Description API Key 需要 push:broadcast 权限
 // @Tags 推送管理
 // @Accept json
 // @Produce json
@@ -679,10 +791,21 @@ func (ctrl *PushController) SendBroadcast(ctx *gin.Context) {
 
 	// 构建推送目标
 	pushReq := services.PushRequest{
-		Title:   req.Title,
-		Content: req.Content,
-		Badge:   req.Badge,
-		Payload: payload,
+		Title:       req.Title,
+		Content:     req.Content,
+		Badge:       req.Badge,
+		Payload:     payload,
+		MessageType: req.MessageType,
+		Category:    req.Category,
+		TTLSeconds:  req.TTLSeconds,
+		Priority:    req.Priority,
+		CollapseKey: req.CollapseKey,
+		Topic:       req.Topic,
+		ViaAPIKey:   ctx.GetString("auth_type") == "api_key",
+		Sandbox:     ctx.GetBool("sandbox"),
+		Rollout:     req.Rollout,
+		Variants:    req.Variants,
+		ABTest:      req.ABTest,
 		Target: services.PushTarget{
 			Type:     "all",
 			Platform: req.Platform,
@@ -692,7 +815,7 @@ func (ctrl *PushController) SendBroadcast(ctx *gin.Context) {
 	}
 
 	// 执行推送
-	result, err := ctrl.pushService.SendPush(uint(appID), userID, pushReq)
+	result, err := ctrl.pushService.SendPush(ctx.Request.Context(), uint(appID), userID, pushReq)
 	if err != nil {
 		response.BadRequest(ctx, err.Error())
 		return
@@ -701,6 +824,50 @@ func (ctrl *PushController) SendBroadcast(ctx *gin.Context) {
 	response.Success(ctx, result)
 }
 
+// CancelPush 取消推送
+// @Summary 取消推送
+// @Description 按批次取消一次推送：尚未发出的推送标记为 cancelled，已发出的推送在支持撤回的通道上后台撤回，撤回结果记录在推送日志的 recall 字段。支持JWT Token和API Key双重认证方式
+// @Tags 推送管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth || ApiKeyAuth
+// @Param appId path int true "应用ID"
+// @Param batchId path string true "推送批次ID（推送日志的 batch_id）"
+// @Success 200 {object} response.APIResponse{data=services.CancelPushResult} "取消成功"
+// @Failure 400 {object} response.APIResponse "请求参数错误"
+// @Failure 401 {object} response.APIResponse "未认证或API密钥无效"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Failure 404 {object} response.APIResponse "推送不存在"
+// @Router /apps/{appId}/push/batches/{batchId}/cancel [post]
+func (ctrl *PushController) CancelPush(ctx *gin.Context) {
+	appID, err := strconv.ParseUint(ctx.Param("appId"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "无效的应用ID")
+		return
+	}
+
+	userID := ctx.GetUint("user_id")
+	if userID == 0 {
+		response.Unauthorized(ctx, "用户信息获取失败")
+		return
+	}
+
+	result, err := ctrl.pushService.CancelPushBatch(uint(appID), userID, ctx.Param("batchId"))
+	if err != nil {
+		switch err.Error() {
+		case "无权限取消推送":
+			response.Forbidden(ctx, err.Error())
+		case "推送不存在":
+			response.NotFound(ctx, err.Error())
+		default:
+			response.InternalServerError(ctx, err.Error())
+		}
+		return
+	}
+
+	response.Success(ctx, result)
+}
+
 // PushStatisticsReportRequest 推送统计上报请求
 type PushStatisticsReportRequest struct {
 	DeviceToken string                               `json:"device_token" binding:"required" example:"1234567890abcdef"`
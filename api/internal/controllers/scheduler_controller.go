@@ -25,16 +25,25 @@ func NewSchedulerController() *SchedulerController {
 // CreateScheduledPushRequest 创建定时推送请求
 type CreateScheduledPushRequest struct {
 	// 兼容前端字段
-	Title        string `json:"title" binding:"required" example:"推送标题"`
-	Content      string `json:"content" binding:"required" example:"推送内容"`
-	Payload      string `json:"payload" example:"{}"`
-	Badge        *int   `json:"badge,omitempty" example:"1"`
-	ScheduledAt  string `json:"scheduled_at" binding:"required" example:"2024-01-01T10:00:00Z"`
-	PushType     string `json:"push_type" binding:"required" example:"single"`
-	TargetConfig string `json:"target_config" example:"device_token_or_config"`
-	RepeatType   string `json:"repeat_type" binding:"required,oneof=none once daily weekly monthly" example:"none"`
-	RepeatConfig string `json:"repeat_config" example:""`
-	Timezone     string `json:"timezone" example:"Asia/Shanghai"`
+	Title        string               `json:"title" binding:"required_unless=MessageType data" example:"推送标题"`
+	Content      string               `json:"content" binding:"required_unless=MessageType data" example:"推送内容"`
+	Payload      string               `json:"payload" example:"{}"`
+	Badge        *services.BadgeValue `json:"badge,omitempty" swaggertype:"string" example:"+1"` // 数字、"+N" 或 "reset"，未设置时按 +1 累加
+	ScheduledAt  string               `json:"scheduled_at" binding:"required" example:"2024-01-01T10:00:00Z"`
+	PushType     string               `json:"push_type" binding:"required" example:"single"`
+	TargetConfig string               `json:"target_config" example:"device_token_or_config"`
+	RepeatType   string               `json:"repeat_type" binding:"required,oneof=none once daily weekly monthly" example:"none"`
+	RepeatConfig string               `json:"repeat_config" example:""`
+	Timezone     string               `json:"timezone" example:"Asia/Shanghai"`
+	LocalTime    bool                 `json:"local_delivery" example:"false"` // 按设备本地时间投递：scheduled_at 的时刻在每个设备时区分别执行
+
+	// 投递参数，与即时推送的同名参数相同
+	MessageType string `json:"message_type,omitempty" example:"notification"` // notification（默认）或 data（静默/透传消息）
+	Category    string `json:"category,omitempty" example:"marketing"`        // 统一消息分类：transactional/marketing/im/account
+	TTLSeconds  int    `json:"ttl_seconds,omitempty" example:"3600"`          // 消息存活时长（秒），从每次执行时开始计算
+	Priority    string `json:"priority,omitempty" example:"normal"`           // 投递优先级：high（默认）/normal
+	CollapseKey string `json:"collapse_key,omitempty" example:"daily_signin"` // 合并键，相同合并键的新消息覆盖旧消息
+	Topic       string `json:"topic,omitempty" example:"activity"`            // 订阅主题，未订阅该主题的设备将被排除
 
 	// 后端内部字段（可选，向后兼容）
 	Name         string `json:"name" example:"每日活动推送"`
@@ -48,17 +57,26 @@ type CreateScheduledPushRequest struct {
 // UpdateScheduledPushRequest 更新定时推送请求
 type UpdateScheduledPushRequest struct {
 	// 前端字段（保持与创建请求一致）
-	Title        string `json:"title" binding:"required" example:"推送标题"`
-	Content      string `json:"content" binding:"required" example:"推送内容"`
-	Payload      string `json:"payload" example:"{}"`
-	Badge        *int   `json:"badge,omitempty" example:"1"`
-	ScheduledAt  string `json:"scheduled_at" binding:"required" example:"2024-01-01T10:00:00Z"`
-	PushType     string `json:"push_type" binding:"required" example:"single"`
-	TargetConfig string `json:"target_config" example:"device_token_or_config"`
-	RepeatType   string `json:"repeat_type" binding:"required,oneof=none once daily weekly monthly" example:"none"`
-	RepeatConfig string `json:"repeat_config" example:""`
-	Timezone     string `json:"timezone" example:"Asia/Shanghai"`
-	Status       string `json:"status" binding:"oneof=pending paused completed failed" example:"pending"`
+	Title        string               `json:"title" binding:"required_unless=MessageType data" example:"推送标题"`
+	Content      string               `json:"content" binding:"required_unless=MessageType data" example:"推送内容"`
+	Payload      string               `json:"payload" example:"{}"`
+	Badge        *services.BadgeValue `json:"badge,omitempty" swaggertype:"string" example:"+1"` // 数字、"+N" 或 "reset"，未设置时按 +1 累加
+	ScheduledAt  string               `json:"scheduled_at" binding:"required" example:"2024-01-01T10:00:00Z"`
+	PushType     string               `json:"push_type" binding:"required" example:"single"`
+	TargetConfig string               `json:"target_config" example:"device_token_or_config"`
+	RepeatType   string               `json:"repeat_type" binding:"required,oneof=none once daily weekly monthly" example:"none"`
+	RepeatConfig string               `json:"repeat_config" example:""`
+	Timezone     string               `json:"timezone" example:"Asia/Shanghai"`
+	LocalTime    bool                 `json:"local_delivery" example:"false"` // 按设备本地时间投递：scheduled_at 的时刻在每个设备时区分别执行
+
+	// 投递参数，与即时推送的同名参数相同
+	MessageType string `json:"message_type,omitempty" example:"notification"` // notification（默认）或 data（静默/透传消息）
+	Category    string `json:"category,omitempty" example:"marketing"`        // 统一消息分类：transactional/marketing/im/account
+	TTLSeconds  int    `json:"ttl_seconds,omitempty" example:"3600"`          // 消息存活时长（秒），从每次执行时开始计算
+	Priority    string `json:"priority,omitempty" example:"normal"`           // 投递优先级：high（默认）/normal
+	CollapseKey string `json:"collapse_key,omitempty" example:"daily_signin"` // 合并键，相同合并键的新消息覆盖旧消息
+	Topic       string `json:"topic,omitempty" example:"activity"`            // 订阅主题，未订阅该主题的设备将被排除
+	Status      string `json:"status" binding:"oneof=pending paused completed failed" example:"pending"`
 
 	// 后端内部字段（可选，向后兼容）
 	Name         string `json:"name" example:"每日活动推送"`
@@ -69,6 +87,30 @@ type UpdateScheduledPushRequest struct {
 	CronExpr     string `json:"cron_expr" example:"0 10 * * *"`
 }
 
+// deliveryOptions 定时推送的投递参数
+func (req *CreateScheduledPushRequest) deliveryOptions() services.DeliveryOptions {
+	return services.DeliveryOptions{
+		MessageType: req.MessageType,
+		Category:    req.Category,
+		TTLSeconds:  req.TTLSeconds,
+		Priority:    req.Priority,
+		CollapseKey: req.CollapseKey,
+		Topic:       req.Topic,
+	}
+}
+
+// deliveryOptions 定时推送的投递参数
+func (req *UpdateScheduledPushRequest) deliveryOptions() services.DeliveryOptions {
+	return services.DeliveryOptions{
+		MessageType: req.MessageType,
+		Category:    req.Category,
+		TTLSeconds:  req.TTLSeconds,
+		Priority:    req.Priority,
+		CollapseKey: req.CollapseKey,
+		Topic:       req.Topic,
+	}
+}
+
 // CreateScheduledPush 创建定时推送
 // @Summary 创建定时推送
 // @Description 创建一个新的定时推送任务
@@ -137,6 +179,12 @@ func (ctrl *SchedulerController) CreateScheduledPush(ctx *gin.Context) {
 		case "tag":
 			targetType = "tags"
 			targetValue = req.TargetConfig
+		case "users":
+			targetType = "users"
+			targetValue = req.TargetConfig
+		case "topics":
+			targetType = "topics"
+			targetValue = req.TargetConfig
 		default:
 			targetType = "all"
 			targetValue = ""
@@ -184,8 +232,9 @@ func (ctrl *SchedulerController) CreateScheduledPush(ctx *gin.Context) {
 
 	// 创建定时推送任务，包含推送内容
 	push, err := ctrl.schedulerService.CreateScheduledPushWithContent(
-		uint(appID), userID, name, req.Title, req.Content, payload, req.PushType,
-		targetType, targetValue, scheduleTime, timezone, repeatType, req.RepeatConfig, req.CronExpr, req.Badge,
+		ctx.Request.Context(), uint(appID), userID, name, req.Title, req.Content, payload, req.PushType,
+		targetType, targetValue, scheduleTime, timezone, repeatType, req.RepeatConfig, req.CronExpr, req.Badge, req.LocalTime,
+		req.deliveryOptions(),
 	)
 	if err != nil {
 		response.BadRequest(ctx, err.Error())
@@ -381,6 +430,12 @@ func (ctrl *SchedulerController) UpdateScheduledPush(ctx *gin.Context) {
 		case "tag":
 			targetType = "tags"
 			targetValue = req.TargetConfig
+		case "users":
+			targetType = "users"
+			targetValue = req.TargetConfig
+		case "topics":
+			targetType = "topics"
+			targetValue = req.TargetConfig
 		default:
 			targetType = "all"
 			targetValue = ""
@@ -428,7 +483,8 @@ func (ctrl *SchedulerController) UpdateScheduledPush(ctx *gin.Context) {
 
 	push, err := ctrl.schedulerService.UpdateScheduledPushWithContent(
 		uint(appID), uint(pushID), name, req.Title, req.Content, payload, req.PushType,
-		targetType, targetValue, scheduleTime, timezone, repeatType, req.RepeatConfig, req.CronExpr, req.Status, req.Badge,
+		targetType, targetValue, scheduleTime, timezone, repeatType, req.RepeatConfig, req.CronExpr, req.Status, req.Badge, req.LocalTime,
+		req.deliveryOptions(),
 	)
 	if err != nil {
 		response.BadRequest(ctx, err.Error())
@@ -574,7 +630,7 @@ func (ctrl *SchedulerController) ExecuteScheduledPush(ctx *gin.Context) {
 	}
 
 	// 执行定时推送任务（包含应用权限验证）
-	err = ctrl.schedulerService.ExecuteScheduledPush(uint(appID), uint(pushID))
+	err = ctrl.schedulerService.ExecuteScheduledPush(ctx.Request.Context(), uint(appID), uint(pushID))
 	if err != nil {
 		response.BadRequest(ctx, err.Error())
 		return
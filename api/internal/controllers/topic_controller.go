@@ -0,0 +1,337 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/doopush/doopush/api/internal/services"
+	"github.com/doopush/doopush/api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// TopicController 订阅主题控制器
+type TopicController struct {
+	topicService *services.TopicService
+}
+
+// NewTopicController 创建订阅主题控制器
+func NewTopicController() *TopicController {
+	return &TopicController{
+		topicService: services.NewTopicService(),
+	}
+}
+
+// CreateTopicRequest 创建主题请求
+type CreateTopicRequest struct {
+	Key               string `json:"key" binding:"required,max=64" example:"order_updates"` // 主题标识，推送时通过该标识指定主题
+	Name              string `json:"name" binding:"required,max=100" example:"订单更新"`
+	Description       string `json:"description" binding:"max=500" example:"订单状态变化通知"`
+	DefaultSubscribed *bool  `json:"default_subscribed" example:"true"` // 设备未显式设置时是否默认订阅，默认 true
+}
+
+// UpdateTopicRequest 更新主题请求
+type UpdateTopicRequest struct {
+	Name              string `json:"name" binding:"required,max=100" example:"订单更新"`
+	Description       string `json:"description" binding:"max=500" example:"订单状态变化通知"`
+	DefaultSubscribed bool   `json:"default_subscribed" example:"true"`
+}
+
+// UpdateDeviceTopicsRequest 更新设备主题订阅请求
+type UpdateDeviceTopicsRequest struct {
+	Topics map[string]bool `json:"topics" binding:"required" example:"promotions:false"` // 主题标识 -> 是否订阅
+}
+
+// CreateTopic 创建订阅主题
+// @Summary 创建订阅主题
+// @Description 创建应用自定义的订阅主题，设备可按主题订阅或退订推送
+// @Tags 订阅主题
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param request body CreateTopicRequest true "主题信息"
+// @Success 200 {object} response.APIResponse{data=models.Topic} "创建成功"
+// @Failure 400 {object} response.APIResponse "请求参数错误或主题标识已存在"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Router /apps/{appId}/topics [post]
+func (ctrl *TopicController) CreateTopic(ctx *gin.Context) {
+	appID, err := strconv.ParseUint(ctx.Param("appId"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "无效的应用ID")
+		return
+	}
+
+	var req CreateTopicRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+	defaultSubscribed := true
+	if req.DefaultSubscribed != nil {
+		defaultSubscribed = *req.DefaultSubscribed
+	}
+
+	topic, err := ctrl.topicService.CreateTopic(uint(appID), req.Key, req.Name, req.Description, defaultSubscribed)
+	if err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, topic)
+}
+
+// GetTopics 获取订阅主题列表
+// @Summary 获取订阅主题列表
+// @Description 获取应用的全部订阅主题
+// @Tags 订阅主题
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Success 200 {object} response.APIResponse{data=[]models.Topic} "主题列表"
+// @Failure 400 {object} response.APIResponse "请求参数错误"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Router /apps/{appId}/topics [get]
+func (ctrl *TopicController) GetTopics(ctx *gin.Context) {
+	appID, err := strconv.ParseUint(ctx.Param("appId"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "无效的应用ID")
+		return
+	}
+
+	topics, err := ctrl.topicService.GetTopics(uint(appID))
+	if err != nil {
+		response.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, topics)
+}
+
+// UpdateTopic 更新订阅主题
+// @Summary 更新订阅主题
+// @Description 更新主题名称、描述和默认订阅状态，主题标识不可修改
+// @Tags 订阅主题
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param id path int true "主题ID"
+// @Param request body UpdateTopicRequest true "主题信息"
+// @Success 200 {object} response.APIResponse{data=models.Topic} "更新成功"
+// @Failure 400 {object} response.APIResponse "请求参数错误"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Failure 404 {object} response.APIResponse "主题不存在"
+// @Router /apps/{appId}/topics/{id} [put]
+func (ctrl *TopicController) UpdateTopic(ctx *gin.Context) {
+	appID, err := strconv.ParseUint(ctx.Param("appId"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "无效的应用ID")
+		return
+	}
+	topicID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "无效的主题ID")
+		return
+	}
+
+	var req UpdateTopicRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	topic, err := ctrl.topicService.UpdateTopic(uint(appID), uint(topicID), req.Name, req.Description, req.DefaultSubscribed)
+	if err != nil {
+		if err.Error() == "主题不存在" {
+			response.NotFound(ctx, err.Error())
+		} else {
+			response.InternalServerError(ctx, err.Error())
+		}
+		return
+	}
+
+	response.Success(ctx, topic)
+}
+
+// DeleteTopic 删除订阅主题
+// @Summary 删除订阅主题
+// @Description 删除主题及所有设备在该主题上的订阅记录
+// @Tags 订阅主题
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param id path int true "主题ID"
+// @Success 200 {object} response.APIResponse "删除成功"
+// @Failure 400 {object} response.APIResponse "请求参数错误"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Failure 404 {object} response.APIResponse "主题不存在"
+// @Router /apps/{appId}/topics/{id} [delete]
+func (ctrl *TopicController) DeleteTopic(ctx *gin.Context) {
+	appID, err := strconv.ParseUint(ctx.Param("appId"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "无效的应用ID")
+		return
+	}
+	topicID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "无效的主题ID")
+		return
+	}
+
+	if err := ctrl.topicService.DeleteTopic(uint(appID), uint(topicID)); err != nil {
+		if err.Error() == "主题不存在" {
+			response.NotFound(ctx, err.Error())
+		} else {
+			response.InternalServerError(ctx, err.Error())
+		}
+		return
+	}
+
+	response.Success(ctx, gin.H{"message": "主题删除成功"})
+}
+
+// GetDeviceTopics 获取设备的主题订阅状态
+// @Summary 获取设备的主题订阅状态
+// @Description 获取指定设备在应用所有主题上的订阅状态，未显式设置的主题取主题默认值
+// @Tags 订阅主题
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param deviceId path int true "设备ID"
+// @Success 200 {object} response.APIResponse{data=[]services.DeviceTopicStatus} "订阅状态"
+// @Failure 400 {object} response.APIResponse "请求参数错误"
+// @Failure 404 {object} response.APIResponse "设备不存在"
+// @Router /apps/{appId}/devices/{deviceId}/topics [get]
+func (ctrl *TopicController) GetDeviceTopics(ctx *gin.Context) {
+	appID, err := strconv.ParseUint(ctx.Param("appId"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "无效的应用ID")
+		return
+	}
+	deviceID, err := strconv.ParseUint(ctx.Param("deviceId"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "无效的设备ID")
+		return
+	}
+
+	device, err := ctrl.topicService.GetDeviceByID(uint(appID), uint(deviceID))
+	if err != nil {
+		response.NotFound(ctx, err.Error())
+		return
+	}
+
+	topics, err := ctrl.topicService.GetDeviceTopics(device)
+	if err != nil {
+		response.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, topics)
+}
+
+// UpdateDeviceTopics 设置设备的主题订阅
+// @Summary 设置设备的主题订阅
+// @Description 管理后台代设备订阅或退订主题
+// @Tags 订阅主题
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param deviceId path int true "设备ID"
+// @Param request body UpdateDeviceTopicsRequest true "主题订阅状态"
+// @Success 200 {object} response.APIResponse{data=[]services.DeviceTopicStatus} "更新后的订阅状态"
+// @Failure 400 {object} response.APIResponse "请求参数错误或主题不存在"
+// @Failure 404 {object} response.APIResponse "设备不存在"
+// @Router /apps/{appId}/devices/{deviceId}/topics [put]
+func (ctrl *TopicController) UpdateDeviceTopics(ctx *gin.Context) {
+	appID, err := strconv.ParseUint(ctx.Param("appId"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "无效的应用ID")
+		return
+	}
+	deviceID, err := strconv.ParseUint(ctx.Param("deviceId"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "无效的设备ID")
+		return
+	}
+
+	var req UpdateDeviceTopicsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	device, err := ctrl.topicService.GetDeviceByID(uint(appID), uint(deviceID))
+	if err != nil {
+		response.NotFound(ctx, err.Error())
+		return
+	}
+
+	topics, err := ctrl.topicService.UpdateDeviceTopics(device, req.Topics)
+	if err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, topics)
+}
+
+// GetOwnTopics 获取当前设备的主题订阅状态
+// @Summary 获取当前设备的主题订阅状态
+// @Description 客户端SDK获取应用所有主题及当前设备的订阅状态，用于渲染通知偏好设置页
+// @Tags 客户端SDK
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param appId path int true "应用ID"
+// @Param X-Device-Token header string true "当前设备推送Token"
+// @Success 200 {object} response.APIResponse{data=[]services.DeviceTopicStatus} "订阅状态"
+// @Failure 401 {object} response.APIResponse "API密钥无效或缺少设备Token"
+// @Failure 404 {object} response.APIResponse "设备不存在"
+// @Router /apps/{appId}/device/topics [get]
+func (ctrl *TopicController) GetOwnTopics(ctx *gin.Context) {
+	topics, err := ctrl.topicService.GetDeviceTopics(sdkDevice(ctx))
+	if err != nil {
+		response.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, topics)
+}
+
+// UpdateOwnTopics 订阅或退订当前设备的主题
+// @Summary 订阅或退订当前设备的主题
+// @Description 客户端SDK按主题订阅或退订推送，指定主题的推送将不再发往已退订的设备
+// @Tags 客户端SDK
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param appId path int true "应用ID"
+// @Param X-Device-Token header string true "当前设备推送Token"
+// @Param request body UpdateDeviceTopicsRequest true "主题订阅状态"
+// @Success 200 {object} response.APIResponse{data=[]services.DeviceTopicStatus} "更新后的订阅状态"
+// @Failure 400 {object} response.APIResponse "请求参数错误或主题不存在"
+// @Failure 401 {object} response.APIResponse "API密钥无效或缺少设备Token"
+// @Failure 404 {object} response.APIResponse "设备不存在"
+// @Router /apps/{appId}/device/topics [put]
+func (ctrl *TopicController) UpdateOwnTopics(ctx *gin.Context) {
+	var req UpdateDeviceTopicsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	topics, err := ctrl.topicService.UpdateDeviceTopics(sdkDevice(ctx), req.Topics)
+	if err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, topics)
+}
@@ -0,0 +1,364 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/internal/services"
+	"github.com/doopush/doopush/api/pkg/response"
+	"github.com/doopush/doopush/api/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookController 事件回调控制器
+type WebhookController struct {
+	webhookService *services.WebhookService
+}
+
+// NewWebhookController 创建事件回调控制器
+func NewWebhookController() *WebhookController {
+	return &WebhookController{
+		webhookService: services.NewWebhookService(),
+	}
+}
+
+// CreateWebhookRequest 创建回调订阅请求
+type CreateWebhookRequest struct {
+	Name   string   `json:"name" binding:"required,max=100" example:"业务后端"`
+	URL    string   `json:"url" binding:"required,max=500" example:"https://example.com/doopush/webhook"`
+	Events []string `json:"events,omitempty" example:"push.delivered,push.clicked"` // 订阅的事件类型，为空表示全部
+}
+
+// UpdateWebhookRequest 更新回调订阅请求，未传的字段保持不变
+type UpdateWebhookRequest struct {
+	Name   *string  `json:"name,omitempty" binding:"omitempty,max=100" example:"业务后端"`
+	URL    *string  `json:"url,omitempty" binding:"omitempty,max=500" example:"https://example.com/doopush/webhook"`
+	Events []string `json:"events,omitempty" example:"push.delivered"` // 传空数组表示订阅全部事件
+	Status *int     `json:"status,omitempty" binding:"omitempty,oneof=0 1" example:"1"`
+}
+
+// WebhookSecretResponse 回调签名密钥响应
+type WebhookSecretResponse struct {
+	Secret  string          `json:"secret" example:"whsec_abc123..."`
+	Webhook *models.Webhook `json:"webhook"`
+	Warning string          `json:"warning" example:"请妥善保存签名密钥，再次获取将无法查看"`
+}
+
+// WebhookDeliveriesResponse 投递记录列表响应
+type WebhookDeliveriesResponse struct {
+	Items []models.WebhookDelivery `json:"items"`
+}
+
+// GetWebhooks 获取回调订阅列表
+// @Summary 获取回调订阅列表
+// @Description 获取应用的事件回调订阅
+// @Tags 事件回调
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Success 200 {object} response.APIResponse{data=[]models.Webhook} "回调订阅列表"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Router /apps/{appId}/webhooks [get]
+func (ctrl *WebhookController) GetWebhooks(ctx *gin.Context) {
+	appID, ok := parseAppID(ctx)
+	if !ok {
+		return
+	}
+
+	webhooks, err := ctrl.webhookService.GetWebhooks(appID)
+	if err != nil {
+		response.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, webhooks)
+}
+
+// CreateWebhook 创建回调订阅
+// @Summary 创建回调订阅
+// @Description 创建事件回调订阅，签名密钥仅在创建时返回一次
+// @Tags 事件回调
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param request body CreateWebhookRequest true "回调订阅"
+// @Success 201 {object} response.APIResponse{data=WebhookSecretResponse} "创建成功"
+// @Failure 400 {object} response.APIResponse "请求参数错误"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Router /apps/{appId}/webhooks [post]
+func (ctrl *WebhookController) CreateWebhook(ctx *gin.Context) {
+	appID, ok := parseAppID(ctx)
+	if !ok {
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	webhook, secret, err := ctrl.webhookService.CreateWebhook(appID, req.Name, req.URL, req.Events)
+	if err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, response.APIResponse{
+		Code:    201,
+		Message: "回调订阅创建成功",
+		Data: WebhookSecretResponse{
+			Secret:  secret,
+			Webhook: webhook,
+			Warning: "请妥善保存签名密钥，再次获取将无法查看",
+		},
+	})
+}
+
+// GetWebhook 获取回调订阅
+// @Summary 获取回调订阅
+// @Description 获取事件回调订阅详情
+// @Tags 事件回调
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param id path int true "回调订阅ID"
+// @Success 200 {object} response.APIResponse{data=models.Webhook} "回调订阅"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Failure 404 {object} response.APIResponse "回调订阅不存在"
+// @Router /apps/{appId}/webhooks/{id} [get]
+func (ctrl *WebhookController) GetWebhook(ctx *gin.Context) {
+	appID, webhookID, ok := parseWebhookParams(ctx)
+	if !ok {
+		return
+	}
+
+	webhook, err := ctrl.webhookService.GetWebhook(appID, webhookID)
+	respondWebhook(ctx, webhook, err)
+}
+
+// UpdateWebhook 更新回调订阅
+// @Summary 更新回调订阅
+// @Description 更新回调地址、订阅的事件或启用状态，禁用后不再发送新事件
+// @Tags 事件回调
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param id path int true "回调订阅ID"
+// @Param request body UpdateWebhookRequest true "回调订阅"
+// @Success 200 {object} response.APIResponse{data=models.Webhook} "更新成功"
+// @Failure 400 {object} response.APIResponse "请求参数错误"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Failure 404 {object} response.APIResponse "回调订阅不存在"
+// @Router /apps/{appId}/webhooks/{id} [put]
+func (ctrl *WebhookController) UpdateWebhook(ctx *gin.Context) {
+	appID, webhookID, ok := parseWebhookParams(ctx)
+	if !ok {
+		return
+	}
+
+	var req UpdateWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	webhook, err := ctrl.webhookService.UpdateWebhook(appID, webhookID, req.Name, req.URL, req.Events, req.Status)
+	respondWebhook(ctx, webhook, err)
+}
+
+// DeleteWebhook 删除回调订阅
+// @Summary 删除回调订阅
+// @Description 删除事件回调订阅，等待重试的投递不再发送
+// @Tags 事件回调
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param id path int true "回调订阅ID"
+// @Success 200 {object} response.APIResponse "删除成功"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Failure 404 {object} response.APIResponse "回调订阅不存在"
+// @Router /apps/{appId}/webhooks/{id} [delete]
+func (ctrl *WebhookController) DeleteWebhook(ctx *gin.Context) {
+	appID, webhookID, ok := parseWebhookParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.webhookService.DeleteWebhook(appID, webhookID); err != nil {
+		respondWebhook(ctx, nil, err)
+		return
+	}
+
+	response.Success(ctx, nil)
+}
+
+// RotateWebhookSecret 重新生成签名密钥
+// @Summary 重新生成签名密钥
+// @Description 重新生成回调签名密钥，旧密钥立即失效
+// @Tags 事件回调
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param id path int true "回调订阅ID"
+// @Success 200 {object} response.APIResponse{data=WebhookSecretResponse} "新的签名密钥"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Failure 404 {object} response.APIResponse "回调订阅不存在"
+// @Router /apps/{appId}/webhooks/{id}/rotate-secret [post]
+func (ctrl *WebhookController) RotateWebhookSecret(ctx *gin.Context) {
+	appID, webhookID, ok := parseWebhookParams(ctx)
+	if !ok {
+		return
+	}
+
+	webhook, secret, err := ctrl.webhookService.RotateSecret(appID, webhookID)
+	if err != nil {
+		respondWebhook(ctx, nil, err)
+		return
+	}
+
+	response.Success(ctx, WebhookSecretResponse{
+		Secret:  secret,
+		Webhook: webhook,
+		Warning: "请妥善保存签名密钥，再次获取将无法查看",
+	})
+}
+
+// TestWebhook 发送测试事件
+// @Summary 发送测试事件
+// @Description 向回调地址发送一条 webhook.test 事件并返回投递结果，失败不重试
+// @Tags 事件回调
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param id path int true "回调订阅ID"
+// @Success 200 {object} response.APIResponse{data=models.WebhookDelivery} "投递结果"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Failure 404 {object} response.APIResponse "回调订阅不存在"
+// @Router /apps/{appId}/webhooks/{id}/test [post]
+func (ctrl *WebhookController) TestWebhook(ctx *gin.Context) {
+	appID, webhookID, ok := parseWebhookParams(ctx)
+	if !ok {
+		return
+	}
+
+	delivery, err := ctrl.webhookService.Test(appID, webhookID)
+	respondWebhook(ctx, delivery, err)
+}
+
+// GetWebhookDeliveries 获取投递记录
+// @Summary 获取投递记录
+// @Description 获取回调订阅的事件投递记录，按时间倒序
+// @Tags 事件回调
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param id path int true "回调订阅ID"
+// @Param status query string false "投递状态筛选" Enums(pending, delivering, success, failed)
+// @Param event query string false "事件类型筛选"
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(20)
+// @Success 200 {object} response.APIResponse{data=WebhookDeliveriesResponse} "投递记录"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Failure 404 {object} response.APIResponse "回调订阅不存在"
+// @Router /apps/{appId}/webhooks/{id}/deliveries [get]
+func (ctrl *WebhookController) GetWebhookDeliveries(ctx *gin.Context) {
+	appID, webhookID, ok := parseWebhookParams(ctx)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	deliveries, total, err := ctrl.webhookService.GetDeliveries(appID, webhookID, ctx.Query("status"), ctx.Query("event"), page, pageSize)
+	if err != nil {
+		respondWebhook(ctx, nil, err)
+		return
+	}
+
+	response.Success(ctx, utils.NewPaginationResponse(page, pageSize, total, WebhookDeliveriesResponse{Items: deliveries}))
+}
+
+// ReplayWebhookDelivery 重放投递
+// @Summary 重放投递
+// @Description 以相同的事件ID和内容重新发送一次事件，生成新的投递记录
+// @Tags 事件回调
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appId path int true "应用ID"
+// @Param id path int true "回调订阅ID"
+// @Param deliveryId path int true "投递记录ID"
+// @Success 200 {object} response.APIResponse{data=models.WebhookDelivery} "新的投递记录"
+// @Failure 400 {object} response.APIResponse "投递尚未结束"
+// @Failure 401 {object} response.APIResponse "未认证"
+// @Failure 403 {object} response.APIResponse "无权限"
+// @Failure 404 {object} response.APIResponse "投递记录不存在"
+// @Router /apps/{appId}/webhooks/{id}/deliveries/{deliveryId}/replay [post]
+func (ctrl *WebhookController) ReplayWebhookDelivery(ctx *gin.Context) {
+	appID, webhookID, ok := parseWebhookParams(ctx)
+	if !ok {
+		return
+	}
+	deliveryID, err := strconv.ParseUint(ctx.Param("deliveryId"), 10, 32)
+	if err != nil {
+		response.BadRequest(ctx, "无效的投递记录ID")
+		return
+	}
+
+	delivery, err := ctrl.webhookService.Replay(appID, webhookID, uint(deliveryID))
+	respondWebhook(ctx, delivery, err)
+}
+
+// parseWebhookParams 解析应用ID和回调订阅ID
+func parseWebhookParams(ctx *gin.Context) (uint, uint, bool) {
+	appID, ok := parseAppID(ctx)
+	if !ok {
+		return 0, 0, false
+	}
+	webhookID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(ctx, "无效的回调订阅ID")
+		return 0, 0, false
+	}
+	return appID, uint(webhookID), true
+}
+
+// respondWebhook 返回回调订阅或投递记录，按错误类型映射状态码
+func respondWebhook(ctx *gin.Context, data interface{}, err error) {
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrWebhookNotFound), errors.Is(err, services.ErrWebhookDeliveryNotFound):
+			response.NotFound(ctx, err.Error())
+		default:
+			response.BadRequest(ctx, err.Error())
+		}
+		return
+	}
+	response.Success(ctx, data)
+}
@@ -3,11 +3,12 @@ package database
 import (
 	"database/sql"
 	"fmt"
-	"log"
+	"strings"
 	"time"
 
 	"github.com/doopush/doopush/api/internal/config"
 	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/pkg/logger"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
@@ -44,10 +45,10 @@ func Connect() {
 			}
 		}
 		if attempt == connectMaxAttempts {
-			log.Fatalf("数据库连接失败（已重试 %d 次）: %v", connectMaxAttempts, err)
+			logger.Fatal("数据库连接失败", "attempts", connectMaxAttempts, "error", err)
 		}
-		log.Printf("数据库连接失败（第 %d/%d 次），%s 后重试: %v",
-			attempt, connectMaxAttempts, connectRetryDelay, err)
+		logger.Warn("数据库连接失败，稍后重试",
+			"attempt", attempt, "max_attempts", connectMaxAttempts, "retry_in", connectRetryDelay.String(), "error", err)
 		time.Sleep(connectRetryDelay)
 	}
 
@@ -55,19 +56,83 @@ func Connect() {
 	sqlDB.SetMaxOpenConns(100)
 
 	DB = db
-	log.Println("数据库连接成功")
+	logger.Info("数据库连接成功")
 }
 
 // AutoMigrate 自动迁移数据表
 func AutoMigrate() {
+	// 定时推送的角标由固定数字改为角标取值，迁移前记录旧的列类型
+	legacyScheduledBadge := columnIsInteger(&models.ScheduledPush{}, "badge")
+	// API 密钥引入权限范围前创建的密钥，迁移后只保留客户端 SDK 权限
+	legacyAPIKeys := DB.Migrator().HasTable(&models.AppAPIKey{}) && !DB.Migrator().HasColumn(&models.AppAPIKey{}, "scopes")
+
 	// 执行自动迁移
 	if err := DB.AutoMigrate(models.AllModels()...); err != nil {
-		log.Fatal("数据库迁移失败:", err)
+		logger.Fatal("数据库迁移失败", "error", err)
+	}
+
+	// 旧任务的角标默认是固定的 1，每次执行都会把设备计数覆盖为 1，改为与普通推送一致的 +1 累加
+	if legacyScheduledBadge {
+		if err := DB.Model(&models.ScheduledPush{}).Where("badge = ?", "1").Update("badge", "").Error; err != nil {
+			logger.Warn("迁移定时推送角标失败", "error", err)
+		}
+	}
+
+	if legacyAPIKeys {
+		migrateLegacyAPIKeyScopes()
 	}
 
 	// 一次性清理 TCP 时代的死字段（GORM AutoMigrate 不会删列）
 	_ = DB.Migrator().DropColumn(&models.Device{}, "gateway_node")
 	_ = DB.Migrator().DropColumn(&models.Device{}, "connection_id")
 
-	log.Println("数据库迁移完成")
+	logger.Info("数据库迁移完成")
+}
+
+// migrateLegacyAPIKeyScopes 为引入权限范围前创建的密钥设置权限。旧密钥不区分用途，
+// 推送日志也没有记录发起的密钥，按应用判断：应用有过正式推送时保留 push:send，避免服务端集成升级后直接失败；
+// 广播和设备查询一律不授予。每个被迁移的密钥都记录日志，便于管理员逐个核对
+func migrateLegacyAPIKeyScopes() {
+	var keys []models.AppAPIKey
+	if err := DB.Find(&keys).Error; err != nil {
+		logger.Warn("迁移API密钥权限范围失败", "error", err)
+		return
+	}
+
+	pushedApps := map[uint]bool{}
+	for _, key := range keys {
+		if _, checked := pushedApps[key.AppID]; !checked {
+			var count int64
+			DB.Model(&models.PushLog{}).Where("app_id = ? AND sandbox = ?", key.AppID, false).Limit(1).Count(&count)
+			pushedApps[key.AppID] = count > 0
+		}
+
+		scopes := append([]string{}, models.SDKAPIKeyScopes...)
+		if pushedApps[key.AppID] {
+			scopes = append(scopes, models.APIKeyScopePushSend)
+		}
+		if err := DB.Model(&models.AppAPIKey{}).Where("id = ?", key.ID).Update("scopes", strings.Join(scopes, ",")).Error; err != nil {
+			logger.Warn("迁移API密钥权限范围失败", "app_id", key.AppID, "api_key_id", key.ID, "error", err)
+			continue
+		}
+		logger.Warn("已收紧升级前创建的API密钥权限，如需广播或查询设备请在控制台「编辑权限」中授予",
+			"app_id", key.AppID, "api_key_id", key.ID, "name", key.Name, "key_suffix", key.KeySuffix, "scopes", strings.Join(scopes, ","))
+	}
+}
+
+// columnIsInteger 表中的列当前是否为整数类型，表或列不存在时返回 false
+func columnIsInteger(model interface{}, column string) bool {
+	if !DB.Migrator().HasColumn(model, column) {
+		return false
+	}
+	columnTypes, err := DB.Migrator().ColumnTypes(model)
+	if err != nil {
+		return false
+	}
+	for _, columnType := range columnTypes {
+		if columnType.Name() == column {
+			return strings.Contains(strings.ToLower(columnType.DatabaseTypeName()), "int")
+		}
+	}
+	return false
 }
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestMigrateLegacyAPIKeyScopes(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.AppAPIKey{}, &models.PushLog{}); err != nil {
+		t.Fatal(err)
+	}
+	DB = db
+
+	// 应用 1 有正式推送，应用 2 只有沙箱推送，应用 3 没有推送
+	db.Create(&models.PushLog{AppID: 1, DeviceID: 1, Title: "t", Content: "c", Channel: "fcm", Payload: "{}"})
+	db.Create(&models.PushLog{AppID: 2, DeviceID: 2, Title: "t", Content: "c", Channel: "fcm", Payload: "{}", Sandbox: true})
+	for i, appID := range []uint{1, 2, 3} {
+		key := models.AppAPIKey{AppID: appID, Name: "legacy", KeyHash: string(rune('a' + i)), KeyPrefix: models.APIKeyPrefixLive, KeySuffix: "abcd",
+			Scopes: "device:register,stats:report,push:send,push:broadcast,devices:read"}
+		if err := db.Create(&key).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	migrateLegacyAPIKeyScopes()
+
+	want := map[uint]string{
+		1: "device:register,stats:report,push:send",
+		2: "device:register,stats:report",
+		3: "device:register,stats:report",
+	}
+	var keys []models.AppAPIKey
+	db.Find(&keys)
+	for _, key := range keys {
+		if key.Scopes != want[key.AppID] {
+			t.Errorf("app %d scopes = %q, want %q", key.AppID, key.Scopes, want[key.AppID])
+		}
+	}
+}
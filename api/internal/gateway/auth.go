@@ -38,10 +38,11 @@ func parseHandshakeParams(r *http.Request) (*HandshakeParams, error) {
 	return &HandshakeParams{AppID: uint(id), AppKey: appKey, Token: token}, nil
 }
 
-// authError 携带 HTTP status hint 的鉴权错误
+// authError 携带 HTTP status hint 的鉴权错误；reason 为固定枚举，用作握手失败指标的标签
 type authError struct {
 	status int
 	msg    string
+	reason string
 }
 
 func (e *authError) Error() string { return e.msg }
@@ -51,22 +52,22 @@ func authenticate(db *gorm.DB, p *HandshakeParams) (deviceID uint, err error) {
 	// 1. App 存在且启用
 	var app models.App
 	if err := db.Where("id = ? AND status = 1", p.AppID).First(&app).Error; err != nil {
-		return 0, &authError{status: http.StatusUnauthorized, msg: "app not found"}
+		return 0, &authError{status: http.StatusUnauthorized, msg: "app not found", reason: "app_not_found"}
 	}
 	// 2. AppKey 哈希匹配
 	keyHash := utils.HashString(p.AppKey)
 	var apiKey models.AppAPIKey
 	if err := db.Where("app_id = ? AND key_hash = ? AND status = 1", p.AppID, keyHash).First(&apiKey).Error; err != nil {
-		return 0, &authError{status: http.StatusUnauthorized, msg: "invalid appkey"}
+		return 0, &authError{status: http.StatusUnauthorized, msg: "invalid appkey", reason: "invalid_appkey"}
 	}
 	if apiKey.ExpiresAt != nil && apiKey.ExpiresAt.Before(time.Now()) {
-		return 0, &authError{status: http.StatusUnauthorized, msg: "appkey expired"}
+		return 0, &authError{status: http.StatusUnauthorized, msg: "appkey expired", reason: "appkey_expired"}
 	}
 	// 3. 设备 token 哈希匹配
 	tokenHash := utils.HashString(p.Token)
 	var device models.Device
 	if err := db.Where("app_id = ? AND token_hash = ? AND status = 1", p.AppID, tokenHash).First(&device).Error; err != nil {
-		return 0, &authError{status: http.StatusForbidden, msg: "invalid token"}
+		return 0, &authError{status: http.StatusForbidden, msg: "invalid token", reason: "invalid_token"}
 	}
 	return device.ID, nil
 }
@@ -75,7 +76,7 @@ func authenticate(db *gorm.DB, p *HandshakeParams) (deviceID uint, err error) {
 func AuthenticateRequest(r *http.Request) (*HandshakeParams, uint, error) {
 	p, err := parseHandshakeParams(r)
 	if err != nil {
-		return nil, 0, &authError{status: http.StatusBadRequest, msg: err.Error()}
+		return nil, 0, &authError{status: http.StatusBadRequest, msg: err.Error(), reason: "bad_request"}
 	}
 	deviceID, err := authenticate(database.DB, p)
 	if err != nil {
@@ -84,7 +85,7 @@ func AuthenticateRequest(r *http.Request) (*HandshakeParams, uint, error) {
 		if errors.As(err, &ae) {
 			return nil, 0, ae
 		}
-		return nil, 0, &authError{status: http.StatusInternalServerError, msg: err.Error()}
+		return nil, 0, &authError{status: http.StatusInternalServerError, msg: err.Error(), reason: "internal"}
 	}
 	return p, deviceID, nil
 }
@@ -97,3 +98,12 @@ func HTTPStatus(err error) int {
 	}
 	return http.StatusInternalServerError
 }
+
+// failureReason returns the metrics label for an auth error, or "internal" if err is not an auth error.
+func failureReason(err error) string {
+	var ae *authError
+	if errors.As(err, &ae) && ae.reason != "" {
+		return ae.reason
+	}
+	return "internal"
+}
@@ -2,12 +2,14 @@ package gateway
 
 import (
 	"context"
-	"log"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/coder/websocket"
+	"github.com/doopush/doopush/api/internal/metrics"
+	"github.com/doopush/doopush/api/pkg/logger"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -33,6 +35,7 @@ func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	// 1. 鉴权（在 Accept 之前）
 	params, _, err := AuthenticateRequest(r)
 	if err != nil {
+		metrics.GatewayHandshakeFailures.WithLabelValues(failureReason(err)).Inc()
 		http.Error(w, err.Error(), HTTPStatus(err))
 		return
 	}
@@ -45,11 +48,16 @@ func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	})
 	if accErr != nil {
 		// websocket.Accept 失败时已写入响应，无需再 WriteHeader
+		metrics.GatewayHandshakeFailures.WithLabelValues("upgrade_failed").Inc()
 		return
 	}
 	h.wg.Add(1)
 	defer h.wg.Done()
 
+	connections := metrics.GatewayConnections.WithLabelValues(strconv.FormatUint(uint64(params.AppID), 10))
+	connections.Inc()
+	defer connections.Dec()
+
 	// 3. 在线态 + 注册到表
 	// 顺序：先 MarkOnline 再 register。这样如果连接在握手后立即断开，
 	// closeFn 触发的 MarkOffline 的 DB goroutine 大概率排在 MarkOnline 的
@@ -68,7 +76,7 @@ func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 	h.reg.register(params.Token, wc) // 内部会异步关掉同 token 老连
 
-	log.Printf("ws open token=%s appID=%d", params.Token, params.AppID)
+	logger.Debug("ws open", "app_id", params.AppID, "token", params.Token)
 
 	// 4. 跑生命周期，阻塞到断开；返回后调 CloseWith 触发清理
 	ctx, cancel := context.WithCancel(r.Context())
@@ -90,6 +98,6 @@ func (h *Handler) Shutdown(timeout time.Duration) {
 	select {
 	case <-done:
 	case <-time.After(timeout):
-		log.Println("shutdown timeout，部分连接清理未完成")
+		logger.Warn("shutdown timeout，部分连接清理未完成")
 	}
 }
@@ -2,12 +2,13 @@ package gateway
 
 import (
 	"context"
-	"log"
+	"fmt"
 	"time"
 
 	"github.com/doopush/doopush/api/internal/database"
 	"github.com/doopush/doopush/api/internal/models"
 	"github.com/doopush/doopush/api/internal/services"
+	"github.com/doopush/doopush/api/pkg/logger"
 	"github.com/doopush/doopush/api/pkg/utils"
 	"github.com/redis/go-redis/v9"
 )
@@ -19,14 +20,14 @@ func MarkOnline(rdb *redis.Client, appID uint, token string) {
 	ctx := context.Background()
 	key := services.OnlineKeyPrefix + token
 	if err := rdb.Set(ctx, key, "1", onlineTTL).Err(); err != nil {
-		log.Printf("redis set online failed: %v", err)
+		logger.Error("redis set online failed", "app_id", appID, "token", token, "error", err)
 	}
 
 	// 数据库异步更新
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
-				log.Printf("db mark online panic token=%s: %v", token, r)
+				logger.Error("db mark online panic", "app_id", appID, "token", token, "panic", fmt.Sprint(r))
 			}
 		}()
 		now := time.Now()
@@ -39,8 +40,10 @@ func MarkOnline(rdb *redis.Client, appID uint, token string) {
 				"last_heartbeat": &now,
 			}).Error
 		if err != nil {
-			log.Printf("db mark online failed token=%s: %v", token, err)
+			logger.Error("db mark online failed", "app_id", appID, "token", token, "error", err)
+			return
 		}
+		emitDeviceEvent(services.WebhookEventDeviceOnline, appID, tokenHash)
 	}()
 }
 
@@ -49,12 +52,12 @@ func MarkOffline(rdb *redis.Client, appID uint, token string) {
 	ctx := context.Background()
 	key := services.OnlineKeyPrefix + token
 	if err := rdb.Del(ctx, key).Err(); err != nil {
-		log.Printf("redis del online failed: %v", err)
+		logger.Error("redis del online failed", "app_id", appID, "token", token, "error", err)
 	}
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
-				log.Printf("db mark offline panic token=%s: %v", token, r)
+				logger.Error("db mark offline panic", "app_id", appID, "token", token, "panic", fmt.Sprint(r))
 			}
 		}()
 		tokenHash := utils.HashString(token)
@@ -62,7 +65,18 @@ func MarkOffline(rdb *redis.Client, appID uint, token string) {
 			Where("app_id = ? AND token_hash = ?", appID, tokenHash).
 			Update("is_online", false).Error
 		if err != nil {
-			log.Printf("db mark offline failed token=%s: %v", token, err)
+			logger.Error("db mark offline failed", "app_id", appID, "token", token, "error", err)
+			return
 		}
+		emitDeviceEvent(services.WebhookEventDeviceOffline, appID, tokenHash)
 	}()
 }
+
+// emitDeviceEvent 发送设备上下线事件回调
+func emitDeviceEvent(event string, appID uint, tokenHash string) {
+	var device models.Device
+	if err := database.DB.Where("app_id = ? AND token_hash = ?", appID, tokenHash).First(&device).Error; err != nil {
+		return
+	}
+	services.NewWebhookService().Emit(services.DeviceEvent(event, &device))
+}
@@ -3,7 +3,6 @@ package gateway
 import (
 	"context"
 	"fmt"
-	"log"
 	"net"
 	"net/http"
 	"os"
@@ -12,9 +11,12 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/doopush/doopush/api/internal/config"
 	"github.com/doopush/doopush/api/internal/database"
+	"github.com/doopush/doopush/api/internal/metrics"
 	"github.com/doopush/doopush/api/internal/redisclient"
 	"github.com/doopush/doopush/api/internal/services"
+	"github.com/doopush/doopush/api/pkg/logger"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -55,13 +57,16 @@ func NewGatewayServer() (*GatewayServer, error) {
 func (s *GatewayServer) Start() error {
 	// 同步清零历史在线态：必须在监听前完成，否则会与首个 device 的 MarkOnline 竞态
 	if err := services.NewDeviceService().SetAllDevicesOffline(); err != nil {
-		log.Printf("清零在线态失败: %v", err)
+		logger.Error("清零在线态失败", "error", err)
 	}
 
 	// 信号驱动优雅关闭
 	go s.handleSignals()
 
-	log.Printf("WebSocket gateway 监听 %s", ListenAddr)
+	// Prometheus 指标在独立的内部地址输出，不挂在对外的 WebSocket 端口上；默认只监听本机
+	metrics.Serve(config.GetString("GATEWAY_METRICS_ADDR", "127.0.0.1:9092"))
+
+	logger.Info("WebSocket gateway 启动", "addr", ListenAddr)
 	if err := s.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		return fmt.Errorf("gateway 启动失败: %w", err)
 	}
@@ -72,7 +77,7 @@ func (s *GatewayServer) handleSignals() {
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, syscall.SIGTERM, syscall.SIGINT)
 	<-ch
-	log.Println("收到关闭信号，开始优雅关闭")
+	logger.Info("收到关闭信号，开始优雅关闭")
 
 	// 1. 停止接受新连接
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
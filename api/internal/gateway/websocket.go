@@ -2,11 +2,13 @@ package gateway
 
 import (
 	"context"
-	"log"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/coder/websocket"
+	"github.com/doopush/doopush/api/internal/metrics"
+	"github.com/doopush/doopush/api/pkg/logger"
 )
 
 const (
@@ -76,11 +78,26 @@ func (w *wsConn) run(ctx context.Context) {
 
 	select {
 	case err := <-readerErr:
-		log.Printf("ws reader exit token=%s: %v", w.token, err)
+		logger.Debug("ws reader exit", "app_id", w.appID, "token", w.token, "error", err)
+		metrics.GatewayCloses.WithLabelValues(closeCodeLabel(err)).Inc()
 		// reader 异常通常意味着对端断开；立刻清理在线态，避免最长 30s 的幽灵在线
 		w.CloseWith(int(websocket.StatusNormalClosure), "reader exit")
 	case err := <-pingErr:
-		log.Printf("ws ping exit token=%s: %v", w.token, err)
+		logger.Debug("ws ping exit", "app_id", w.appID, "token", w.token, "error", err)
+		// 服务关闭导致的退出不算心跳超时，也不记为 1008 关闭
+		if ctx.Err() == nil {
+			metrics.GatewayPingTimeouts.Inc()
+			metrics.GatewayCloses.WithLabelValues(strconv.Itoa(int(websocket.StatusPolicyViolation))).Inc()
+		}
 		w.CloseWith(int(websocket.StatusPolicyViolation), "pong timeout")
 	}
 }
+
+// closeCodeLabel 返回对端关闭码作为指标标签；没有关闭帧的断开（网络中断、进程退出）记为 abnormal
+func closeCodeLabel(err error) string {
+	code := websocket.CloseStatus(err)
+	if code == -1 {
+		return "abnormal"
+	}
+	return strconv.Itoa(int(code))
+}
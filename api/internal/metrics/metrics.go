@@ -0,0 +1,152 @@
+// Package metrics 定义 API 服务与 WebSocket 网关暴露的 Prometheus 指标。
+// 指标由 serve 和 gateway 进程在独立的内部监听地址（METRICS_ADDR / GATEWAY_METRICS_ADDR）的 /metrics 路径输出，
+// 不挂在对外的 API 和 WebSocket 端口上。
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/doopush/doopush/api/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "doopush"
+
+// API 服务
+var (
+	// HTTPRequestDuration HTTP 请求耗时，route 为路由模板（如 /api/v1/apps/:appId/push），未匹配的路由记为 unmatched
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP 请求耗时",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// 推送发送
+var (
+	// PushesTotal 推送发送结果，result 为 sent/failed，失败时 error_code 为厂商错误码
+	PushesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pushes_total",
+		Help:      "推送发送次数",
+	}, []string{"channel", "result", "error_code"})
+
+	// VendorRequestDuration 请求厂商推送服务的耗时，包括鉴权请求；status 为 HTTP 状态码，网络错误记为 error
+	VendorRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "vendor_request_duration_seconds",
+		Help:      "厂商推送服务请求耗时",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30},
+	}, []string{"channel", "status"})
+
+	// PushQueueDepth 各状态下等待发送的推送数
+	PushQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "push_queue_depth",
+		Help:      "等待发送的推送数",
+	}, []string{"status"})
+
+	// SchedulerLag 定时推送实际开始执行时间与计划执行时间的差值
+	SchedulerLag = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "scheduler_lag_seconds",
+		Help:      "定时推送执行延迟",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800},
+	})
+
+	// SchedulerLastTick 调度器最近一次执行的时间戳，长时间不变说明调度器卡住
+	SchedulerLastTick = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "scheduler_last_tick_timestamp_seconds",
+		Help:      "调度器最近一次执行的时间戳",
+	})
+)
+
+// WebSocket 网关
+var (
+	// GatewayConnections 各应用当前的 WebSocket 连接数
+	GatewayConnections = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "gateway_connections",
+		Help:      "当前 WebSocket 连接数",
+	}, []string{"app_id"})
+
+	// GatewayHandshakeFailures 握手失败次数
+	GatewayHandshakeFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gateway_handshake_failures_total",
+		Help:      "WebSocket 握手失败次数",
+	}, []string{"reason"})
+
+	// GatewayPingTimeouts 因 pong 超时断开的连接数
+	GatewayPingTimeouts = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gateway_ping_timeouts_total",
+		Help:      "pong 超时断开的连接数",
+	})
+
+	// GatewayCloses 连接关闭次数。对端断开时 code 为对端关闭码，无关闭帧的断开记为 abnormal；pong 超时由服务端以 1008 关闭
+	GatewayCloses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gateway_closes_total",
+		Help:      "WebSocket 连接关闭次数",
+	}, []string{"code"})
+)
+
+// Handler 返回 /metrics 处理器
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Serve 在独立的内部地址上输出 /metrics，addr 为空时不输出。监听失败只记录日志，不影响主服务
+func Serve(addr string) {
+	if addr == "" {
+		logger.Info("未配置指标监听地址，不输出 /metrics")
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		logger.Info("指标服务启动", "addr", addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("指标服务启动失败", "addr", addr, "error", err)
+		}
+	}()
+}
+
+// InstrumentVendorTransport 包装厂商请求使用的 Transport，记录请求耗时。next 为 nil 时使用 http.DefaultTransport
+func InstrumentVendorTransport(channel string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &vendorTransport{channel: channel, next: next}
+}
+
+type vendorTransport struct {
+	channel string
+	next    http.RoundTripper
+}
+
+func (t *vendorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	VendorRequestDuration.WithLabelValues(t.channel, status).Observe(time.Since(start).Seconds())
+	return resp, err
+}
+
+// CloseIdleConnections 透传给底层 Transport，保持 http.Client.CloseIdleConnections 的行为
+func (t *vendorTransport) CloseIdleConnections() {
+	if c, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("dial failed")
+}
+
+func TestInstrumentVendorTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: InstrumentVendorTransport("xiaomi", nil)}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	failing := &http.Client{Transport: InstrumentVendorTransport("xiaomi", failingTransport{})}
+	if _, err := failing.Get(server.URL); err == nil {
+		t.Fatal("expected transport error")
+	}
+
+	// 状态码 503 和网络错误各一条时间序列
+	if n := testutil.CollectAndCount(VendorRequestDuration); n != 2 {
+		t.Fatalf("series = %d, want 2", n)
+	}
+}
@@ -190,6 +190,9 @@ func parseActionFromRequest(c *gin.Context) (action, resource, resourceID string
 				}
 			case "config":
 				resource = "config"
+				if len(pathParts) > i+1 && isNumeric(pathParts[i+1]) {
+					resourceID = pathParts[i+1]
+				}
 			case "groups":
 				resource = "group"
 				if len(pathParts) > i+1 && isNumeric(pathParts[i+1]) {
@@ -200,6 +203,16 @@ func parseActionFromRequest(c *gin.Context) (action, resource, resourceID string
 				if len(pathParts) > i+1 && isNumeric(pathParts[i+1]) {
 					resourceID = pathParts[i+1]
 				}
+			case "webhooks":
+				resource = "webhook"
+				if len(pathParts) > i+1 && isNumeric(pathParts[i+1]) {
+					resourceID = pathParts[i+1]
+				}
+			case "alert-rules":
+				resource = "alert_rule"
+				if len(pathParts) > i+1 && isNumeric(pathParts[i+1]) {
+					resourceID = pathParts[i+1]
+				}
 			}
 		}
 	}
@@ -211,6 +224,9 @@ func parseActionFromRequest(c *gin.Context) (action, resource, resourceID string
 	} else if strings.Contains(path, "/auth/logout") {
 		action = "logout"
 		resource = "user"
+	} else if strings.HasSuffix(path, "/reveal") {
+		// 查看推送配置明文
+		action = "reveal"
 	}
 
 	return action, resource, resourceID
@@ -291,12 +307,23 @@ func filterSensitiveFields(data map[string]interface{}) map[string]interface{} {
 		"password", "pwd", "secret", "token", "key", "api_key",
 		"authorization", "auth", "credential", "private_key",
 	}
+	// 只按完整字段名匹配，避免过滤 repeat_config 等普通字段
+	sensitiveExactFields := []string{
+		"config", // 推送配置 JSON 中包含厂商凭据
+	}
 
 	filtered := make(map[string]interface{})
 	for key, value := range data {
 		keyLower := strings.ToLower(key)
 		isSensitive := false
 
+		for _, sensitive := range sensitiveExactFields {
+			if keyLower == sensitive {
+				isSensitive = true
+				break
+			}
+		}
+
 		for _, sensitive := range sensitiveFields {
 			if strings.Contains(keyLower, sensitive) {
 				isSensitive = true
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -9,7 +10,9 @@ import (
 	"github.com/doopush/doopush/api/internal/config"
 	"github.com/doopush/doopush/api/internal/database"
 	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/internal/services"
 	"github.com/doopush/doopush/api/pkg/auth"
+	"github.com/doopush/doopush/api/pkg/logger"
 	"github.com/doopush/doopush/api/pkg/response"
 	"github.com/doopush/doopush/api/pkg/utils"
 	"github.com/gin-gonic/gin"
@@ -57,8 +60,8 @@ func JWTAuth() gin.HandlerFunc {
 	}
 }
 
-// APIKeyAuth API密钥认证中间件
-func APIKeyAuth() gin.HandlerFunc {
+// APIKeyAuth API密钥认证中间件，scope 为接口要求的密钥权限范围
+func APIKeyAuth(scope string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取API Key (支持Header和Query参数)
 		apiKey := c.GetHeader("X-API-Key")
@@ -79,26 +82,25 @@ func APIKeyAuth() gin.HandlerFunc {
 			return
 		}
 
-		// 获取应用ID参数（如果存在）
-		appIDStr := c.Param("appId")
-		if appIDStr != "" {
-			appID, err := strconv.ParseUint(appIDStr, 10, 32)
-			if err != nil {
-				response.BadRequest(c, "无效的应用ID")
-				c.Abort()
-				return
-			}
+		// 获取应用ID参数，权限范围按应用校验
+		appID, err := strconv.ParseUint(c.Param("appId"), 10, 32)
+		if err != nil {
+			response.BadRequest(c, "无效的应用ID")
+			c.Abort()
+			return
+		}
 
-			// 验证API Key是否属于该应用
-			if !validateAPIKeyForApp(apiKey, uint(appID)) {
-				response.Unauthorized(c, "API密钥与应用不匹配")
-				c.Abort()
-				return
-			}
+		// 验证API Key是否属于该应用，并检查权限范围、IP白名单和频率限制
+		key, err := validateAPIKeyForApp(c, apiKey, uint(appID), scope)
+		if err != nil {
+			abortAPIKeyError(c, err)
+			return
 		}
 
-		// 将API密钥存储到上下文
+		// 将API密钥存储到上下文，测试密钥的请求只访问沙箱设备，推送不发往厂商
 		c.Set("api_key", apiKey)
+		c.Set("api_key_record", key)
+		c.Set("sandbox", key.IsTest())
 
 		c.Next()
 	}
@@ -197,8 +199,8 @@ func RequireAppRole(requiredRole string) gin.HandlerFunc {
 }
 
 // DualAuth 双重认证中间件 (支持JWT和API Key认证)
-// 专为推送API设计，确保API Key认证时也能正确验证权限
-func DualAuth() gin.HandlerFunc {
+// 专为推送API设计，API Key 认证时检查 scope 指定的权限范围，JWT 认证由应用角色控制
+func DualAuth(scope string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 优先尝试JWT认证
 		authHeader := c.GetHeader("Authorization")
@@ -244,25 +246,33 @@ func DualAuth() gin.HandlerFunc {
 				return
 			}
 
-			// 验证API Key是否属于该应用
-			if validateAPIKeyForApp(apiKey, uint(appID)) {
-				// API Key认证成功，查询应用的owner用户ID
-				var permission models.UserAppPermission
-				if err := database.DB.Where("app_id = ? AND role = ?", appID, "owner").First(&permission).Error; err == nil {
-					// 为API Key认证设置虚拟用户信息，使用应用owner的ID
-					c.Set("user_id", permission.UserID)
-					c.Set("username", "api_key_user")
-					c.Set("email", "")
-					c.Set("api_key", apiKey)
-					c.Set("auth_type", "api_key")
-					c.Set("app_id", uint(appID))
-					c.Next()
-					return
+			// 验证API Key是否属于该应用，并检查权限范围、IP白名单和频率限制
+			key, err := validateAPIKeyForApp(c, apiKey, uint(appID), scope)
+			if err != nil {
+				if errors.Is(err, errAPIKeyInvalid) {
+					err = errors.New("无效的API密钥")
 				}
+				abortAPIKeyError(c, err)
+				return
 			}
 
-			response.Unauthorized(c, "无效的API密钥")
-			c.Abort()
+			// API Key认证成功，查询应用的owner用户ID
+			var permission models.UserAppPermission
+			if err := database.DB.Where("app_id = ? AND role = ?", appID, "owner").First(&permission).Error; err != nil {
+				response.Unauthorized(c, "无效的API密钥")
+				c.Abort()
+				return
+			}
+			// 为API Key认证设置虚拟用户信息，使用应用owner的ID
+			c.Set("user_id", permission.UserID)
+			c.Set("username", "api_key_user")
+			c.Set("email", "")
+			c.Set("api_key", apiKey)
+			c.Set("api_key_record", key)
+			c.Set("auth_type", "api_key")
+			c.Set("app_id", uint(appID))
+			c.Set("sandbox", key.IsTest())
+			c.Next()
 			return
 		}
 
@@ -278,7 +288,8 @@ func CORS() gin.HandlerFunc {
 		origin := c.Request.Header.Get("Origin")
 		c.Header("Access-Control-Allow-Origin", origin)
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, X-Device-Token, X-Request-Id, traceparent, tracestate")
+		c.Header("Access-Control-Expose-Headers", "X-Trace-Id, X-Request-Id")
 		c.Header("Access-Control-Allow-Credentials", "true")
 
 		if c.Request.Method == "OPTIONS" {
@@ -290,30 +301,74 @@ func CORS() gin.HandlerFunc {
 	}
 }
 
-// validateAPIKeyForApp 验证API Key是否属于指定应用
-func validateAPIKeyForApp(apiKey string, appID uint) bool {
-	// 计算API Key的哈希值
-	keyHash := utils.HashString(apiKey)
+// API密钥校验失败的原因
+var (
+	errAPIKeyInvalid     = errors.New("API密钥与应用不匹配")
+	errAPIKeyIPDenied    = errors.New("请求IP不在API密钥的白名单内")
+	errAPIKeyRateLimited = errors.New("API密钥请求过于频繁，请稍后再试")
+)
 
-	// 查询数据库验证API Key
+// errAPIKeyScope 密钥缺少接口要求的权限范围
+type errAPIKeyScope string
+
+func (e errAPIKeyScope) Error() string {
+	return "API密钥缺少权限: " + string(e)
+}
+
+// abortAPIKeyError 按校验失败原因返回对应的状态码
+func abortAPIKeyError(c *gin.Context, err error) {
+	var scopeErr errAPIKeyScope
+	switch {
+	case errors.As(err, &scopeErr), errors.Is(err, errAPIKeyIPDenied):
+		response.Forbidden(c, err.Error())
+	case errors.Is(err, errAPIKeyRateLimited):
+		c.Header("Retry-After", strconv.Itoa(60-time.Now().Second()))
+		response.Error(c, http.StatusTooManyRequests, err.Error())
+	default:
+		response.Unauthorized(c, err.Error())
+	}
+	c.Abort()
+}
+
+// validateAPIKeyForApp 验证API Key是否属于指定应用，并依次检查权限范围、IP白名单和频率限制。
+// scope 为空时不检查权限范围
+func validateAPIKeyForApp(c *gin.Context, apiKey string, appID uint, scope string) (*models.AppAPIKey, error) {
+	// 计算API Key的哈希值，查询数据库验证API Key
 	var appAPIKey models.AppAPIKey
-	err := database.DB.Where("app_id = ? AND key_hash = ? AND status = 1", appID, keyHash).First(&appAPIKey).Error
+	if err := database.DB.Where("app_id = ? AND key_hash = ? AND status = 1", appID, utils.HashString(apiKey)).First(&appAPIKey).Error; err != nil {
+		return nil, errAPIKeyInvalid
+	}
+	// 检查是否过期
+	if appAPIKey.ExpiresAt != nil && appAPIKey.ExpiresAt.Before(time.Now()) {
+		return nil, errAPIKeyInvalid
+	}
 
-	// 如果找到对应记录且未过期，则验证通过
-	if err == nil {
-		// 检查是否过期
-		if appAPIKey.ExpiresAt != nil && appAPIKey.ExpiresAt.Before(time.Now()) {
-			return false // 密钥已过期
-		}
+	if scope != "" && !appAPIKey.HasScope(scope) {
+		return nil, errAPIKeyScope(scope)
+	}
+	if !appAPIKey.AllowsIP(c.ClientIP()) {
+		logger.WarnContext(c.Request.Context(), "API密钥请求IP不在白名单内", "app_id", appID, "api_key_id", appAPIKey.ID, "ip", c.ClientIP())
+		return nil, errAPIKeyIPDenied
+	}
+	if !services.NewAppService().AllowAPIKeyRequest(c.Request.Context(), &appAPIKey, time.Now()) {
+		return nil, errAPIKeyRateLimited
+	}
 
-		// 异步更新最后使用时间
-		go func() {
-			now := time.Now()
-			database.DB.Model(&appAPIKey).Update("last_used", &now)
-		}()
+	// 异步更新最后使用时间
+	go func() {
+		now := time.Now()
+		database.DB.Model(&appAPIKey).Update("last_used", &now)
+	}()
 
+	return &appAPIKey, nil
+}
+
+// APIKeyAllows 当前请求是否具备指定的API密钥权限范围。JWT 认证的请求由应用角色控制，总是返回 true
+func APIKeyAllows(c *gin.Context, scope string) bool {
+	value, exists := c.Get("api_key_record")
+	if !exists {
 		return true
 	}
-
-	return false
+	key, ok := value.(*models.AppAPIKey)
+	return ok && key.HasScope(scope)
 }
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/doopush/doopush/api/internal/services"
+	"github.com/doopush/doopush/api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// DeviceTokenAuth 客户端SDK设备认证中间件，需在 APIKeyAuth 之后使用。
+// 通过 X-Device-Token 头定位调用方设备，后续接口只能操作该设备自身的数据。
+func DeviceTokenAuth() gin.HandlerFunc {
+	deviceService := services.NewDeviceService()
+
+	return func(c *gin.Context) {
+		token := c.GetHeader("X-Device-Token")
+		if token == "" {
+			response.Unauthorized(c, "缺少设备Token")
+			c.Abort()
+			return
+		}
+
+		appID, err := strconv.ParseUint(c.Param("appId"), 10, 32)
+		if err != nil {
+			response.BadRequest(c, "无效的应用ID")
+			c.Abort()
+			return
+		}
+
+		// 测试密钥只能操作沙箱设备，正式密钥只能操作正式设备
+		device, err := deviceService.GetDeviceByTokenHash(uint(appID), token)
+		if err != nil || device.Sandbox != c.GetBool("sandbox") {
+			response.NotFound(c, "设备不存在，请先注册设备")
+			c.Abort()
+			return
+		}
+
+		c.Set("device", device)
+		c.Next()
+	}
+}
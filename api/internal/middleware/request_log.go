@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/doopush/doopush/api/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestLogger 为每个请求分配请求 ID 并记录访问日志。
+// 请求头带 X-Request-Id 时沿用（最长 64 字符），请求 ID 写入响应头和请求 ctx，之后以该 ctx 记录的日志都带 request_id
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader("X-Request-Id")
+		if requestID == "" || len(requestID) > 64 {
+			requestID = newRequestID()
+		}
+		c.Header("X-Request-Id", requestID)
+		c.Request = c.Request.WithContext(logger.ContextWithRequestID(c.Request.Context(), requestID))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []any{
+			"method", c.Request.Method,
+			"route", c.FullPath(),
+			"path", c.Request.URL.Path,
+			"status", status,
+			"latency_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+		}
+		if appID := c.Param("appId"); appID != "" {
+			attrs = append(attrs, "app_id", appID)
+		}
+		if userID := c.GetUint("user_id"); userID != 0 {
+			attrs = append(attrs, "user_id", userID)
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "error", c.Errors.String())
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		slog.Default().Log(c.Request.Context(), level, "HTTP 请求", attrs...)
+	}
+}
+
+// newRequestID 生成 16 位十六进制请求 ID
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
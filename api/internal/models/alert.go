@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AlertRule 应用告警规则，由调度器定期评估
+type AlertRule struct {
+	ID            uint           `gorm:"primarykey" json:"id"`
+	AppID         uint           `gorm:"not null;index;comment:应用ID" json:"app_id"`
+	Name          string         `gorm:"size:100;not null;comment:名称" json:"name" example:"华为失败率过高"`
+	Type          string         `gorm:"size:30;not null;comment:规则类型 failure_rate/error_code/callback_failure_rate/cert_expiry" json:"type" example:"failure_rate"`
+	Channel       string         `gorm:"size:20;comment:推送通道或厂商，为空表示全部" json:"channel" example:"huawei"`
+	ErrorCode     string         `gorm:"size:200;comment:错误代码，逗号分隔，仅 error_code 类型使用" json:"error_code" example:"AUTHENTICATION_ERROR"`
+	Threshold     float64        `gorm:"not null;comment:阈值 失败率百分比/出现次数/证书剩余天数" json:"threshold" example:"20"`
+	WindowMinutes int            `gorm:"default:15;comment:统计窗口（分钟）" json:"window_minutes" example:"15"`
+	MinSamples    int            `gorm:"default:20;comment:失败率规则的最少样本数" json:"min_samples" example:"20"`
+	Status        int            `gorm:"default:1;comment:状态 1=启用 0=禁用" json:"status" example:"1"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// Alert 告警记录。同一规则同一对象（通道、厂商）同时只有一条 firing 告警，恢复后 FiringKey 置空
+type Alert struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	AppID      uint       `gorm:"not null;index;comment:应用ID" json:"app_id"`
+	RuleID     uint       `gorm:"not null;index;comment:告警规则ID" json:"rule_id"`
+	RuleName   string     `gorm:"size:100;not null;comment:规则名称快照" json:"rule_name" example:"华为失败率过高"`
+	Type       string     `gorm:"size:30;not null;comment:规则类型" json:"type" example:"failure_rate"`
+	Subject    string     `gorm:"size:20;not null;comment:告警对象（通道或厂商）" json:"subject" example:"huawei"`
+	Status     string     `gorm:"size:20;not null;default:firing;index;comment:状态 firing/resolved" json:"status" example:"firing"`
+	Value      float64    `gorm:"comment:触发时的观测值" json:"value" example:"35.5"`
+	Threshold  float64    `gorm:"comment:触发时的阈值" json:"threshold" example:"20"`
+	Message    string     `gorm:"size:500;comment:告警说明" json:"message"`
+	FiringKey  *string    `gorm:"size:64;uniqueIndex;comment:去重键 <规则ID>:<对象>，恢复后置空" json:"-"`
+	FiredAt    time.Time  `gorm:"not null;comment:触发时间" json:"fired_at"`
+	ResolvedAt *time.Time `gorm:"comment:恢复时间" json:"resolved_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (AlertRule) TableName() string {
+	return "alert_rules"
+}
+
+func (Alert) TableName() string {
+	return "alerts"
+}
@@ -1,8 +1,12 @@
 package models
 
 import (
+	"net/netip"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/doopush/doopush/api/internal/secrets"
 	"gorm.io/gorm"
 )
 
@@ -15,6 +19,8 @@ type App struct {
 	Platform    string         `gorm:"size:20;not null;comment:平台类型" json:"platform" example:"both" binding:"required,oneof=ios android both"`
 	AppIcon     string         `gorm:"size:255;comment:应用图标URL" json:"app_icon" example:"/uploads/icons/app_123.png"`
 	Status      int            `gorm:"default:1;comment:应用状态 1=正常 0=禁用" json:"status" example:"1"`
+	QuietStart  string         `gorm:"column:quiet_hours_start;size:5;comment:静默时段开始(设备本地时间HH:MM)" json:"quiet_hours_start" example:"22:00"`
+	QuietEnd    string         `gorm:"column:quiet_hours_end;size:5;comment:静默时段结束(设备本地时间HH:MM)" json:"quiet_hours_end" example:"08:00"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
@@ -26,25 +32,137 @@ type App struct {
 	UserPermissions []UserAppPermission `gorm:"foreignKey:AppID" json:"user_permissions,omitempty"`
 }
 
+// AppFrequencyCap 应用频控配置，0 表示不限制
+type AppFrequencyCap struct {
+	ID              uint      `gorm:"primarykey" json:"id"`
+	AppID           uint      `gorm:"uniqueIndex;not null;comment:应用ID" json:"app_id"`
+	MaxPushPerDay   int       `gorm:"not null;default:0;comment:应用每日推送配额" json:"max_push_per_day" example:"100000"`
+	DeviceDailyCap  int       `gorm:"not null;default:0;comment:单设备每日推送上限" json:"device_daily_cap" example:"3"`
+	DeviceWeeklyCap int       `gorm:"not null;default:0;comment:单设备每周推送上限" json:"device_weekly_cap" example:"10"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// AppApprovalPolicy 应用推送审批策略，两项条件都未设置时不需要审批
+type AppApprovalPolicy struct {
+	ID                     uint      `gorm:"primarykey" json:"id"`
+	AppID                  uint      `gorm:"uniqueIndex;not null;comment:应用ID" json:"app_id"`
+	DeviceThreshold        int       `gorm:"not null;default:0;comment:需要审批的目标设备数阈值" json:"device_threshold" example:"10000"` // 目标设备数超过该值时需要审批，0=不按设备数审批
+	RequireForBroadcast    bool      `gorm:"not null;default:false;comment:广播是否需要审批" json:"require_for_broadcast" example:"true"`
+	AllowDeveloperApproval bool      `gorm:"not null;default:false;comment:是否允许其他开发者审批" json:"allow_developer_approval" example:"false"` // false=仅 owner 可审批
+	CreatedAt              time.Time `json:"created_at"`
+	UpdatedAt              time.Time `json:"updated_at"`
+}
+
+// AppConfigHealth 推送配置凭据健康状态，由后台任务定期向厂商鉴权接口检查，也可以手动触发
+type AppConfigHealth struct {
+	ID            uint       `gorm:"primarykey" json:"id"`
+	AppID         uint       `gorm:"not null;index;comment:应用ID" json:"app_id"`
+	ConfigID      uint       `gorm:"uniqueIndex;not null;comment:推送配置ID" json:"config_id"`
+	Status        string     `gorm:"size:20;not null;comment:检查结果 healthy/unhealthy/unsupported" json:"status" example:"healthy"`
+	Message       string     `gorm:"size:500;comment:检查说明或错误信息" json:"message" example:"已获取鉴权令牌"`
+	CheckedAt     time.Time  `gorm:"not null;comment:最近检查时间" json:"checked_at"`
+	LastSuccessAt *time.Time `gorm:"comment:最近一次检查通过的时间" json:"last_success_at"`
+	ExpiresAt     *time.Time `gorm:"comment:凭据过期时间(APNs证书)" json:"expires_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
 // AppAPIKey 应用API密钥模型
 type AppAPIKey struct {
-	ID        uint           `gorm:"primarykey" json:"id"`
-	AppID     uint           `gorm:"not null;comment:应用ID" json:"app_id" binding:"required"`
-	Name      string         `gorm:"size:100;not null" json:"name" example:"生产环境密钥" binding:"required"`
-	KeyHash   string         `gorm:"size:64;uniqueIndex;not null;comment:API密钥哈希" json:"-"`
-	KeyPrefix string         `gorm:"size:10;not null;comment:密钥前缀" json:"key_prefix" example:"dp_live_"`
-	KeySuffix string         `gorm:"size:8;not null;comment:密钥后缀" json:"key_suffix" example:"1a2b3c4d"`
-	Status    int            `gorm:"default:1;comment:密钥状态 1=启用 0=禁用" json:"status" example:"1"`
-	LastUsed  *time.Time     `gorm:"comment:最后使用时间" json:"last_used"`
-	ExpiresAt *time.Time     `gorm:"comment:过期时间" json:"expires_at"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID           uint           `gorm:"primarykey" json:"id"`
+	AppID        uint           `gorm:"not null;comment:应用ID" json:"app_id" binding:"required"`
+	Name         string         `gorm:"size:100;not null" json:"name" example:"生产环境密钥" binding:"required"`
+	KeyHash      string         `gorm:"size:64;uniqueIndex;not null;comment:API密钥哈希" json:"-"`
+	KeyPrefix    string         `gorm:"size:10;not null;comment:密钥前缀" json:"key_prefix" example:"dp_live_"` // dp_live_=正式密钥，dp_test_=测试密钥
+	KeySuffix    string         `gorm:"size:8;not null;comment:密钥后缀" json:"key_suffix" example:"1a2b3c4d"`
+	Status       int            `gorm:"default:1;comment:密钥状态 1=启用 0=禁用" json:"status" example:"1"`
+	Scopes       string         `gorm:"size:255;not null;default:'device:register,stats:report';comment:权限范围，逗号分隔" json:"scopes" example:"device:register,stats:report"` // 升级前创建的密钥见 database.migrateLegacyAPIKeyScopes
+	AllowedCIDRs string         `gorm:"column:allowed_cidrs;size:1000;comment:IP白名单CIDR，逗号分隔，为空不限制" json:"allowed_cidrs" example:"10.0.0.0/8,203.0.113.5/32"`
+	RateLimit    int            `gorm:"not null;default:0;comment:每分钟请求上限，0=不限制" json:"rate_limit" example:"600"`
+	LastUsed     *time.Time     `gorm:"comment:最后使用时间" json:"last_used"`
+	ExpiresAt    *time.Time     `gorm:"comment:过期时间" json:"expires_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// 关联关系
 	App App `gorm:"foreignKey:AppID" json:"app,omitempty"`
 }
 
+// API 密钥前缀
+const (
+	APIKeyPrefixLive = "dp_live_" // 正式密钥，推送发往真实厂商
+	APIKeyPrefixTest = "dp_test_" // 测试密钥，注册的设备和发起的推送进入沙箱，不调用厂商接口
+)
+
+// IsTest 是否为测试密钥
+func (k *AppAPIKey) IsTest() bool {
+	return k.KeyPrefix == APIKeyPrefixTest
+}
+
+// API 密钥权限范围
+const (
+	APIKeyScopeDeviceRegister = "device:register" // 注册设备及设备自身的标签、别名、订阅等管理
+	APIKeyScopeStatsReport    = "stats:report"    // 上报点击、打开事件
+	APIKeyScopePushSend       = "push:send"       // 向指定设备、标签、分组等发送推送，管理分批发送任务
+	APIKeyScopePushBroadcast  = "push:broadcast"  // 向全部设备广播
+	APIKeyScopeDevicesRead    = "devices:read"    // 查询设备列表和详情
+)
+
+// APIKeyScopes 全部权限范围
+var APIKeyScopes = []string{
+	APIKeyScopeDeviceRegister, APIKeyScopeStatsReport, APIKeyScopePushSend, APIKeyScopePushBroadcast, APIKeyScopeDevicesRead,
+}
+
+// SDKAPIKeyScopes 客户端 SDK 密钥的默认权限范围，不能发送推送
+var SDKAPIKeyScopes = []string{APIKeyScopeDeviceRegister, APIKeyScopeStatsReport}
+
+// ScopeList 密钥的权限范围列表
+func (k *AppAPIKey) ScopeList() []string {
+	return splitList(k.Scopes)
+}
+
+// HasScope 密钥是否具备指定权限范围
+func (k *AppAPIKey) HasScope(scope string) bool {
+	return slices.Contains(k.ScopeList(), scope)
+}
+
+// CIDRList 密钥的 IP 白名单，为空表示不限制
+func (k *AppAPIKey) CIDRList() []string {
+	return splitList(k.AllowedCIDRs)
+}
+
+// AllowsIP 请求 IP 是否在密钥的白名单内，未设置白名单时总是允许
+func (k *AppAPIKey) AllowsIP(ip string) bool {
+	cidrs := k.CIDRList()
+	if len(cidrs) == 0 {
+		return true
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, cidr := range cidrs {
+		if prefix, err := netip.ParsePrefix(cidr); err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList 拆分逗号分隔的列表，忽略空白项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // AppConfig 应用推送配置模型
 type AppConfig struct {
 	ID       uint   `gorm:"primarykey" json:"id"`
@@ -54,12 +172,45 @@ type AppConfig struct {
 	Config   string `gorm:"type:json;comment:推送配置JSON" json:"config" example:"{\"cert_path\":\"/path/to/cert.p12\"}"`
 	Status   int    `gorm:"default:1;comment:配置状态 1=启用 0=禁用" json:"status" example:"1"`
 
+	CategoryMap string `gorm:"type:text;comment:消息分类映射JSON" json:"category_map" example:"{\"im\":{\"channel_id\":\"im_channel\"}}"` // 统一消息分类 -> 厂商原生字段
+
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// 关联关系
-	App App `gorm:"foreignKey:AppID" json:"app,omitempty"`
+	App    App              `gorm:"foreignKey:AppID" json:"app,omitempty"`
+	Health *AppConfigHealth `gorm:"foreignKey:ConfigID" json:"health,omitempty"`
+
+	plainConfig string // 保存期间暂存明文，保存后恢复
+}
+
+// BeforeSave 推送配置加密后入库（信封加密，见 secrets 包）
+func (c *AppConfig) BeforeSave(tx *gorm.DB) error {
+	encrypted, err := secrets.Encrypt(c.Config)
+	if err != nil {
+		return err
+	}
+	c.plainConfig = c.Config
+	c.Config = encrypted
+	return nil
+}
+
+// AfterSave 恢复明文，调用方可以继续使用同一对象
+func (c *AppConfig) AfterSave(tx *gorm.DB) error {
+	c.Config = c.plainConfig
+	c.plainConfig = ""
+	return nil
+}
+
+// AfterFind 读取后解密推送配置，未加密的历史数据原样返回
+func (c *AppConfig) AfterFind(tx *gorm.DB) error {
+	plaintext, err := secrets.Decrypt(c.Config)
+	if err != nil {
+		return err
+	}
+	c.Config = plaintext
+	return nil
 }
 
 // TableName 设置表名
@@ -76,3 +227,18 @@ func (AppAPIKey) TableName() string {
 func (AppConfig) TableName() string {
 	return "app_configs"
 }
+
+// TableName 设置表名
+func (AppConfigHealth) TableName() string {
+	return "app_config_healths"
+}
+
+// TableName 设置表名
+func (AppFrequencyCap) TableName() string {
+	return "app_frequency_caps"
+}
+
+// TableName 设置表名
+func (AppApprovalPolicy) TableName() string {
+	return "app_approval_policies"
+}
@@ -24,6 +24,12 @@ type Device struct {
 	IsOnline      bool           `gorm:"default:false;index;comment:实时在线状态" json:"is_online"`
 	LastSeen      *time.Time     `gorm:"comment:最后活跃时间" json:"last_seen"`
 	LastHeartbeat *time.Time     `gorm:"comment:最后心跳时间" json:"last_heartbeat"`
+	BadgeCount    int            `gorm:"not null;default:0;comment:服务端角标计数" json:"badge_count" example:"3"`
+	ExternalUID   string         `gorm:"column:external_user_id;size:128;index;comment:外部用户ID（别名）" json:"external_user_id,omitempty" example:"12345"`
+	Locale        string         `gorm:"size:20;comment:设备语言" json:"locale,omitempty" example:"zh-CN"`
+	Timezone      string         `gorm:"size:64;comment:设备时区" json:"timezone,omitempty" example:"Asia/Shanghai"`
+	OptOutCats    string         `gorm:"column:opt_out_categories;size:100;comment:退订的消息分类，逗号分隔" json:"opt_out_categories,omitempty" example:"marketing"`
+	Sandbox       bool           `gorm:"not null;default:false;index;comment:是否为测试密钥注册的沙箱设备" json:"sandbox"` // 沙箱设备只接收测试密钥发起的推送，推送由沙箱记录而不发往厂商
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
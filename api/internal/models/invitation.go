@@ -3,8 +3,13 @@ package models
 import "time"
 
 // AppInvitation 应用成员邀请，同时作为收件箱中的历史记录。
+// 推送审批也以收件箱条目的形式发给审批人，Type 为 push_approval；
+// 告警以 Type 为 alert 的条目通知应用成员，Status 随告警同步为 firing/resolved。
 type AppInvitation struct {
 	ID                   uint       `gorm:"primarykey" json:"id"`
+	Type                 string     `gorm:"size:20;not null;default:invitation;index" json:"type"`
+	PushApprovalID       *uint      `gorm:"index" json:"push_approval_id,omitempty"`
+	AlertID              *uint      `gorm:"index" json:"alert_id,omitempty"`
 	AppID                uint       `gorm:"not null;index" json:"app_id"`
 	InviterID            uint       `gorm:"not null;index" json:"inviter_id"`
 	InviteeID            uint       `gorm:"not null;index" json:"invitee_id"`
@@ -16,6 +21,7 @@ type AppInvitation struct {
 	InviterNameSnapshot  string     `gorm:"size:100;not null" json:"inviter_name"`
 	InviteeNameSnapshot  string     `gorm:"size:100;not null" json:"invitee_name"`
 	InviteeEmailSnapshot string     `gorm:"size:100;not null" json:"invitee_email"`
+	Message              string     `gorm:"size:500" json:"message,omitempty"`
 	ReadAt               *time.Time `json:"read_at"`
 	RespondedAt          *time.Time `json:"responded_at"`
 	CreatedAt            time.Time  `json:"created_at"`
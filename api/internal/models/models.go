@@ -12,16 +12,25 @@ func AllModels() []interface{} {
 		&App{},
 		&AppAPIKey{},
 		&AppConfig{},
+		&AppConfigHealth{},
+		&AppFrequencyCap{},
+		&AppApprovalPolicy{},
 
 		// 设备相关
 		&Device{},
 		&DeviceTagMap{},
 		&DeviceGroupMap{},
+		&Topic{},
+		&TopicSubscription{},
 
 		// 推送相关
 		&PushLog{},
 		&PushResult{},
+		&PushRecall{},
 		&PushQueue{},
+		&PushCampaign{},
+		&PushVariant{},
+		&PushApproval{},
 
 		// 回执相关
 		&HuaweiCallback{},
@@ -42,10 +51,19 @@ func AllModels() []interface{} {
 
 		// 系统功能
 		&ScheduledPush{},
+		&ScheduledPushRun{},
 		&PushStatistics{},
 		&AuditLog{},
 		&SystemConfig{},
 		&UploadFile{},
 		&ExportToken{},
+
+		// 事件回调
+		&Webhook{},
+		&WebhookDelivery{},
+
+		// 告警
+		&AlertRule{},
+		&Alert{},
 	}
 }
@@ -8,25 +8,45 @@ import (
 
 // PushLog 推送日志模型
 type PushLog struct {
-	ID        uint           `gorm:"primarykey" json:"id" example:"1"`
-	AppID     uint           `gorm:"not null;index;comment:应用ID" json:"app_id" binding:"required"`
-	DeviceID  uint           `gorm:"not null;index;comment:设备ID" json:"device_id" binding:"required"`
-	Title     string         `gorm:"size:200;not null;comment:推送标题" json:"title" example:"新消息" binding:"required"`
-	Content   string         `gorm:"type:text;not null;comment:推送内容" json:"content" example:"您有一条新消息" binding:"required"`
-	Payload   string         `gorm:"type:json;comment:推送载荷" json:"payload" example:"{\"action\":\"open_page\"}"`
-	Channel   string         `gorm:"size:20;not null;comment:推送通道" json:"channel" example:"apns" binding:"required"`
-	Status    string         `gorm:"size:20;default:pending;comment:推送状态" json:"status" example:"pending"`
-	DedupKey  string         `gorm:"size:64;index;comment:去重键" json:"dedup_key"`
-	SendAt    *time.Time     `gorm:"comment:发送时间" json:"send_at"`
-	Badge     int            `gorm:"not null;default:1;comment:badge数量" json:"badge"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID          uint           `gorm:"primarykey" json:"id" example:"1"`
+	AppID       uint           `gorm:"not null;index;comment:应用ID" json:"app_id" binding:"required"`
+	DeviceID    uint           `gorm:"not null;index;comment:设备ID" json:"device_id" binding:"required"`
+	Title       string         `gorm:"size:200;not null;comment:推送标题" json:"title" example:"新消息" binding:"required"`
+	Content     string         `gorm:"type:text;not null;comment:推送内容" json:"content" example:"您有一条新消息" binding:"required"`
+	Payload     string         `gorm:"type:json;comment:推送载荷" json:"payload" example:"{\"action\":\"open_page\"}"`
+	Channel     string         `gorm:"size:20;not null;comment:推送通道" json:"channel" example:"apns" binding:"required"`
+	Status      string         `gorm:"size:20;default:pending;index;comment:推送状态" json:"status" example:"pending"`
+	DedupKey    string         `gorm:"size:64;index;comment:去重键" json:"dedup_key"`
+	SendAt      *time.Time     `gorm:"comment:发送时间" json:"send_at"`
+	Badge       int            `gorm:"not null;default:1;comment:badge数量" json:"badge"`
+	BadgeSpec   string         `gorm:"size:20;not null;default:'';comment:投递时的角标取值" json:"-"`                                         // 数字、+N 或 reset，投递时才更新设备角标计数，空=不改变
+	MessageType string         `gorm:"size:20;not null;default:notification;comment:消息类型" json:"message_type" example:"notification"` // notification=通知栏消息，data=静默/透传消息
+	Category    string         `gorm:"size:32;index;comment:消息分类" json:"category,omitempty" example:"transactional"`
+	TTLSeconds  int            `gorm:"not null;default:0;comment:消息存活时长(秒)" json:"ttl_seconds,omitempty" example:"300"` // 0=使用通道默认值
+	Priority    string         `gorm:"size:10;comment:投递优先级" json:"priority,omitempty" example:"high"`                  // high/normal，空=high
+	CollapseKey string         `gorm:"size:64;comment:合并键" json:"collapse_key,omitempty" example:"score_update"`        // 相同合并键的消息相互覆盖
+	ExpiresAt   *time.Time     `gorm:"index;comment:过期时间" json:"expires_at,omitempty"`                                  // 超过该时间仍未发出的消息标记为 expired
+	HoldUntil   *time.Time     `gorm:"index;comment:静默时段暂存至" json:"hold_until,omitempty"`                               // status=held 时到该时间释放投递
+	SkipReason  string         `gorm:"size:32;comment:未投递原因" json:"skip_reason,omitempty" example:"topic_unsubscribed"` // status=excluded 时记录排除原因
+	CapReserved string         `gorm:"size:16;not null;default:'';comment:占用的频控计数" json:"-"`                            // 日期:a（应用配额）/d（设备日/周计数），取消或驳回时归还
+	Cancelling  bool           `gorm:"not null;default:false;comment:发送中被取消" json:"-"`                                  // 取消时正在调用厂商接口，发出后撤回
+	CampaignID  uint           `gorm:"index;comment:分批发送任务ID" json:"campaign_id,omitempty" example:"12"`                // 0=不属于分批发送
+	BatchID     string         `gorm:"size:32;index;comment:推送批次ID" json:"batch_id,omitempty"`                          // 同一次发送请求创建的日志共享批次ID
+	Variant     string         `gorm:"size:16;comment:A/B测试变体" json:"variant,omitempty" example:"B"`
+	TraceID     string         `gorm:"size:32;index;comment:链路追踪ID" json:"trace_id,omitempty" example:"4bf92f3577b34da6a3ce929d0e0e4736"`
+	TraceParent string         `gorm:"size:55;comment:W3C traceparent" json:"-"`                           // 异步投递时接续发送请求的链路
+	Sandbox     bool           `gorm:"not null;default:false;index;comment:是否为测试密钥发起的沙箱推送" json:"sandbox"` // 沙箱推送不计入统计和告警
+	OpenedAt    *time.Time     `gorm:"comment:首次打开时间" json:"opened_at,omitempty"`
+	ClickedAt   *time.Time     `gorm:"comment:首次点击时间" json:"clicked_at,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// 关联关系
 	App        App         `gorm:"foreignKey:AppID" json:"app,omitempty"`
 	Device     Device      `gorm:"foreignKey:DeviceID" json:"device,omitempty"`
 	PushResult *PushResult `gorm:"foreignKey:PushLogID" json:"result,omitempty"`
+	Recall     *PushRecall `gorm:"foreignKey:PushLogID" json:"recall,omitempty"`
 }
 
 // PushResult 推送结果模型
@@ -47,6 +67,55 @@ type PushResult struct {
 	PushLog PushLog `gorm:"foreignKey:PushLogID" json:"push_log,omitempty"`
 }
 
+// PushVariant A/B 测试的内容变体
+type PushVariant struct {
+	ID         uint           `gorm:"primarykey" json:"id"`
+	CampaignID uint           `gorm:"not null;uniqueIndex:idx_campaign_variant;comment:分批发送任务ID" json:"campaign_id"`
+	Key        string         `gorm:"size:16;not null;uniqueIndex:idx_campaign_variant;comment:变体标识" json:"key" example:"A"`
+	Title      string         `gorm:"size:200;not null;comment:推送标题" json:"title" example:"限时优惠"`
+	Content    string         `gorm:"type:text;not null;comment:推送内容" json:"content" example:"全场八折，仅限今天"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// PushApproval 推送审批：命中审批策略的推送在批准前不会投递
+type PushApproval struct {
+	ID           uint           `gorm:"primarykey" json:"id" example:"8"`
+	AppID        uint           `gorm:"not null;index;comment:应用ID" json:"app_id"`
+	BatchID      string         `gorm:"size:32;not null;uniqueIndex;comment:推送批次ID" json:"batch_id"`
+	RequesterID  uint           `gorm:"not null;index;comment:发起人ID" json:"requester_id"`
+	ViaAPIKey    bool           `gorm:"not null;default:false;comment:是否通过API Key发起" json:"via_api_key"`                     // API Key 以应用 owner 身份认证，所有审批人都可批准
+	Status       string         `gorm:"size:20;not null;default:pending;index;comment:审批状态" json:"status" example:"pending"` // pending/approved/rejected/cancelled
+	Reason       string         `gorm:"size:20;not null;comment:需要审批的原因" json:"reason" example:"broadcast"`                  // broadcast/device_threshold
+	Title        string         `gorm:"size:200;comment:推送标题" json:"title" example:"双十一预热"`
+	TargetType   string         `gorm:"size:20;comment:推送目标类型" json:"target_type" example:"all"`
+	DeviceCount  int            `gorm:"not null;default:0;comment:目标设备数" json:"device_count" example:"52000"`
+	ScheduleTime *time.Time     `gorm:"comment:计划推送时间" json:"schedule_time,omitempty"`
+	Request      string         `gorm:"type:json;comment:推送请求" json:"request"`
+	ReviewerID   *uint          `gorm:"comment:审批人ID" json:"reviewer_id,omitempty"`
+	ReviewNote   string         `gorm:"size:255;comment:审批意见" json:"review_note,omitempty"`
+	ReviewedAt   *time.Time     `gorm:"comment:审批时间" json:"reviewed_at,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// PushRecall 推送撤回记录
+type PushRecall struct {
+	ID           uint           `gorm:"primarykey" json:"id"`
+	AppID        uint           `gorm:"not null;index;comment:应用ID" json:"app_id"`
+	PushLogID    uint           `gorm:"not null;uniqueIndex;comment:推送日志ID" json:"push_log_id"`
+	Method       string         `gorm:"size:20;comment:撤回方式" json:"method" example:"revoke"`            // revoke=厂商撤回接口，replace=按合并键覆盖，sandbox=沙箱推送
+	Status       string         `gorm:"size:20;not null;comment:撤回结果" json:"status" example:"recalled"` // recalled/failed/unsupported
+	ErrorCode    string         `gorm:"size:50;comment:错误代码" json:"error_code,omitempty"`
+	ErrorMessage string         `gorm:"size:500;comment:错误信息" json:"error_message,omitempty"`
+	ResponseData string         `gorm:"type:json;comment:推送服务响应数据" json:"response_data"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
 // PushQueue 推送队列模型
 type PushQueue struct {
 	ID           uint           `gorm:"primarykey" json:"id"`
@@ -55,6 +124,12 @@ type PushQueue struct {
 	Content      string         `gorm:"type:text;not null;comment:推送内容" json:"content" binding:"required"`
 	Payload      string         `gorm:"type:json;comment:推送载荷" json:"payload"`
 	Target       string         `gorm:"type:json;not null;comment:推送目标" json:"target" binding:"required"`
+	MessageType  string         `gorm:"size:20;not null;default:notification;comment:消息类型" json:"message_type"` // 投递参数与推送请求相同
+	Category     string         `gorm:"size:32;comment:消息分类" json:"category,omitempty"`
+	TTLSeconds   int            `gorm:"not null;default:0;comment:消息存活时长(秒)" json:"ttl_seconds,omitempty"`
+	PushPriority string         `gorm:"size:10;comment:投递优先级" json:"push_priority,omitempty"` // high/normal，Priority 为队列优先级
+	CollapseKey  string         `gorm:"size:64;comment:合并键" json:"collapse_key,omitempty"`
+	Topic        string         `gorm:"size:64;comment:订阅主题" json:"topic,omitempty"`
 	ScheduleTime *time.Time     `gorm:"comment:计划推送时间" json:"schedule_time"`
 	Status       string         `gorm:"size:20;default:pending;comment:队列状态" json:"status" example:"pending"`
 	Priority     int            `gorm:"default:5;comment:优先级 1-10" json:"priority" example:"5"`
@@ -71,6 +146,29 @@ type PushQueue struct {
 	App App `gorm:"foreignKey:AppID" json:"app,omitempty"`
 }
 
+// PushCampaign 分批发送任务：按速率节流、按比例灰度的广播推送
+type PushCampaign struct {
+	ID             uint           `gorm:"primarykey" json:"id" example:"12"`
+	AppID          uint           `gorm:"not null;index;comment:应用ID" json:"app_id"`
+	UserID         uint           `gorm:"not null;comment:创建者ID" json:"user_id"`
+	Status         string         `gorm:"size:20;not null;default:running;index;comment:任务状态" json:"status" example:"running"` // running/paused/cancelled/completed
+	Stage          string         `gorm:"size:20;not null;default:full;comment:灰度阶段" json:"stage" example:"canary"`            // canary=灰度中，full=全量
+	RatePerMinute  int            `gorm:"not null;default:0;comment:每分钟发送上限" json:"rate_per_minute" example:"600"`             // 0=不限速
+	RolloutPercent int            `gorm:"not null;default:100;comment:灰度比例" json:"rollout_percent" example:"5"`
+	PromoteAfter   int            `gorm:"not null;default:0;comment:灰度完成后自动全量等待秒数" json:"promote_after_seconds" example:"1800"` // 0=手动全量
+	MaxFailureRate float64        `gorm:"not null;default:0;comment:自动全量允许的最大失败率" json:"max_failure_rate" example:"0.05"`
+	MinClickRate   float64        `gorm:"not null;default:0;comment:自动全量要求的最小点击率" json:"min_click_rate" example:"0.01"`
+	TargetType     string         `gorm:"size:20;not null;default:'';comment:推送目标类型" json:"target_type" example:"all"` // all 时恢复、全量需要 push:broadcast 权限
+	Total          int            `gorm:"not null;default:0;comment:目标推送数" json:"total" example:"20000"`
+	CanaryDoneAt   *time.Time     `gorm:"comment:灰度发送完成时间" json:"canary_done_at,omitempty"`
+	PausedReason   string         `gorm:"size:64;comment:暂停原因" json:"paused_reason,omitempty" example:"failure_rate_exceeded"`
+	WinnerMetric   string         `gorm:"size:10;comment:A/B测试胜出指标" json:"winner_metric,omitempty" example:"click"` // open/click
+	WinnerVariant  string         `gorm:"size:16;comment:A/B测试胜出变体" json:"winner_variant,omitempty" example:"B"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
 // TableName 设置表名
 func (PushLog) TableName() string {
 	return "push_logs"
@@ -81,7 +179,27 @@ func (PushResult) TableName() string {
 	return "push_results"
 }
 
+// TableName 设置表名
+func (PushVariant) TableName() string {
+	return "push_variants"
+}
+
+// TableName 设置表名
+func (PushRecall) TableName() string {
+	return "push_recalls"
+}
+
+// TableName 设置表名
+func (PushApproval) TableName() string {
+	return "push_approvals"
+}
+
 // TableName 设置表名
 func (PushQueue) TableName() string {
 	return "push_queue"
 }
+
+// TableName 设置表名
+func (PushCampaign) TableName() string {
+	return "push_campaigns"
+}
@@ -16,7 +16,15 @@ type ScheduledPush struct {
 	Title   string `gorm:"size:200;comment:推送标题" json:"title" example:"签到提醒"`
 	Content string `gorm:"type:text;comment:推送内容" json:"content" example:"别忘记每日签到领取奖励"`
 	Payload string `gorm:"type:json;comment:推送载荷" json:"payload" example:"{\"action\":\"open_signin\"}"`
-	Badge   int    `gorm:"not null;default:1;comment:badge数量" json:"badge" example:"1"`
+	Badge   string `gorm:"size:20;not null;default:'';comment:角标：数字、+N 或 reset，空=+1" json:"badge" example:"+1"`
+
+	// 投递参数，每次执行时与即时推送的同名参数相同
+	MessageType string `gorm:"size:20;not null;default:notification;comment:消息类型" json:"message_type" example:"notification"` // notification=通知栏消息，data=静默/透传消息
+	Category    string `gorm:"size:32;comment:消息分类" json:"category,omitempty" example:"marketing"`
+	TTLSeconds  int    `gorm:"not null;default:0;comment:消息存活时长(秒)" json:"ttl_seconds,omitempty" example:"3600"` // 从每次执行时开始计算，0=使用通道默认值
+	Priority    string `gorm:"size:10;comment:投递优先级" json:"priority,omitempty" example:"normal"`                 // high/normal，空=high
+	CollapseKey string `gorm:"size:64;comment:合并键" json:"collapse_key,omitempty" example:"daily_signin"`
+	Topic       string `gorm:"size:64;comment:订阅主题" json:"topic,omitempty" example:"activity"` // 未订阅该主题的设备将被排除
 
 	TemplateID   *uint          `gorm:"comment:模板ID" json:"template_id"`
 	PushType     string         `gorm:"size:20;not null;comment:推送类型" json:"push_type" example:"broadcast"`
@@ -24,6 +32,7 @@ type ScheduledPush struct {
 	TargetValue  string         `gorm:"size:200;comment:目标值" json:"target_config" example:"vip_users"`
 	ScheduleTime time.Time      `gorm:"not null;comment:调度时间" json:"scheduled_at" binding:"required"`
 	Timezone     string         `gorm:"size:50;default:UTC;comment:时区" json:"timezone" example:"Asia/Shanghai"`
+	LocalTime    bool           `gorm:"column:local_delivery;not null;default:false;comment:按设备本地时间投递" json:"local_delivery" example:"false"`
 	RepeatType   string         `gorm:"size:20;default:once;comment:重复类型" json:"repeat_type" example:"daily"`
 	RepeatConfig string         `gorm:"size:200;comment:重复配置" json:"repeat_config" example:"1,3,5"`
 	CronExpr     string         `gorm:"size:200;comment:Cron表达式" json:"cron_expr" example:"0 9 * * *"`
@@ -31,6 +40,7 @@ type ScheduledPush struct {
 	LastRunAt    *time.Time     `gorm:"comment:上次运行时间" json:"last_run_at"`
 	Status       string         `gorm:"size:20;default:pending;comment:任务状态" json:"status" example:"pending"`
 	CreatedBy    uint           `gorm:"not null;comment:创建者ID" json:"created_by"`
+	TraceParent  string         `gorm:"size:55;comment:创建时的W3C traceparent" json:"-"` // 每次执行的链路链接到创建任务的链路
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
@@ -39,6 +49,24 @@ type ScheduledPush struct {
 	App App `gorm:"foreignKey:AppID" json:"app,omitempty"`
 }
 
+// ScheduledPushRun 本地时间投递的时区批次执行记录，同一任务同一天每个时区只成功执行一次
+type ScheduledPushRun struct {
+	ID              uint      `gorm:"primarykey" json:"id"`
+	ScheduledPushID uint      `gorm:"not null;uniqueIndex:idx_push_run_zone;comment:定时推送ID" json:"scheduled_push_id"`
+	RunDate         string    `gorm:"size:10;not null;uniqueIndex:idx_push_run_zone;comment:本次执行的日期" json:"run_date" example:"2026-10-18"`
+	Timezone        string    `gorm:"size:64;not null;uniqueIndex:idx_push_run_zone;comment:时区批次" json:"timezone" example:"Asia/Shanghai"`
+	Status          string    `gorm:"size:20;not null;default:running;comment:执行状态" json:"status" example:"completed"`
+	Error           string    `gorm:"size:500;comment:失败原因" json:"error,omitempty"`
+	Attempts        int       `gorm:"not null;default:1;comment:执行次数" json:"attempts" example:"1"` // 失败的批次间隔一段时间后重试
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (ScheduledPushRun) TableName() string {
+	return "scheduled_push_runs"
+}
+
 // PushStatistics 推送统计模型
 type PushStatistics struct {
 	ID            uint           `gorm:"primarykey" json:"id"`
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Topic 订阅主题模型（应用自定义的通知类别，如 订单更新、促销活动）
+type Topic struct {
+	ID                uint      `gorm:"primarykey" json:"id" example:"1"`
+	AppID             uint      `gorm:"not null;uniqueIndex:idx_app_topic_key;comment:应用ID" json:"app_id"`
+	Key               string    `gorm:"size:64;not null;uniqueIndex:idx_app_topic_key;comment:主题标识" json:"key" example:"order_updates"`
+	Name              string    `gorm:"size:100;not null;comment:主题名称" json:"name" example:"订单更新"`
+	Description       string    `gorm:"size:500;comment:主题描述" json:"description" example:"订单状态变化通知"`
+	DefaultSubscribed bool      `gorm:"not null;comment:设备未显式设置时是否默认订阅" json:"default_subscribed" example:"true"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (Topic) TableName() string {
+	return "topics"
+}
+
+// TopicSubscription 设备主题订阅模型，仅记录设备显式设置过的订阅状态
+type TopicSubscription struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	AppID      uint      `gorm:"not null;index;comment:应用ID" json:"app_id"`
+	DeviceID   uint      `gorm:"not null;uniqueIndex:idx_device_topic;comment:设备ID" json:"device_id"`
+	TopicID    uint      `gorm:"not null;uniqueIndex:idx_device_topic;index;comment:主题ID" json:"topic_id"`
+	Subscribed bool      `gorm:"not null;comment:是否订阅" json:"subscribed"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (TopicSubscription) TableName() string {
+	return "topic_subscriptions"
+}
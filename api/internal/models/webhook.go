@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Webhook 应用事件回调订阅
+type Webhook struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	AppID     uint           `gorm:"not null;index;comment:应用ID" json:"app_id"`
+	Name      string         `gorm:"size:100;not null;comment:名称" json:"name" example:"业务后端"`
+	URL       string         `gorm:"size:500;not null;comment:回调地址" json:"url" example:"https://example.com/doopush/webhook"`
+	Secret    string         `gorm:"size:64;not null;comment:签名密钥" json:"-"`
+	Events    string         `gorm:"size:500;comment:订阅的事件类型，逗号分隔，为空表示全部" json:"events" example:"push.delivered,push.clicked"`
+	Status    int            `gorm:"default:1;comment:状态 1=启用 0=禁用" json:"status" example:"1"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// WebhookDelivery 事件回调投递记录
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primarykey" json:"id"`
+	AppID          uint       `gorm:"not null;index;comment:应用ID" json:"app_id"`
+	WebhookID      uint       `gorm:"not null;index;comment:回调订阅ID" json:"webhook_id"`
+	EventID        string     `gorm:"size:32;not null;index;comment:事件ID，重放时保持不变" json:"event_id"`
+	Event          string     `gorm:"size:50;not null;comment:事件类型" json:"event" example:"push.delivered"`
+	Payload        string     `gorm:"type:json;comment:事件内容" json:"payload"`
+	Status         string     `gorm:"size:20;default:pending;index;comment:投递状态 pending/delivering/success/failed" json:"status" example:"success"`
+	Attempts       int        `gorm:"default:0;comment:已尝试次数" json:"attempts"`
+	NextAttemptAt  *time.Time `gorm:"index;comment:下次尝试时间" json:"next_attempt_at,omitempty"`
+	ResponseStatus int        `gorm:"comment:最后一次响应状态码" json:"response_status"`
+	ResponseBody   string     `gorm:"type:text;comment:最后一次响应内容" json:"response_body"`
+	Error          string     `gorm:"type:text;comment:最后一次错误信息" json:"error"`
+	DeliveredAt    *time.Time `gorm:"comment:投递成功时间" json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
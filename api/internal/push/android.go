@@ -2,6 +2,7 @@ package push
 
 import (
 	"bytes"
+	"context"
 	"crypto/md5"
 	"crypto/rsa"
 	"crypto/sha256"
@@ -68,7 +69,8 @@ func NewAndroidProvider(channel string) *AndroidProvider {
 		channel: channel,
 		config:  AndroidProviderConfig{},
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: vendorTransport(channel, nil),
 		},
 	}
 }
@@ -96,7 +98,8 @@ func NewAndroidProviderWithConfig(channel string, config AndroidConfig) *Android
 			CallBack:          config.CallBack,
 		},
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: vendorTransport(channel, nil),
 		},
 	}
 
@@ -213,6 +216,7 @@ type FCMv1AndroidNotification struct {
 	VibrateTimings        []string `json:"vibrate_timings,omitempty"`
 	Visibility            string   `json:"visibility,omitempty"`
 	NotificationCount     int      `json:"notification_count,omitempty"`
+	Image                 string   `json:"image,omitempty"`
 }
 
 // HuaweiTokenResponse 华为 OAuth 令牌响应结构
@@ -293,6 +297,16 @@ type HuaweiAndroidNotification struct {
 	ForegroundShow    bool                 `json:"foreground_show,omitempty"`
 	ProfileID         string               `json:"profile_id,omitempty"`
 	Interruption      bool                 `json:"interruption,omitempty"`
+	Buttons           []*HuaweiButton      `json:"buttons,omitempty"`
+}
+
+// HuaweiButton 华为通知按钮配置
+type HuaweiButton struct {
+	Name       string `json:"name"`                  // 按钮名称
+	ActionType int    `json:"action_type"`           // 按钮动作类型：0=打开应用首页 1=打开自定义页面 2=打开网页
+	IntentType int    `json:"intent_type,omitempty"` // 打开自定义页面方式：0=intent 1=action
+	Intent     string `json:"intent,omitempty"`      // intent或URL
+	Data       string `json:"data,omitempty"`        // 透传数据
 }
 
 // HuaweiClickAction 华为点击动作配置
@@ -344,14 +358,12 @@ type FirebaseServiceAccount struct {
 
 // OPPO推送常量
 const (
-	oppoHost    = "https://api.push.oppomobile.com"
 	oppoAuthURL = "/server/v1/auth"
 	oppoSendURL = "/server/v1/message/notification/unicast"
 )
 
 // VIVO推送常量
 const (
-	vivoHost    = "https://api-push.vivo.com.cn"
 	vivoAuthURL = "/message/auth"
 	vivoSendURL = "/message/send"
 )
@@ -703,7 +715,7 @@ type FCMClaims struct {
 }
 
 // generateFCMAccessToken 生成 FCM access token
-func (a *AndroidProvider) generateFCMAccessToken() (string, error) {
+func (a *AndroidProvider) generateFCMAccessToken(ctx context.Context) (string, error) {
 	// 解析服务账号密钥
 	var serviceAccount FirebaseServiceAccount
 	if err := json.Unmarshal([]byte(a.config.ServiceAccountKey), &serviceAccount); err != nil {
@@ -721,7 +733,7 @@ func (a *AndroidProvider) generateFCMAccessToken() (string, error) {
 	claims := FCMClaims{
 		Iss:   serviceAccount.ClientEmail,
 		Sub:   serviceAccount.ClientEmail,
-		Aud:   "https://oauth2.googleapis.com/token",
+		Aud:   vendorURL(EndpointFCMOAuth, "/token"),
 		Scope: "https://www.googleapis.com/auth/cloud-platform",
 		RegisteredClaims: jwt.RegisteredClaims{
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -739,7 +751,7 @@ func (a *AndroidProvider) generateFCMAccessToken() (string, error) {
 	}
 
 	// 获取 OAuth 2.0 access token
-	return a.getOAuthAccessToken(jwtToken)
+	return a.getOAuthAccessToken(ctx, jwtToken)
 }
 
 // parsePrivateKey 解析 RSA 私钥
@@ -767,14 +779,14 @@ func parsePrivateKey(privateKeyData string) (*rsa.PrivateKey, error) {
 }
 
 // getOAuthAccessToken 获取 OAuth 2.0 access token
-func (a *AndroidProvider) getOAuthAccessToken(jwtToken string) (string, error) {
+func (a *AndroidProvider) getOAuthAccessToken(ctx context.Context, jwtToken string) (string, error) {
 	// 准备请求数据
 	data := url.Values{}
 	data.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
 	data.Set("assertion", jwtToken)
 
 	// 创建请求
-	req, err := http.NewRequest("POST", "https://oauth2.googleapis.com/token", strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", vendorURL(EndpointFCMOAuth, "/token"), strings.NewReader(data.Encode()))
 	if err != nil {
 		return "", fmt.Errorf("创建 OAuth 请求失败: %v", err)
 	}
@@ -808,22 +820,22 @@ func (a *AndroidProvider) getOAuthAccessToken(jwtToken string) (string, error) {
 }
 
 // SendPush 发送Android推送
-func (a *AndroidProvider) SendPush(device *models.Device, pushLog *models.PushLog) *models.PushResult {
+func (a *AndroidProvider) SendPush(ctx context.Context, device *models.Device, pushLog *models.PushLog) *models.PushResult {
 	switch a.channel {
 	case "fcm":
-		return a.sendFCM(device, pushLog)
+		return a.sendFCM(ctx, device, pushLog)
 	case "huawei":
-		return a.sendHuawei(device, pushLog)
+		return a.sendHuawei(ctx, device, pushLog)
 	case "honor":
-		return a.sendHonor(device, pushLog)
+		return a.withAuthRetry(func() *models.PushResult { return a.sendHonor(ctx, device, pushLog) })
 	case "xiaomi":
-		return a.sendXiaomi(device, pushLog)
+		return a.sendXiaomi(ctx, device, pushLog)
 	case "oppo":
-		return a.sendOPPO(device, pushLog)
+		return a.withAuthRetry(func() *models.PushResult { return a.sendOPPO(ctx, device, pushLog) })
 	case "vivo":
-		return a.sendVIVO(device, pushLog)
+		return a.withAuthRetry(func() *models.PushResult { return a.sendVIVO(ctx, device, pushLog) })
 	case "meizu":
-		return a.sendMeizu(device, pushLog)
+		return a.sendMeizu(ctx, device, pushLog)
 	default:
 		result := &models.PushResult{
 			AppID:        pushLog.AppID,
@@ -838,9 +850,9 @@ func (a *AndroidProvider) SendPush(device *models.Device, pushLog *models.PushLo
 }
 
 // sendFCM 发送FCM推送
-func (a *AndroidProvider) sendFCM(device *models.Device, pushLog *models.PushLog) *models.PushResult {
+func (a *AndroidProvider) sendFCM(ctx context.Context, device *models.Device, pushLog *models.PushLog) *models.PushResult {
 	// 生成 access token
-	accessToken, err := a.generateFCMAccessToken()
+	accessToken, err := a.generateFCMAccessToken(ctx)
 	if err != nil {
 		return a.createAuthError(pushLog, "FCM", err)
 	}
@@ -851,9 +863,11 @@ func (a *AndroidProvider) sendFCM(device *models.Device, pushLog *models.PushLog
 		var customData map[string]interface{}
 		if err := json.Unmarshal([]byte(pushLog.Payload), &customData); err == nil {
 			for k, v := range customData {
-				// 将所有值转换为字符串（FCM v1 API 要求）
+				// 将所有值转换为字符串（FCM v1 API 要求），对象和数组序列化为JSON
 				if str, ok := v.(string); ok {
 					dataMap[k] = str
+				} else if b, err := json.Marshal(v); err == nil {
+					dataMap[k] = string(b)
 				} else {
 					dataMap[k] = fmt.Sprintf("%v", v)
 				}
@@ -879,17 +893,35 @@ func (a *AndroidProvider) sendFCM(device *models.Device, pushLog *models.PushLog
 			},
 			Data: dataMap,
 			Android: &FCMv1AndroidConfig{
-				Priority: "high",
+				Priority:    "high",
+				CollapseKey: pushLog.CollapseKey,
 				Notification: &FCMv1AndroidNotification{
 					Title:             pushLog.Title,
 					Body:              pushLog.Content,
 					Sound:             "default",
+					Tag:               pushLog.CollapseKey, // 相同tag的通知会被替换，撤回时据此覆盖已展示的通知
 					ClickAction:       "FLUTTER_NOTIFICATION_CLICK",
 					NotificationCount: pushLog.Badge,
 				},
 			},
 		},
 	}
+	if isNormalPriority(pushLog) {
+		message.Message.Android.Priority = "normal"
+	}
+	if ttl := remainingTTL(pushLog); ttl > 0 {
+		message.Message.Android.TTL = fmt.Sprintf("%ds", ttl)
+	}
+	// 富媒体：FCM 仅支持大图，长文本和按钮保留在 data 中由应用自行渲染
+	if rich := parseRichContent(pushLog.Payload); rich.ImageURL != "" {
+		message.Message.Notification.Image = rich.ImageURL
+		message.Message.Android.Notification.Image = rich.ImageURL
+	}
+	if isDataMessage(pushLog) {
+		// 仅数据消息：去掉通知部分，由应用自行处理
+		message.Message.Notification = nil
+		message.Message.Android.Notification = nil
+	}
 
 	// 序列化载荷
 	payloadBytes, err := json.Marshal(message)
@@ -898,10 +930,10 @@ func (a *AndroidProvider) sendFCM(device *models.Device, pushLog *models.PushLog
 	}
 
 	// 构建 FCM v1 API 请求 URL
-	requestURL := fmt.Sprintf("https://fcm.googleapis.com/v1/projects/%s/messages:send", a.config.ProjectID)
+	requestURL := vendorURL(EndpointFCM, fmt.Sprintf("/v1/projects/%s/messages:send", a.config.ProjectID))
 
 	// 创建 HTTP 请求
-	req, err := http.NewRequest("POST", requestURL, bytes.NewReader(payloadBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", requestURL, bytes.NewReader(payloadBytes))
 	if err != nil {
 		return a.createRequestError(pushLog, err)
 	}
@@ -941,6 +973,36 @@ func (a *AndroidProvider) sendFCM(device *models.Device, pushLog *models.PushLog
 	return result
 }
 
+// withAuthRetry 缓存的鉴权令牌在过期前被厂商作废时（如重置密钥），清除缓存后重新获取令牌并重试一次
+func (a *AndroidProvider) withAuthRetry(send func() *models.PushResult) *models.PushResult {
+	result := send()
+	if result.Success || result.ErrorCode != "AUTHENTICATION_ERROR" || !a.invalidateAuthToken() {
+		return result
+	}
+	return send()
+}
+
+// invalidateAuthToken 清除缓存的鉴权令牌，返回清除前是否存在缓存的令牌
+func (a *AndroidProvider) invalidateAuthToken() bool {
+	a.authMutex.Lock()
+	defer a.authMutex.Unlock()
+
+	cached := false
+	if a.oppoAuthClient != nil && a.oppoAuthClient.authToken != "" {
+		a.oppoAuthClient.authToken = ""
+		cached = true
+	}
+	if a.vivoAuthClient != nil && a.vivoAuthClient.authToken != "" {
+		a.vivoAuthClient.authToken = ""
+		cached = true
+	}
+	if a.honorAuthClient != nil && a.honorAuthClient.accessToken != "" {
+		a.honorAuthClient.accessToken = ""
+		cached = true
+	}
+	return cached
+}
+
 // min 辅助函数，返回两个整数的最小值
 func min(a, b int) int {
 	if a < b {
@@ -950,7 +1012,7 @@ func min(a, b int) int {
 }
 
 // sendHuawei 发送华为推送
-func (a *AndroidProvider) sendHuawei(device *models.Device, pushLog *models.PushLog) *models.PushResult {
+func (a *AndroidProvider) sendHuawei(ctx context.Context, device *models.Device, pushLog *models.PushLog) *models.PushResult {
 	result := &models.PushResult{
 		AppID:        pushLog.AppID,
 		PushLogID:    pushLog.ID,
@@ -959,7 +1021,7 @@ func (a *AndroidProvider) sendHuawei(device *models.Device, pushLog *models.Push
 	}
 
 	// 获取 access token
-	accessToken, err := a.getHuaweiAccessToken()
+	accessToken, err := a.getHuaweiAccessToken(ctx)
 	if err != nil {
 		return a.createAuthError(pushLog, "华为", err)
 	}
@@ -968,7 +1030,7 @@ func (a *AndroidProvider) sendHuawei(device *models.Device, pushLog *models.Push
 	message := a.buildHuaweiMessage(device, pushLog)
 
 	// 发送推送
-	huaweiCode, huaweiMsg, err := a.sendHuaweiMessage(accessToken, message)
+	huaweiCode, huaweiMsg, requestID, err := a.sendHuaweiMessage(ctx, accessToken, message)
 	if err != nil {
 		// 检查是否是网络错误
 		if huaweiCode == "" {
@@ -980,13 +1042,13 @@ func (a *AndroidProvider) sendHuawei(device *models.Device, pushLog *models.Push
 	}
 
 	result.Success = true
-	result.ResponseData = `{"message_id":"success"}`
+	result.ResponseData = fmt.Sprintf(`{"message_id":"%s"}`, requestID)
 
 	return result
 }
 
 // sendHonor 荣耀推送主函数
-func (a *AndroidProvider) sendHonor(device *models.Device, pushLog *models.PushLog) *models.PushResult {
+func (a *AndroidProvider) sendHonor(ctx context.Context, device *models.Device, pushLog *models.PushLog) *models.PushResult {
 	result := &models.PushResult{
 		AppID:        pushLog.AppID,
 		PushLogID:    pushLog.ID,
@@ -995,7 +1057,7 @@ func (a *AndroidProvider) sendHonor(device *models.Device, pushLog *models.PushL
 	}
 
 	// 获取 access token
-	accessToken, err := a.getHonorAccessToken()
+	accessToken, err := a.getHonorAccessToken(ctx)
 	if err != nil {
 		return a.createAuthError(pushLog, "荣耀", err)
 	}
@@ -1004,7 +1066,7 @@ func (a *AndroidProvider) sendHonor(device *models.Device, pushLog *models.PushL
 	message := a.buildHonorMessage(device, pushLog)
 
 	// 发送推送
-	honorCode, honorMsg, err := a.sendHonorMessage(accessToken, message)
+	honorCode, honorMsg, err := a.sendHonorMessage(ctx, accessToken, message)
 	if err != nil {
 		// 检查是否是网络错误
 		if honorCode == 0 {
@@ -1022,9 +1084,9 @@ func (a *AndroidProvider) sendHonor(device *models.Device, pushLog *models.PushL
 }
 
 // getHuaweiAccessToken 获取华为OAuth 2.0 access token
-func (a *AndroidProvider) getHuaweiAccessToken() (string, error) {
+func (a *AndroidProvider) getHuaweiAccessToken(ctx context.Context) (string, error) {
 	// 华为OAuth 2.0 token endpoint
-	tokenURL := "https://oauth-login.cloud.huawei.com/oauth2/v2/token"
+	tokenURL := vendorURL(EndpointHuaweiOAuth, "/oauth2/v2/token")
 
 	// 准备请求参数
 	data := url.Values{}
@@ -1033,7 +1095,7 @@ func (a *AndroidProvider) getHuaweiAccessToken() (string, error) {
 	data.Set("client_secret", a.config.AppSecret)
 
 	// 创建请求
-	req, err := http.NewRequest("POST", tokenURL, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenURL, strings.NewReader(data.Encode()))
 	if err != nil {
 		return "", fmt.Errorf("创建华为认证请求失败: %v", err)
 	}
@@ -1130,7 +1192,7 @@ func (a *AndroidProvider) buildHuaweiMessage(device *models.Device, pushLog *mod
 	// 构建Android配置 - 添加必需的ClickAction和华为特有参数
 	androidConfig := &HuaweiAndroidConfig{
 		Urgency:  "HIGH",
-		TTL:      "86400s",
+		TTL:      fmt.Sprintf("%ds", ttlSeconds(pushLog, 86400)),
 		Category: category,         // 华为自定义分类，避免频控
 		Data:     string(dataJSON), // 在Android配置中也设置数据，确保数据传递
 		BiTag:    fmt.Sprintf("%d", pushLog.ID),
@@ -1159,6 +1221,15 @@ func (a *AndroidProvider) buildHuaweiMessage(device *models.Device, pushLog *mod
 		},
 	}
 
+	if isNormalPriority(pushLog) {
+		androidConfig.Urgency = "NORMAL"
+	}
+	if pushLog.CollapseKey != "" {
+		// 华为离线缓存分组取值 0~100，通知ID用于覆盖已展示的同组通知
+		androidConfig.CollapseKey = collapseNotifyID(pushLog.CollapseKey, 100)
+		androidConfig.Notification.NotifyID = collapseNotifyID(pushLog.CollapseKey, 1000000)
+	}
+
 	// 构建消息体 - 基于官方Demo格式
 	message := &HuaweiMessage{
 		Notification: notification,
@@ -1166,6 +1237,30 @@ func (a *AndroidProvider) buildHuaweiMessage(device *models.Device, pushLog *mod
 		Token:        []string{device.Token},
 		Data:         string(dataJSON), // 华为推送的data字段为字符串格式
 	}
+	// 富媒体：大图标、长文本样式与按钮
+	if rich := parseRichContent(pushLog.Payload); !rich.IsEmpty() {
+		hwNotification := androidConfig.Notification
+		hwNotification.Image = rich.ImageURL
+		if rich.BigText != "" {
+			hwNotification.Style = 1 // 1=长文本样式
+			hwNotification.BigTitle = pushLog.Title
+			hwNotification.BigBody = rich.BigText
+		}
+		for _, button := range rich.Buttons {
+			hwNotification.Buttons = append(hwNotification.Buttons, &HuaweiButton{
+				Name:       button.Title,
+				ActionType: richButtonActionType(button.Action),
+				Intent:     button.Value,
+			})
+		}
+	}
+	if isDataMessage(pushLog) {
+		// 透传消息：不携带通知栏字段，高优先级透传需单独申请权限，这里使用NORMAL
+		message.Notification = nil
+		androidConfig.Notification = nil
+		androidConfig.Urgency = "NORMAL"
+		androidConfig.Category = ""
+	}
 
 	// 返回完整的请求结构
 	return &HuaweiMessageRequest{
@@ -1181,21 +1276,21 @@ type HuaweiResponse struct {
 	RequestID string `json:"requestId"`
 }
 
-// sendHuaweiMessage 发送华为推送消息，返回华为错误码、错误消息和错误
-func (a *AndroidProvider) sendHuaweiMessage(accessToken string, message *HuaweiMessageRequest) (string, string, error) {
+// sendHuaweiMessage 发送华为推送消息，返回华为错误码、错误消息、消息ID（requestId，撤回时使用）和错误
+func (a *AndroidProvider) sendHuaweiMessage(ctx context.Context, accessToken string, message *HuaweiMessageRequest) (string, string, string, error) {
 	// 华为推送API endpoint
-	pushURL := fmt.Sprintf("https://push-api.cloud.huawei.com/v1/%s/messages:send", a.config.AppID)
+	pushURL := vendorURL(EndpointHuawei, fmt.Sprintf("/v1/%s/messages:send", a.config.AppID))
 
 	// 序列化消息
 	messageJSON, err := json.Marshal(message)
 	if err != nil {
-		return "", "", fmt.Errorf("序列化华为推送消息失败: %v", err)
+		return "", "", "", fmt.Errorf("序列化华为推送消息失败: %v", err)
 	}
 
 	// 创建请求
-	req, err := http.NewRequest("POST", pushURL, bytes.NewBuffer(messageJSON))
+	req, err := http.NewRequestWithContext(ctx, "POST", pushURL, bytes.NewBuffer(messageJSON))
 	if err != nil {
-		return "", "", fmt.Errorf("创建华为推送请求失败: %v", err)
+		return "", "", "", fmt.Errorf("创建华为推送请求失败: %v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
@@ -1204,34 +1299,34 @@ func (a *AndroidProvider) sendHuaweiMessage(accessToken string, message *HuaweiM
 	// 发送请求
 	resp, err := a.httpClient.Do(req)
 	if err != nil {
-		return "", "", fmt.Errorf("华为推送请求失败: %v", err)
+		return "", "", "", fmt.Errorf("华为推送请求失败: %v", err)
 	}
 	defer resp.Body.Close()
 
 	// 读取响应
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", "", fmt.Errorf("读取华为推送响应失败: %v", err)
+		return "", "", "", fmt.Errorf("读取华为推送响应失败: %v", err)
 	}
 
 	// 解析华为响应
 	var huaweiResp HuaweiResponse
 	if err := json.Unmarshal(body, &huaweiResp); err != nil {
-		return "", "", fmt.Errorf("解析华为推送响应失败: %v, 原始响应: %s", err, string(body))
+		return "", "", "", fmt.Errorf("解析华为推送响应失败: %v, 原始响应: %s", err, string(body))
 	}
 
 	// 检查华为的响应码
 	if huaweiResp.Code != "80000000" {
-		return huaweiResp.Code, huaweiResp.Msg, fmt.Errorf("华为推送失败，错误码: %s, 错误信息: %s", huaweiResp.Code, huaweiResp.Msg)
+		return huaweiResp.Code, huaweiResp.Msg, "", fmt.Errorf("华为推送失败，错误码: %s, 错误信息: %s", huaweiResp.Code, huaweiResp.Msg)
 	}
 
-	return huaweiResp.Code, huaweiResp.Msg, nil
+	return huaweiResp.Code, huaweiResp.Msg, huaweiResp.RequestID, nil
 }
 
 // sendHonorMessage 发送荣耀推送消息
-func (a *AndroidProvider) sendHonorMessage(accessToken string, message *HonorMessageRequest) (int, string, error) {
+func (a *AndroidProvider) sendHonorMessage(ctx context.Context, accessToken string, message *HonorMessageRequest) (int, string, error) {
 	// 荣耀推送API endpoint
-	pushURL := fmt.Sprintf("https://push-api.cloud.honor.com/api/v1/%s/sendMessage", a.config.AppID)
+	pushURL := vendorURL(EndpointHonor, fmt.Sprintf("/api/v1/%s/sendMessage", a.config.AppID))
 
 	// 序列化消息
 	messageJSON, err := json.Marshal(message)
@@ -1240,7 +1335,7 @@ func (a *AndroidProvider) sendHonorMessage(accessToken string, message *HonorMes
 	}
 
 	// 创建请求
-	req, err := http.NewRequest("POST", pushURL, bytes.NewBuffer(messageJSON))
+	req, err := http.NewRequestWithContext(ctx, "POST", pushURL, bytes.NewBuffer(messageJSON))
 	if err != nil {
 		return 0, "", fmt.Errorf("创建荣耀推送请求失败: %v", err)
 	}
@@ -1280,7 +1375,7 @@ func (a *AndroidProvider) sendHonorMessage(accessToken string, message *HonorMes
 // buildXiaomiMessage 构建小米推送消息
 
 // getOppoAuthToken 获取OPPO认证token
-func (a *AndroidProvider) getOppoAuthToken() (string, error) {
+func (a *AndroidProvider) getOppoAuthToken(ctx context.Context) (string, error) {
 	if a.oppoAuthClient == nil {
 		return "", fmt.Errorf("OPPO认证客户端未初始化")
 	}
@@ -1314,8 +1409,8 @@ func (a *AndroidProvider) getOppoAuthToken() (string, error) {
 	params.Add("timestamp", authReq.Timestamp)
 
 	// 发送认证请求
-	authURL := oppoHost + oppoAuthURL
-	req, err := http.NewRequest("POST", authURL, strings.NewReader(params.Encode()))
+	authURL := vendorURL(EndpointOppo, oppoAuthURL)
+	req, err := http.NewRequestWithContext(ctx, "POST", authURL, strings.NewReader(params.Encode()))
 	if err != nil {
 		return "", fmt.Errorf("创建OPPO认证请求失败: %v", err)
 	}
@@ -1353,7 +1448,7 @@ func (a *AndroidProvider) getOppoAuthToken() (string, error) {
 }
 
 // getVivoAuthToken 获取VIVO认证token
-func (a *AndroidProvider) getVivoAuthToken() (string, error) {
+func (a *AndroidProvider) getVivoAuthToken(ctx context.Context) (string, error) {
 	if a.vivoAuthClient == nil {
 		return "", fmt.Errorf("VIVO认证客户端未初始化")
 	}
@@ -1389,8 +1484,8 @@ func (a *AndroidProvider) getVivoAuthToken() (string, error) {
 	}
 
 	// 发送认证请求
-	authURL := vivoHost + vivoAuthURL
-	req, err := http.NewRequest("POST", authURL, bytes.NewBuffer(requestJSON))
+	authURL := vendorURL(EndpointVivo, vivoAuthURL)
+	req, err := http.NewRequestWithContext(ctx, "POST", authURL, bytes.NewBuffer(requestJSON))
 	if err != nil {
 		return "", fmt.Errorf("创建VIVO认证请求失败: %v", err)
 	}
@@ -1433,7 +1528,7 @@ func (a *AndroidProvider) getVivoAuthToken() (string, error) {
 }
 
 // getHonorAccessToken 获取荣耀认证token
-func (a *AndroidProvider) getHonorAccessToken() (string, error) {
+func (a *AndroidProvider) getHonorAccessToken(ctx context.Context) (string, error) {
 	if a.honorAuthClient == nil {
 		return "", fmt.Errorf("荣耀认证客户端未初始化")
 	}
@@ -1454,8 +1549,8 @@ func (a *AndroidProvider) getHonorAccessToken() (string, error) {
 	data.Set("client_secret", a.honorAuthClient.clientSecret)
 
 	// 发送认证请求
-	authURL := "https://iam.developer.honor.com/auth/token"
-	req, err := http.NewRequest("POST", authURL, strings.NewReader(data.Encode()))
+	authURL := vendorURL(EndpointHonorOAuth, "/auth/token")
+	req, err := http.NewRequestWithContext(ctx, "POST", authURL, strings.NewReader(data.Encode()))
 	if err != nil {
 		return "", fmt.Errorf("创建荣耀认证请求失败: %v", err)
 	}
@@ -1508,6 +1603,9 @@ func (a *AndroidProvider) buildHonorMessage(device *models.Device, pushLog *mode
 	importance := "NORMAL" // 默认为服务通讯类消息
 	ttl := "86400s"        // 默认消息存活时间1天
 	targetUserType := 0    // 默认为0正式消息,1测试消息
+	if remaining := remainingTTL(pushLog); remaining > 0 {
+		ttl = fmt.Sprintf("%ds", remaining)
+	}
 
 	// 从pushLog.Payload中解析荣耀特有参数
 	if pushLog.Payload != "" && pushLog.Payload != "{}" {
@@ -1584,6 +1682,10 @@ func (a *AndroidProvider) buildHonorMessage(device *models.Device, pushLog *mode
 		},
 	}
 
+	if pushLog.CollapseKey != "" {
+		androidConfig.Notification.NotifyId = collapseNotifyID(pushLog.CollapseKey, 1000000) // 相同notifyId的通知会被覆盖
+	}
+
 	// 构建消息体
 	message := &HonorMessageRequest{
 		Notification: notification,
@@ -1591,6 +1693,28 @@ func (a *AndroidProvider) buildHonorMessage(device *models.Device, pushLog *mode
 		Token:        []string{device.Token},
 		Data:         string(dataJSON),
 	}
+	// 富媒体：大图标、长文本样式与按钮
+	if rich := parseRichContent(pushLog.Payload); !rich.IsEmpty() {
+		honorNotification := androidConfig.Notification
+		honorNotification.Image = rich.ImageURL
+		if rich.BigText != "" {
+			honorNotification.Style = 1 // 1=长文本样式
+			honorNotification.BigTitle = pushLog.Title
+			honorNotification.BigBody = rich.BigText
+		}
+		for _, button := range rich.Buttons {
+			honorNotification.Buttons = append(honorNotification.Buttons, &HonorButton{
+				Name:       button.Title,
+				ActionType: richButtonActionType(button.Action),
+				Intent:     button.Value,
+			})
+		}
+	}
+	if isDataMessage(pushLog) {
+		// 透传消息：仅下发data
+		message.Notification = nil
+		androidConfig.Notification = nil
+	}
 
 	return message
 }
@@ -1604,6 +1728,7 @@ func (a *AndroidProvider) buildOppoMessage(device *models.Device, pushLog *model
 	offLine := true     // 默认启用离线消息
 	offLineTTL := 86400 // 默认离线消息存活24小时
 	callBackUrl := a.config.CallBack
+	offLineTTL = ttlSeconds(pushLog, offLineTTL)
 
 	// 构建自定义数据用于action_parameters
 	customData := make(map[string]interface{})
@@ -1670,6 +1795,12 @@ func (a *AndroidProvider) buildOppoMessage(device *models.Device, pushLog *model
 		CallBackUrl:      callBackUrl,
 	}
 
+	// 富媒体：OPPO 仅支持长文本样式，大图需先上传获取图片ID，按钮不支持
+	if rich := parseRichContent(pushLog.Payload); rich.BigText != "" {
+		notification.Style = 2 // 2=长文本样式
+		notification.Content = rich.BigText
+	}
+
 	// 构建OPPO推送消息
 	message := &OppoMessage{
 		TargetType:           2,            // 2 = registration_id
@@ -1690,6 +1821,9 @@ func (a *AndroidProvider) buildVivoMessage(device *models.Device, pushLog *model
 	skipContent := ""   // 跳转内容
 	networkType := -1   // 网络类型，默认为-1（不限制）
 	classification := 0 // 消息分类，默认为0（运营消息）
+	if ttl := remainingTTL(pushLog); ttl > 0 {
+		timeToLive = max(ttl, 60) // vivo 离线保存时长最短60秒
+	}
 	extra := make(map[string]string)
 	if a.config.CallBack != "" {
 		extra["callback.id"] = a.config.CallBack
@@ -1771,6 +1905,75 @@ func (a *AndroidProvider) buildVivoMessage(device *models.Device, pushLog *model
 	return message
 }
 
+// MeizuUnvarnishedBody 魅族透传消息体（序列化为MessageJSON）
+type MeizuUnvarnishedBody struct {
+	Title        string             `json:"title"`        // 标题
+	Content      string             `json:"content"`      // 透传内容
+	PushTimeInfo *MeizuPushTimeInfo `json:"pushTimeInfo"` // 推送时间信息
+}
+
+// buildMeizuUnvarnishedMessage 构建魅族透传消息，内容为统一标识字段与自定义数据
+func (a *AndroidProvider) buildMeizuUnvarnishedMessage(device *models.Device, pushLog *models.PushLog) (*MeizuMessage, error) {
+	dataMap := make(map[string]interface{})
+	if pushLog.Payload != "" && pushLog.Payload != "{}" {
+		var customData map[string]interface{}
+		if err := json.Unmarshal([]byte(pushLog.Payload), &customData); err == nil {
+			for k, v := range customData {
+				if k != "meizu" {
+					dataMap[k] = v
+				}
+			}
+		}
+	}
+	dataMap["badge"] = pushLog.Badge
+	dataMap["push_log_id"] = pushLog.ID
+	if pushLog.DedupKey != "" {
+		dataMap["dedup_key"] = pushLog.DedupKey
+	}
+	dataMap["dp_source"] = "doopush"
+
+	content, err := json.Marshal(dataMap)
+	if err != nil {
+		return nil, fmt.Errorf("序列化魅族透传内容失败: %v", err)
+	}
+
+	body := &MeizuUnvarnishedBody{
+		Title:   pushLog.Title,
+		Content: string(content),
+		PushTimeInfo: &MeizuPushTimeInfo{
+			OffLine:   1,
+			ValidTime: meizuValidHours(pushLog),
+		},
+	}
+	messageJSON, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("序列化魅族消息体失败: %v", err)
+	}
+
+	message := &MeizuMessage{
+		AppID:       a.config.AppID,
+		PushIds:     device.Token,
+		MessageJSON: string(messageJSON),
+	}
+	sign, err := a.getMeizuSignature(message)
+	if err != nil {
+		return nil, fmt.Errorf("生成魅族MD5签名失败: %v", err)
+	}
+	message.Sign = sign
+
+	return message, nil
+}
+
+// meizuValidHours 魅族离线有效时长（小时，1~72），未设置存活时长时默认24小时
+func meizuValidHours(pushLog *models.PushLog) int {
+	ttl := remainingTTL(pushLog)
+	if ttl <= 0 {
+		return 24
+	}
+	hours := (ttl + 3599) / 3600
+	return min(max(hours, 1), 72)
+}
+
 // buildMeizuMessage 构建魅族推送消息
 func (a *AndroidProvider) buildMeizuMessage(device *models.Device, pushLog *models.PushLog) (*MeizuMessage, error) {
 	// 设置默认参数
@@ -1782,8 +1985,8 @@ func (a *AndroidProvider) buildMeizuMessage(device *models.Device, pushLog *mode
 			Content:       pushLog.Content,
 		},
 		PushTimeInfo: &MeizuPushTimeInfo{
-			OffLine:   1,  // 默认启用离线消息
-			ValidTime: 24, // 默认24小时
+			OffLine:   1,                        // 默认启用离线消息
+			ValidTime: meizuValidHours(pushLog), // 默认24小时
 		},
 	}
 
@@ -1984,6 +2187,21 @@ func (a *AndroidProvider) buildMeizuMessage(device *models.Device, pushLog *mode
 		}
 	}
 
+	// 富媒体：未通过魅族参数指定展开方式时，使用长文本或大图展开
+	if rich := parseRichContent(pushLog.Payload); messageBody.NoticeExpandInfo == nil {
+		if rich.BigText != "" {
+			messageBody.NoticeExpandInfo = &MeizuNoticeExpandInfo{
+				NoticeExpandType:    1,
+				NoticeExpandContent: rich.BigText,
+			}
+		} else if rich.ImageURL != "" {
+			messageBody.NoticeExpandInfo = &MeizuNoticeExpandInfo{
+				NoticeExpandType:   2,
+				NoticeExpandImgUrl: rich.ImageURL,
+			}
+		}
+	}
+
 	// 序列化消息体为JSON字符串
 	messageJSON, err := json.Marshal(messageBody)
 	if err != nil {
@@ -2023,8 +2241,11 @@ func (a *AndroidProvider) buildXiaomiMessage(device *models.Device, pushLog *mod
 	// 解析小米特有参数
 	passThrough := 0
 	notifyType := 7
-	timeToLive := int64(86400000)
+	timeToLive := int64(ttlSeconds(pushLog, 86400)) * 1000
 	channelID := ""
+	if pushLog.CollapseKey != "" {
+		message.NotifyID = collapseNotifyID(pushLog.CollapseKey, 1000000) // 相同notify_id的通知会被覆盖
+	}
 
 	// 从pushLog.Payload中解析小米特有参数
 	if pushLog.Payload != "" && pushLog.Payload != "{}" {
@@ -2047,6 +2268,9 @@ func (a *AndroidProvider) buildXiaomiMessage(device *models.Device, pushLog *mod
 		}
 	}
 
+	if isDataMessage(pushLog) {
+		passThrough = 1 // 透传消息
+	}
 	message.PassThrough = passThrough
 	message.NotifyType = notifyType
 	message.TimeToLive = timeToLive
@@ -2080,6 +2304,20 @@ func (a *AndroidProvider) buildXiaomiMessage(device *models.Device, pushLog *mod
 		}
 	}
 
+	// 富媒体：长文本优先，其次大图（需使用小米上传接口返回的图片地址），按钮依次放在左/中/右
+	if rich := parseRichContent(pushLog.Payload); !rich.IsEmpty() && !isDataMessage(pushLog) {
+		if rich.BigText != "" {
+			extraMap["notification_style_type"] = "1"
+			message.Description = rich.BigText
+		} else if rich.ImageURL != "" {
+			extraMap["notification_style_type"] = "2"
+			extraMap["notification_bigPic_uri"] = rich.ImageURL
+		}
+		for k, v := range xiaomiButtonExtras(rich.Buttons) {
+			extraMap[k] = v
+		}
+	}
+
 	message.Extra = extraMap
 
 	// 构建payload字段（小米推送的自定义数据载荷）
@@ -2093,9 +2331,9 @@ func (a *AndroidProvider) buildXiaomiMessage(device *models.Device, pushLog *mod
 }
 
 // sendXiaomiMessage 发送小米推送消息，返回小米错误码、错误消息、消息ID和错误
-func (a *AndroidProvider) sendXiaomiMessage(message *XiaomiMessage, device *models.Device) (string, string, string, error) {
+func (a *AndroidProvider) sendXiaomiMessage(ctx context.Context, message *XiaomiMessage, device *models.Device) (string, string, string, error) {
 	// 小米推送API endpoint - 向regid推送消息
-	pushURL := "https://api.xmpush.xiaomi.com/v3/message/regid"
+	pushURL := vendorURL(EndpointXiaomi, "/v3/message/regid")
 
 	// 构建请求参数
 	data := url.Values{}
@@ -2131,7 +2369,7 @@ func (a *AndroidProvider) sendXiaomiMessage(message *XiaomiMessage, device *mode
 	}
 
 	// 创建HTTP请求
-	req, err := http.NewRequest("POST", pushURL, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", pushURL, strings.NewReader(data.Encode()))
 	if err != nil {
 		return "", "", "", fmt.Errorf("创建小米推送请求失败: %v", err)
 	}
@@ -2185,9 +2423,9 @@ func (a *AndroidProvider) sendXiaomiMessage(message *XiaomiMessage, device *mode
 }
 
 // sendOppoMessage 发送OPPO推送消息，返回OPPO错误码、错误消息、消息ID和错误
-func (a *AndroidProvider) sendOppoMessage(message *OppoMessage, device *models.Device) (string, string, string, error) {
+func (a *AndroidProvider) sendOppoMessage(ctx context.Context, message *OppoMessage, device *models.Device) (string, string, string, error) {
 	// 获取认证token
-	authToken, err := a.getOppoAuthToken()
+	authToken, err := a.getOppoAuthToken(ctx)
 	if err != nil {
 		return "", "", "", fmt.Errorf("获取OPPO认证token失败: %v", err)
 	}
@@ -2200,7 +2438,7 @@ func (a *AndroidProvider) sendOppoMessage(message *OppoMessage, device *models.D
 	}
 
 	// 构建推送API URL
-	pushURL := oppoHost + oppoSendURL
+	pushURL := vendorURL(EndpointOppo, oppoSendURL)
 
 	// 构建form data参数
 	params := url.Values{}
@@ -2208,7 +2446,7 @@ func (a *AndroidProvider) sendOppoMessage(message *OppoMessage, device *models.D
 	params.Add("auth_token", authToken)
 
 	// 创建HTTP请求
-	req, err := http.NewRequest("POST", pushURL, strings.NewReader(params.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", pushURL, strings.NewReader(params.Encode()))
 	if err != nil {
 		return "", "", "", fmt.Errorf("创建OPPO推送请求失败: %v", err)
 	}
@@ -2255,9 +2493,9 @@ func (a *AndroidProvider) sendOppoMessage(message *OppoMessage, device *models.D
 }
 
 // sendVivoMessage 发送VIVO推送消息，返回VIVO错误码、错误消息、任务ID和错误
-func (a *AndroidProvider) sendVivoMessage(message *VivoMessage, device *models.Device) (string, string, string, error) {
+func (a *AndroidProvider) sendVivoMessage(ctx context.Context, message *VivoMessage, device *models.Device) (string, string, string, error) {
 	// 获取认证token
-	authToken, err := a.getVivoAuthToken()
+	authToken, err := a.getVivoAuthToken(ctx)
 	if err != nil {
 		return "", "", "", fmt.Errorf("获取VIVO认证token失败: %v", err)
 	}
@@ -2269,10 +2507,10 @@ func (a *AndroidProvider) sendVivoMessage(message *VivoMessage, device *models.D
 	}
 
 	// 构建推送API URL
-	pushURL := vivoHost + vivoSendURL
+	pushURL := vendorURL(EndpointVivo, vivoSendURL)
 
 	// 创建HTTP请求
-	req, err := http.NewRequest("POST", pushURL, bytes.NewBuffer(requestJSON))
+	req, err := http.NewRequestWithContext(ctx, "POST", pushURL, bytes.NewBuffer(requestJSON))
 	if err != nil {
 		return "", "", "", fmt.Errorf("创建VIVO推送请求失败: %v", err)
 	}
@@ -2446,7 +2684,7 @@ func (a *AndroidProvider) mapXiaomiError(result *models.PushResult, xiaomiResult
 }
 
 // sendXiaomi 发送小米推送
-func (a *AndroidProvider) sendXiaomi(device *models.Device, pushLog *models.PushLog) *models.PushResult {
+func (a *AndroidProvider) sendXiaomi(ctx context.Context, device *models.Device, pushLog *models.PushLog) *models.PushResult {
 	result := &models.PushResult{
 		AppID:        pushLog.AppID,
 		PushLogID:    pushLog.ID,
@@ -2458,7 +2696,7 @@ func (a *AndroidProvider) sendXiaomi(device *models.Device, pushLog *models.Push
 	message := a.buildXiaomiMessage(device, pushLog)
 
 	// 发送推送消息
-	xiaomiResult, xiaomiMsg, messageID, err := a.sendXiaomiMessage(message, device)
+	xiaomiResult, xiaomiMsg, messageID, err := a.sendXiaomiMessage(ctx, message, device)
 	if err != nil {
 		// 检查是否是网络错误
 		if xiaomiResult == "" {
@@ -2476,7 +2714,7 @@ func (a *AndroidProvider) sendXiaomi(device *models.Device, pushLog *models.Push
 }
 
 // sendOPPO 发送OPPO推送
-func (a *AndroidProvider) sendOPPO(device *models.Device, pushLog *models.PushLog) *models.PushResult {
+func (a *AndroidProvider) sendOPPO(ctx context.Context, device *models.Device, pushLog *models.PushLog) *models.PushResult {
 	result := &models.PushResult{
 		AppID:        pushLog.AppID,
 		PushLogID:    pushLog.ID,
@@ -2484,11 +2722,16 @@ func (a *AndroidProvider) sendOPPO(device *models.Device, pushLog *models.PushLo
 		ResponseData: "{}",
 	}
 
+	// OPPO服务端API仅支持通知栏消息
+	if isDataMessage(pushLog) {
+		return a.createUnsupportedMessageTypeError(pushLog, "OPPO")
+	}
+
 	// 构建OPPO推送消息
 	message := a.buildOppoMessage(device, pushLog)
 
 	// 发送推送消息
-	oppoResult, oppoMsg, messageID, err := a.sendOppoMessage(message, device)
+	oppoResult, oppoMsg, messageID, err := a.sendOppoMessage(ctx, message, device)
 	if err != nil {
 		// 检查是否是网络错误
 		if oppoResult == "" {
@@ -2506,7 +2749,7 @@ func (a *AndroidProvider) sendOPPO(device *models.Device, pushLog *models.PushLo
 }
 
 // sendVIVO 发送VIVO推送
-func (a *AndroidProvider) sendVIVO(device *models.Device, pushLog *models.PushLog) *models.PushResult {
+func (a *AndroidProvider) sendVIVO(ctx context.Context, device *models.Device, pushLog *models.PushLog) *models.PushResult {
 	result := &models.PushResult{
 		AppID:        pushLog.AppID,
 		PushLogID:    pushLog.ID,
@@ -2514,11 +2757,16 @@ func (a *AndroidProvider) sendVIVO(device *models.Device, pushLog *models.PushLo
 		ResponseData: "{}",
 	}
 
+	// VIVO服务端API仅支持通知栏消息
+	if isDataMessage(pushLog) {
+		return a.createUnsupportedMessageTypeError(pushLog, "VIVO")
+	}
+
 	// 构建VIVO推送消息
 	message := a.buildVivoMessage(device, pushLog)
 
 	// 发送推送消息
-	vivoResult, vivoMsg, taskID, err := a.sendVivoMessage(message, device)
+	vivoResult, vivoMsg, taskID, err := a.sendVivoMessage(ctx, message, device)
 	if err != nil {
 		// 检查是否是网络错误
 		if vivoResult == "" {
@@ -2536,7 +2784,7 @@ func (a *AndroidProvider) sendVIVO(device *models.Device, pushLog *models.PushLo
 }
 
 // sendMeizu 发送魅族推送
-func (a *AndroidProvider) sendMeizu(device *models.Device, pushLog *models.PushLog) *models.PushResult {
+func (a *AndroidProvider) sendMeizu(ctx context.Context, device *models.Device, pushLog *models.PushLog) *models.PushResult {
 	result := &models.PushResult{
 		AppID:        pushLog.AppID,
 		PushLogID:    pushLog.ID,
@@ -2545,7 +2793,13 @@ func (a *AndroidProvider) sendMeizu(device *models.Device, pushLog *models.PushL
 	}
 
 	// 构建魅族推送消息
-	message, err := a.buildMeizuMessage(device, pushLog)
+	var message *MeizuMessage
+	var err error
+	if isDataMessage(pushLog) {
+		message, err = a.buildMeizuUnvarnishedMessage(device, pushLog)
+	} else {
+		message, err = a.buildMeizuMessage(device, pushLog)
+	}
 	if err != nil {
 		result.ErrorCode = "MESSAGE_BUILD_ERROR"
 		result.ErrorMessage = fmt.Sprintf("构建魅族推送消息失败: %v", err)
@@ -2553,7 +2807,7 @@ func (a *AndroidProvider) sendMeizu(device *models.Device, pushLog *models.PushL
 	}
 
 	// 发送推送消息
-	meizuCode, meizuMsg, msgId, err := a.sendMeizuMessage(message, device)
+	meizuCode, meizuMsg, msgId, err := a.sendMeizuMessage(ctx, message, isDataMessage(pushLog))
 	if err != nil {
 		// 检查是否是网络错误
 		if meizuCode == "" {
@@ -2890,6 +3144,18 @@ func (a *AndroidProvider) createResponseError(pushLog *models.PushLog, err error
 	}
 }
 
+// createUnsupportedMessageTypeError 创建消息类型不支持的错误结果
+func (a *AndroidProvider) createUnsupportedMessageTypeError(pushLog *models.PushLog, provider string) *models.PushResult {
+	return &models.PushResult{
+		AppID:        pushLog.AppID,
+		PushLogID:    pushLog.ID,
+		Success:      false,
+		ErrorCode:    "UNSUPPORTED_MESSAGE_TYPE",
+		ErrorMessage: fmt.Sprintf("%s 不支持透传消息", provider),
+		ResponseData: "{}",
+	}
+}
+
 // getMeizuSignature 生成魅族推送MD5签名
 func (a *AndroidProvider) getMeizuSignature(message *MeizuMessage) (string, error) {
 	// 构建参数map
@@ -2933,10 +3199,13 @@ func (a *AndroidProvider) getMeizuSignature(message *MeizuMessage) (string, erro
 	return signature, nil
 }
 
-// sendMeizuMessage 发送魅族推送消息
-func (a *AndroidProvider) sendMeizuMessage(message *MeizuMessage, device *models.Device) (string, string, string, error) {
+// sendMeizuMessage 发送魅族推送消息，unvarnished 为 true 时走透传接口
+func (a *AndroidProvider) sendMeizuMessage(ctx context.Context, message *MeizuMessage, unvarnished bool) (string, string, string, error) {
 	// 魅族推送API endpoint
-	pushURL := "https://server-api-push.meizu.com/garcia/api/server/push/varnished/pushByPushId"
+	pushURL := vendorURL(EndpointMeizu, "/garcia/api/server/push/varnished/pushByPushId")
+	if unvarnished {
+		pushURL = vendorURL(EndpointMeizu, "/garcia/api/server/push/unvarnished/pushByPushId")
+	}
 
 	// 构建表单参数
 	data := url.Values{}
@@ -2946,7 +3215,7 @@ func (a *AndroidProvider) sendMeizuMessage(message *MeizuMessage, device *models
 	data.Set("sign", message.Sign)
 
 	// 创建HTTP请求
-	req, err := http.NewRequest("POST", pushURL, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", pushURL, strings.NewReader(data.Encode()))
 	if err != nil {
 		return "", "", "", fmt.Errorf("创建魅族推送请求失败: %v", err)
 	}
@@ -2999,6 +3268,10 @@ func (a *AndroidProvider) mapMeizuError(result *models.PushResult, meizuCode str
 		// 成功，不应该调用此函数
 		result.Success = true
 		return
+	case "1001", "1003":
+		// 系统错误、服务器忙
+		result.ErrorCode = "SERVER_ERROR"
+		result.ErrorMessage = fmt.Sprintf("魅族推送服务器错误: %s", meizuMsg)
 	case "1005":
 		// 参数错误
 		result.ErrorCode = "INVALID_PARAMETER"
@@ -0,0 +1,199 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/doopush/doopush/api/internal/models"
+)
+
+const richPayload = `{
+	"image_url": "https://example.com/banner.png",
+	"big_text": "订单已发货，预计明天送达",
+	"buttons": [
+		{"title": "查看物流", "action": "open_url", "value": "https://example.com/track"},
+		{"title": "订单详情", "action": "open_page", "value": "app://order/42"},
+		{"title": "打开应用", "action": "open_app"},
+		{"title": "第四个按钮", "action": "open_app"}
+	]
+}`
+
+func TestParseRichContent(t *testing.T) {
+	rich := parseRichContent(richPayload)
+	if rich.ImageURL != "https://example.com/banner.png" || rich.BigText == "" {
+		t.Fatalf("rich = %+v", rich)
+	}
+	if len(rich.Buttons) != maxRichButtons {
+		t.Fatalf("buttons = %d, want %d", len(rich.Buttons), maxRichButtons)
+	}
+	if !parseRichContent("{}").IsEmpty() || !parseRichContent(`{"order_id":"42"}`).IsEmpty() {
+		t.Fatal("payload without rich fields should be empty")
+	}
+}
+
+func TestHuaweiHonorRichAndDataMessage(t *testing.T) {
+	provider := NewAndroidProvider("huawei")
+	device := &models.Device{Token: "device-1"}
+
+	hw := provider.buildHuaweiMessage(device, &models.PushLog{ID: 1, Title: "标题", Content: "内容", Payload: richPayload})
+	hwNotification := hw.Message.Android.Notification
+	if hwNotification.Image != "https://example.com/banner.png" || hwNotification.Style != 1 || hwNotification.BigTitle != "标题" || hwNotification.BigBody != "订单已发货，预计明天送达" {
+		t.Fatalf("huawei notification = %+v", hwNotification)
+	}
+	if len(hwNotification.Buttons) != 3 {
+		t.Fatalf("huawei buttons = %d, want 3", len(hwNotification.Buttons))
+	}
+	for i, want := range []HuaweiButton{
+		{Name: "查看物流", ActionType: 2, Intent: "https://example.com/track"},
+		{Name: "订单详情", ActionType: 1, Intent: "app://order/42"},
+		{Name: "打开应用", ActionType: 0},
+	} {
+		if *hwNotification.Buttons[i] != want {
+			t.Errorf("huawei button %d = %+v, want %+v", i, *hwNotification.Buttons[i], want)
+		}
+	}
+
+	hwData := provider.buildHuaweiMessage(device, &models.PushLog{ID: 1, Title: "标题", Content: "内容", MessageType: "data", Payload: richPayload})
+	if hwData.Message.Notification != nil || hwData.Message.Android.Notification != nil || hwData.Message.Android.Urgency != "NORMAL" {
+		t.Fatalf("huawei data message = %+v", hwData.Message.Android)
+	}
+	if hwData.Message.Data == "" {
+		t.Fatal("huawei data message has no data")
+	}
+
+	honor := provider.buildHonorMessage(device, &models.PushLog{ID: 1, Title: "标题", Content: "内容", Payload: richPayload})
+	honorNotification := honor.Android.Notification
+	if honorNotification.Image != "https://example.com/banner.png" || honorNotification.Style != 1 || honorNotification.BigBody != "订单已发货，预计明天送达" {
+		t.Fatalf("honor notification = %+v", honorNotification)
+	}
+	if len(honorNotification.Buttons) != 3 || honorNotification.Buttons[0].ActionType != 2 || honorNotification.Buttons[1].ActionType != 1 {
+		t.Fatalf("honor buttons = %+v", honorNotification.Buttons)
+	}
+
+	honorData := provider.buildHonorMessage(device, &models.PushLog{ID: 1, Title: "标题", Content: "内容", MessageType: "data", Payload: "{}"})
+	if honorData.Notification != nil || honorData.Android.Notification != nil || honorData.Data == "" {
+		t.Fatalf("honor data message = %+v", honorData)
+	}
+}
+
+func TestXiaomiRichAndDataMessage(t *testing.T) {
+	provider := NewAndroidProvider("xiaomi")
+	device := &models.Device{Token: "device-1", App: models.App{PackageName: "com.example.app"}}
+
+	for _, tc := range []struct {
+		name        string
+		payload     string
+		description string
+		extras      map[string]string
+	}{
+		{
+			name:        "长文本优先于大图",
+			payload:     richPayload,
+			description: "订单已发货，预计明天送达",
+			extras: map[string]string{
+				"notification_style_type":                       "1",
+				"notification_style_button_left_name":           "查看物流",
+				"notification_style_button_left_notify_effect":  "3",
+				"notification_style_button_left_web_uri":        "https://example.com/track",
+				"notification_style_button_mid_notify_effect":   "2",
+				"notification_style_button_mid_intent_uri":      "app://order/42",
+				"notification_style_button_right_notify_effect": "1",
+			},
+		},
+		{
+			name:        "大图",
+			payload:     `{"image_url":"https://example.com/banner.png","buttons":[{"title":"查看","action":"open_url","value":"https://example.com"}]}`,
+			description: "内容",
+			extras: map[string]string{
+				"notification_style_type":                 "2",
+				"notification_bigPic_uri":                 "https://example.com/banner.png",
+				"notification_style_button_right_name":    "查看",
+				"notification_style_button_right_web_uri": "https://example.com",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			message := provider.buildXiaomiMessage(device, &models.PushLog{ID: 1, Title: "标题", Content: "内容", Payload: tc.payload})
+			if message.PassThrough != 0 || message.Description != tc.description {
+				t.Fatalf("pass_through = %d, description = %q", message.PassThrough, message.Description)
+			}
+			for k, want := range tc.extras {
+				if got := message.Extra[k]; got != want {
+					t.Errorf("extra[%s] = %v, want %s", k, got, want)
+				}
+			}
+		})
+	}
+
+	// 透传消息不携带富媒体样式
+	message := provider.buildXiaomiMessage(device, &models.PushLog{ID: 1, Title: "标题", Content: "内容", MessageType: "data", Payload: richPayload})
+	if message.PassThrough != 1 || message.Description != "内容" {
+		t.Fatalf("pass_through = %d, description = %q", message.PassThrough, message.Description)
+	}
+	if _, ok := message.Extra["notification_style_type"]; ok {
+		t.Fatalf("data message has rich style: %v", message.Extra)
+	}
+}
+
+func TestOppoMeizuRichContent(t *testing.T) {
+	device := &models.Device{Token: "device-1"}
+
+	oppo := NewAndroidProvider("oppo").buildOppoMessage(device, &models.PushLog{ID: 1, Title: "标题", Content: "内容", Payload: richPayload})
+	if oppo.Notification.Style != 2 || oppo.Notification.Content != "订单已发货，预计明天送达" {
+		t.Fatalf("oppo notification = %+v", oppo.Notification)
+	}
+	oppo = NewAndroidProvider("oppo").buildOppoMessage(device, &models.PushLog{ID: 1, Title: "标题", Content: "内容", Payload: `{"image_url":"https://example.com/banner.png"}`})
+	if oppo.Notification.Style != 1 || oppo.Notification.Content != "内容" {
+		t.Fatalf("oppo image-only notification = %+v", oppo.Notification)
+	}
+
+	for _, tc := range []struct {
+		payload string
+		want    MeizuNoticeExpandInfo
+	}{
+		{richPayload, MeizuNoticeExpandInfo{NoticeExpandType: 1, NoticeExpandContent: "订单已发货，预计明天送达"}},
+		{`{"image_url":"https://example.com/banner.png"}`, MeizuNoticeExpandInfo{NoticeExpandType: 2, NoticeExpandImgUrl: "https://example.com/banner.png"}},
+	} {
+		message, err := NewAndroidProvider("meizu").buildMeizuMessage(device, &models.PushLog{ID: 1, Title: "标题", Content: "内容", Payload: tc.payload})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body MeizuMessageBody
+		if err := json.Unmarshal([]byte(message.MessageJSON), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.NoticeExpandInfo == nil || *body.NoticeExpandInfo != tc.want {
+			t.Errorf("meizu expand info = %+v, want %+v", body.NoticeExpandInfo, tc.want)
+		}
+	}
+}
+
+func TestAndroidDataMessageSupport(t *testing.T) {
+	pushLog := &models.PushLog{ID: 1, AppID: 1, Title: "标题", Content: "内容", MessageType: "data", Payload: "{}"}
+
+	// OPPO 和 vivo 服务端接口不支持透传消息，发送前直接拒绝
+	for _, channel := range []string{"oppo", "vivo"} {
+		result := NewAndroidProvider(channel).SendPush(context.Background(), &models.Device{Token: "device-1"}, pushLog)
+		if result.Success || result.ErrorCode != "UNSUPPORTED_MESSAGE_TYPE" {
+			t.Errorf("%s: success=%v code=%s, want UNSUPPORTED_MESSAGE_TYPE", channel, result.Success, result.ErrorCode)
+		}
+	}
+
+	// 魅族透传消息使用透传消息体，自定义数据放在 content 中，魅族特有参数不下发
+	message, err := NewAndroidProvider("meizu").buildMeizuUnvarnishedMessage(&models.Device{Token: "device-1"}, &models.PushLog{ID: 1, Title: "标题", MessageType: "data", Payload: `{"order_id":"42","meizu":{"notice_msg_type":1}}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body MeizuUnvarnishedBody
+	if err := json.Unmarshal([]byte(message.MessageJSON), &body); err != nil {
+		t.Fatal(err)
+	}
+	var content map[string]interface{}
+	if err := json.Unmarshal([]byte(body.Content), &content); err != nil {
+		t.Fatalf("meizu content = %q: %v", body.Content, err)
+	}
+	if _, ok := content["meizu"]; ok || content["order_id"] != "42" || content["push_log_id"] != float64(1) {
+		t.Fatalf("meizu content = %v", content)
+	}
+}
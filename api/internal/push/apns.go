@@ -2,6 +2,7 @@ package push
 
 import (
 	"bytes"
+	"context"
 	"crypto/ecdsa"
 	"crypto/tls"
 	"crypto/x509"
@@ -10,12 +11,13 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/net/http2"
 
 	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/pkg/logger"
 )
 
 // APNsProvider iOS推送服务提供者
@@ -87,6 +89,26 @@ func NewAPNsProviderWithConfig(config APNsConfig) (*APNsProvider, error) {
 	return nil, fmt.Errorf("未提供有效的APNs认证配置")
 }
 
+// CertificateNotAfter 解析 PEM 证书的过期时间，包含证书链时取第一个证书
+func CertificateNotAfter(certPEM string) (time.Time, error) {
+	rest := []byte(certPEM)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return time.Time{}, fmt.Errorf("未找到PEM格式的证书")
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("解析证书失败: %v", err)
+		}
+		return cert.NotAfter, nil
+	}
+}
+
 // normalizeConfig 规范化配置字段，兼容前端字段名
 func normalizeConfig(config *APNsConfig) {
 	// 前端 private_key -> 后端 auth_key_p8
@@ -117,12 +139,8 @@ func NewAPNsProviderWithP8(keyP8, keyID, teamID, bundleID, environment string) (
 
 	// 创建HTTP/2客户端
 	client := &http.Client{
-		Transport: &http2.Transport{
-			TLSClientConfig: &tls.Config{
-				ServerName: getAPNsHost(environment),
-			},
-		},
-		Timeout: 30 * time.Second,
+		Transport: newAPNsTransport(environment, nil),
+		Timeout:   30 * time.Second,
 	}
 
 	return &APNsProvider{
@@ -138,19 +156,9 @@ func NewAPNsProviderWithP8(keyP8, keyID, teamID, bundleID, environment string) (
 
 // NewAPNsProviderWithCert 使用证书创建APNs推送提供者
 func NewAPNsProviderWithCert(cert tls.Certificate, environment, bundleID string) (*APNsProvider, error) {
-	// 创建TLS配置
-	tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		ServerName:   getAPNsHost(environment),
-	}
-
 	// 创建HTTP/2客户端
-	transport := &http2.Transport{
-		TLSClientConfig: tlsConfig,
-	}
-
 	client := &http.Client{
-		Transport: transport,
+		Transport: newAPNsTransport(environment, []tls.Certificate{cert}),
 		Timeout:   30 * time.Second,
 	}
 
@@ -186,20 +194,9 @@ func parseP8PrivateKey(keyData []byte) (*ecdsa.PrivateKey, error) {
 	return ecdsaKey, nil
 }
 
-// getAPNsHost 获取APNs服务器主机名
-func getAPNsHost(environment string) string {
-	if environment == "production" {
-		return "api.push.apple.com"
-	}
-	return "api.development.push.apple.com"
-}
-
 // getAPNsURL 获取APNs服务器URL
 func getAPNsURL(environment string) string {
-	if environment == "production" {
-		return "https://api.push.apple.com/3/device/"
-	}
-	return "https://api.development.push.apple.com/3/device/"
+	return vendorURL(apnsEndpoint(environment), "/3/device/")
 }
 
 // APNsPayload APNs推送载荷
@@ -225,32 +222,120 @@ type APNsAlert struct {
 	Subtitle string `json:"subtitle,omitempty"`
 }
 
+// APNsOptions APNs推送选项，对应 payload.apns
+type APNsOptions struct {
+	PushType          string `json:"push_type,omitempty" binding:"omitempty,oneof=alert background voip complication fileprovider mdm liveactivity location pushtotalk" example:"alert"` // apns-push-type
+	CollapseID        string `json:"collapse_id,omitempty" binding:"omitempty,max=64" example:"order_1001"`                                                                              // apns-collapse-id，相同ID的通知会被合并
+	Expiration        *int64 `json:"expiration,omitempty" binding:"omitempty,min=0" example:"1735660800"`                                                                                // apns-expiration，UNIX时间戳（秒），0表示仅尝试投递一次
+	Priority          int    `json:"priority,omitempty" binding:"omitempty,oneof=1 5 10" example:"10"`                                                                                   // apns-priority
+	ThreadID          string `json:"thread_id,omitempty" example:"chat_42"`                                                                                                              // 通知分组
+	MutableContent    bool   `json:"mutable_content,omitempty" example:"false"`                                                                                                          // 允许 Notification Service Extension 修改内容
+	Category          string `json:"category,omitempty" example:"MESSAGE"`                                                                                                               // 通知动作分类
+	Subtitle          string `json:"subtitle,omitempty" example:"来自张三"`                                                                                                                  // 副标题
+	InterruptionLevel string `json:"interruption_level,omitempty" binding:"omitempty,oneof=passive active time-sensitive critical" example:"active"`                                     // 中断级别
+	Sound             string `json:"sound,omitempty" example:"default"`                                                                                                                  // 提示音，默认 default
+}
+
+// parseAPNsOptions 从推送载荷中解析 apns 选项
+func parseAPNsOptions(payload map[string]interface{}) APNsOptions {
+	var opts APNsOptions
+	raw, ok := payload["apns"]
+	if !ok || raw == nil {
+		return opts
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return opts
+	}
+	_ = json.Unmarshal(data, &opts)
+	return opts
+}
+
+// apnsTopic 根据推送类型计算 apns-topic，部分类型要求在 Bundle ID 后追加固定后缀
+func apnsTopic(bundleID, pushType string) string {
+	if bundleID == "" {
+		return ""
+	}
+	switch pushType {
+	case "voip":
+		return bundleID + ".voip"
+	case "complication":
+		return bundleID + ".complication"
+	case "fileprovider":
+		return bundleID + ".pushkit.fileprovider"
+	case "liveactivity":
+		return bundleID + ".push-type.liveactivity"
+	case "location":
+		return bundleID + ".location-query"
+	case "pushtotalk":
+		return bundleID + ".voip-ptt"
+	default:
+		return bundleID
+	}
+}
+
 // SendPush 发送APNs推送
-func (a *APNsProvider) SendPush(device *models.Device, pushLog *models.PushLog) *models.PushResult {
+func (a *APNsProvider) SendPush(ctx context.Context, device *models.Device, pushLog *models.PushLog) *models.PushResult {
+	// 解析自定义数据与 APNs 选项
+	var customData map[string]interface{}
+	if pushLog.Payload != "" {
+		if err := json.Unmarshal([]byte(pushLog.Payload), &customData); err != nil {
+			customData = nil
+		}
+	}
+	opts := parseAPNsOptions(customData)
+
 	// 构建 APNs 标准 aps 字段
+	alert := map[string]interface{}{
+		"title": pushLog.Title,
+		"body":  pushLog.Content,
+	}
+	if opts.Subtitle != "" {
+		alert["subtitle"] = opts.Subtitle
+	}
+	sound := "default"
+	if opts.Sound != "" {
+		sound = opts.Sound
+	}
 	apsMap := map[string]interface{}{
-		"alert": map[string]interface{}{
-			"title": pushLog.Title,
-			"body":  pushLog.Content,
-		},
-		"sound": "default",
+		"alert": alert,
+		"sound": sound,
 		"badge": pushLog.Badge,
 	}
+	// 富媒体附件由 Notification Service Extension 下载，需开启 mutable-content
+	rich := parseRichContent(pushLog.Payload)
+	if rich.AttachmentURL != "" || rich.ImageURL != "" {
+		apsMap["mutable-content"] = 1
+	}
+	if isDataMessage(pushLog) {
+		// 静默推送：仅唤醒应用后台处理，不展示通知
+		apsMap = map[string]interface{}{"content-available": 1}
+	}
+	if opts.ThreadID != "" {
+		apsMap["thread-id"] = opts.ThreadID
+	}
+	if opts.Category != "" {
+		apsMap["category"] = opts.Category
+	}
+	if opts.MutableContent {
+		apsMap["mutable-content"] = 1
+	}
+	if opts.InterruptionLevel != "" {
+		apsMap["interruption-level"] = opts.InterruptionLevel
+	}
 
 	// 顶层载荷对象（根级字典），自定义键与统计标识合并在顶层
 	payloadMap := map[string]interface{}{"aps": apsMap}
 
 	// 合并自定义数据到顶层（避免嵌入额外层级）
-	if pushLog.Payload != "" {
-		var customData map[string]interface{}
-		if err := json.Unmarshal([]byte(pushLog.Payload), &customData); err == nil {
-			for k, v := range customData {
-				if k == "aps" { // 避免覆盖标准字段
-					continue
-				}
-				payloadMap[k] = v
-			}
+	for k, v := range customData {
+		if k == "aps" || k == "apns" { // 避免覆盖标准字段，apns 选项已转为请求头与 aps 字段
+			continue
 		}
+		payloadMap[k] = v
+	}
+	if rich.AttachmentURL == "" && rich.ImageURL != "" {
+		payloadMap["attachment_url"] = rich.ImageURL // 未指定附件时使用通用大图
 	}
 
 	// 注入统计标识，供客户端上报使用
@@ -278,7 +363,7 @@ func (a *APNsProvider) SendPush(device *models.Device, pushLog *models.PushLog)
 	requestURL := getAPNsURL(a.environment) + device.Token
 
 	// 创建HTTP请求
-	req, err := http.NewRequest("POST", requestURL, bytes.NewBuffer(payloadJSON))
+	req, err := http.NewRequestWithContext(ctx, "POST", requestURL, bytes.NewBuffer(payloadJSON))
 	if err != nil {
 		return &models.PushResult{
 			AppID:        pushLog.AppID,
@@ -291,13 +376,47 @@ func (a *APNsProvider) SendPush(device *models.Device, pushLog *models.PushLog)
 	}
 
 	// 设置请求头
+	pushType := opts.PushType
+	if pushType == "" {
+		pushType = "alert"
+		if isDataMessage(pushLog) {
+			pushType = "background"
+		}
+	}
+	priority := opts.Priority
+	if priority == 0 {
+		priority = 10
+		if isNormalPriority(pushLog) {
+			priority = 5
+		}
+	}
+	if pushType == "background" {
+		priority = 5 // 后台推送必须使用低优先级，否则 APNs 会拒绝
+	}
+	var expiration int64
+	if opts.Expiration != nil {
+		expiration = *opts.Expiration
+	} else if pushLog.ExpiresAt != nil {
+		expiration = pushLog.ExpiresAt.Unix()
+	} else if pushLog.TTLSeconds > 0 {
+		expiration = time.Now().Add(time.Duration(pushLog.TTLSeconds) * time.Second).Unix()
+	}
+	collapseID := opts.CollapseID
+	if collapseID == "" {
+		collapseID = pushLog.CollapseKey
+	}
+
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("apns-priority", "10")
-	req.Header.Set("apns-expiration", "0")
+	req.Header.Set("apns-push-type", pushType)
+	req.Header.Set("apns-priority", strconv.Itoa(priority))
+	req.Header.Set("apns-expiration", strconv.FormatInt(expiration, 10))
+	if collapseID != "" {
+		req.Header.Set("apns-collapse-id", collapseID)
+	}
 
 	// 设置Bundle ID（如果配置了）
-	if a.bundleID != "" {
-		req.Header.Set("apns-topic", a.bundleID)
+	if topic := apnsTopic(a.bundleID, pushType); topic != "" {
+		req.Header.Set("apns-topic", topic)
 	}
 
 	// 根据认证类型设置认证头
@@ -353,7 +472,7 @@ func (a *APNsProvider) SendPush(device *models.Device, pushLog *models.PushLog)
 		result.ErrorCode = ""
 		result.ErrorMessage = ""
 
-		fmt.Printf("✅ APNs推送成功: %s -> %s\n", device.Token[:20]+"...", pushLog.Title)
+		logger.DebugContext(ctx, "APNs推送成功", "app_id", pushLog.AppID, "push_log_id", pushLog.ID, "channel", "apns", "token", device.Token)
 	} else {
 		// 推送失败，解析错误信息
 		result.Success = false
@@ -367,8 +486,8 @@ func (a *APNsProvider) SendPush(device *models.Device, pushLog *models.PushLog)
 			result.ErrorMessage = fmt.Sprintf("APNs返回状态码: %d", resp.StatusCode)
 		}
 
-		fmt.Printf("❌ APNs推送失败: %s -> %s (%s)\n",
-			device.Token[:20]+"...", pushLog.Title, result.ErrorMessage)
+		logger.WarnContext(ctx, "APNs推送失败", "app_id", pushLog.AppID, "push_log_id", pushLog.ID, "channel", "apns",
+			"token", device.Token, "error_code", result.ErrorCode, "error", result.ErrorMessage)
 	}
 
 	return result
@@ -402,8 +521,8 @@ type APNsErrorResponse struct {
 	Timestamp int64  `json:"timestamp,omitempty"`
 }
 
-// TestConnection 测试APNs连接（用于配置验证）
-func (a *APNsProvider) TestConnection() error {
+// TestConnection 测试APNs连接（用于配置验证和凭据检查）
+func (a *APNsProvider) TestConnection(ctx context.Context) error {
 	// 构建测试载荷（空载荷）
 	payload := map[string]interface{}{
 		"aps": map[string]interface{}{
@@ -416,7 +535,7 @@ func (a *APNsProvider) TestConnection() error {
 	// 使用无效的设备token进行连接测试
 	testURL := getAPNsURL(a.environment) + "00000000000000000000000000000000"
 
-	req, err := http.NewRequest("POST", testURL, bytes.NewBuffer(payloadJSON))
+	req, err := http.NewRequestWithContext(ctx, "POST", testURL, bytes.NewBuffer(payloadJSON))
 	if err != nil {
 		return fmt.Errorf("创建测试请求失败: %v", err)
 	}
@@ -448,6 +567,11 @@ func (a *APNsProvider) TestConnection() error {
 		return fmt.Errorf("APNs认证失败，请检查证书或密钥配置")
 	}
 	if resp.StatusCode == 403 {
+		// 403 的 reason 区分密钥无效（InvalidProviderToken）、令牌过期、证书与 Topic 不匹配等情况
+		var apnsError APNsErrorResponse
+		if body, _ := io.ReadAll(resp.Body); json.Unmarshal(body, &apnsError) == nil && apnsError.Reason != "" {
+			return fmt.Errorf("APNs授权失败（%s），请检查Bundle ID、密钥或证书权限", apnsError.Reason)
+		}
 		return fmt.Errorf("APNs授权失败，请检查Bundle ID或证书权限")
 	}
 
@@ -0,0 +1,248 @@
+package push
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/doopush/doopush/api/internal/models"
+)
+
+func TestCertificateNotAfter(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notAfter := time.Date(2027, 1, 2, 3, 4, 5, 0, time.UTC)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Apple Push Services: com.example.app"},
+		NotBefore:    notAfter.AddDate(-1, 0, 0),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	// 证书和私钥放在同一个 PEM 中时，跳过私钥块
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})) +
+		string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	got, err := CertificateNotAfter(certPEM)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got.Equal(notAfter) {
+		t.Fatalf("NotAfter = %v, want %v", got, notAfter)
+	}
+
+	if _, err := CertificateNotAfter("not a certificate"); err == nil {
+		t.Fatal("expected error for invalid PEM")
+	}
+}
+
+func TestAPNsTopic(t *testing.T) {
+	for _, tc := range []struct {
+		bundleID string
+		pushType string
+		want     string
+	}{
+		{"com.example.app", "alert", "com.example.app"},
+		{"com.example.app", "background", "com.example.app"},
+		{"com.example.app", "voip", "com.example.app.voip"},
+		{"com.example.app", "complication", "com.example.app.complication"},
+		{"com.example.app", "fileprovider", "com.example.app.pushkit.fileprovider"},
+		{"com.example.app", "liveactivity", "com.example.app.push-type.liveactivity"},
+		{"com.example.app", "location", "com.example.app.location-query"},
+		{"com.example.app", "pushtotalk", "com.example.app.voip-ptt"},
+		{"", "voip", ""},
+	} {
+		if got := apnsTopic(tc.bundleID, tc.pushType); got != tc.want {
+			t.Errorf("apnsTopic(%q, %q) = %q, want %q", tc.bundleID, tc.pushType, got, tc.want)
+		}
+	}
+}
+
+// apnsRequest 模拟 APNs 收到的请求
+type apnsRequest struct {
+	header  http.Header
+	payload map[string]interface{}
+}
+
+// startFakeAPNs 启动记录请求头与载荷的模拟 APNs 服务器，返回指向它的提供者
+func startFakeAPNs(t *testing.T) (*APNsProvider, *apnsRequest) {
+	t.Helper()
+	received := &apnsRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.header = r.Header.Clone()
+		received.payload = nil
+		json.NewDecoder(r.Body).Decode(&received.payload)
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("VENDOR_URL_BASE", server.URL)
+	InitEndpoints()
+	t.Cleanup(func() { endpoints.Store(nil) })
+	return &APNsProvider{client: server.Client(), environment: "production", authType: "p12", bundleID: "com.example.app"}, received
+}
+
+func TestAPNsHeaders(t *testing.T) {
+	provider, received := startFakeAPNs(t)
+	expiresAt := time.Unix(1893456000, 0)
+
+	for _, tc := range []struct {
+		name       string
+		pushLog    models.PushLog
+		pushType   string
+		priority   string
+		topic      string
+		expiration func(got int64) bool
+		collapseID string
+	}{
+		{
+			name:       "默认通知",
+			pushLog:    models.PushLog{Payload: "{}"},
+			pushType:   "alert",
+			priority:   "10",
+			topic:      "com.example.app",
+			expiration: func(got int64) bool { return got == 0 },
+		},
+		{
+			name:       "普通优先级",
+			pushLog:    models.PushLog{Payload: "{}", Priority: "normal"},
+			pushType:   "alert",
+			priority:   "5",
+			topic:      "com.example.app",
+			expiration: func(got int64) bool { return got == 0 },
+		},
+		{
+			name:       "静默推送强制低优先级",
+			pushLog:    models.PushLog{Payload: `{"apns":{"priority":10}}`, MessageType: "data"},
+			pushType:   "background",
+			priority:   "5",
+			topic:      "com.example.app",
+			expiration: func(got int64) bool { return got == 0 },
+		},
+		{
+			name:       "显式指定 background 强制低优先级",
+			pushLog:    models.PushLog{Payload: `{"apns":{"push_type":"background","priority":10}}`},
+			pushType:   "background",
+			priority:   "5",
+			topic:      "com.example.app",
+			expiration: func(got int64) bool { return got == 0 },
+		},
+		{
+			name:       "VoIP 推送主题后缀",
+			pushLog:    models.PushLog{Payload: `{"apns":{"push_type":"voip"}}`},
+			pushType:   "voip",
+			priority:   "10",
+			topic:      "com.example.app.voip",
+			expiration: func(got int64) bool { return got == 0 },
+		},
+		{
+			name:       "实时活动主题后缀",
+			pushLog:    models.PushLog{Payload: `{"apns":{"push_type":"liveactivity","priority":5}}`},
+			pushType:   "liveactivity",
+			priority:   "5",
+			topic:      "com.example.app.push-type.liveactivity",
+			expiration: func(got int64) bool { return got == 0 },
+		},
+		{
+			name:     "存活时长换算为过期时间",
+			pushLog:  models.PushLog{Payload: "{}", TTLSeconds: 3600},
+			pushType: "alert",
+			priority: "10",
+			topic:    "com.example.app",
+			expiration: func(got int64) bool {
+				want := time.Now().Add(time.Hour).Unix()
+				return got >= want-5 && got <= want
+			},
+		},
+		{
+			name:       "过期时间优先于存活时长",
+			pushLog:    models.PushLog{Payload: "{}", TTLSeconds: 3600, ExpiresAt: &expiresAt},
+			pushType:   "alert",
+			priority:   "10",
+			topic:      "com.example.app",
+			expiration: func(got int64) bool { return got == expiresAt.Unix() },
+		},
+		{
+			name:       "APNs 选项覆盖过期时间",
+			pushLog:    models.PushLog{Payload: `{"apns":{"expiration":1700000000}}`, TTLSeconds: 3600},
+			pushType:   "alert",
+			priority:   "10",
+			topic:      "com.example.app",
+			expiration: func(got int64) bool { return got == 1700000000 },
+		},
+		{
+			name:       "合并键作为 collapse-id",
+			pushLog:    models.PushLog{Payload: "{}", CollapseKey: "order-1"},
+			pushType:   "alert",
+			priority:   "10",
+			topic:      "com.example.app",
+			expiration: func(got int64) bool { return got == 0 },
+			collapseID: "order-1",
+		},
+		{
+			name:       "APNs 选项覆盖 collapse-id",
+			pushLog:    models.PushLog{Payload: `{"apns":{"collapse_id":"custom"}}`, CollapseKey: "order-1"},
+			pushType:   "alert",
+			priority:   "10",
+			topic:      "com.example.app",
+			expiration: func(got int64) bool { return got == 0 },
+			collapseID: "custom",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pushLog := tc.pushLog
+			pushLog.ID, pushLog.AppID, pushLog.Title, pushLog.Content = 1, 1, "标题", "内容"
+			if result := provider.SendPush(context.Background(), &models.Device{Token: "a1b2c3"}, &pushLog); !result.Success {
+				t.Fatalf("send failed: %s %s", result.ErrorCode, result.ErrorMessage)
+			}
+			header := received.header
+			if got := header.Get("apns-push-type"); got != tc.pushType {
+				t.Errorf("apns-push-type = %q, want %q", got, tc.pushType)
+			}
+			if got := header.Get("apns-priority"); got != tc.priority {
+				t.Errorf("apns-priority = %q, want %q", got, tc.priority)
+			}
+			if got := header.Get("apns-topic"); got != tc.topic {
+				t.Errorf("apns-topic = %q, want %q", got, tc.topic)
+			}
+			if got, _ := strconv.ParseInt(header.Get("apns-expiration"), 10, 64); !tc.expiration(got) {
+				t.Errorf("apns-expiration = %d", got)
+			}
+			if got := header.Get("apns-collapse-id"); got != tc.collapseID {
+				t.Errorf("apns-collapse-id = %q, want %q", got, tc.collapseID)
+			}
+		})
+	}
+}
+
+func TestAPNsDataMessagePayload(t *testing.T) {
+	provider, received := startFakeAPNs(t)
+	pushLog := &models.PushLog{ID: 7, AppID: 1, Title: "标题", Content: "内容", MessageType: "data", Payload: `{"order_id":"42","image_url":"https://example.com/a.png"}`}
+
+	if result := provider.SendPush(context.Background(), &models.Device{Token: "a1b2c3"}, pushLog); !result.Success {
+		t.Fatalf("send failed: %s %s", result.ErrorCode, result.ErrorMessage)
+	}
+	aps, _ := received.payload["aps"].(map[string]interface{})
+	if len(aps) != 1 || aps["content-available"] != float64(1) {
+		t.Fatalf("aps = %v, want only content-available", aps)
+	}
+	if received.payload["order_id"] != "42" || received.payload["push_log_id"] != float64(7) {
+		t.Fatalf("payload = %v", received.payload)
+	}
+}
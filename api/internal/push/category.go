@@ -0,0 +1,123 @@
+package push
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// 统一消息分类
+const (
+	CategoryTransactional = "transactional" // 交易/服务通知，如订单、物流
+	CategoryMarketing     = "marketing"     // 营销/运营消息
+	CategoryIM            = "im"            // 即时通讯
+	CategoryAccount       = "account"       // 账号与安全
+)
+
+// IsValidCategory 是否为支持的统一消息分类
+func IsValidCategory(category string) bool {
+	switch category {
+	case CategoryTransactional, CategoryMarketing, CategoryIM, CategoryAccount:
+		return true
+	}
+	return false
+}
+
+// CategoryMapping 分类映射表：统一分类 -> 厂商原生字段（与 payload 中对应厂商对象的字段一致）
+type CategoryMapping map[string]map[string]interface{}
+
+// ParseCategoryMapping 解析 AppConfig 中保存的分类映射表
+func ParseCategoryMapping(raw string) (CategoryMapping, error) {
+	mapping := CategoryMapping{}
+	if raw == "" || raw == "{}" || raw == "null" {
+		return mapping, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+		return nil, fmt.Errorf("分类映射格式错误: %v", err)
+	}
+	for category := range mapping {
+		if !IsValidCategory(category) {
+			return nil, fmt.Errorf("不支持的消息分类: %s", category)
+		}
+	}
+	return mapping, nil
+}
+
+// defaultCategoryMappings 内置默认映射，仅覆盖厂商公开且与应用无关的取值；
+// OPPO、小米的私信通道ID需在厂商后台申请，只能由应用自行配置
+var defaultCategoryMappings = map[string]CategoryMapping{
+	"huawei": {
+		CategoryMarketing: {"importance": "LOW", "category": "MARKETING"},
+		CategoryIM:        {"importance": "NORMAL", "category": "IM"},
+		CategoryAccount:   {"importance": "NORMAL", "category": "ACCOUNT"},
+	},
+	"honor": {
+		CategoryMarketing:     {"importance": "LOW"},
+		CategoryTransactional: {"importance": "NORMAL"},
+		CategoryIM:            {"importance": "NORMAL"},
+		CategoryAccount:       {"importance": "NORMAL"},
+	},
+	"oppo": {
+		CategoryMarketing:     {"category": "MARKETING", "notify_level": 1},
+		CategoryTransactional: {"category": "ORDER"},
+		CategoryIM:            {"category": "IM"},
+		CategoryAccount:       {"category": "ACCOUNT"},
+	},
+	"vivo": {
+		CategoryMarketing:     {"classification": 0},
+		CategoryTransactional: {"classification": 1},
+		CategoryIM:            {"classification": 1},
+		CategoryAccount:       {"classification": 1},
+	},
+	"apns": {
+		CategoryMarketing: {"interruption_level": "passive"},
+	},
+}
+
+// categoryRequiredFields 非营销类消息在各厂商必须携带的分类字段，缺失时厂商会按营销消息处理或直接拦截
+var categoryRequiredFields = map[string]string{
+	"huawei": "category",
+	"vivo":   "classification",
+	"oppo":   "channel_id",
+	"xiaomi": "channel_id",
+}
+
+// ResolveCategoryFields 将统一分类翻译为指定通道的厂商字段（应用配置优先于内置默认值）
+func ResolveCategoryFields(channel, category string, appMapping CategoryMapping) (map[string]interface{}, error) {
+	if !IsValidCategory(category) {
+		return nil, fmt.Errorf("不支持的消息分类: %s", category)
+	}
+
+	fields := make(map[string]interface{})
+	if defaults, ok := defaultCategoryMappings[channel]; ok {
+		for k, v := range defaults[category] {
+			fields[k] = v
+		}
+	}
+	for k, v := range appMapping[category] {
+		fields[k] = v
+	}
+
+	if category != CategoryMarketing {
+		if required, ok := categoryRequiredFields[channel]; ok {
+			if _, exists := fields[required]; !exists {
+				return nil, fmt.Errorf("%s 通道未配置 %s 分类的 %s 映射", channel, category, required)
+			}
+		}
+	}
+	return fields, nil
+}
+
+// MergeCategoryFields 将分类字段合并进厂商参数对象，显式参数与分类映射冲突时返回错误
+func MergeCategoryFields(channel string, vendorParams map[string]interface{}, fields map[string]interface{}) (map[string]interface{}, error) {
+	merged := make(map[string]interface{}, len(vendorParams)+len(fields))
+	for k, v := range vendorParams {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		if existing, ok := merged[k]; ok && fmt.Sprint(existing) != fmt.Sprint(v) {
+			return nil, fmt.Errorf("%s 参数 %s=%v 与消息分类映射值 %v 冲突", channel, k, existing, v)
+		}
+		merged[k] = v
+	}
+	return merged, nil
+}
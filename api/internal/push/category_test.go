@@ -0,0 +1,47 @@
+package push
+
+import "testing"
+
+func TestResolveCategoryFields_Defaults(t *testing.T) {
+	fields, err := ResolveCategoryFields("huawei", CategoryIM, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fields["category"] != "IM" || fields["importance"] != "NORMAL" {
+		t.Fatalf("got %+v", fields)
+	}
+
+	// 营销消息不要求私信通道
+	if _, err := ResolveCategoryFields("xiaomi", CategoryMarketing, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestResolveCategoryFields_MissingMapping(t *testing.T) {
+	if _, err := ResolveCategoryFields("oppo", CategoryTransactional, nil); err == nil {
+		t.Fatal("expected error for oppo without channel_id, got nil")
+	}
+
+	mapping := CategoryMapping{CategoryTransactional: {"channel_id": "order"}}
+	fields, err := ResolveCategoryFields("oppo", CategoryTransactional, mapping)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fields["channel_id"] != "order" || fields["category"] != "ORDER" {
+		t.Fatalf("got %+v", fields)
+	}
+}
+
+func TestMergeCategoryFields_Conflict(t *testing.T) {
+	fields := map[string]interface{}{"category": "MARKETING"}
+	if _, err := MergeCategoryFields("huawei", map[string]interface{}{"category": "IM"}, fields); err == nil {
+		t.Fatal("expected conflict error, got nil")
+	}
+	merged, err := MergeCategoryFields("huawei", map[string]interface{}{"category": "MARKETING", "ttl": "3600s"}, fields)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if merged["ttl"] != "3600s" {
+		t.Fatalf("got %+v", merged)
+	}
+}
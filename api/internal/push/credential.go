@@ -0,0 +1,117 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 凭据检查结果状态
+const (
+	CredentialHealthy     = "healthy"     // 已从厂商获取鉴权令牌或通过 APNs 鉴权
+	CredentialUnhealthy   = "unhealthy"   // 配置无效、鉴权失败或证书已过期
+	CredentialUnsupported = "unsupported" // 厂商没有独立的鉴权接口，仅校验配置格式
+)
+
+// CredentialCheck 凭据检查结果
+type CredentialCheck struct {
+	Status    string
+	Message   string
+	ExpiresAt *time.Time // 凭据过期时间，目前只有 APNs 证书可以获取
+}
+
+// CheckCredentials 向厂商鉴权接口验证推送配置中的凭据，不需要设备 Token：
+// Android 各厂商获取一次 access token，APNs 使用无效设备 Token 发起请求，只要鉴权通过即视为正常
+func (m *PushManager) CheckCredentials(ctx context.Context, platform, channel, configJSON string) *CredentialCheck {
+	switch platform {
+	case "ios":
+		return m.checkAPNsCredentials(ctx, configJSON)
+	case "android":
+		return m.checkAndroidCredentials(ctx, channel, configJSON)
+	default:
+		return credentialFailure(fmt.Errorf("不支持的平台: %s", platform))
+	}
+}
+
+func (m *PushManager) checkAPNsCredentials(ctx context.Context, configJSON string) *CredentialCheck {
+	var config APNsConfig
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return credentialFailure(fmt.Errorf("配置格式错误: %v", err))
+	}
+	normalizeConfig(&config)
+
+	provider, err := NewAPNsProviderWithConfig(config)
+	if err != nil {
+		return credentialFailure(err)
+	}
+	return checkAPNsProvider(ctx, provider, config.CertPEM)
+}
+
+// checkAPNsProvider 检查证书有效期后发起连接测试，P8 密钥不会过期
+func checkAPNsProvider(ctx context.Context, provider *APNsProvider, certPEM string) *CredentialCheck {
+	check := &CredentialCheck{}
+	if provider.authType == "p12" && certPEM != "" {
+		notAfter, err := CertificateNotAfter(certPEM)
+		if err != nil {
+			return credentialFailure(err)
+		}
+		check.ExpiresAt = &notAfter
+		if time.Now().After(notAfter) {
+			check.Status = CredentialUnhealthy
+			check.Message = "APNs证书已于 " + notAfter.Format("2006-01-02") + " 过期"
+			return check
+		}
+	}
+
+	if err := provider.TestConnection(ctx); err != nil {
+		check.Status = CredentialUnhealthy
+		check.Message = err.Error()
+		return check
+	}
+	check.Status = CredentialHealthy
+	check.Message = "APNs鉴权通过"
+	return check
+}
+
+func (m *PushManager) checkAndroidCredentials(ctx context.Context, channel, configJSON string) *CredentialCheck {
+	if err := m.validateAndroidConfig(channel, configJSON); err != nil {
+		return credentialFailure(err)
+	}
+	var config AndroidConfig
+	json.Unmarshal([]byte(configJSON), &config)
+	return checkAndroidProvider(ctx, NewAndroidProviderWithConfig(channel, config))
+}
+
+// checkAndroidProvider 使用新建的提供者获取 access token，不会命中缓存的令牌
+func checkAndroidProvider(ctx context.Context, provider *AndroidProvider) *CredentialCheck {
+	var err error
+	switch provider.channel {
+	case "fcm":
+		_, err = provider.generateFCMAccessToken(ctx)
+	case "huawei":
+		_, err = provider.getHuaweiAccessToken(ctx)
+	case "honor":
+		_, err = provider.getHonorAccessToken(ctx)
+	case "oppo":
+		_, err = provider.getOppoAuthToken(ctx)
+	case "vivo":
+		_, err = provider.getVivoAuthToken(ctx)
+	case "xiaomi", "meizu":
+		// 小米、魅族直接使用 AppSecret 签名推送请求，鉴权失败会在推送时以 AUTH_ERROR 返回
+		return &CredentialCheck{
+			Status:  CredentialUnsupported,
+			Message: "该厂商没有独立的鉴权接口，仅校验了配置格式",
+		}
+	default:
+		return credentialFailure(fmt.Errorf("暂不支持的 Android 推送通道: %s", provider.channel))
+	}
+	if err != nil {
+		return credentialFailure(err)
+	}
+	return &CredentialCheck{Status: CredentialHealthy, Message: "已获取鉴权令牌"}
+}
+
+func credentialFailure(err error) *CredentialCheck {
+	return &CredentialCheck{Status: CredentialUnhealthy, Message: err.Error()}
+}
@@ -0,0 +1,197 @@
+package push
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// redirectTransport 将厂商请求转发到本地模拟服务器，保留原请求路径
+type redirectTransport struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	req.Host = t.target.Host
+	return t.next.RoundTrip(req)
+}
+
+// fakeVendor 启动模拟厂商服务器，并把提供者的请求转发过去
+func fakeVendor(t *testing.T, provider *AndroidProvider, handler http.HandlerFunc) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	target, _ := url.Parse(server.URL)
+	provider.httpClient.Transport = &redirectTransport{target: target, next: http.DefaultTransport}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func TestCheckAndroidCredentials(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(rsaKey)
+	serviceAccount, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"project_id":   "demo-project",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})),
+		"client_email": "push@demo-project.iam.gserviceaccount.com",
+	})
+
+	cases := []struct {
+		name    string
+		channel string
+		config  AndroidConfig
+		path    string
+		ok      interface{}
+		failure interface{}
+		status  int // 鉴权失败时的 HTTP 状态码
+	}{
+		{
+			name: "fcm", channel: "fcm",
+			config:  AndroidConfig{ServiceAccountKey: string(serviceAccount)},
+			path:    "/token",
+			ok:      map[string]interface{}{"access_token": "ya29.token", "expires_in": 3599},
+			failure: map[string]string{"error": "invalid_grant", "error_description": "Invalid JWT Signature."},
+			status:  http.StatusBadRequest,
+		},
+		{
+			name: "huawei", channel: "huawei",
+			config:  AndroidConfig{AppID: "10086", AppSecret: "secret"},
+			path:    "/oauth2/v2/token",
+			ok:      map[string]interface{}{"access_token": "CgB6e3x9", "expires_in": 3600},
+			failure: map[string]interface{}{"error": 1101, "error_description": "invalid client"},
+			status:  http.StatusBadRequest,
+		},
+		{
+			name: "honor", channel: "honor",
+			config:  AndroidConfig{AppID: "104400", ClientID: "cid", ClientSecret: "secret"},
+			path:    "/auth/token",
+			ok:      map[string]interface{}{"access_token": "honor-token", "expires_in": 3600},
+			failure: map[string]interface{}{"error": "invalid_client"},
+			status:  http.StatusUnauthorized,
+		},
+		{
+			name: "oppo", channel: "oppo",
+			config:  AndroidConfig{AppID: "30001", AppKey: "key", AppSecret: "master"},
+			path:    "/server/v1/auth",
+			ok:      map[string]interface{}{"code": 0, "data": map[string]interface{}{"auth_token": "oppo-token"}},
+			failure: map[string]interface{}{"code": 11, "message": "Invalid AppKey"},
+			status:  http.StatusOK,
+		},
+		{
+			name: "vivo", channel: "vivo",
+			config:  AndroidConfig{AppID: "10004", AppKey: "key", AppSecret: "secret"},
+			path:    "/message/auth",
+			ok:      map[string]interface{}{"result": 0, "authToken": "vivo-token"},
+			failure: map[string]interface{}{"result": 10050, "desc": "appId或appKey错误"},
+			status:  http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, healthy := range []bool{true, false} {
+				provider := NewAndroidProviderWithConfig(tc.channel, tc.config)
+				fakeVendor(t, provider, func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != tc.path {
+						writeJSON(w, http.StatusNotFound, map[string]string{"path": r.URL.Path})
+						return
+					}
+					if healthy {
+						writeJSON(w, http.StatusOK, tc.ok)
+					} else {
+						writeJSON(w, tc.status, tc.failure)
+					}
+				})
+
+				check := checkAndroidProvider(context.Background(), provider)
+				want := CredentialHealthy
+				if !healthy {
+					want = CredentialUnhealthy
+				}
+				if check.Status != want {
+					t.Fatalf("healthy=%v: status = %s (%s), want %s", healthy, check.Status, check.Message, want)
+				}
+			}
+		})
+	}
+
+	check := checkAndroidProvider(context.Background(), NewAndroidProviderWithConfig("xiaomi", AndroidConfig{}))
+	if check.Status != CredentialUnsupported {
+		t.Fatalf("xiaomi status = %s, want unsupported", check.Status)
+	}
+}
+
+func TestCheckAPNsCredentials(t *testing.T) {
+	// 模拟 APNs：鉴权通过时对无效设备 Token 返回 400 BadDeviceToken
+	var reason string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if reason == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"reason": "BadDeviceToken"})
+			return
+		}
+		writeJSON(w, http.StatusForbidden, map[string]string{"reason": reason})
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	keyDER, _ := x509.MarshalPKCS8PrivateKey(key)
+	keyP8 := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+	provider, err := NewAPNsProviderWithP8(keyP8, "ABC123", "TEAM123", "com.example.app", "development")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.client = &http.Client{Transport: &redirectTransport{target: target, next: server.Client().Transport}}
+
+	if check := checkAPNsProvider(context.Background(), provider, ""); check.Status != CredentialHealthy || check.ExpiresAt != nil {
+		t.Fatalf("p8 status = %s (%s)", check.Status, check.Message)
+	}
+
+	reason = "InvalidProviderToken"
+	if check := checkAPNsProvider(context.Background(), provider, ""); check.Status != CredentialUnhealthy {
+		t.Fatalf("invalid key status = %s", check.Status)
+	}
+
+	// 已过期的证书不发起请求，直接返回过期时间
+	notAfter := time.Now().Add(-24 * time.Hour).UTC().Truncate(time.Second)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Apple Push Services: com.example.app"},
+		NotBefore:    notAfter.AddDate(-1, 0, 0),
+		NotAfter:     notAfter,
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	certProvider, _ := NewAPNsProviderWithCert(tls.Certificate{}, "development", "com.example.app")
+	check := checkAPNsProvider(context.Background(), certProvider, certPEM)
+	if check.Status != CredentialUnhealthy || check.ExpiresAt == nil || !check.ExpiresAt.Equal(notAfter) {
+		t.Fatalf("expired cert check = %+v", check)
+	}
+}
@@ -0,0 +1,122 @@
+package push
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/net/http2"
+
+	"github.com/doopush/doopush/api/internal/config"
+)
+
+// 厂商接口地址名称，每个名称对应一个厂商域名。
+// 配置 VENDOR_URL_<名称大写> 可以单独覆盖，如 VENDOR_URL_HUAWEI_OAUTH=https://huawei-sandbox.example.com；
+// 配置 VENDOR_URL_BASE 时所有地址变为 <VENDOR_URL_BASE>/<名称>，用于指向 doopush fake-vendors 模拟服务器
+const (
+	EndpointAPNsProduction  = "apns_production"
+	EndpointAPNsDevelopment = "apns_development"
+	EndpointFCMOAuth        = "fcm_oauth"
+	EndpointFCM             = "fcm"
+	EndpointHuaweiOAuth     = "huawei_oauth"
+	EndpointHuawei          = "huawei"
+	EndpointHonorOAuth      = "honor_oauth"
+	EndpointHonor           = "honor"
+	EndpointXiaomi          = "xiaomi"
+	EndpointOppo            = "oppo"
+	EndpointVivo            = "vivo"
+	EndpointMeizu           = "meizu"
+)
+
+// defaultEndpoints 各厂商正式环境地址
+var defaultEndpoints = map[string]string{
+	EndpointAPNsProduction:  "https://api.push.apple.com",
+	EndpointAPNsDevelopment: "https://api.development.push.apple.com",
+	EndpointFCMOAuth:        "https://oauth2.googleapis.com",
+	EndpointFCM:             "https://fcm.googleapis.com",
+	EndpointHuaweiOAuth:     "https://oauth-login.cloud.huawei.com",
+	EndpointHuawei:          "https://push-api.cloud.huawei.com",
+	EndpointHonorOAuth:      "https://iam.developer.honor.com",
+	EndpointHonor:           "https://push-api.cloud.honor.com",
+	EndpointXiaomi:          "https://api.xmpush.xiaomi.com",
+	EndpointOppo:            "https://api.push.oppomobile.com",
+	EndpointVivo:            "https://api-push.vivo.com.cn",
+	EndpointMeizu:           "https://server-api-push.meizu.com",
+}
+
+var endpoints atomic.Pointer[map[string]string]
+
+// InitEndpoints 按配置加载厂商接口地址，返回被覆盖的地址（名称 -> 地址），未调用时首次使用自动加载
+func InitEndpoints() map[string]string {
+	base := strings.TrimRight(config.GetString("VENDOR_URL_BASE"), "/")
+	loaded := make(map[string]string, len(defaultEndpoints))
+	overridden := make(map[string]string)
+	for name, defaultURL := range defaultEndpoints {
+		value := defaultURL
+		if base != "" {
+			value = base + "/" + name
+		}
+		if custom := config.GetString("VENDOR_URL_" + strings.ToUpper(name)); custom != "" {
+			value = custom
+		}
+		value = strings.TrimRight(value, "/")
+		loaded[name] = value
+		if value != defaultURL {
+			overridden[name] = value
+		}
+	}
+	endpoints.Store(&loaded)
+	return overridden
+}
+
+// EndpointNames 所有厂商接口地址名称
+func EndpointNames() []string {
+	names := make([]string, 0, len(defaultEndpoints))
+	for name := range defaultEndpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// vendorURL 返回厂商接口完整地址，path 以 / 开头
+func vendorURL(name, path string) string {
+	loaded := endpoints.Load()
+	if loaded == nil {
+		InitEndpoints()
+		loaded = endpoints.Load()
+	}
+	return (*loaded)[name] + path
+}
+
+// apnsEndpoint APNs 环境对应的地址名称
+func apnsEndpoint(environment string) string {
+	if environment == "production" {
+		return EndpointAPNsProduction
+	}
+	return EndpointAPNsDevelopment
+}
+
+// newAPNsTransport 创建 APNs 的 HTTP/2 Transport。
+// 地址为 http:// 时使用明文 HTTP/2（h2c），仅用于本地模拟服务器，此时证书认证不会生效
+func newAPNsTransport(environment string, certificates []tls.Certificate) http.RoundTripper {
+	transport := &http2.Transport{
+		TLSClientConfig: &tls.Config{Certificates: certificates},
+	}
+	if base, err := url.Parse(vendorURL(apnsEndpoint(environment), "")); err == nil {
+		transport.TLSClientConfig.ServerName = base.Hostname()
+		if base.Scheme == "http" {
+			transport.AllowHTTP = true
+			transport.DialTLSContext = func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, addr)
+			}
+		}
+	}
+	return vendorTransport("apns", transport)
+}
@@ -0,0 +1,203 @@
+package push
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/internal/push/fakevendor"
+)
+
+// startFakeVendors 启动模拟厂商服务器，并通过 VENDOR_URL_BASE 让所有厂商接口指向它
+func startFakeVendors(t *testing.T) *fakevendor.Server {
+	t.Helper()
+	fake := fakevendor.New(time.Hour)
+	server := httptest.NewServer(fake.Handler())
+	t.Cleanup(server.Close)
+	t.Setenv("VENDOR_URL_BASE", server.URL)
+	if overridden := InitEndpoints(); len(overridden) != len(EndpointNames()) {
+		t.Fatalf("overridden endpoints = %d, want %d", len(overridden), len(EndpointNames()))
+	}
+	t.Cleanup(func() { endpoints.Store(nil) })
+	return fake
+}
+
+func testServiceAccount(t *testing.T, email string) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	serviceAccount, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"project_id":   "demo-project",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email": email,
+	})
+	return string(serviceAccount)
+}
+
+func TestVendorURLOverrides(t *testing.T) {
+	t.Cleanup(func() { endpoints.Store(nil) })
+
+	t.Setenv("VENDOR_URL_BASE", "")
+	if overridden := InitEndpoints(); len(overridden) != 0 {
+		t.Fatalf("overridden = %v, want none", overridden)
+	}
+	if got := vendorURL(EndpointHuawei, "/v1/1/messages:send"); got != "https://push-api.cloud.huawei.com/v1/1/messages:send" {
+		t.Fatalf("default huawei url = %s", got)
+	}
+
+	t.Setenv("VENDOR_URL_BASE", "http://127.0.0.1:9900/")
+	t.Setenv("VENDOR_URL_OPPO", "https://oppo-sandbox.example.com/")
+	InitEndpoints()
+	if got := getAPNsURL("production"); got != "http://127.0.0.1:9900/apns_production/3/device/" {
+		t.Fatalf("apns url = %s", got)
+	}
+	if got := vendorURL(EndpointOppo, oppoAuthURL); got != "https://oppo-sandbox.example.com/server/v1/auth" {
+		t.Fatalf("oppo url = %s", got)
+	}
+}
+
+func TestAndroidProvidersAgainstFakeVendors(t *testing.T) {
+	fake := startFakeVendors(t)
+
+	cases := []struct {
+		channel      string
+		config       AndroidConfig
+		invalidToken string // 设备 Token 无效时的错误码
+		rateLimit    string // 触发频控时的错误码
+		serverError  string // 厂商服务不可用时的错误码
+	}{
+		{"fcm", AndroidConfig{ServiceAccountKey: testServiceAccount(t, "push@demo-project.iam.gserviceaccount.com")}, "INVALID_TOKEN", "QUOTA_EXCEEDED", "SERVER_ERROR"},
+		{"huawei", AndroidConfig{AppID: "10086", AppSecret: "secret"}, "INVALID_TOKEN", "QUOTA_EXCEEDED", "SERVER_ERROR"},
+		{"honor", AndroidConfig{AppID: "104400", ClientID: "cid", ClientSecret: "secret"}, "INVALID_TOKEN", "QUOTA_EXCEEDED", "SERVER_ERROR"},
+		{"xiaomi", AndroidConfig{AppID: "2882303761", AppSecret: "secret"}, "INVALID_TOKEN", "QUOTA_EXCEEDED", "SERVER_ERROR"},
+		{"oppo", AndroidConfig{AppID: "30001", AppKey: "key", AppSecret: "master"}, "INVALID_TOKEN", "QUOTA_EXCEEDED", "SERVER_ERROR"},
+		{"vivo", AndroidConfig{AppID: "10004", AppKey: "key", AppSecret: "secret"}, "INVALID_TOKEN", "RATE_LIMIT_EXCEEDED", "SERVER_ERROR"},
+		{"meizu", AndroidConfig{AppID: "110001", AppSecret: "secret"}, "INVALID_TOKEN", "RATE_LIMIT_EXCEEDED", "SERVER_ERROR"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.channel, func(t *testing.T) {
+			provider := NewAndroidProviderWithConfig(tc.channel, tc.config)
+			pushLog := &models.PushLog{ID: 1, AppID: 1, Title: "订单已发货", Content: "您的订单已发货", Payload: "{}", Channel: tc.channel}
+
+			result := provider.SendPush(context.Background(), &models.Device{Token: "device-1", Channel: tc.channel}, pushLog)
+			if !result.Success {
+				t.Fatalf("send failed: %s %s", result.ErrorCode, result.ErrorMessage)
+			}
+			vendor := tc.channel
+			messages := fake.Messages(vendor)
+			if len(messages) != 1 || messages[0].Token != "device-1" || messages[0].Title != pushLog.Title || messages[0].Body != pushLog.Content {
+				t.Fatalf("messages = %+v", messages)
+			}
+
+			for token, want := range map[string]string{
+				"invalid_token-1": tc.invalidToken,
+				"rate_limit-1":    tc.rateLimit,
+				"server_error-1":  tc.serverError,
+			} {
+				result := provider.SendPush(context.Background(), &models.Device{Token: token, Channel: tc.channel}, pushLog)
+				if result.Success || result.ErrorCode != want {
+					t.Errorf("token %s: success=%v code=%s (%s), want %s", token, result.Success, result.ErrorCode, result.ErrorMessage, want)
+				}
+			}
+			if got := len(fake.Messages(vendor)); got != 1 {
+				t.Fatalf("failed sends were recorded: %d messages", got)
+			}
+		})
+	}
+}
+
+func TestCachedVendorTokenExpiry(t *testing.T) {
+	fake := startFakeVendors(t)
+	pushLog := &models.PushLog{ID: 1, AppID: 1, Title: "标题", Content: "内容", Payload: "{}"}
+	device := &models.Device{Token: "device-1"}
+
+	for _, channel := range []string{"oppo", "vivo", "honor"} {
+		t.Run(channel, func(t *testing.T) {
+			provider := NewAndroidProviderWithConfig(channel, AndroidConfig{
+				AppID: "10001", AppKey: "key", AppSecret: "secret", ClientID: "cid", ClientSecret: "secret",
+			})
+			if result := provider.SendPush(context.Background(), device, pushLog); !result.Success {
+				t.Fatalf("first send failed: %s", result.ErrorMessage)
+			}
+
+			// 缓存的令牌被厂商作废后，重新获取令牌并重试
+			fake.ExpireTokens()
+			if result := provider.SendPush(context.Background(), device, pushLog); !result.Success {
+				t.Fatalf("send after token expiry failed: %s %s", result.ErrorCode, result.ErrorMessage)
+			}
+
+			// 重试一次后仍然鉴权失败则返回错误
+			fake.Fail(channel, fakevendor.ScenarioTokenExpired, 2)
+			if result := provider.SendPush(context.Background(), device, pushLog); result.Success || result.ErrorCode != "AUTHENTICATION_ERROR" {
+				t.Fatalf("repeated auth failure: success=%v code=%s", result.Success, result.ErrorCode)
+			}
+			if got := len(fake.Messages(channel)); got != 2 {
+				t.Fatalf("messages = %d, want 2", got)
+			}
+		})
+	}
+}
+
+func TestAPNsAgainstFakeVendors(t *testing.T) {
+	fake := startFakeVendors(t)
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	keyP8 := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	pushLog := &models.PushLog{ID: 1, AppID: 1, Title: "标题", Content: "内容", Payload: "{}"}
+
+	provider, err := NewAPNsProviderWithP8(keyP8, "ABC123", "TEAM123", "com.example.app", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := provider.SendPush(context.Background(), &models.Device{Token: "a1b2c3"}, pushLog); !result.Success {
+		t.Fatalf("send failed: %s %s", result.ErrorCode, result.ErrorMessage)
+	}
+	if messages := fake.Messages("apns"); len(messages) != 1 || messages[0].Title != "标题" || messages[0].Body != "内容" {
+		t.Fatalf("messages = %+v", messages)
+	}
+	if result := provider.SendPush(context.Background(), &models.Device{Token: "invalid_token-1"}, pushLog); result.ErrorCode != "Unregistered" {
+		t.Fatalf("invalid token code = %s", result.ErrorCode)
+	}
+	if check := checkAPNsProvider(context.Background(), provider, ""); check.Status != CredentialHealthy {
+		t.Fatalf("health check = %s (%s)", check.Status, check.Message)
+	}
+
+	invalid, _ := NewAPNsProviderWithP8(keyP8, "invalid-key", "TEAM123", "com.example.app", "production")
+	if check := checkAPNsProvider(context.Background(), invalid, ""); check.Status != CredentialUnhealthy {
+		t.Fatalf("invalid key health check = %s", check.Status)
+	}
+}
+
+func TestCredentialChecksAgainstFakeVendors(t *testing.T) {
+	startFakeVendors(t)
+
+	for _, tc := range []struct {
+		channel string
+		config  AndroidConfig
+		want    string
+	}{
+		{"huawei", AndroidConfig{AppID: "10086", AppSecret: "secret"}, CredentialHealthy},
+		{"huawei", AndroidConfig{AppID: "10086", AppSecret: "invalid-secret"}, CredentialUnhealthy},
+		{"oppo", AndroidConfig{AppID: "30001", AppKey: "invalid-key", AppSecret: "master"}, CredentialUnhealthy},
+		{"fcm", AndroidConfig{ServiceAccountKey: testServiceAccount(t, "invalid@demo-project.iam.gserviceaccount.com")}, CredentialUnhealthy},
+	} {
+		if check := checkAndroidProvider(context.Background(), NewAndroidProviderWithConfig(tc.channel, tc.config)); check.Status != tc.want {
+			t.Errorf("%s %+v: status = %s (%s), want %s", tc.channel, tc.config, check.Status, check.Message, tc.want)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Package fakevendor 模拟各推送厂商的鉴权与推送接口，配合 VENDOR_URL_BASE 使用，
+// 让 CI 和本地开发无需真实凭据即可走完推送提供者的完整请求流程。
+//
+// 请求路径的第一段是厂商接口地址名称（与 push 包的 Endpoint 常量一致），如 /huawei_oauth/oauth2/v2/token。
+// 响应的错误码与推送提供者的错误映射保持一致。可以通过以下方式制造失败：
+//   - 凭据（AppKey、AppID、ClientID、服务账号邮箱、APNs KeyID、小米 AppSecret）以 invalid 开头时鉴权失败；
+//   - 设备 Token 以场景名开头时（如 rate_limit-xxx）推送返回该厂商对应的错误；
+//   - Fail 或 POST /_fake/fail 为厂商排队若干次失败，优先于设备 Token 生效；
+//   - ExpireTokens 或 POST /_fake/expire-tokens 使已签发的鉴权令牌全部失效。
+package fakevendor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// 推送失败场景
+const (
+	ScenarioInvalidToken = "invalid_token" // 设备 Token 无效或已注销
+	ScenarioRateLimit    = "rate_limit"    // 触发厂商频控
+	ScenarioServerError  = "server_error"  // 厂商服务暂时不可用
+	ScenarioTokenExpired = "token_expired" // 鉴权令牌已过期，同时作废本次请求使用的令牌
+)
+
+// Scenarios 所有失败场景
+var Scenarios = []string{ScenarioInvalidToken, ScenarioRateLimit, ScenarioServerError, ScenarioTokenExpired}
+
+// Vendors 模拟的厂商
+var Vendors = []string{"apns", "fcm", "huawei", "honor", "xiaomi", "oppo", "vivo", "meizu"}
+
+// Message 模拟服务器收到并"送达"的推送
+type Message struct {
+	Vendor     string    `json:"vendor"`
+	Token      string    `json:"token"`
+	Title      string    `json:"title"`
+	Body       string    `json:"body"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// Server 模拟厂商服务器，可并发使用
+type Server struct {
+	tokenTTL time.Duration
+
+	mu       sync.Mutex
+	seq      int64
+	tokens   map[string]time.Time // 已签发的鉴权令牌 -> 过期时间
+	failures map[string][]string  // 厂商 -> 排队的失败场景
+	messages []Message
+}
+
+// New 创建模拟厂商服务器，tokenTTL 为签发的鉴权令牌有效期，不大于 0 时为 1 小时
+func New(tokenTTL time.Duration) *Server {
+	if tokenTTL <= 0 {
+		tokenTTL = time.Hour
+	}
+	return &Server{
+		tokenTTL: tokenTTL,
+		tokens:   make(map[string]time.Time),
+		failures: make(map[string][]string),
+	}
+}
+
+// Handler 返回模拟服务器的 HTTP 处理器，同时支持明文 HTTP/2（APNs 客户端只使用 HTTP/2）
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /_fake/messages", s.handleListMessages)
+	mux.HandleFunc("DELETE /_fake/messages", s.handleClearMessages)
+	mux.HandleFunc("POST /_fake/expire-tokens", s.handleExpireTokens)
+	mux.HandleFunc("POST /_fake/fail", s.handleFail)
+	mux.HandleFunc("POST /_fake/reset", s.handleReset)
+	mux.HandleFunc("/", s.handleVendor)
+	return h2c.NewHandler(mux, &http2.Server{})
+}
+
+// Messages 返回收到的推送，vendor 为空时返回全部
+func (s *Server) Messages(vendor string) []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	messages := make([]Message, 0, len(s.messages))
+	for _, message := range s.messages {
+		if vendor == "" || message.Vendor == vendor {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// ExpireTokens 使已签发的鉴权令牌全部失效，模拟令牌在客户端缓存期内过期
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = make(map[string]time.Time)
+}
+
+// Fail 让厂商接下来的 count 次推送请求以指定场景失败
+func (s *Server) Fail(vendor, scenario string, count int) error {
+	if !slices.Contains(Vendors, vendor) {
+		return fmt.Errorf("不支持的厂商: %s", vendor)
+	}
+	if !slices.Contains(Scenarios, scenario) {
+		return fmt.Errorf("不支持的失败场景: %s", scenario)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < count; i++ {
+		s.failures[vendor] = append(s.failures[vendor], scenario)
+	}
+	return nil
+}
+
+// Reset 清除收到的推送、已签发的令牌和排队的失败
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = make(map[string]time.Time)
+	s.failures = make(map[string][]string)
+	s.messages = nil
+}
+
+// issueToken 签发鉴权令牌
+func (s *Server) issueToken(vendor string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	token := fmt.Sprintf("%s-token-%d", vendor, s.seq)
+	s.tokens[token] = time.Now().Add(s.tokenTTL)
+	return token
+}
+
+// validToken 令牌是否由本服务器签发且未过期
+func (s *Server) validToken(token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiresAt, ok := s.tokens[token]
+	return ok && time.Now().Before(expiresAt)
+}
+
+// revokeToken 作废令牌
+func (s *Server) revokeToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, token)
+}
+
+// nextID 生成消息 ID
+func (s *Server) nextID(prefix string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	return fmt.Sprintf("%s-%d", prefix, s.seq)
+}
+
+// outcome 决定推送请求的结果：排队的失败优先，其次是鉴权，最后是设备 Token 前缀；返回空字符串表示成功
+func (s *Server) outcome(vendor, deviceToken string, authorized bool) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if queue := s.failures[vendor]; len(queue) > 0 {
+		s.failures[vendor] = queue[1:]
+		return queue[0]
+	}
+	if !authorized {
+		return ScenarioTokenExpired
+	}
+	for _, scenario := range Scenarios {
+		if strings.HasPrefix(deviceToken, scenario) {
+			return scenario
+		}
+	}
+	return ""
+}
+
+// record 记录成功送达的推送
+func (s *Server) record(message Message) {
+	message.ReceivedAt = time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, message)
+}
+
+// invalidCredential 以 invalid 开头的凭据视为无效
+func invalidCredential(value string) bool {
+	return strings.HasPrefix(value, "invalid")
+}
+
+func (s *Server) handleListMessages(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Messages(r.URL.Query().Get("vendor")))
+}
+
+func (s *Server) handleClearMessages(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.messages = nil
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleExpireTokens(w http.ResponseWriter, r *http.Request) {
+	s.ExpireTokens()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleFail(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Vendor   string `json:"vendor"`
+		Scenario string `json:"scenario"`
+		Count    int    `json:"count"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "请求参数错误"})
+		return
+	}
+	if req.Count <= 0 {
+		req.Count = 1
+	}
+	if err := s.Fail(req.Vendor, req.Scenario, req.Count); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleReset(w http.ResponseWriter, r *http.Request) {
+	s.Reset()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
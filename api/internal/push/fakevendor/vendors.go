@@ -0,0 +1,414 @@
+package fakevendor
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// handleVendor 按路径第一段的接口地址名称分发到各厂商
+func (s *Server) handleVendor(w http.ResponseWriter, r *http.Request) {
+	endpoint, path, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	path = "/" + path
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	switch {
+	case endpoint == "apns_production" || endpoint == "apns_development":
+		s.apns(w, r, path)
+	case endpoint == "fcm_oauth" && path == "/token":
+		s.fcmAuth(w, r)
+	case endpoint == "fcm" && strings.HasSuffix(path, "/messages:send"):
+		s.fcmSend(w, r)
+	case endpoint == "huawei_oauth" && path == "/oauth2/v2/token":
+		s.huaweiAuth(w, r)
+	case endpoint == "huawei" && strings.HasSuffix(path, "/messages:send"):
+		s.huaweiSend(w, r)
+	case endpoint == "huawei" && strings.HasSuffix(path, "/messages:revoke"):
+		writeJSON(w, http.StatusOK, map[string]string{"code": "80000000", "msg": "Success"})
+	case endpoint == "honor_oauth" && path == "/auth/token":
+		s.honorAuth(w, r)
+	case endpoint == "honor" && strings.HasSuffix(path, "/sendMessage"):
+		s.honorSend(w, r)
+	case endpoint == "xiaomi" && path == "/v3/message/regid":
+		s.xiaomiSend(w, r)
+	case endpoint == "xiaomi" && path == "/v1/message/recall":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"result": "ok", "code": 0})
+	case endpoint == "oppo" && path == "/server/v1/auth":
+		s.oppoAuth(w, r)
+	case endpoint == "oppo" && path == "/server/v1/message/notification/unicast":
+		s.oppoSend(w, r)
+	case endpoint == "vivo" && path == "/message/auth":
+		s.vivoAuth(w, r)
+	case endpoint == "vivo" && path == "/message/send":
+		s.vivoSend(w, r)
+	case endpoint == "vivo" && path == "/message/recall":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"result": 0, "desc": "请求成功"})
+	case endpoint == "meizu" && strings.HasPrefix(path, "/garcia/api/server/push/"):
+		s.meizuSend(w, r)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown endpoint", "path": r.URL.Path})
+	}
+}
+
+// apns 模拟 APNs：P8 使用 KeyID 判断凭据是否有效；证书认证无法在明文连接上校验，一律视为有效。
+// 全零设备 Token 是连接测试使用的，鉴权通过后返回 BadDeviceToken
+func (s *Server) apns(w http.ResponseWriter, r *http.Request, path string) {
+	deviceToken, ok := strings.CutPrefix(path, "/3/device/")
+	if !ok || deviceToken == "" {
+		writeJSON(w, http.StatusNotFound, map[string]string{"reason": "BadPath"})
+		return
+	}
+	authorized := true
+	if auth := r.Header.Get("authorization"); auth != "" {
+		authorized = false
+		if token, _, err := jwt.NewParser().ParseUnverified(strings.TrimPrefix(auth, "bearer "), jwt.MapClaims{}); err == nil {
+			kid, _ := token.Header["kid"].(string)
+			authorized = kid != "" && !invalidCredential(kid)
+		}
+	}
+
+	var payload struct {
+		Aps struct {
+			Alert json.RawMessage `json:"alert"`
+		} `json:"aps"`
+	}
+	json.NewDecoder(r.Body).Decode(&payload)
+
+	reject := func(status int, reason string) {
+		writeJSON(w, status, map[string]interface{}{"reason": reason, "timestamp": time.Now().UnixMilli()})
+	}
+	switch s.outcome("apns", deviceToken, authorized) {
+	case ScenarioTokenExpired:
+		if authorized {
+			reject(http.StatusForbidden, "ExpiredProviderToken")
+		} else {
+			reject(http.StatusForbidden, "InvalidProviderToken")
+		}
+	case ScenarioInvalidToken:
+		reject(http.StatusGone, "Unregistered")
+	case ScenarioRateLimit:
+		reject(http.StatusTooManyRequests, "TooManyRequests")
+	case ScenarioServerError:
+		reject(http.StatusServiceUnavailable, "ServiceUnavailable")
+	default:
+		if strings.Trim(deviceToken, "0") == "" {
+			reject(http.StatusBadRequest, "BadDeviceToken")
+			return
+		}
+		var alert struct {
+			Title string `json:"title"`
+			Body  string `json:"body"`
+		}
+		if json.Unmarshal(payload.Aps.Alert, &alert) != nil {
+			json.Unmarshal(payload.Aps.Alert, &alert.Body)
+		}
+		s.record(Message{Vendor: "apns", Token: deviceToken, Title: alert.Title, Body: alert.Body})
+		w.Header().Set("apns-id", s.nextID("apns"))
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (s *Server) fcmAuth(w http.ResponseWriter, r *http.Request) {
+	claims := jwt.MapClaims{}
+	_, _, err := jwt.NewParser().ParseUnverified(r.FormValue("assertion"), claims)
+	email, _ := claims["iss"].(string)
+	if err != nil || email == "" || invalidCredential(email) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "Invalid JWT Signature."})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": s.issueToken("fcm"),
+		"expires_in":   int(s.tokenTTL.Seconds()),
+		"token_type":   "Bearer",
+	})
+}
+
+func (s *Server) fcmSend(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Message struct {
+			Token        string `json:"token"`
+			Notification struct {
+				Title string `json:"title"`
+				Body  string `json:"body"`
+			} `json:"notification"`
+		} `json:"message"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	reject := func(status int, state, message, errorCode string) {
+		body := map[string]interface{}{"code": status, "message": message, "status": state}
+		if errorCode != "" {
+			body["details"] = []map[string]string{{"@type": "type.googleapis.com/google.firebase.fcm.v1.FcmError", "errorCode": errorCode}}
+		}
+		writeJSON(w, status, map[string]interface{}{"error": body})
+	}
+	switch s.outcome("fcm", req.Message.Token, s.validToken(bearer)) {
+	case ScenarioTokenExpired:
+		s.revokeToken(bearer)
+		reject(http.StatusUnauthorized, "UNAUTHENTICATED", "Request had invalid authentication credentials.", "")
+	case ScenarioInvalidToken:
+		reject(http.StatusNotFound, "NOT_FOUND", "Requested entity was not found.", "UNREGISTERED")
+	case ScenarioRateLimit:
+		reject(http.StatusTooManyRequests, "RESOURCE_EXHAUSTED", "Quota exceeded.", "QUOTA_EXCEEDED")
+	case ScenarioServerError:
+		reject(http.StatusServiceUnavailable, "UNAVAILABLE", "The service is currently unavailable.", "UNAVAILABLE")
+	default:
+		s.record(Message{Vendor: "fcm", Token: req.Message.Token, Title: req.Message.Notification.Title, Body: req.Message.Notification.Body})
+		writeJSON(w, http.StatusOK, map[string]string{"name": "projects/fake/messages/" + s.nextID("fcm")})
+	}
+}
+
+func (s *Server) huaweiAuth(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("client_id") == "" || invalidCredential(r.FormValue("client_id")) || invalidCredential(r.FormValue("client_secret")) {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": 1101, "error_description": "invalid client"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": s.issueToken("huawei"),
+		"expires_in":   int(s.tokenTTL.Seconds()),
+		"token_type":   "Bearer",
+	})
+}
+
+func (s *Server) huaweiSend(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Message struct {
+			Token        []string `json:"token"`
+			Notification struct {
+				Title string `json:"title"`
+				Body  string `json:"body"`
+			} `json:"notification"`
+		} `json:"message"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	deviceToken := firstOf(req.Message.Token)
+
+	reject := func(status int, code, msg string) {
+		writeJSON(w, status, map[string]string{"code": code, "msg": msg, "requestId": s.nextID("huawei")})
+	}
+	switch s.outcome("huawei", deviceToken, s.validToken(bearer)) {
+	case ScenarioTokenExpired:
+		s.revokeToken(bearer)
+		reject(http.StatusUnauthorized, "80100000", "OAuth token expired")
+	case ScenarioInvalidToken:
+		reject(http.StatusOK, "80300002", "illegal token")
+	case ScenarioRateLimit:
+		reject(http.StatusOK, "80300010", "the number of messages sent exceeds the limit")
+	case ScenarioServerError:
+		reject(http.StatusInternalServerError, "80200001", "internal service error")
+	default:
+		s.record(Message{Vendor: "huawei", Token: deviceToken, Title: req.Message.Notification.Title, Body: req.Message.Notification.Body})
+		writeJSON(w, http.StatusOK, map[string]string{"code": "80000000", "msg": "Success", "requestId": s.nextID("huawei")})
+	}
+}
+
+func (s *Server) honorAuth(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("client_id") == "" || invalidCredential(r.FormValue("client_id")) || invalidCredential(r.FormValue("client_secret")) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client", "error_description": "client authentication failed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": s.issueToken("honor"),
+		"expires_in":   int(s.tokenTTL.Seconds()),
+		"token_type":   "Bearer",
+	})
+}
+
+func (s *Server) honorSend(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token   []string `json:"token"`
+		Android struct {
+			Notification struct {
+				Title string `json:"title"`
+				Body  string `json:"body"`
+			} `json:"notification"`
+		} `json:"android"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	deviceToken := firstOf(req.Token)
+
+	reject := func(code int, message string) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"code": code, "message": message})
+	}
+	switch s.outcome("honor", deviceToken, s.validToken(bearer)) {
+	case ScenarioTokenExpired:
+		s.revokeToken(bearer)
+		reject(401, "access token expired")
+	case ScenarioInvalidToken:
+		reject(404, "invalid push token")
+	case ScenarioRateLimit:
+		reject(429, "too many requests")
+	case ScenarioServerError:
+		reject(503, "service unavailable")
+	default:
+		notification := req.Android.Notification
+		s.record(Message{Vendor: "honor", Token: deviceToken, Title: notification.Title, Body: notification.Body})
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"code":    200,
+			"message": "success",
+			"data":    map[string]interface{}{"sendResult": true, "requestId": s.nextID("honor")},
+		})
+	}
+}
+
+func (s *Server) xiaomiSend(w http.ResponseWriter, r *http.Request) {
+	secret := strings.TrimPrefix(r.Header.Get("Authorization"), "key=")
+	deviceToken := r.FormValue("registration_id")
+
+	reject := func(result string, code int, description string) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"result": result, "code": code, "description": description})
+	}
+	switch s.outcome("xiaomi", deviceToken, secret != "" && !invalidCredential(secret)) {
+	case ScenarioTokenExpired:
+		reject("InvalidAppSecret", 22006, "invalid app secret")
+	case ScenarioInvalidToken:
+		reject("InvalidRegistrationId", 20301, "invalid registration id")
+	case ScenarioRateLimit:
+		reject("QuotaExceeded", 200002, "quota exceeded")
+	case ScenarioServerError:
+		reject("ServerUnavailable", 10000, "server unavailable")
+	default:
+		s.record(Message{Vendor: "xiaomi", Token: deviceToken, Title: r.FormValue("title"), Body: r.FormValue("description")})
+		writeJSON(w, http.StatusOK, map[string]interface{}{"result": "ok", "code": 0, "data": map[string]string{"id": s.nextID("xiaomi")}})
+	}
+}
+
+func (s *Server) oppoAuth(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("app_key") == "" || invalidCredential(r.FormValue("app_key")) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"code": 10001, "message": "Invalid AppKey"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"code":    0,
+		"message": "Success",
+		"data":    map[string]interface{}{"auth_token": s.issueToken("oppo"), "create_time": time.Now().UnixMilli()},
+	})
+}
+
+func (s *Server) oppoSend(w http.ResponseWriter, r *http.Request) {
+	var message struct {
+		TargetValue  string `json:"target_value"`
+		Notification struct {
+			Title   string `json:"title"`
+			Content string `json:"content"`
+		} `json:"notification"`
+	}
+	json.Unmarshal([]byte(r.FormValue("message")), &message)
+	authToken := r.FormValue("auth_token")
+
+	reject := func(code int, msg string) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"code": code, "message": msg})
+	}
+	switch s.outcome("oppo", message.TargetValue, s.validToken(authToken)) {
+	case ScenarioTokenExpired:
+		s.revokeToken(authToken)
+		reject(10001, "Invalid AuthToken")
+	case ScenarioInvalidToken:
+		reject(10004, "Invalid RegistrationId")
+	case ScenarioRateLimit:
+		reject(10003, "Exceed Push Limit")
+	case ScenarioServerError:
+		reject(20001, "Service Unavailable")
+	default:
+		s.record(Message{Vendor: "oppo", Token: message.TargetValue, Title: message.Notification.Title, Body: message.Notification.Content})
+		writeJSON(w, http.StatusOK, map[string]interface{}{"code": 0, "message": "Success", "data": map[string]string{"messageId": s.nextID("oppo")}})
+	}
+}
+
+func (s *Server) vivoAuth(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		AppID  string `json:"appId"`
+		AppKey string `json:"appKey"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	if req.AppID == "" || invalidCredential(req.AppID) || invalidCredential(req.AppKey) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"result": 10301, "desc": "appId或appKey错误"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"result":    0,
+		"desc":      "请求成功",
+		"authToken": s.issueToken("vivo"),
+		"validTime": time.Now().Add(s.tokenTTL).UnixMilli(),
+	})
+}
+
+func (s *Server) vivoSend(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RegID   string `json:"regId"`
+		Title   string `json:"title"`
+		Content string `json:"content"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	authToken := r.Header.Get("authToken")
+
+	reject := func(result int, desc string) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"result": result, "desc": desc})
+	}
+	switch s.outcome("vivo", req.RegID, s.validToken(authToken)) {
+	case ScenarioTokenExpired:
+		s.revokeToken(authToken)
+		reject(10302, "authToken已过期")
+	case ScenarioInvalidToken:
+		reject(30001, "regId无效")
+	case ScenarioRateLimit:
+		reject(50000, "推送频率超限")
+	case ScenarioServerError:
+		reject(40000, "服务暂时不可用")
+	default:
+		s.record(Message{Vendor: "vivo", Token: req.RegID, Title: req.Title, Body: req.Content})
+		writeJSON(w, http.StatusOK, map[string]interface{}{"result": 0, "desc": "请求成功", "taskId": s.nextID("vivo")})
+	}
+}
+
+// meizuSend 模拟魅族通知栏与透传接口，魅族以 AppSecret 签名，无法校验签名时以 AppID 判断凭据是否有效
+func (s *Server) meizuSend(w http.ResponseWriter, r *http.Request) {
+	deviceToken := firstOf(strings.Split(r.FormValue("pushIds"), ","))
+	var body struct {
+		Title         string `json:"title"`
+		Content       string `json:"content"`
+		NoticeBarInfo struct {
+			Title   string `json:"title"`
+			Content string `json:"content"`
+		} `json:"noticeBarInfo"`
+	}
+	json.Unmarshal([]byte(r.FormValue("messageJson")), &body)
+	appID := r.FormValue("appId")
+
+	reject := func(code, message string) {
+		writeJSON(w, http.StatusOK, map[string]string{"code": code, "message": message})
+	}
+	switch s.outcome("meizu", deviceToken, appID != "" && r.FormValue("sign") != "" && !invalidCredential(appID)) {
+	case ScenarioTokenExpired:
+		reject("1006", "签名认证失败")
+	case ScenarioInvalidToken:
+		reject("110003", "pushId非法")
+	case ScenarioRateLimit:
+		reject("110010", "推送速率过快")
+	case ScenarioServerError:
+		reject("1003", "服务器忙")
+	default:
+		title, content := body.NoticeBarInfo.Title, body.NoticeBarInfo.Content
+		if title == "" && content == "" {
+			title, content = body.Title, body.Content
+		}
+		s.record(Message{Vendor: "meizu", Token: deviceToken, Title: title, Body: content})
+		writeJSON(w, http.StatusOK, map[string]interface{}{"code": "200", "message": "", "value": map[string]interface{}{}, "msgId": s.nextID("meizu")})
+	}
+}
+
+func firstOf(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
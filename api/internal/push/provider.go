@@ -1,18 +1,80 @@
 package push
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
 	"time"
 
 	"github.com/doopush/doopush/api/internal/database"
+	"github.com/doopush/doopush/api/internal/metrics"
 	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/internal/tracing"
+	"github.com/doopush/doopush/api/pkg/logger"
 	"github.com/doopush/doopush/api/pkg/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
 )
 
 // PushProvider 推送服务提供者接口
 type PushProvider interface {
-	SendPush(device *models.Device, pushLog *models.PushLog) *models.PushResult
+	SendPush(ctx context.Context, device *models.Device, pushLog *models.PushLog) *models.PushResult
+}
+
+// vendorTransport 厂商请求使用的 Transport：记录请求耗时指标并创建链路追踪 span
+func vendorTransport(channel string, next http.RoundTripper) http.RoundTripper {
+	return tracing.Transport(channel, metrics.InstrumentVendorTransport(channel, next))
+}
+
+// isDataMessage 是否为静默/透传消息（不展示通知栏）
+func isDataMessage(pushLog *models.PushLog) bool {
+	return pushLog.MessageType == "data"
+}
+
+// remainingTTL 返回消息剩余的存活时长（秒），未设置存活时长时返回 0。
+// 有过期时间时按过期时间倒推，被静默时段、分批发送或审批延后的消息不会重新获得完整的存活时长
+func remainingTTL(pushLog *models.PushLog) int {
+	if pushLog.ExpiresAt != nil {
+		return max(int(math.Ceil(time.Until(*pushLog.ExpiresAt).Seconds())), 1)
+	}
+	return pushLog.TTLSeconds
+}
+
+// ttlSeconds 返回统一的消息存活时长（秒），未设置时使用通道默认值
+func ttlSeconds(pushLog *models.PushLog, defaultTTL int) int {
+	if ttl := remainingTTL(pushLog); ttl > 0 {
+		return ttl
+	}
+	return defaultTTL
+}
+
+// isNormalPriority 是否为普通优先级，未设置时按高优先级处理
+func isNormalPriority(pushLog *models.PushLog) bool {
+	return pushLog.Priority == "normal"
+}
+
+// collapseNotifyID 将合并键映射为厂商要求的数字ID（1~max），相同合并键得到相同ID
+func collapseNotifyID(collapseKey string, max uint32) int {
+	h := fnv.New32a()
+	h.Write([]byte(collapseKey))
+	return int(h.Sum32()%max) + 1
+}
+
+// IsInvalidToken 厂商是否报告设备 token 无效或已注销：APNs 的 BadDeviceToken、Unregistered（HTTP 410），
+// 以及 FCM 和各国内厂商映射后的 INVALID_TOKEN
+func IsInvalidToken(result *models.PushResult) bool {
+	if result == nil || result.Success {
+		return false
+	}
+	switch result.ErrorCode {
+	case "INVALID_TOKEN", "BadDeviceToken", "Unregistered", "HTTP_410":
+		return true
+	}
+	return false
 }
 
 // PushManager 推送管理器
@@ -73,9 +135,13 @@ func (m *PushManager) createAPNsProvider(app *models.App, environment string) (P
 	// 获取APNs配置
 	var config models.AppConfig
 	err := database.DB.Where("app_id = ? AND platform = ? AND channel = ?", app.ID, "ios", "apns").First(&config).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		// 配置存在但无法读取（如主密钥缺失导致解密失败），不能回退到模拟推送
+		return nil, fmt.Errorf("读取APNs配置失败: %v", err)
+	}
 	if err != nil {
 		// 使用模拟推送
-		fmt.Printf("警告: 应用 %s 未配置APNs证书，使用模拟推送\n", app.Name)
+		logger.Warn("应用未配置APNs证书，使用模拟推送", "app_id", app.ID, "channel", "apns")
 		return &MockAPNsProvider{}, nil
 	}
 
@@ -107,7 +173,7 @@ func (m *PushManager) createAPNsProvider(app *models.App, environment string) (P
 	// 使用新的配置创建提供者
 	provider, err := NewAPNsProviderWithConfig(apnsConfig)
 	if err != nil {
-		fmt.Printf("警告: 应用 %s APNs配置无效，使用模拟推送: %v\n", app.Name, err)
+		logger.Warn("应用APNs配置无效，使用模拟推送", "app_id", app.ID, "channel", "apns", "error", err)
 		return &MockAPNsProvider{}, nil
 	}
 
@@ -251,9 +317,15 @@ func (m *PushManager) createHonorProvider(config AndroidConfig) (PushProvider, e
 }
 
 // SendPush 统一推送接口
-func (m *PushManager) SendPush(device *models.Device, pushLog *models.PushLog) *models.PushResult {
-	provider, err := m.GetProvider(device)
+func (m *PushManager) SendPush(ctx context.Context, device *models.Device, pushLog *models.PushLog) *models.PushResult {
+	ctx, span := tracing.Start(ctx, "PushProvider.SendPush",
+		attribute.String("doopush.channel", device.Channel),
+		attribute.String("doopush.platform", device.Platform))
+	defer span.End()
+
+	provider, err := m.providerFor(device, pushLog)
 	if err != nil {
+		tracing.Fail(span, "PROVIDER_ERROR", err.Error())
 		return &models.PushResult{
 			AppID:        pushLog.AppID,
 			PushLogID:    pushLog.ID,
@@ -264,13 +336,17 @@ func (m *PushManager) SendPush(device *models.Device, pushLog *models.PushLog) *
 		}
 	}
 
-	return provider.SendPush(device, pushLog)
+	result := provider.SendPush(ctx, device, pushLog)
+	if !result.Success {
+		tracing.Fail(span, result.ErrorCode, result.ErrorMessage)
+	}
+	return result
 }
 
 // MockAPNsProvider 模拟APNs提供者 (用于开发测试)
 type MockAPNsProvider struct{}
 
-func (m *MockAPNsProvider) SendPush(device *models.Device, pushLog *models.PushLog) *models.PushResult {
+func (m *MockAPNsProvider) SendPush(ctx context.Context, device *models.Device, pushLog *models.PushLog) *models.PushResult {
 	result := &models.PushResult{
 		AppID:        pushLog.AppID,
 		PushLogID:    pushLog.ID,
@@ -279,11 +355,11 @@ func (m *MockAPNsProvider) SendPush(device *models.Device, pushLog *models.PushL
 	}
 
 	if result.Success {
-		fmt.Printf("✅ 模拟iOS推送成功: [%s] %s\n", pushLog.Title, pushLog.Content)
+		logger.DebugContext(ctx, "模拟iOS推送成功", "app_id", pushLog.AppID, "push_log_id", pushLog.ID, "channel", "apns")
 	} else {
 		result.ErrorCode = "MOCK_FAILURE"
 		result.ErrorMessage = "模拟推送失败"
-		fmt.Printf("❌ 模拟iOS推送失败: %s\n", result.ErrorMessage)
+		logger.WarnContext(ctx, "模拟iOS推送失败", "app_id", pushLog.AppID, "push_log_id", pushLog.ID, "channel", "apns", "error", result.ErrorMessage)
 	}
 
 	return result
@@ -353,7 +429,7 @@ func (m *PushManager) validateAPNsConfig(configJSON string) error {
 	}
 
 	// 测试连接
-	if err := provider.TestConnection(); err != nil {
+	if err := provider.TestConnection(context.Background()); err != nil {
 		return fmt.Errorf("连接测试失败: %v", err)
 	}
 
@@ -550,5 +626,5 @@ func (m *PushManager) TestConfigWithDevice(appID uint, title, content, platform,
 	}
 
 	// 执行实际推送测试
-	return provider.SendPush(device, pushLog)
+	return provider.SendPush(context.Background(), device, pushLog)
 }
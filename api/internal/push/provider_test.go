@@ -0,0 +1,54 @@
+package push
+
+import (
+	"testing"
+	"time"
+
+	"github.com/doopush/doopush/api/internal/models"
+)
+
+func TestRemainingTTL(t *testing.T) {
+	late := time.Now().Add(90 * time.Second)
+	expired := time.Now().Add(-time.Minute)
+	for _, tc := range []struct {
+		name    string
+		pushLog models.PushLog
+		want    int
+	}{
+		{"unset", models.PushLog{}, 0},
+		{"no expiry", models.PushLog{TTLSeconds: 300}, 300},
+		{"sent late", models.PushLog{TTLSeconds: 300, ExpiresAt: &late}, 90},
+		{"already expired", models.PushLog{TTLSeconds: 300, ExpiresAt: &expired}, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := remainingTTL(&tc.pushLog); got != tc.want {
+				t.Fatalf("remainingTTL = %d, want %d", got, tc.want)
+			}
+		})
+	}
+
+	// 魅族按剩余时长向上取整到小时
+	if hours := meizuValidHours(&models.PushLog{TTLSeconds: 300, ExpiresAt: &late}); hours != 1 {
+		t.Fatalf("meizuValidHours = %d, want 1", hours)
+	}
+}
+
+func TestIsInvalidToken(t *testing.T) {
+	for _, tc := range []struct {
+		result *models.PushResult
+		want   bool
+	}{
+		{&models.PushResult{ErrorCode: "BadDeviceToken"}, true},
+		{&models.PushResult{ErrorCode: "Unregistered"}, true},
+		{&models.PushResult{ErrorCode: "HTTP_410"}, true},
+		{&models.PushResult{ErrorCode: "INVALID_TOKEN"}, true},
+		{&models.PushResult{ErrorCode: "DeviceTokenNotForTopic"}, false},
+		{&models.PushResult{ErrorCode: "QUOTA_EXCEEDED"}, false},
+		{&models.PushResult{Success: true}, false},
+		{nil, false},
+	} {
+		if got := IsInvalidToken(tc.result); got != tc.want {
+			t.Errorf("IsInvalidToken(%+v) = %v, want %v", tc.result, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/pkg/logger"
+)
+
+// 撤回结果
+const (
+	RecallStatusRecalled    = "recalled"
+	RecallStatusFailed      = "failed"
+	RecallStatusUnsupported = "unsupported"
+)
+
+const (
+	huaweiRevokeURL = "/v1/%s/messages:revoke"
+	xiaomiRecallURL = "/v1/message/recall"
+	vivoRecallURL   = "/message/recall"
+)
+
+// PushRecaller 支持撤回已发送消息的推送服务提供者
+type PushRecaller interface {
+	RecallPush(device *models.Device, pushLog *models.PushLog, sendResult *models.PushResult) *models.PushRecall
+}
+
+// RecallPush 撤回已发送的推送，通道不支持撤回时返回 unsupported 记录
+func (m *PushManager) RecallPush(device *models.Device, pushLog *models.PushLog, sendResult *models.PushResult) *models.PushRecall {
+	provider, err := m.providerFor(device, pushLog)
+	if err != nil {
+		return recallFailure(newRecall(pushLog, ""), "PROVIDER_ERROR", err.Error())
+	}
+	recaller, ok := provider.(PushRecaller)
+	if !ok {
+		return recallUnsupported(pushLog, fmt.Sprintf("%s 通道不支持撤回", device.Channel))
+	}
+	return recaller.RecallPush(device, pushLog, sendResult)
+}
+
+// newRecall 创建撤回记录，默认结果为失败
+func newRecall(pushLog *models.PushLog, method string) *models.PushRecall {
+	return &models.PushRecall{
+		AppID:        pushLog.AppID,
+		PushLogID:    pushLog.ID,
+		Method:       method,
+		Status:       RecallStatusFailed,
+		ResponseData: "{}",
+	}
+}
+
+// recallFailure 标记撤回失败
+func recallFailure(recall *models.PushRecall, code, message string) *models.PushRecall {
+	recall.Status = RecallStatusFailed
+	recall.ErrorCode = code
+	recall.ErrorMessage = message
+	return recall
+}
+
+// recallUnsupported 通道或消息不支持撤回
+func recallUnsupported(pushLog *models.PushLog, message string) *models.PushRecall {
+	recall := newRecall(pushLog, "")
+	recall.Status = RecallStatusUnsupported
+	recall.ErrorMessage = message
+	return recall
+}
+
+// sendResultField 读取发送结果响应中的消息ID等字段
+func sendResultField(sendResult *models.PushResult, key string) string {
+	if sendResult == nil || sendResult.ResponseData == "" {
+		return ""
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(sendResult.ResponseData), &data); err != nil {
+		return ""
+	}
+	value, _ := data[key].(string)
+	if value == "unknown" || value == "success" {
+		return ""
+	}
+	return value
+}
+
+// 覆盖撤回时替换原通知展示的内容
+const (
+	recallReplacementTitle   = "消息已撤回"
+	recallReplacementContent = "该消息已被发送方撤回"
+)
+
+// replacementLog 构造覆盖撤回用的通知：沿用原消息的合并键替换已展示或尚未展示的通知。
+// 静默消息无法替换已展示的通知，因此覆盖消息必须是可见通知；同时携带 dp_recall 供 SDK 识别
+func replacementLog(pushLog *models.PushLog, collapseKey string) *models.PushLog {
+	replacement := *pushLog
+	replacement.MessageType = "notification"
+	replacement.Title = recallReplacementTitle
+	replacement.Content = recallReplacementContent
+	replacement.CollapseKey = collapseKey
+	replacement.Payload = fmt.Sprintf(`{"dp_recall":"%d"}`, pushLog.ID)
+	// 原消息可能已过期，覆盖消息按原存活时长重新计算
+	replacement.ExpiresAt = nil
+	return &replacement
+}
+
+// replaceResult 将覆盖消息的发送结果转换为撤回记录
+func replaceResult(pushLog *models.PushLog, result *models.PushResult) *models.PushRecall {
+	recall := newRecall(pushLog, "replace")
+	if result.ResponseData != "" {
+		recall.ResponseData = result.ResponseData
+	}
+	if !result.Success {
+		return recallFailure(recall, result.ErrorCode, result.ErrorMessage)
+	}
+	recall.Status = RecallStatusRecalled
+	return recall
+}
+
+// RecallPush 撤回 Android 推送：华为、小米、VIVO 调用厂商撤回接口，FCM 按合并键以撤回提示替换原通知
+func (a *AndroidProvider) RecallPush(device *models.Device, pushLog *models.PushLog, sendResult *models.PushResult) *models.PushRecall {
+	switch a.channel {
+	case "huawei":
+		return a.recallHuawei(device, pushLog, sendResult)
+	case "xiaomi":
+		return a.recallXiaomi(pushLog, sendResult)
+	case "vivo":
+		return a.recallVivo(pushLog, sendResult)
+	case "fcm":
+		if pushLog.CollapseKey == "" {
+			return recallUnsupported(pushLog, "原消息未设置合并键，无法覆盖撤回")
+		}
+		return replaceResult(pushLog, a.sendFCM(context.Background(), device, replacementLog(pushLog, pushLog.CollapseKey)))
+	default:
+		return recallUnsupported(pushLog, fmt.Sprintf("%s 通道不支持撤回", a.channel))
+	}
+}
+
+// recallHuawei 调用华为消息撤回接口
+func (a *AndroidProvider) recallHuawei(device *models.Device, pushLog *models.PushLog, sendResult *models.PushResult) *models.PushRecall {
+	recall := newRecall(pushLog, "revoke")
+	messageID := sendResultField(sendResult, "message_id")
+	if messageID == "" {
+		return recallFailure(recall, "MISSING_MESSAGE_ID", "缺少华为消息ID")
+	}
+
+	accessToken, err := a.getHuaweiAccessToken(context.Background())
+	if err != nil {
+		return recallFailure(recall, "AUTH_ERROR", err.Error())
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"message_id": messageID,
+		"token":      []string{device.Token},
+	})
+	req, err := http.NewRequest("POST", vendorURL(EndpointHuawei, fmt.Sprintf(huaweiRevokeURL, a.config.AppID)), bytes.NewReader(body))
+	if err != nil {
+		return recallFailure(recall, "REQUEST_ERROR", err.Error())
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	respBody, err := a.doRecallRequest(req)
+	if err != nil {
+		return recallFailure(recall, "NETWORK_ERROR", err.Error())
+	}
+	recall.ResponseData = string(respBody)
+
+	var huaweiResp HuaweiResponse
+	if err := json.Unmarshal(respBody, &huaweiResp); err != nil {
+		return recallFailure(recall, "RESPONSE_ERROR", "解析华为撤回响应失败")
+	}
+	if huaweiResp.Code != "80000000" {
+		return recallFailure(recall, huaweiResp.Code, huaweiResp.Msg)
+	}
+	recall.Status = RecallStatusRecalled
+	return recall
+}
+
+// recallXiaomi 调用小米消息撤回接口
+func (a *AndroidProvider) recallXiaomi(pushLog *models.PushLog, sendResult *models.PushResult) *models.PushRecall {
+	recall := newRecall(pushLog, "revoke")
+	messageID := sendResultField(sendResult, "message_id")
+	if messageID == "" {
+		return recallFailure(recall, "MISSING_MESSAGE_ID", "缺少小米消息ID")
+	}
+
+	data := url.Values{}
+	data.Set("msg_id", messageID)
+	req, err := http.NewRequest("POST", vendorURL(EndpointXiaomi, xiaomiRecallURL), strings.NewReader(data.Encode()))
+	if err != nil {
+		return recallFailure(recall, "REQUEST_ERROR", err.Error())
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", fmt.Sprintf("key=%s", a.config.AppSecret))
+
+	respBody, err := a.doRecallRequest(req)
+	if err != nil {
+		return recallFailure(recall, "NETWORK_ERROR", err.Error())
+	}
+	recall.ResponseData = string(respBody)
+
+	var xiaomiResp XiaomiResponse
+	if err := json.Unmarshal(respBody, &xiaomiResp); err != nil {
+		return recallFailure(recall, "RESPONSE_ERROR", "解析小米撤回响应失败")
+	}
+	if xiaomiResp.Result != "ok" {
+		return recallFailure(recall, fmt.Sprintf("%d", xiaomiResp.Code), xiaomiResp.Description)
+	}
+	recall.Status = RecallStatusRecalled
+	return recall
+}
+
+// recallVivo 调用VIVO消息撤回接口
+func (a *AndroidProvider) recallVivo(pushLog *models.PushLog, sendResult *models.PushResult) *models.PushRecall {
+	recall := newRecall(pushLog, "revoke")
+	taskID := sendResultField(sendResult, "task_id")
+	if taskID == "" {
+		return recallFailure(recall, "MISSING_MESSAGE_ID", "缺少VIVO任务ID")
+	}
+
+	authToken, err := a.getVivoAuthToken(context.Background())
+	if err != nil {
+		return recallFailure(recall, "AUTH_ERROR", err.Error())
+	}
+
+	body, _ := json.Marshal(map[string]string{"taskId": taskID})
+	req, err := http.NewRequest("POST", vendorURL(EndpointVivo, vivoRecallURL), bytes.NewReader(body))
+	if err != nil {
+		return recallFailure(recall, "REQUEST_ERROR", err.Error())
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("authToken", authToken)
+
+	respBody, err := a.doRecallRequest(req)
+	if err != nil {
+		return recallFailure(recall, "NETWORK_ERROR", err.Error())
+	}
+	recall.ResponseData = string(respBody)
+
+	var vivoResp VivoSendResponse
+	if err := json.Unmarshal(respBody, &vivoResp); err != nil {
+		return recallFailure(recall, "RESPONSE_ERROR", "解析VIVO撤回响应失败")
+	}
+	if vivoResp.Result != 0 {
+		return recallFailure(recall, fmt.Sprintf("%d", vivoResp.Result), vivoResp.Desc)
+	}
+	recall.Status = RecallStatusRecalled
+	return recall
+}
+
+// doRecallRequest 发送撤回请求并读取响应
+func (a *AndroidProvider) doRecallRequest(req *http.Request) ([]byte, error) {
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// RecallPush 撤回 APNs 推送：以相同的 apns-collapse-id 发送撤回提示替换原通知
+func (a *APNsProvider) RecallPush(device *models.Device, pushLog *models.PushLog, sendResult *models.PushResult) *models.PushRecall {
+	collapseID := pushLog.CollapseKey
+	if collapseID == "" {
+		var customData map[string]interface{}
+		if err := json.Unmarshal([]byte(pushLog.Payload), &customData); err == nil {
+			collapseID = parseAPNsOptions(customData).CollapseID
+		}
+	}
+	if collapseID == "" {
+		return recallUnsupported(pushLog, "原消息未设置合并键，无法覆盖撤回")
+	}
+	return replaceResult(pushLog, a.SendPush(context.Background(), device, replacementLog(pushLog, collapseID)))
+}
+
+// RecallPush 模拟撤回
+func (m *MockAPNsProvider) RecallPush(device *models.Device, pushLog *models.PushLog, sendResult *models.PushResult) *models.PushRecall {
+	recall := newRecall(pushLog, "replace")
+	recall.Status = RecallStatusRecalled
+	logger.Debug("模拟iOS撤回成功", "app_id", pushLog.AppID, "push_log_id", pushLog.ID, "channel", "apns")
+	return recall
+}
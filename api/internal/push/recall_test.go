@@ -0,0 +1,50 @@
+package push
+
+import (
+	"testing"
+
+	"github.com/doopush/doopush/api/internal/models"
+)
+
+func TestSendResultField(t *testing.T) {
+	tests := []struct {
+		response string
+		key      string
+		want     string
+	}{
+		{`{"message_id":"abc123"}`, "message_id", "abc123"},
+		{`{"task_id":"998"}`, "task_id", "998"},
+		{`{"message_id":"success"}`, "message_id", ""},
+		{`{"message_id":"unknown"}`, "message_id", ""},
+		{`{}`, "message_id", ""},
+		{`not json`, "message_id", ""},
+	}
+	for _, tt := range tests {
+		got := sendResultField(&models.PushResult{ResponseData: tt.response}, tt.key)
+		if got != tt.want {
+			t.Errorf("sendResultField(%s, %s) = %q, want %q", tt.response, tt.key, got, tt.want)
+		}
+	}
+	if got := sendResultField(nil, "message_id"); got != "" {
+		t.Errorf("nil result should yield empty id, got %q", got)
+	}
+}
+
+func TestReplacementLog(t *testing.T) {
+	original := &models.PushLog{ID: 42, Title: "错误的标题", MessageType: "notification", Payload: `{"action":"open"}`}
+	replacement := replacementLog(original, "promo")
+
+	// 静默消息无法替换已展示的通知
+	if replacement.MessageType != "notification" || replacement.Title == original.Title {
+		t.Errorf("replacement should be a visible notification with new text, got %q %q", replacement.MessageType, replacement.Title)
+	}
+	if replacement.CollapseKey != "promo" {
+		t.Errorf("collapse key = %q, want promo", replacement.CollapseKey)
+	}
+	if replacement.Payload != `{"dp_recall":"42"}` {
+		t.Errorf("payload = %s", replacement.Payload)
+	}
+	if original.MessageType != "notification" || original.CollapseKey != "" {
+		t.Error("original push log must not be modified")
+	}
+}
@@ -0,0 +1,83 @@
+package push
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// RichButton 通知按钮（厂商无关）
+type RichButton struct {
+	Title  string `json:"title" binding:"required,max=20" example:"查看详情"`                                                // 按钮文字
+	Action string `json:"action" binding:"required,oneof=open_app open_url open_page" example:"open_url"`                // 按钮动作：打开应用、打开网页、打开应用内页面
+	Value  string `json:"value,omitempty" binding:"required_unless=Action open_app" example:"https://example.com/promo"` // open_url 为网页地址，open_page 为 intent URI / Activity
+}
+
+// RichContent 富媒体通知内容（厂商无关），对应 payload 中的 image_url/big_text/buttons/attachment_url
+type RichContent struct {
+	ImageURL      string       `json:"image_url,omitempty"`
+	BigText       string       `json:"big_text,omitempty"`
+	Buttons       []RichButton `json:"buttons,omitempty"`
+	AttachmentURL string       `json:"attachment_url,omitempty"`
+}
+
+// IsEmpty 是否未设置任何富媒体字段
+func (r RichContent) IsEmpty() bool {
+	return r.ImageURL == "" && r.BigText == "" && len(r.Buttons) == 0 && r.AttachmentURL == ""
+}
+
+// maxRichButtons 通知按钮数量上限（各厂商中的最小公约数）
+const maxRichButtons = 3
+
+// parseRichContent 从推送载荷中解析富媒体字段
+func parseRichContent(payload string) RichContent {
+	var rich RichContent
+	if payload == "" || payload == "{}" {
+		return rich
+	}
+	_ = json.Unmarshal([]byte(payload), &rich)
+	if len(rich.Buttons) > maxRichButtons {
+		rich.Buttons = rich.Buttons[:maxRichButtons]
+	}
+	return rich
+}
+
+// richButtonActionType 转换为华为/荣耀按钮动作类型：0=打开应用首页 1=打开自定义页面 2=打开网页
+func richButtonActionType(action string) int {
+	switch action {
+	case "open_page":
+		return 1
+	case "open_url":
+		return 2
+	default:
+		return 0
+	}
+}
+
+// xiaomiButtonExtras 转换为小米通知按钮 extra 字段，按钮依次放在 left/mid/right 位置
+func xiaomiButtonExtras(buttons []RichButton) map[string]string {
+	extras := make(map[string]string)
+	positions := []string{"left", "mid", "right"}
+	if len(buttons) == 1 {
+		positions = []string{"right"}
+	} else if len(buttons) == 2 {
+		positions = []string{"left", "right"}
+	}
+	for i, button := range buttons {
+		if i >= len(positions) {
+			break
+		}
+		prefix := fmt.Sprintf("notification_style_button_%s_", positions[i])
+		extras[prefix+"name"] = button.Title
+		switch button.Action {
+		case "open_url":
+			extras[prefix+"notify_effect"] = "3"
+			extras[prefix+"web_uri"] = button.Value
+		case "open_page":
+			extras[prefix+"notify_effect"] = "2"
+			extras[prefix+"intent_uri"] = button.Value
+		default:
+			extras[prefix+"notify_effect"] = "1"
+		}
+	}
+	return extras
+}
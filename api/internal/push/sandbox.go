@@ -0,0 +1,61 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/pkg/logger"
+)
+
+// SandboxProvider 沙箱推送提供者，用于测试密钥（dp_test_）发起的推送。
+// 不调用任何厂商接口，把将要发送的内容记录到推送结果中并返回成功，适用于所有平台和通道
+type SandboxProvider struct{}
+
+// SendPush 记录推送内容，不发往厂商
+func (p *SandboxProvider) SendPush(ctx context.Context, device *models.Device, pushLog *models.PushLog) *models.PushResult {
+	record := map[string]interface{}{
+		"sandbox":          true,
+		"message_id":       fmt.Sprintf("sandbox-%d", pushLog.ID),
+		"platform":         device.Platform,
+		"channel":          device.Channel,
+		"push_environment": device.PushEnv,
+		"token":            device.Token,
+		"title":            pushLog.Title,
+		"content":          pushLog.Content,
+		"message_type":     pushLog.MessageType,
+		"badge":            pushLog.Badge,
+		"ttl_seconds":      pushLog.TTLSeconds,
+		"priority":         pushLog.Priority,
+		"collapse_key":     pushLog.CollapseKey,
+	}
+	if json.Valid([]byte(pushLog.Payload)) {
+		record["payload"] = json.RawMessage(pushLog.Payload)
+	}
+	responseData, _ := json.Marshal(record)
+
+	logger.DebugContext(ctx, "沙箱推送已记录", "app_id", pushLog.AppID, "push_log_id", pushLog.ID, "channel", device.Channel)
+	return &models.PushResult{
+		AppID:        pushLog.AppID,
+		PushLogID:    pushLog.ID,
+		Success:      true,
+		ResponseData: string(responseData),
+	}
+}
+
+// RecallPush 沙箱推送的撤回总是成功
+func (p *SandboxProvider) RecallPush(device *models.Device, pushLog *models.PushLog, sendResult *models.PushResult) *models.PushRecall {
+	recall := newRecall(pushLog, "sandbox")
+	recall.Status = RecallStatusRecalled
+	recall.ResponseData = fmt.Sprintf(`{"sandbox":true,"message_id":%q}`, sendResultField(sendResult, "message_id"))
+	return recall
+}
+
+// providerFor 获取推送或撤回使用的提供者：沙箱推送和沙箱设备一律使用沙箱提供者，避免测试消息发到真实设备
+func (m *PushManager) providerFor(device *models.Device, pushLog *models.PushLog) (PushProvider, error) {
+	if pushLog.Sandbox || device.Sandbox {
+		return &SandboxProvider{}, nil
+	}
+	return m.GetProvider(device)
+}
@@ -0,0 +1,58 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/internal/push/fakevendor"
+)
+
+func TestSandboxProviderRecordsWithoutCallingVendors(t *testing.T) {
+	fake := startFakeVendors(t)
+	manager := NewPushManager()
+
+	for _, tc := range []struct {
+		name    string
+		device  models.Device
+		pushLog models.PushLog
+	}{
+		{"sandbox device", models.Device{Platform: "android", Channel: "huawei", Token: "device-1", Sandbox: true}, models.PushLog{ID: 7, AppID: 1}},
+		{"sandbox push", models.Device{Platform: "ios", Channel: "apns", Token: "device-2", PushEnv: "production"}, models.PushLog{ID: 8, AppID: 1, Sandbox: true}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.pushLog.Title, tc.pushLog.Content, tc.pushLog.Payload = "订单已发货", "您的订单已发货", `{"order_id":"42"}`
+
+			result := manager.SendPush(context.Background(), &tc.device, &tc.pushLog)
+			if !result.Success || result.PushLogID != tc.pushLog.ID {
+				t.Fatalf("result = %+v", result)
+			}
+			var record struct {
+				Sandbox bool              `json:"sandbox"`
+				Channel string            `json:"channel"`
+				Token   string            `json:"token"`
+				Title   string            `json:"title"`
+				Payload map[string]string `json:"payload"`
+			}
+			if err := json.Unmarshal([]byte(result.ResponseData), &record); err != nil {
+				t.Fatal(err)
+			}
+			if !record.Sandbox || record.Channel != tc.device.Channel || record.Token != tc.device.Token ||
+				record.Title != tc.pushLog.Title || record.Payload["order_id"] != "42" {
+				t.Fatalf("record = %+v", record)
+			}
+
+			recall := manager.RecallPush(&tc.device, &tc.pushLog, result)
+			if recall.Status != RecallStatusRecalled || recall.Method != "sandbox" {
+				t.Fatalf("recall = %+v", recall)
+			}
+		})
+	}
+
+	for _, vendor := range fakevendor.Vendors {
+		if messages := fake.Messages(vendor); len(messages) != 0 {
+			t.Fatalf("sandbox push reached vendor %s: %+v", vendor, messages)
+		}
+	}
+}
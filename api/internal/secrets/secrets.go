@@ -0,0 +1,312 @@
+// Package secrets 推送配置等凭据的信封加密。
+//
+// 每条数据使用随机生成的数据密钥（AES-256-GCM）加密，数据密钥再由主密钥加密后与密文一起保存，
+// 主密钥只存在于环境变量或密钥文件中，数据库泄露不会暴露凭据。
+// 密文是带版本号的 JSON 对象，可以直接保存在 JSON 列中：
+//
+//	{"_enc":1,"kid":"<主密钥ID>","dek":"<加密的数据密钥>","data":"<加密的数据>"}
+//
+// 未配置主密钥时不加密，已有的明文数据在下次保存或执行 rotate-keys 时加密。
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/doopush/doopush/api/internal/config"
+)
+
+// envelopeVersion 当前密文格式版本
+const envelopeVersion = 1
+
+var (
+	ErrNoMasterKey      = errors.New("未配置主密钥，无法解密")
+	ErrUnknownMasterKey = errors.New("密文使用的主密钥不在当前配置中")
+)
+
+// envelope 带版本号的密文
+type envelope struct {
+	Version int    `json:"_enc"`
+	KeyID   string `json:"kid"`
+	DataKey string `json:"dek"`
+	Data    string `json:"data"`
+}
+
+// masterKey 主密钥，ID 为密钥 SHA-256 的前 8 位十六进制，不需要单独配置
+type masterKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// keyring 当前主密钥用于加密，历史主密钥仅用于解密，轮换期间新旧密文都能读取
+type keyring struct {
+	current *masterKey
+	keys    map[string]*masterKey
+}
+
+var ring atomic.Pointer[keyring]
+
+// Init 按配置加载主密钥：
+// CONFIG_MASTER_KEY 或 CONFIG_MASTER_KEY_FILE（文件内容）为当前主密钥，base64 编码的 32 字节；
+// CONFIG_MASTER_KEY_PREVIOUS 为逗号分隔的历史主密钥，轮换完成前保留
+func Init() error {
+	current := config.GetString("CONFIG_MASTER_KEY")
+	if current == "" {
+		if path := config.GetString("CONFIG_MASTER_KEY_FILE"); path != "" {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("读取主密钥文件失败: %v", err)
+			}
+			current = strings.TrimSpace(string(content))
+		}
+	}
+
+	var previous []string
+	for _, key := range strings.Split(config.GetString("CONFIG_MASTER_KEY_PREVIOUS"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			previous = append(previous, key)
+		}
+	}
+
+	r, err := newKeyring(current, previous)
+	if err != nil {
+		return err
+	}
+	ring.Store(r)
+	return nil
+}
+
+func newKeyring(current string, previous []string) (*keyring, error) {
+	r := &keyring{keys: make(map[string]*masterKey)}
+	if current == "" {
+		if len(previous) > 0 {
+			return nil, errors.New("配置了历史主密钥但未配置当前主密钥")
+		}
+		return r, nil
+	}
+
+	for i, encoded := range append([]string{current}, previous...) {
+		key, err := parseMasterKey(encoded)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			r.current = key
+		}
+		r.keys[key.id] = key
+	}
+	return r, nil
+}
+
+func parseMasterKey(encoded string) (*masterKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(raw) != 32 {
+		return nil, errors.New("主密钥必须是 base64 编码的 32 字节")
+	}
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(raw)
+	return &masterKey{id: hex.EncodeToString(sum[:4]), aead: aead}, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func loadRing() *keyring {
+	if r := ring.Load(); r != nil {
+		return r
+	}
+	return &keyring{}
+}
+
+// Enabled 是否配置了主密钥
+func Enabled() bool {
+	return loadRing().current != nil
+}
+
+// KeyID 当前主密钥ID，未配置时为空
+func KeyID() string {
+	if r := loadRing(); r.current != nil {
+		return r.current.id
+	}
+	return ""
+}
+
+// GenerateMasterKey 生成新的主密钥（base64 编码）
+func GenerateMasterKey() string {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// Encrypt 使用新的数据密钥和当前主密钥加密。未配置主密钥、内容为空或已加密时原样返回。
+// 只有当前主密钥环能解开的密文才算已加密，形似密文的明文仍会被加密
+func Encrypt(plaintext string) (string, error) {
+	r := loadRing()
+	if r.current == nil || plaintext == "" {
+		return plaintext, nil
+	}
+	if env, ok := parseEnvelope(plaintext); ok && r.opens(env) {
+		return plaintext, nil
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	env := envelope{
+		Version: envelopeVersion,
+		KeyID:   r.current.id,
+		// 数据密钥以主密钥ID作为附加数据，防止密文被挪到其他主密钥下
+		DataKey: seal(r.current.aead, dataKey, []byte(r.current.id)),
+		Data:    seal(dataAEAD, []byte(plaintext), nil),
+	}
+	out, err := json.Marshal(env)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// Decrypt 解密 Encrypt 的结果，明文（未加密的历史数据）原样返回
+func Decrypt(value string) (string, error) {
+	env, ok := parseEnvelope(value)
+	if !ok {
+		return value, nil
+	}
+	if env.Version != envelopeVersion {
+		return "", fmt.Errorf("不支持的密文版本: %d", env.Version)
+	}
+
+	r := loadRing()
+	if r.current == nil {
+		return "", ErrNoMasterKey
+	}
+	key, ok := r.keys[env.KeyID]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownMasterKey, env.KeyID)
+	}
+
+	dataKey, err := open(key.aead, env.DataKey, []byte(env.KeyID))
+	if err != nil {
+		return "", fmt.Errorf("数据密钥解密失败: %v", err)
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dataAEAD, env.Data, nil)
+	if err != nil {
+		return "", fmt.Errorf("数据解密失败: %v", err)
+	}
+	return string(plaintext), nil
+}
+
+// IsEncrypted 是否为 Encrypt 生成的密文
+func IsEncrypted(value string) bool {
+	_, ok := parseEnvelope(value)
+	return ok
+}
+
+// NeedsRotation 是否需要用当前主密钥重新加密：明文，或由历史主密钥加密
+func NeedsRotation(value string) bool {
+	if value == "" {
+		return false
+	}
+	env, ok := parseEnvelope(value)
+	return !ok || env.KeyID != KeyID()
+}
+
+// Rotate 解密后使用新的数据密钥和当前主密钥重新加密
+func Rotate(value string) (string, error) {
+	if !Enabled() {
+		return "", errors.New("未配置主密钥，无法重新加密")
+	}
+	plaintext, err := Decrypt(value)
+	if err != nil {
+		return "", err
+	}
+	return Encrypt(plaintext)
+}
+
+// opens 密文是否由主密钥环中的主密钥加密：版本和主密钥ID有效，且数据密钥能够解开
+func (r *keyring) opens(env envelope) bool {
+	key, ok := r.keys[env.KeyID]
+	if !ok || env.Version != envelopeVersion {
+		return false
+	}
+	_, err := open(key.aead, env.DataKey, []byte(env.KeyID))
+	return err == nil
+}
+
+// parseEnvelope 严格解析密文：只能包含信封的四个字段，且主密钥ID格式正确。
+// 恰好带有 _enc 字段的明文配置不会被当作密文
+func parseEnvelope(value string) (envelope, bool) {
+	var env envelope
+	// MySQL JSON 列会重排字段顺序，不能按前缀判断
+	if !strings.Contains(value, `"_enc"`) {
+		return env, false
+	}
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&env); err != nil || decoder.More() {
+		return env, false
+	}
+	if env.Version <= 0 || !validKeyID(env.KeyID) || env.DataKey == "" || env.Data == "" {
+		return env, false
+	}
+	return env, true
+}
+
+// validKeyID 主密钥ID为 8 位十六进制，见 parseMasterKey
+func validKeyID(id string) bool {
+	if len(id) != 8 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// seal 加密并编码为 base64(nonce || ciphertext)
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) string {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		panic(err)
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, plaintext, additionalData))
+}
+
+func open(aead cipher.AEAD, encoded string, additionalData []byte) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < aead.NonceSize() {
+		return nil, errors.New("密文长度无效")
+	}
+	return aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], additionalData)
+}
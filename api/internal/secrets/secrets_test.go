@@ -0,0 +1,145 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// useKeys 替换主密钥，测试结束后恢复
+func useKeys(t *testing.T, current string, previous ...string) {
+	t.Helper()
+	r, err := newKeyring(current, previous)
+	if err != nil {
+		t.Fatalf("newKeyring: %v", err)
+	}
+	old := ring.Load()
+	ring.Store(r)
+	t.Cleanup(func() { ring.Store(old) })
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	useKeys(t, GenerateMasterKey())
+
+	plaintext := `{"app_id":"1","app_secret":"s3cr3t"}`
+	first, err := Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if strings.Contains(first, "s3cr3t") || !IsEncrypted(first) {
+		t.Fatalf("ciphertext leaks plaintext or is not recognized: %s", first)
+	}
+	if !json.Valid([]byte(first)) {
+		t.Fatalf("ciphertext must be valid JSON for JSON columns: %s", first)
+	}
+
+	// 每次加密使用新的数据密钥和随机数
+	second, _ := Encrypt(plaintext)
+	if first == second {
+		t.Fatal("two encryptions must differ")
+	}
+
+	got, err := Decrypt(first)
+	if err != nil || got != plaintext {
+		t.Fatalf("Decrypt = %q, %v", got, err)
+	}
+
+	// MySQL JSON 列返回时字段顺序和空白会变化
+	var fields map[string]interface{}
+	json.Unmarshal([]byte(first), &fields)
+	reordered := `{"dek": "` + fields["dek"].(string) + `", "kid": "` + fields["kid"].(string) +
+		`", "_enc": 1, "data": "` + fields["data"].(string) + `"}`
+	if got, err := Decrypt(reordered); err != nil || got != plaintext {
+		t.Fatalf("Decrypt(reordered) = %q, %v", got, err)
+	}
+
+	// 已加密的内容不重复加密
+	if again, _ := Encrypt(first); again != first {
+		t.Fatal("Encrypt must not double-encrypt")
+	}
+}
+
+func TestEncryptLookalikePlaintext(t *testing.T) {
+	useKeys(t, GenerateMasterKey())
+
+	cases := []string{
+		// 配置中恰好带有 _enc 字段
+		`{"_enc":1,"app_secret":"s3cr3t"}`,
+		// 信封格式齐全，但不是当前主密钥环加密的
+		`{"_enc":1,"kid":"deadbeef","dek":"c2VjcmV0","data":"s3cr3t"}`,
+		`{"_enc":2,"kid":"` + KeyID() + `","dek":"c2VjcmV0","data":"s3cr3t"}`,
+	}
+	for _, plaintext := range cases {
+		ciphertext, err := Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt(%s): %v", plaintext, err)
+		}
+		if strings.Contains(ciphertext, "s3cr3t") {
+			t.Fatalf("lookalike plaintext stored unencrypted: %s", ciphertext)
+		}
+		if got, err := Decrypt(ciphertext); err != nil || got != plaintext {
+			t.Fatalf("Decrypt = %q, %v, want %q", got, err, plaintext)
+		}
+	}
+
+	if IsEncrypted(cases[0]) {
+		t.Fatal("plaintext with extra fields must not be treated as an envelope")
+	}
+}
+
+func TestPlaintextPassthrough(t *testing.T) {
+	useKeys(t, "")
+
+	plaintext := `{"key_id":"ABC"}`
+	if got, _ := Encrypt(plaintext); got != plaintext {
+		t.Fatalf("without master key Encrypt must be a no-op, got %s", got)
+	}
+	if got, _ := Decrypt(plaintext); got != plaintext {
+		t.Fatalf("plaintext must be returned unchanged, got %s", got)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	oldKey, newKey := GenerateMasterKey(), GenerateMasterKey()
+	useKeys(t, oldKey)
+	plaintext := `{"master_secret":"abc"}`
+	ciphertext, _ := Encrypt(plaintext)
+	oldID := KeyID()
+
+	// 新主密钥上线，旧主密钥保留为历史密钥
+	useKeys(t, newKey, oldKey)
+	if !NeedsRotation(ciphertext) || !NeedsRotation(plaintext) {
+		t.Fatal("old ciphertext and plaintext must need rotation")
+	}
+	rotated, err := Rotate(ciphertext)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if NeedsRotation(rotated) || KeyID() == oldID {
+		t.Fatal("rotated value must use the current key")
+	}
+
+	// 移除旧主密钥后，旧密文无法解密，新密文正常
+	useKeys(t, newKey)
+	if _, err := Decrypt(ciphertext); !errors.Is(err, ErrUnknownMasterKey) {
+		t.Fatalf("expected ErrUnknownMasterKey, got %v", err)
+	}
+	if got, err := Decrypt(rotated); err != nil || got != plaintext {
+		t.Fatalf("Decrypt(rotated) = %q, %v", got, err)
+	}
+
+	useKeys(t, "")
+	if _, err := Decrypt(rotated); !errors.Is(err, ErrNoMasterKey) {
+		t.Fatalf("expected ErrNoMasterKey, got %v", err)
+	}
+}
+
+func TestInvalidMasterKey(t *testing.T) {
+	if _, err := newKeyring("c2hvcnQ=", nil); err == nil {
+		t.Fatal("short key must be rejected")
+	}
+	if _, err := newKeyring("", []string{GenerateMasterKey()}); err == nil {
+		t.Fatal("previous keys without current key must be rejected")
+	}
+}
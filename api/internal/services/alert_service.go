@@ -0,0 +1,577 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/doopush/doopush/api/internal/database"
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/internal/push"
+	"github.com/doopush/doopush/api/pkg/logger"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAlertRuleNotFound = errors.New("告警规则不存在")
+)
+
+// 告警规则类型
+const (
+	// AlertTypeFailureRate 窗口内推送失败率（百分比）超过阈值
+	AlertTypeFailureRate = "failure_rate"
+	// AlertTypeErrorCode 窗口内指定错误代码出现次数达到阈值，默认检测厂商鉴权失败
+	AlertTypeErrorCode = "error_code"
+	// AlertTypeCallbackFailureRate 当日厂商回执失败率（百分比）超过阈值
+	AlertTypeCallbackFailureRate = "callback_failure_rate"
+	// AlertTypeCertExpiry APNs 证书剩余有效天数低于阈值
+	AlertTypeCertExpiry = "cert_expiry"
+)
+
+// 告警状态，同时用作告警收件箱条目的状态
+const (
+	AlertStatusFiring   = "firing"
+	AlertStatusResolved = "resolved"
+)
+
+// InboxTypeAlert 告警收件箱条目
+const InboxTypeAlert = "alert"
+
+const (
+	// alertEvaluateInterval 调度器每轮都会调用 EvaluateDue，实际评估间隔
+	alertEvaluateInterval = time.Minute
+	// defaultAlertErrorCodes 厂商鉴权失败的错误代码（Android 各厂商、APNs）
+	defaultAlertErrorCodes = "AUTHENTICATION_ERROR,AUTH_ERROR"
+)
+
+var (
+	alertEvaluateRunning atomic.Bool
+	alertLastEvaluated   atomic.Int64
+)
+
+// AlertRuleParams 告警规则参数
+type AlertRuleParams struct {
+	Name          string
+	Type          string
+	Channel       string
+	ErrorCode     string
+	Threshold     float64
+	WindowMinutes int
+	MinSamples    int
+}
+
+// WebhookAlertEventData 告警事件内容
+type WebhookAlertEventData struct {
+	AlertID    uint       `json:"alert_id" example:"7"`
+	RuleID     uint       `json:"rule_id" example:"3"`
+	RuleName   string     `json:"rule_name" example:"华为失败率过高"`
+	Type       string     `json:"type" example:"failure_rate"`
+	Subject    string     `json:"subject" example:"huawei"`
+	Status     string     `json:"status" example:"firing"`
+	Value      float64    `json:"value" example:"35.5"`
+	Threshold  float64    `json:"threshold" example:"20"`
+	Message    string     `json:"message"`
+	FiredAt    time.Time  `json:"fired_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// alertObservation 一次评估中单个对象（通道、厂商）的观测结果
+type alertObservation struct {
+	Subject  string
+	Value    float64
+	Breached bool
+	Message  string
+}
+
+// AlertService 告警服务
+type AlertService struct{}
+
+// NewAlertService 创建告警服务
+func NewAlertService() *AlertService {
+	return &AlertService{}
+}
+
+// GetRules 获取应用的告警规则
+func (s *AlertService) GetRules(appID uint) ([]models.AlertRule, error) {
+	var rules []models.AlertRule
+	if err := database.DB.Where("app_id = ?", appID).Order("id ASC").Find(&rules).Error; err != nil {
+		return nil, errors.New("获取告警规则失败")
+	}
+	return rules, nil
+}
+
+// GetRule 获取告警规则
+func (s *AlertService) GetRule(appID, ruleID uint) (*models.AlertRule, error) {
+	var rule models.AlertRule
+	if err := database.DB.Where("id = ? AND app_id = ?", ruleID, appID).First(&rule).Error; err != nil {
+		return nil, ErrAlertRuleNotFound
+	}
+	return &rule, nil
+}
+
+// CreateRule 创建告警规则
+func (s *AlertService) CreateRule(appID uint, params AlertRuleParams) (*models.AlertRule, error) {
+	if err := normalizeAlertRule(&params); err != nil {
+		return nil, err
+	}
+
+	rule := &models.AlertRule{
+		AppID:         appID,
+		Name:          params.Name,
+		Type:          params.Type,
+		Channel:       params.Channel,
+		ErrorCode:     params.ErrorCode,
+		Threshold:     params.Threshold,
+		WindowMinutes: params.WindowMinutes,
+		MinSamples:    params.MinSamples,
+		Status:        1,
+	}
+	if err := database.DB.Create(rule).Error; err != nil {
+		return nil, errors.New("告警规则创建失败")
+	}
+	return rule, nil
+}
+
+// UpdateRule 更新告警规则，停用时恢复该规则正在触发的告警
+func (s *AlertService) UpdateRule(appID, ruleID uint, params AlertRuleParams, status *int) (*models.AlertRule, error) {
+	rule, err := s.GetRule(appID, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := normalizeAlertRule(&params); err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{
+		"name":           params.Name,
+		"type":           params.Type,
+		"channel":        params.Channel,
+		"error_code":     params.ErrorCode,
+		"threshold":      params.Threshold,
+		"window_minutes": params.WindowMinutes,
+		"min_samples":    params.MinSamples,
+	}
+	if status != nil {
+		updates["status"] = *status
+	}
+	if err := database.DB.Model(rule).Updates(updates).Error; err != nil {
+		return nil, errors.New("告警规则更新失败")
+	}
+
+	if (status != nil && *status == 0) || params.Type != rule.Type {
+		s.resolveRuleAlerts(rule.ID)
+	}
+	return s.GetRule(appID, ruleID)
+}
+
+// DeleteRule 删除告警规则，并恢复该规则正在触发的告警
+func (s *AlertService) DeleteRule(appID, ruleID uint) error {
+	rule, err := s.GetRule(appID, ruleID)
+	if err != nil {
+		return err
+	}
+	if err := database.DB.Delete(rule).Error; err != nil {
+		return errors.New("删除告警规则失败")
+	}
+	s.resolveRuleAlerts(rule.ID)
+	return nil
+}
+
+// GetAlerts 获取应用的告警记录
+func (s *AlertService) GetAlerts(appID uint, status string, ruleID uint, page, pageSize int) ([]models.Alert, int64, error) {
+	query := database.DB.Model(&models.Alert{}).Where("app_id = ?", appID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if ruleID > 0 {
+		query = query.Where("rule_id = ?", ruleID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.New("获取告警记录失败")
+	}
+	var alerts []models.Alert
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&alerts).Error; err != nil {
+		return nil, 0, errors.New("获取告警记录失败")
+	}
+	return alerts, total, nil
+}
+
+// EvaluateDue 由调度器定期调用，距上次评估超过 alertEvaluateInterval 时评估所有启用的告警规则
+func (s *AlertService) EvaluateDue() {
+	now := time.Now()
+	if now.Unix()-alertLastEvaluated.Load() < int64(alertEvaluateInterval/time.Second) {
+		return
+	}
+	if !alertEvaluateRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer alertEvaluateRunning.Store(false)
+	alertLastEvaluated.Store(now.Unix())
+
+	var rules []models.AlertRule
+	if err := database.DB.Where("status = 1").Find(&rules).Error; err != nil {
+		logger.Error("获取告警规则失败", "error", err)
+		return
+	}
+	for i := range rules {
+		observations, err := s.observe(&rules[i], now)
+		if err != nil {
+			logger.Error("评估告警规则失败", "app_id", rules[i].AppID, "rule_id", rules[i].ID, "error", err)
+			continue
+		}
+		s.apply(&rules[i], observations)
+	}
+}
+
+// observe 按规则类型计算各对象的观测值
+func (s *AlertService) observe(rule *models.AlertRule, now time.Time) ([]alertObservation, error) {
+	switch rule.Type {
+	case AlertTypeFailureRate:
+		return s.observeFailureRate(rule, now)
+	case AlertTypeErrorCode:
+		return s.observeErrorCode(rule, now)
+	case AlertTypeCallbackFailureRate:
+		return s.observeCallbackFailureRate(rule)
+	case AlertTypeCertExpiry:
+		return s.observeCertExpiry(rule, now)
+	}
+	return nil, fmt.Errorf("不支持的告警规则类型: %s", rule.Type)
+}
+
+// resultsInWindow 规则窗口内的推送结果，按推送通道过滤，不含沙箱推送
+func resultsInWindow(rule *models.AlertRule, now time.Time) *gorm.DB {
+	query := database.DB.Model(&models.PushResult{}).
+		Joins("JOIN push_logs ON push_logs.id = push_results.push_log_id").
+		Where("push_results.app_id = ? AND push_results.created_at >= ? AND push_logs.sandbox = ?", rule.AppID,
+			now.Add(-time.Duration(rule.WindowMinutes)*time.Minute), false)
+	if rule.Channel != "" {
+		query = query.Where("push_logs.channel = ?", rule.Channel)
+	}
+	return query
+}
+
+func (s *AlertService) observeFailureRate(rule *models.AlertRule, now time.Time) ([]alertObservation, error) {
+	var rows []struct {
+		Subject string
+		Total   int64
+		Failed  int64
+	}
+	if err := resultsInWindow(rule, now).
+		Select("push_logs.channel AS subject, COUNT(*) AS total, SUM(CASE WHEN push_results.success THEN 0 ELSE 1 END) AS failed").
+		Group("push_logs.channel").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	observations := make([]alertObservation, 0, len(rows))
+	for _, row := range rows {
+		rate, breached := rateBreached(row.Failed, row.Total, rule.Threshold, rule.MinSamples)
+		observations = append(observations, alertObservation{
+			Subject:  row.Subject,
+			Value:    rate,
+			Breached: breached,
+			Message: fmt.Sprintf("%s 通道最近 %d 分钟推送失败率 %.1f%%（%d/%d），超过阈值 %g%%",
+				row.Subject, rule.WindowMinutes, rate, row.Failed, row.Total, rule.Threshold),
+		})
+	}
+	return observations, nil
+}
+
+func (s *AlertService) observeErrorCode(rule *models.AlertRule, now time.Time) ([]alertObservation, error) {
+	var rows []struct {
+		Subject string
+		Total   int64
+	}
+	if err := resultsInWindow(rule, now).
+		Where("push_results.success = ? AND push_results.error_code IN ?", false, strings.Split(rule.ErrorCode, ",")).
+		Select("push_logs.channel AS subject, COUNT(*) AS total").
+		Group("push_logs.channel").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	observations := make([]alertObservation, 0, len(rows))
+	for _, row := range rows {
+		observations = append(observations, alertObservation{
+			Subject:  row.Subject,
+			Value:    float64(row.Total),
+			Breached: float64(row.Total) >= rule.Threshold,
+			Message: fmt.Sprintf("%s 通道最近 %d 分钟出现 %d 次 %s，请检查推送配置中的凭据是否过期",
+				row.Subject, rule.WindowMinutes, row.Total, rule.ErrorCode),
+		})
+	}
+	return observations, nil
+}
+
+// observeCallbackFailureRate 厂商回执按天统计，窗口为当天（UTC）
+func (s *AlertService) observeCallbackFailureRate(rule *models.AlertRule) ([]alertObservation, error) {
+	query := database.DB.Where("app_id = ? AND date = ?", rule.AppID, todayUTC())
+	if rule.Channel != "" {
+		query = query.Where("vendor = ?", rule.Channel)
+	}
+	var stats []models.CallbackStatistics
+	if err := query.Find(&stats).Error; err != nil {
+		return nil, err
+	}
+
+	observations := make([]alertObservation, 0, len(stats))
+	for _, stat := range stats {
+		rate, breached := rateBreached(int64(stat.FailureCount), int64(stat.TotalCount), rule.Threshold, rule.MinSamples)
+		observations = append(observations, alertObservation{
+			Subject:  stat.Vendor,
+			Value:    rate,
+			Breached: breached,
+			Message: fmt.Sprintf("%s 今日回执失败率 %.1f%%（%d/%d），超过阈值 %g%%",
+				stat.Vendor, rate, stat.FailureCount, stat.TotalCount, rule.Threshold),
+		})
+	}
+	return observations, nil
+}
+
+// observeCertExpiry 检查 APNs 证书（cert_pem）的剩余有效期，使用 P8 密钥的配置不会过期
+func (s *AlertService) observeCertExpiry(rule *models.AlertRule, now time.Time) ([]alertObservation, error) {
+	var config models.AppConfig
+	if err := database.DB.Where("app_id = ? AND platform = ? AND channel = ? AND status = 1", rule.AppID, "ios", "apns").
+		First(&config).Error; err != nil {
+		return nil, nil
+	}
+	var apnsConfig push.APNsConfig
+	if err := json.Unmarshal([]byte(config.Config), &apnsConfig); err != nil || apnsConfig.CertPEM == "" {
+		return nil, nil
+	}
+	notAfter, err := push.CertificateNotAfter(apnsConfig.CertPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	days, breached := certExpiryBreached(notAfter, now, rule.Threshold)
+	message := fmt.Sprintf("APNs 证书将于 %s 过期，剩余 %d 天", notAfter.Format("2006-01-02"), int(days))
+	if days <= 0 {
+		message = fmt.Sprintf("APNs 证书已于 %s 过期", notAfter.Format("2006-01-02"))
+	}
+	return []alertObservation{{Subject: "apns", Value: days, Breached: breached, Message: message}}, nil
+}
+
+// rateBreached 计算失败率（百分比），样本数不足 minSamples 时不触发
+func rateBreached(failed, total int64, threshold float64, minSamples int) (float64, bool) {
+	if total == 0 {
+		return 0, false
+	}
+	rate := math.Round(float64(failed)*1000/float64(total)) / 10
+	return rate, total >= int64(minSamples) && rate > threshold
+}
+
+// certExpiryBreached 计算证书剩余天数（向下取整，过期为负数），低于阈值天数时触发
+func certExpiryBreached(notAfter, now time.Time, thresholdDays float64) (float64, bool) {
+	days := math.Floor(notAfter.Sub(now).Hours() / 24)
+	return days, days < thresholdDays
+}
+
+// apply 根据观测结果触发新告警、更新或恢复已触发的告警。未出现在观测结果中的对象视为已恢复
+func (s *AlertService) apply(rule *models.AlertRule, observations []alertObservation) {
+	var firing []models.Alert
+	if err := database.DB.Where("rule_id = ? AND status = ?", rule.ID, AlertStatusFiring).Find(&firing).Error; err != nil {
+		logger.Error("获取告警失败", "app_id", rule.AppID, "rule_id", rule.ID, "error", err)
+		return
+	}
+	active := make(map[string]*models.Alert, len(firing))
+	for i := range firing {
+		active[firing[i].Subject] = &firing[i]
+	}
+
+	breached := make(map[string]bool)
+	for _, observation := range observations {
+		if !observation.Breached {
+			continue
+		}
+		breached[observation.Subject] = true
+		if alert, ok := active[observation.Subject]; ok {
+			database.DB.Model(alert).Updates(map[string]interface{}{
+				"value": observation.Value, "message": observation.Message,
+			})
+			continue
+		}
+		s.fire(rule, observation)
+	}
+
+	for subject, alert := range active {
+		if !breached[subject] {
+			s.resolve(alert)
+		}
+	}
+}
+
+// fire 创建告警并通知。FiringKey 唯一索引保证多实例同时评估时只创建一条
+func (s *AlertService) fire(rule *models.AlertRule, observation alertObservation) {
+	firingKey := fmt.Sprintf("%d:%s", rule.ID, observation.Subject)
+	alert := &models.Alert{
+		AppID:     rule.AppID,
+		RuleID:    rule.ID,
+		RuleName:  rule.Name,
+		Type:      rule.Type,
+		Subject:   observation.Subject,
+		Status:    AlertStatusFiring,
+		Value:     observation.Value,
+		Threshold: rule.Threshold,
+		Message:   observation.Message,
+		FiringKey: &firingKey,
+		FiredAt:   time.Now(),
+	}
+	if err := database.DB.Create(alert).Error; err != nil {
+		if !errors.Is(err, gorm.ErrDuplicatedKey) && !isDuplicateEntryError(err) {
+			logger.Error("创建告警失败", "app_id", rule.AppID, "rule_id", rule.ID, "error", err)
+		}
+		return
+	}
+	logger.Warn("告警触发", "app_id", alert.AppID, "rule_id", alert.RuleID, "alert_id", alert.ID,
+		"subject", alert.Subject, "value", alert.Value, "message", alert.Message)
+
+	if err := s.notifyMembers(alert); err != nil {
+		logger.Error("发送告警收件箱通知失败", "app_id", alert.AppID, "alert_id", alert.ID, "error", err)
+	}
+	NewWebhookService().Emit(alertEvent(WebhookEventAlertFiring, alert))
+}
+
+// resolve 恢复告警并通知，已被其他实例恢复时不重复通知
+func (s *AlertService) resolve(alert *models.Alert) {
+	now := time.Now()
+	result := database.DB.Model(&models.Alert{}).
+		Where("id = ? AND status = ?", alert.ID, AlertStatusFiring).
+		Updates(map[string]interface{}{"status": AlertStatusResolved, "resolved_at": now, "firing_key": nil})
+	if result.Error != nil {
+		logger.Error("恢复告警失败", "app_id", alert.AppID, "alert_id", alert.ID, "error", result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+	alert.Status = AlertStatusResolved
+	alert.ResolvedAt = &now
+	alert.FiringKey = nil
+	logger.Info("告警恢复", "app_id", alert.AppID, "rule_id", alert.RuleID, "alert_id", alert.ID, "subject", alert.Subject)
+
+	database.DB.Model(&models.AppInvitation{}).
+		Where("alert_id = ? AND status = ?", alert.ID, AlertStatusFiring).
+		Updates(map[string]interface{}{"status": AlertStatusResolved, "responded_at": now})
+	NewWebhookService().Emit(alertEvent(WebhookEventAlertResolved, alert))
+}
+
+// resolveRuleAlerts 恢复规则正在触发的所有告警，用于规则停用、删除或类型变更
+func (s *AlertService) resolveRuleAlerts(ruleID uint) {
+	var firing []models.Alert
+	database.DB.Where("rule_id = ? AND status = ?", ruleID, AlertStatusFiring).Find(&firing)
+	for i := range firing {
+		s.resolve(&firing[i])
+	}
+}
+
+// notifyMembers 向应用的所有者和开发者发送告警收件箱条目
+func (s *AlertService) notifyMembers(alert *models.Alert) error {
+	var app models.App
+	if err := database.DB.First(&app, alert.AppID).Error; err != nil {
+		return errors.New("应用不存在")
+	}
+	var permissions []models.UserAppPermission
+	if err := database.DB.Preload("User").
+		Where("app_id = ? AND role IN ?", alert.AppID, []string{"owner", "developer"}).
+		Find(&permissions).Error; err != nil {
+		return err
+	}
+
+	for _, permission := range permissions {
+		item := models.AppInvitation{
+			Type: InboxTypeAlert, AlertID: &alert.ID,
+			AppID: alert.AppID, InviteeID: permission.UserID,
+			Role: permission.Role, Status: AlertStatusFiring,
+			AppNameSnapshot: app.Name, AppIconSnapshot: app.AppIcon,
+			InviterNameSnapshot: "DooPush 告警",
+			InviteeNameSnapshot: displayUserName(permission.User), InviteeEmailSnapshot: permission.User.Email,
+			Message: alert.RuleName + "：" + alert.Message,
+		}
+		if err := database.DB.Create(&item).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// alertEvent 由告警构造告警事件
+func alertEvent(eventType string, alert *models.Alert) WebhookEvent {
+	return WebhookEvent{
+		AppID: alert.AppID,
+		Type:  eventType,
+		Data: WebhookAlertEventData{
+			AlertID:    alert.ID,
+			RuleID:     alert.RuleID,
+			RuleName:   alert.RuleName,
+			Type:       alert.Type,
+			Subject:    alert.Subject,
+			Status:     alert.Status,
+			Value:      alert.Value,
+			Threshold:  alert.Threshold,
+			Message:    alert.Message,
+			FiredAt:    alert.FiredAt,
+			ResolvedAt: alert.ResolvedAt,
+		},
+	}
+}
+
+// normalizeAlertRule 校验告警规则参数并补充默认值
+func normalizeAlertRule(params *AlertRuleParams) error {
+	params.Name = strings.TrimSpace(params.Name)
+	params.Channel = strings.TrimSpace(params.Channel)
+	if params.Name == "" {
+		return errors.New("告警规则名称不能为空")
+	}
+	if params.WindowMinutes == 0 {
+		params.WindowMinutes = 15
+	}
+	if params.WindowMinutes < 1 || params.WindowMinutes > 1440 {
+		return errors.New("统计窗口需在 1-1440 分钟之间")
+	}
+	if params.MinSamples <= 0 {
+		params.MinSamples = 20
+	}
+
+	switch params.Type {
+	case AlertTypeFailureRate, AlertTypeCallbackFailureRate:
+		if params.Threshold <= 0 || params.Threshold >= 100 {
+			return errors.New("失败率阈值需在 0-100 之间")
+		}
+		params.ErrorCode = ""
+	case AlertTypeErrorCode:
+		if params.Threshold == 0 {
+			params.Threshold = 1
+		}
+		if params.Threshold < 1 {
+			return errors.New("错误出现次数阈值不能小于 1")
+		}
+		codes := make([]string, 0)
+		for _, code := range strings.Split(params.ErrorCode, ",") {
+			if code = strings.TrimSpace(code); code != "" {
+				codes = append(codes, code)
+			}
+		}
+		if len(codes) == 0 {
+			params.ErrorCode = defaultAlertErrorCodes
+		} else {
+			params.ErrorCode = strings.Join(codes, ",")
+		}
+	case AlertTypeCertExpiry:
+		if params.Threshold == 0 {
+			params.Threshold = 30
+		}
+		if params.Threshold < 1 {
+			return errors.New("证书剩余天数阈值不能小于 1")
+		}
+		params.Channel = "apns"
+		params.ErrorCode = ""
+	default:
+		return fmt.Errorf("不支持的告警规则类型: %s", params.Type)
+	}
+	return nil
+}
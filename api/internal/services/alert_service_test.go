@@ -0,0 +1,74 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateBreached(t *testing.T) {
+	cases := []struct {
+		failed, total int64
+		threshold     float64
+		minSamples    int
+		wantRate      float64
+		wantBreached  bool
+	}{
+		{failed: 0, total: 0, threshold: 20, minSamples: 20, wantRate: 0, wantBreached: false},
+		{failed: 7, total: 20, threshold: 20, minSamples: 20, wantRate: 35, wantBreached: true},
+		{failed: 4, total: 20, threshold: 20, minSamples: 20, wantRate: 20, wantBreached: false},
+		{failed: 5, total: 10, threshold: 20, minSamples: 20, wantRate: 50, wantBreached: false},
+		{failed: 1, total: 3, threshold: 20, minSamples: 1, wantRate: 33.3, wantBreached: true},
+	}
+	for _, c := range cases {
+		rate, breached := rateBreached(c.failed, c.total, c.threshold, c.minSamples)
+		if rate != c.wantRate || breached != c.wantBreached {
+			t.Errorf("rateBreached(%d, %d, %g, %d) = %g, %v, want %g, %v",
+				c.failed, c.total, c.threshold, c.minSamples, rate, breached, c.wantRate, c.wantBreached)
+		}
+	}
+}
+
+func TestCertExpiryBreached(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	if days, breached := certExpiryBreached(now.Add(45*24*time.Hour), now, 30); days != 45 || breached {
+		t.Fatalf("45 days left: got %g, %v", days, breached)
+	}
+	if days, breached := certExpiryBreached(now.Add(29*24*time.Hour+time.Hour), now, 30); days != 29 || !breached {
+		t.Fatalf("29 days left: got %g, %v", days, breached)
+	}
+	if days, breached := certExpiryBreached(now.Add(-time.Hour), now, 30); days >= 0 || !breached {
+		t.Fatalf("expired: got %g, %v", days, breached)
+	}
+}
+
+func TestNormalizeAlertRule(t *testing.T) {
+	params := AlertRuleParams{Name: " 鉴权失败 ", Type: AlertTypeErrorCode}
+	if err := normalizeAlertRule(&params); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if params.Name != "鉴权失败" || params.ErrorCode != defaultAlertErrorCodes || params.Threshold != 1 || params.WindowMinutes != 15 {
+		t.Fatalf("defaults not applied: %+v", params)
+	}
+
+	params = AlertRuleParams{Name: "证书", Type: AlertTypeCertExpiry, Channel: "huawei"}
+	if err := normalizeAlertRule(&params); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if params.Channel != "apns" || params.Threshold != 30 {
+		t.Fatalf("cert_expiry defaults not applied: %+v", params)
+	}
+
+	invalid := []AlertRuleParams{
+		{Name: "", Type: AlertTypeFailureRate, Threshold: 20},
+		{Name: "失败率", Type: AlertTypeFailureRate, Threshold: 0},
+		{Name: "失败率", Type: AlertTypeFailureRate, Threshold: 120},
+		{Name: "失败率", Type: AlertTypeFailureRate, Threshold: 20, WindowMinutes: 2000},
+		{Name: "未知", Type: "latency", Threshold: 1},
+	}
+	for _, p := range invalid {
+		if err := normalizeAlertRule(&p); err == nil {
+			t.Errorf("normalizeAlertRule(%+v) should fail", p)
+		}
+	}
+}
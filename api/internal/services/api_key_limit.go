@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/pkg/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// apiKeyRedis API密钥频率限制计数使用的 Redis 客户端，为 nil 时不做频率限制
+var apiKeyRedis *redis.Client
+
+// SetAPIKeyRedis 注入API密钥频率限制计数使用的 Redis 客户端
+func SetAPIKeyRedis(rdb *redis.Client) {
+	apiKeyRedis = rdb
+}
+
+// apiKeyRateScript 累加密钥当前分钟的请求数，首次计数时设置过期时间
+var apiKeyRateScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then redis.call('EXPIRE', KEYS[1], ARGV[1]) end
+return count
+`)
+
+// apiKeyRateKey 密钥每分钟请求计数键
+func apiKeyRateKey(keyID uint, now time.Time) string {
+	return fmt.Sprintf("ratelimit:apikey:%d:%s", keyID, now.Format("200601021504"))
+}
+
+// AllowAPIKeyRequest 按每分钟固定窗口检查密钥的请求频率，超过上限返回 false。
+// 未设置上限或 Redis 不可用时放行，与推送频控的容错策略一致
+func (s *AppService) AllowAPIKeyRequest(ctx context.Context, key *models.AppAPIKey, now time.Time) bool {
+	if key.RateLimit <= 0 || apiKeyRedis == nil {
+		return true
+	}
+	count, err := apiKeyRateScript.Run(ctx, apiKeyRedis, []string{apiKeyRateKey(key.ID, now)},
+		int((2 * time.Minute).Seconds())).Int()
+	if err != nil {
+		logger.WarnContext(ctx, "API密钥频率检查失败，跳过频率限制", "app_id", key.AppID, "api_key_id", key.ID, "error", err)
+		return true
+	}
+	return count <= key.RateLimit
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/netip"
+	"slices"
 	"strings"
 	"time"
 
@@ -19,6 +21,7 @@ var (
 	ErrEmailUserNotFound = errors.New("该邮箱对应的用户不存在或已被禁用")
 	ErrInvalidAppRole    = errors.New("无效的应用角色")
 	ErrLastOwner         = errors.New("应用必须至少保留一位所有者")
+	ErrInvalidAPIKeyOpts = errors.New("API密钥参数无效")
 )
 
 // AppService 应用服务
@@ -376,8 +379,61 @@ func (s *AppService) GetAppAPIKeys(appID uint, userID uint) ([]models.AppAPIKey,
 	return apiKeys, nil
 }
 
-// CreateAPIKey 创建API密钥
-func (s *AppService) CreateAPIKey(appID uint, userID uint, name string) (*models.AppAPIKey, string, error) {
+// APIKeyOptions API密钥的权限范围、IP白名单和频率限制
+type APIKeyOptions struct {
+	Scopes       []string // 为空时使用客户端 SDK 权限（注册设备、上报统计），不能发送推送
+	AllowedCIDRs []string // CIDR 或单个 IP，为空不限制
+	RateLimit    int      // 每分钟请求上限，0=不限制
+}
+
+// normalizeAPIKeyOptions 校验并规范化密钥选项，返回存储用的逗号分隔列表
+func normalizeAPIKeyOptions(opts APIKeyOptions) (scopes string, cidrs string, err error) {
+	if len(opts.Scopes) == 0 {
+		opts.Scopes = models.SDKAPIKeyScopes
+	}
+	for _, scope := range opts.Scopes {
+		if !slices.Contains(models.APIKeyScopes, scope) {
+			return "", "", fmt.Errorf("%w：不支持的权限范围 %s", ErrInvalidAPIKeyOpts, scope)
+		}
+	}
+	// 按固定顺序存储，去掉重复项
+	var scopeList []string
+	for _, scope := range models.APIKeyScopes {
+		if slices.Contains(opts.Scopes, scope) {
+			scopeList = append(scopeList, scope)
+		}
+	}
+
+	var cidrList []string
+	for _, value := range opts.AllowedCIDRs {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(value)
+			if addrErr != nil {
+				return "", "", fmt.Errorf("%w：无效的IP或CIDR %s", ErrInvalidAPIKeyOpts, value)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		if cidr := prefix.Masked().String(); !slices.Contains(cidrList, cidr) {
+			cidrList = append(cidrList, cidr)
+		}
+	}
+	cidrs = strings.Join(cidrList, ",")
+	if len(cidrs) > 1000 {
+		return "", "", fmt.Errorf("%w：IP白名单过长", ErrInvalidAPIKeyOpts)
+	}
+	if opts.RateLimit < 0 {
+		return "", "", fmt.Errorf("%w：频率限制不能为负数", ErrInvalidAPIKeyOpts)
+	}
+	return strings.Join(scopeList, ","), cidrs, nil
+}
+
+// CreateAPIKey 创建API密钥，test 为 true 时创建测试密钥
+func (s *AppService) CreateAPIKey(appID uint, userID uint, name string, test bool, opts APIKeyOptions) (*models.AppAPIKey, string, error) {
 	// 检查用户权限 (需要developer以上权限)
 	userService := NewUserService()
 	hasPermission, err := userService.CheckAppPermission(userID, appID, "developer")
@@ -387,10 +443,18 @@ func (s *AppService) CreateAPIKey(appID uint, userID uint, name string) (*models
 	if !hasPermission {
 		return nil, "", errors.New("无权限创建API密钥")
 	}
+	scopes, cidrs, err := normalizeAPIKeyOptions(opts)
+	if err != nil {
+		return nil, "", err
+	}
 
 	// 生成API密钥
+	keyPrefix := models.APIKeyPrefixLive
+	if test {
+		keyPrefix = models.APIKeyPrefixTest
+	}
 	keyBody := utils.GenerateAPIKey()
-	apiKey := fmt.Sprintf("dp_live_%s", keyBody)
+	apiKey := keyPrefix + keyBody
 
 	// 提取后缀 (取最后8个字符)
 	keySuffix := keyBody[len(keyBody)-8:]
@@ -399,12 +463,15 @@ func (s *AppService) CreateAPIKey(appID uint, userID uint, name string) (*models
 	}
 
 	appAPIKey := &models.AppAPIKey{
-		AppID:     appID,
-		Name:      name,
-		KeyHash:   utils.HashString(apiKey),
-		KeyPrefix: "dp_live_",
-		KeySuffix: keySuffix,
-		Status:    1,
+		AppID:        appID,
+		Name:         name,
+		KeyHash:      utils.HashString(apiKey),
+		KeyPrefix:    keyPrefix,
+		KeySuffix:    keySuffix,
+		Status:       1,
+		Scopes:       scopes,
+		AllowedCIDRs: cidrs,
+		RateLimit:    opts.RateLimit,
 	}
 
 	if err := database.DB.Create(appAPIKey).Error; err != nil {
@@ -414,6 +481,35 @@ func (s *AppService) CreateAPIKey(appID uint, userID uint, name string) (*models
 	return appAPIKey, apiKey, nil
 }
 
+// UpdateAPIKey 修改API密钥的权限范围、IP白名单和频率限制
+func (s *AppService) UpdateAPIKey(appID uint, keyID uint, userID uint, opts APIKeyOptions) (*models.AppAPIKey, error) {
+	// 检查用户权限 (需要developer以上权限)
+	userService := NewUserService()
+	hasPermission, err := userService.CheckAppPermission(userID, appID, "developer")
+	if err != nil {
+		return nil, errors.New("权限检查失败")
+	}
+	if !hasPermission {
+		return nil, errors.New("无权限修改API密钥")
+	}
+	scopes, cidrs, err := normalizeAPIKeyOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var apiKey models.AppAPIKey
+	if err := database.DB.Where("id = ? AND app_id = ?", keyID, appID).First(&apiKey).Error; err != nil {
+		return nil, errors.New("API密钥不存在")
+	}
+	apiKey.Scopes = scopes
+	apiKey.AllowedCIDRs = cidrs
+	apiKey.RateLimit = opts.RateLimit
+	if err := database.DB.Model(&apiKey).Select("scopes", "allowed_cidrs", "rate_limit").Updates(&apiKey).Error; err != nil {
+		return nil, errors.New("修改API密钥失败")
+	}
+	return &apiKey, nil
+}
+
 // DeleteAPIKey 删除API密钥
 func (s *AppService) DeleteAPIKey(appID uint, keyID uint, userID uint) error {
 	// 检查用户权限 (需要developer以上权限)
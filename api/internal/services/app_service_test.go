@@ -1,6 +1,13 @@
 package services
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/doopush/doopush/api/internal/models"
+)
 
 func TestIsValidAppRole(t *testing.T) {
 	tests := []struct {
@@ -22,3 +29,70 @@ func TestIsValidAppRole(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizeAPIKeyOptions(t *testing.T) {
+	scopes, cidrs, err := normalizeAPIKeyOptions(APIKeyOptions{})
+	if err != nil || scopes != "device:register,stats:report" || cidrs != "" {
+		t.Fatalf("default = %q %q %v, want SDK scopes", scopes, cidrs, err)
+	}
+
+	scopes, cidrs, err = normalizeAPIKeyOptions(APIKeyOptions{
+		Scopes:       []string{"push:send", "device:register", "push:send"},
+		AllowedCIDRs: []string{" 10.1.2.3/16 ", "203.0.113.7", "", "10.1.0.0/16", "2001:db8::1/32"},
+		RateLimit:    60,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scopes != "device:register,push:send" {
+		t.Errorf("scopes = %q", scopes)
+	}
+	if cidrs != "10.1.0.0/16,203.0.113.7/32,2001:db8::/32" {
+		t.Errorf("cidrs = %q", cidrs)
+	}
+
+	for _, opts := range []APIKeyOptions{
+		{Scopes: []string{"push:everything"}},
+		{AllowedCIDRs: []string{"10.0.0.0/33"}},
+		{AllowedCIDRs: []string{"example.com"}},
+		{RateLimit: -1},
+	} {
+		if _, _, err := normalizeAPIKeyOptions(opts); !errors.Is(err, ErrInvalidAPIKeyOpts) {
+			t.Errorf("normalizeAPIKeyOptions(%+v) error = %v, want ErrInvalidAPIKeyOpts", opts, err)
+		}
+	}
+}
+
+func TestAllowAPIKeyRequest(t *testing.T) {
+	s := NewAppService()
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 10, 0, 30, 0, time.UTC)
+	key := &models.AppAPIKey{ID: 1, AppID: 1, RateLimit: 2}
+
+	// 未注入 Redis 时放行
+	SetAPIKeyRedis(nil)
+	for i := 0; i < 3; i++ {
+		if !s.AllowAPIKeyRequest(ctx, key, now) {
+			t.Fatal("request limited without redis")
+		}
+	}
+
+	SetAPIKeyRedis(newCapRedis(t))
+	t.Cleanup(func() { SetAPIKeyRedis(nil) })
+	for i, want := range []bool{true, true, false} {
+		if got := s.AllowAPIKeyRequest(ctx, key, now); got != want {
+			t.Errorf("request %d allowed = %v, want %v", i, got, want)
+		}
+	}
+	// 其他密钥和下一分钟单独计数
+	if !s.AllowAPIKeyRequest(ctx, &models.AppAPIKey{ID: 2, AppID: 1, RateLimit: 2}, now) {
+		t.Error("other key limited")
+	}
+	if !s.AllowAPIKeyRequest(ctx, key, now.Add(time.Minute)) {
+		t.Error("next minute limited")
+	}
+	// 未设置上限不计数
+	if !s.AllowAPIKeyRequest(ctx, &models.AppAPIKey{ID: 1, AppID: 1}, now) {
+		t.Error("unlimited key limited")
+	}
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/doopush/doopush/api/internal/database"
+	"github.com/doopush/doopush/api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 角标操作类型
+const (
+	BadgeSet       = "set"       // 设置为指定值
+	BadgeIncrement = "increment" // 在服务端计数上累加
+	BadgeReset     = "reset"     // 清零
+)
+
+// BadgeValue 推送请求中的角标取值，支持数字、"+N" 和 "reset"
+type BadgeValue struct {
+	Op     string
+	Amount int
+}
+
+// ParseBadgeValue 解析角标取值：数字为绝对值，"+N" 为累加，"reset" 为清零
+func ParseBadgeValue(s string) (BadgeValue, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == BadgeReset:
+		return BadgeValue{Op: BadgeReset}, nil
+	case strings.HasPrefix(s, "+"):
+		n, err := strconv.Atoi(s[1:])
+		if err != nil || n <= 0 {
+			return BadgeValue{}, errors.New("无效的角标增量")
+		}
+		return BadgeValue{Op: BadgeIncrement, Amount: n}, nil
+	default:
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return BadgeValue{}, errors.New("无效的角标值")
+		}
+		return BadgeValue{Op: BadgeSet, Amount: n}, nil
+	}
+}
+
+// UnmarshalJSON 同时接受数字和字符串形式的角标
+func (b *BadgeValue) UnmarshalJSON(data []byte) error {
+	var n int
+	if err := json.Unmarshal(data, &n); err == nil {
+		if n < 0 {
+			return errors.New("无效的角标值")
+		}
+		*b = BadgeValue{Op: BadgeSet, Amount: n}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return errors.New("角标必须为数字、\"+N\" 或 \"reset\"")
+	}
+	v, err := ParseBadgeValue(s)
+	if err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+// String 按 ParseBadgeValue 接受的格式输出角标
+func (b BadgeValue) String() string {
+	switch b.Op {
+	case BadgeIncrement:
+		return "+" + strconv.Itoa(b.Amount)
+	case BadgeReset:
+		return BadgeReset
+	default:
+		return strconv.Itoa(b.Amount)
+	}
+}
+
+// MarshalJSON 按请求格式输出角标
+func (b BadgeValue) MarshalJSON() ([]byte, error) {
+	if b.Op == BadgeSet || b.Op == "" {
+		return json.Marshal(b.Amount)
+	}
+	return json.Marshal(b.String())
+}
+
+// Apply 根据当前计数计算新的角标值
+func (b BadgeValue) Apply(current int) int {
+	switch b.Op {
+	case BadgeIncrement:
+		return current + b.Amount
+	case BadgeReset:
+		return 0
+	default:
+		return b.Amount
+	}
+}
+
+// applyDeviceBadge 按角标取值更新设备的服务端角标计数，返回新的角标值。
+// 在投递时调用，暂存、等待审批后被拒绝、取消或过期的推送不会改变计数
+func applyDeviceBadge(deviceID uint, spec string) (int, error) {
+	badge, err := ParseBadgeValue(spec)
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var device models.Device
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "badge_count").
+			First(&device, deviceID).Error; err != nil {
+			return err
+		}
+		count = badge.Apply(device.BadgeCount)
+		return tx.Model(&device).Update("badge_count", count).Error
+	})
+	if err != nil {
+		return 0, errors.New("更新角标计数失败")
+	}
+	return count, nil
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBadgeValueUnmarshal(t *testing.T) {
+	tests := []struct {
+		input   string
+		current int
+		want    int
+	}{
+		{input: `5`, current: 3, want: 5},
+		{input: `"+1"`, current: 3, want: 4},
+		{input: `"+2"`, current: 0, want: 2},
+		{input: `"reset"`, current: 7, want: 0},
+		{input: `"0"`, current: 7, want: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			var badge BadgeValue
+			if err := json.Unmarshal([]byte(test.input), &badge); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := badge.Apply(test.current); got != test.want {
+				t.Fatalf("Apply(%d) = %d, want %d", test.current, got, test.want)
+			}
+		})
+	}
+}
+
+func TestBadgeValueUnmarshal_Invalid(t *testing.T) {
+	for _, input := range []string{`-1`, `"+0"`, `"-1"`, `"abc"`, `true`} {
+		var badge BadgeValue
+		if err := json.Unmarshal([]byte(input), &badge); err == nil {
+			t.Fatalf("input %s expected error, got nil", input)
+		}
+	}
+}
+
+func TestBadgeValueStringRoundTrip(t *testing.T) {
+	for _, spec := range []string{"3", "0", "+2", "reset"} {
+		badge, err := ParseBadgeValue(spec)
+		if err != nil {
+			t.Fatalf("ParseBadgeValue(%q): %v", spec, err)
+		}
+		if got := badge.String(); got != spec {
+			t.Fatalf("String() = %q, want %q", got, spec)
+		}
+	}
+}
@@ -88,7 +88,7 @@ func (a *statsAccumulator) flush() {
 			}),
 		}).Create(&row).Error
 		if err != nil {
-			logger.Error("更新回执统计失败", b.vendor, err)
+			logger.Error("更新回执统计失败", "channel", b.vendor, "error", err)
 		}
 	}
 }
@@ -133,6 +133,7 @@ func (s *CallbackService) ProcessHuaweiCallback(callback interface{}) error {
 	deviceMap := s.findDevicesByTokens("huawei", tokenStrs)
 
 	stats := newStatsAccumulator()
+	events := make([]WebhookEvent, 0)
 	rows := make([]models.HuaweiCallback, 0, len(tokenStrs))
 	for _, token := range tokenStrs {
 		device, ok := deviceMap[token]
@@ -162,9 +163,11 @@ func (s *CallbackService) ProcessHuaweiCallback(callback interface{}) error {
 			RawData:      string(rawData),
 		})
 		stats.add(device.AppID, "huawei", todayUTC(), statDelta{total: 1, success: 1, delivery: 1})
+		events = append(events, pushEvent(WebhookEventPushDelivered, pushLog))
 	}
 	createInBatches(rows)
 	stats.flush()
+	NewWebhookService().EmitEvents(events)
 	return nil
 }
 
@@ -191,6 +194,7 @@ func (s *CallbackService) ProcessHonorCallback(callback interface{}) error {
 	deviceMap := s.findDevicesByTokens("honor", tokens)
 
 	stats := newStatsAccumulator()
+	events := make([]WebhookEvent, 0)
 	rows := make([]models.HonorCallback, 0, len(statuses))
 	for _, si := range statuses {
 		status, ok := si.(map[string]interface{})
@@ -236,9 +240,13 @@ func (s *CallbackService) ProcessHonorCallback(callback interface{}) error {
 			failure:  boolToInt(!success),
 			delivery: boolToInt(success),
 		})
+		if success {
+			events = append(events, pushEvent(WebhookEventPushDelivered, pushLog))
+		}
 	}
 	createInBatches(rows)
 	stats.flush()
+	NewWebhookService().EmitEvents(events)
 	return nil
 }
 
@@ -296,6 +304,7 @@ func (s *CallbackService) ProcessOppoCallback(callbacks []interface{}) error {
 	deviceMap := s.findDevicesByTokens("oppo", allTokens)
 
 	stats := newStatsAccumulator()
+	events := make([]WebhookEvent, 0)
 	rows := make([]models.OppoCallback, 0)
 	for _, it := range items {
 		for _, regID := range strings.Split(it.regIDs, ",") {
@@ -311,6 +320,8 @@ func (s *CallbackService) ProcessOppoCallback(callbacks []interface{}) error {
 			if !ok {
 				continue
 			}
+			// OPPO 回执：eventType 为 regid_invalid 表示 registration id 无效，其余为送达
+			success := it.eventType != "regid_invalid"
 			rows = append(rows, models.OppoCallback{
 				BaseCallback: models.BaseCallback{
 					AppID:       device.AppID,
@@ -319,7 +330,7 @@ func (s *CallbackService) ProcessOppoCallback(callbacks []interface{}) error {
 					MessageID:   it.messageID,
 					DeviceToken: regID,
 					EventType:   1,
-					Success:     true,
+					Success:     success,
 					Timestamp:   it.timestamp,
 					ProcessedAt: time.Now(),
 				},
@@ -330,11 +341,22 @@ func (s *CallbackService) ProcessOppoCallback(callbacks []interface{}) error {
 				EventTypeName:   it.eventType,
 				RawData:         string(it.raw),
 			})
-			stats.add(device.AppID, "oppo", todayUTC(), statDelta{total: 1, success: 1, delivery: 1})
+			stats.add(device.AppID, "oppo", todayUTC(), statDelta{
+				total:    1,
+				success:  boolToInt(success),
+				failure:  boolToInt(!success),
+				delivery: boolToInt(success),
+			})
+			if success {
+				events = append(events, pushEvent(WebhookEventPushDelivered, pushLog))
+			} else {
+				events = append(events, DeviceEvent(WebhookEventDeviceTokenInvalid, &device))
+			}
 		}
 	}
 	createInBatches(rows)
 	stats.flush()
+	NewWebhookService().EmitEvents(events)
 	return nil
 }
 
@@ -353,6 +375,7 @@ func (s *CallbackService) ProcessVivoCallback(callback map[string]interface{}) e
 	deviceMap := s.findDevicesByTokens("vivo", tokens)
 
 	stats := newStatsAccumulator()
+	events := make([]WebhookEvent, 0)
 	rows := make([]models.VivoCallback, 0, len(callback))
 	for taskID, di := range callback {
 		data, ok := di.(map[string]interface{})
@@ -399,9 +422,13 @@ func (s *CallbackService) ProcessVivoCallback(callback map[string]interface{}) e
 			failure:  boolToInt(!success),
 			delivery: boolToInt(success),
 		})
+		if success {
+			events = append(events, pushEvent(WebhookEventPushDelivered, pushLog))
+		}
 	}
 	createInBatches(rows)
 	stats.flush()
+	NewWebhookService().EmitEvents(events)
 	return nil
 }
 
@@ -424,6 +451,7 @@ func (s *CallbackService) ProcessXiaomiCallback(callback map[string]interface{})
 	deviceMap := s.findDevicesByTokens("xiaomi", allTokens)
 
 	stats := newStatsAccumulator()
+	events := make([]WebhookEvent, 0)
 	rows := make([]models.XiaomiCallback, 0)
 	for msgID, di := range callback {
 		data, ok := di.(map[string]interface{})
@@ -478,18 +506,22 @@ func (s *CallbackService) ProcessXiaomiCallback(callback map[string]interface{})
 				success: boolToInt(success),
 				failure: boolToInt(!success),
 			}
-			if success {
-				if eventType == 2 {
-					delta.click = 1
-				} else {
-					delta.delivery = 1
-				}
+			switch {
+			case !success:
+				events = append(events, DeviceEvent(WebhookEventDeviceTokenInvalid, &device))
+			case eventType == 2:
+				delta.click = 1
+				events = append(events, pushEvent(WebhookEventPushClicked, pushLog))
+			default:
+				delta.delivery = 1
+				events = append(events, pushEvent(WebhookEventPushDelivered, pushLog))
 			}
 			stats.add(device.AppID, "xiaomi", todayUTC(), delta)
 		}
 	}
 	createInBatches(rows)
 	stats.flush()
+	NewWebhookService().EmitEvents(events)
 	return nil
 }
 
@@ -512,6 +544,7 @@ func (s *CallbackService) ProcessMeizuCallback(callback map[string]interface{})
 	deviceMap := s.findDevicesByTokens("meizu", allTokens)
 
 	stats := newStatsAccumulator()
+	events := make([]WebhookEvent, 0)
 	rows := make([]models.MeizuCallback, 0)
 	for msgID, di := range callback {
 		data, ok := di.(map[string]interface{})
@@ -561,14 +594,17 @@ func (s *CallbackService) ProcessMeizuCallback(callback map[string]interface{})
 			delta := statDelta{total: 1, success: 1}
 			if eventType == 2 {
 				delta.click = 1
+				events = append(events, pushEvent(WebhookEventPushClicked, pushLog))
 			} else {
 				delta.delivery = 1
+				events = append(events, pushEvent(WebhookEventPushDelivered, pushLog))
 			}
 			stats.add(device.AppID, "meizu", todayUTC(), delta)
 		}
 	}
 	createInBatches(rows)
 	stats.flush()
+	NewWebhookService().EmitEvents(events)
 	return nil
 }
 
@@ -596,7 +632,7 @@ func (s *CallbackService) findDevicesByTokens(vendor string, tokens []string) ma
 	}
 	var devices []models.Device
 	if err := database.DB.Where("token IN ? AND channel = ?", uniq, vendor).Find(&devices).Error; err != nil {
-		logger.Error("批量查询设备失败", vendor, err)
+		logger.Error("批量查询设备失败", "channel", vendor, "error", err)
 		return result
 	}
 	for _, d := range devices {
@@ -629,7 +665,7 @@ func createInBatches[T any](rows []T) {
 		return
 	}
 	if err := database.DB.CreateInBatches(rows, 200).Error; err != nil {
-		logger.Error("批量保存回执失败", err)
+		logger.Error("批量保存回执失败", "error", err)
 	}
 }
 
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// campaignRedis 分批发送任务租约使用的 Redis 客户端，为 nil 时只在本进程内去重（单实例部署）
+var campaignRedis *redis.Client
+
+// SetCampaignRedis 注入分批发送任务租约使用的 Redis 客户端
+func SetCampaignRedis(rdb *redis.Client) {
+	campaignRedis = rdb
+}
+
+// campaignLeaseTTL 租约的基础有效期，每发送一条续期一次，实例退出后最迟在该时长后由其他实例接管
+const campaignLeaseTTL = 30 * time.Second
+
+// campaignLeaseRenewScript 租约仍由 ARGV[1] 持有时续期，返回 0 表示租约已失去
+var campaignLeaseRenewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// campaignLeaseReleaseScript 租约仍由 ARGV[1] 持有时删除
+var campaignLeaseReleaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// campaignLeaseKey 任务租约键
+func campaignLeaseKey(campaignID uint) string {
+	return fmt.Sprintf("campaign:lease:%d", campaignID)
+}
+
+// acquireCampaignLease 获取任务租约，保证多实例部署时每个任务只有一个发送协程
+func acquireCampaignLease(ctx context.Context, rdb *redis.Client, campaignID uint, token string, ttl time.Duration) (bool, error) {
+	return rdb.SetNX(ctx, campaignLeaseKey(campaignID), token, ttl).Result()
+}
+
+// renewCampaignLease 续期任务租约，返回 false 表示租约已过期或被其他实例持有
+func renewCampaignLease(ctx context.Context, rdb *redis.Client, campaignID uint, token string, ttl time.Duration) (bool, error) {
+	renewed, err := campaignLeaseRenewScript.Run(ctx, rdb, []string{campaignLeaseKey(campaignID)}, token, ttl.Milliseconds()).Int()
+	return renewed == 1, err
+}
+
+// releaseCampaignLease 释放任务租约，其他实例可立即接管
+func releaseCampaignLease(ctx context.Context, rdb *redis.Client, campaignID uint, token string) error {
+	return campaignLeaseReleaseScript.Run(ctx, rdb, []string{campaignLeaseKey(campaignID)}, token).Err()
+}
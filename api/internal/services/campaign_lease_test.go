@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCampaignLease(t *testing.T) {
+	rdb := newCapRedis(t)
+	ctx := context.Background()
+
+	if ok, err := acquireCampaignLease(ctx, rdb, 1, "a", time.Minute); err != nil || !ok {
+		t.Fatalf("first acquire = %v, %v; want true", ok, err)
+	}
+	// 另一个实例拿不到同一任务的租约，也不能续期或释放
+	if ok, _ := acquireCampaignLease(ctx, rdb, 1, "b", time.Minute); ok {
+		t.Error("second instance acquired a held lease")
+	}
+	if ok, _ := renewCampaignLease(ctx, rdb, 1, "b", time.Minute); ok {
+		t.Error("second instance renewed a lease it does not hold")
+	}
+	releaseCampaignLease(ctx, rdb, 1, "b")
+	if ok, _ := renewCampaignLease(ctx, rdb, 1, "a", time.Minute); !ok {
+		t.Error("holder failed to renew its lease")
+	}
+
+	// 持有者释放后其他实例可以接管
+	if err := releaseCampaignLease(ctx, rdb, 1, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := acquireCampaignLease(ctx, rdb, 1, "b", time.Minute); !ok {
+		t.Error("lease not available after release")
+	}
+}
@@ -0,0 +1,480 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/doopush/doopush/api/internal/database"
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/internal/push"
+	"github.com/doopush/doopush/api/pkg/logger"
+	"github.com/doopush/doopush/api/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// 自动全量检查未通过时的暂停原因
+const (
+	PauseFailureRateExceeded = "failure_rate_exceeded"
+	PauseClickRateTooLow     = "click_rate_too_low"
+)
+
+// RolloutOptions 分批发送参数，速率和比例至少设置一项
+type RolloutOptions struct {
+	RatePerMinute  int     `json:"rate_per_minute,omitempty" binding:"omitempty,min=1,max=600000" example:"600"` // 每分钟最多发送的条数
+	Percent        int     `json:"percent,omitempty" binding:"omitempty,min=1,max=100" example:"5"`              // 灰度比例，先发送给该比例的设备
+	PromoteAfter   int     `json:"promote_after_seconds,omitempty" binding:"omitempty,min=60" example:"1800"`    // 灰度发送完成后等待的秒数，到期指标正常则自动全量；0=手动全量
+	MaxFailureRate float64 `json:"max_failure_rate,omitempty" binding:"omitempty,min=0,max=1" example:"0.05"`    // 自动全量允许的最大失败率
+	MinClickRate   float64 `json:"min_click_rate,omitempty" binding:"omitempty,min=0,max=1" example:"0.01"`      // 自动全量要求的最小点击率
+}
+
+// validateRollout 校验分批发送参数
+func validateRollout(opts *RolloutOptions) error {
+	if opts.RatePerMinute < 0 || opts.Percent < 0 || opts.Percent > 100 || opts.PromoteAfter < 0 {
+		return errors.New("无效的分批发送参数")
+	}
+	if opts.MaxFailureRate < 0 || opts.MaxFailureRate > 1 || opts.MinClickRate < 0 || opts.MinClickRate > 1 {
+		return errors.New("无效的分批发送参数")
+	}
+	if opts.RatePerMinute == 0 && (opts.Percent == 0 || opts.Percent == 100) {
+		return errors.New("分批发送需要设置发送速率或灰度比例")
+	}
+	return nil
+}
+
+// canarySize 灰度阶段发送的数量，至少 1 条
+func canarySize(total, percent int) int {
+	if percent <= 0 || percent >= 100 {
+		return total
+	}
+	size := (total*percent + 99) / 100
+	if size < 1 && total > 0 {
+		size = 1
+	}
+	return size
+}
+
+// CampaignService 分批发送任务服务
+type CampaignService struct{}
+
+// NewCampaignService 创建分批发送任务服务
+func NewCampaignService() *CampaignService {
+	return &CampaignService{}
+}
+
+// CampaignProgress 分批发送任务及各状态推送数量
+type CampaignProgress struct {
+	models.PushCampaign
+	Counts   map[string]int64 `json:"counts"`             // 推送日志状态 -> 数量
+	Variants []VariantStats   `json:"variants,omitempty"` // A/B 测试各变体统计
+}
+
+// ErrCampaignBroadcastScope 面向全部设备的任务恢复或全量发送时，API 密钥缺少 push:broadcast 权限
+var ErrCampaignBroadcastScope = errors.New("API密钥缺少权限: " + models.APIKeyScopePushBroadcast)
+
+// activeCampaigns 本进程中正在发送的任务，保证每个任务只有一个发送协程；多实例之间由 Redis 租约互斥
+var activeCampaigns sync.Map
+
+// createCampaign 创建分批发送任务，请求包含变体时同时创建 A/B 测试变体。
+// 需要审批的任务以 pending_approval 状态创建，批准后才开始发送
+func (s *CampaignService) createCampaign(appID, userID uint, req PushRequest, awaitingApproval bool) (*models.PushCampaign, []models.PushVariant, error) {
+	status := "running"
+	if awaitingApproval {
+		status = "pending_approval"
+	}
+	campaign := &models.PushCampaign{
+		AppID:          appID,
+		UserID:         userID,
+		Status:         status,
+		TargetType:     req.Target.Type,
+		Stage:          "full",
+		RolloutPercent: 100,
+	}
+	if opts := req.Rollout; opts != nil {
+		campaign.RatePerMinute = opts.RatePerMinute
+		campaign.PromoteAfter = opts.PromoteAfter
+		campaign.MaxFailureRate = opts.MaxFailureRate
+		campaign.MinClickRate = opts.MinClickRate
+		if opts.Percent > 0 && opts.Percent < 100 {
+			campaign.Stage = "canary"
+			campaign.RolloutPercent = opts.Percent
+		}
+	}
+	if len(req.Variants) > 0 {
+		campaign.WinnerMetric = "click"
+		if ab := req.ABTest; ab != nil {
+			if ab.WinnerMetric != "" {
+				campaign.WinnerMetric = ab.WinnerMetric
+			}
+			if ab.TestPercent > 0 && ab.TestPercent < 100 {
+				// 测试组之外的设备相当于灰度之外的设备，等待胜出变体
+				campaign.Stage = "canary"
+				campaign.RolloutPercent = ab.TestPercent
+				campaign.PromoteAfter = ab.WinnerAfterHours * 3600
+			}
+		}
+	}
+
+	var variants []models.PushVariant
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(campaign).Error; err != nil {
+			return err
+		}
+		for _, input := range req.Variants {
+			variants = append(variants, models.PushVariant{
+				CampaignID: campaign.ID,
+				Key:        input.Key,
+				Title:      input.Title,
+				Content:    input.Content,
+			})
+		}
+		if len(variants) > 0 {
+			return tx.Create(&variants).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("创建分批发送任务失败: %v", err)
+	}
+	return campaign, variants, nil
+}
+
+// startCampaign 启动任务的发送协程，本进程或其他实例已在发送时忽略
+func (s *CampaignService) startCampaign(campaignID uint) {
+	if _, running := activeCampaigns.LoadOrStore(campaignID, struct{}{}); running {
+		return
+	}
+	go func() {
+		defer activeCampaigns.Delete(campaignID)
+
+		token := ""
+		if campaignRedis != nil {
+			ctx := context.Background()
+			token = utils.GenerateAPIKey()
+			acquired, err := acquireCampaignLease(ctx, campaignRedis, campaignID, token, campaignLeaseTTL)
+			if err != nil {
+				// 无法确认其他实例是否在发送，等待下次调度重试，避免超出发送速率
+				logger.Warn("获取分批发送任务租约失败", "campaign_id", campaignID, "error", err)
+				return
+			}
+			if !acquired {
+				return
+			}
+			defer releaseCampaignLease(ctx, campaignRedis, campaignID, token)
+		}
+		s.runCampaign(campaignID, token)
+	}()
+}
+
+// ResumeCampaigns 为进行中的任务启动发送协程（服务重启、静默时段释放或灰度等待到期后继续发送）
+func (s *CampaignService) ResumeCampaigns() {
+	var ids []uint
+	if err := database.DB.Model(&models.PushCampaign{}).Where("status = ?", "running").Pluck("id", &ids).Error; err != nil {
+		logger.Error("获取进行中的分批发送任务失败", "error", err)
+		return
+	}
+	for _, id := range ids {
+		s.startCampaign(id)
+	}
+}
+
+// runCampaign 按速率逐条发送任务中的待发送推送，每条发送前重新读取任务状态以响应暂停和取消。
+// leaseToken 非空时每条发送后续期租约，租约失去后停止发送
+func (s *CampaignService) runCampaign(campaignID uint, leaseToken string) {
+	pushService := NewPushService()
+	pushManager := push.NewPushManager()
+
+	for {
+		var campaign models.PushCampaign
+		if err := database.DB.First(&campaign, campaignID).Error; err != nil || campaign.Status != "running" {
+			return
+		}
+
+		var pushLog models.PushLog
+		err := database.DB.Where("campaign_id = ? AND status = ?", campaignID, "pending").Order("id ASC").First(&pushLog).Error
+		if err != nil {
+			// 当前阶段已发送完毕
+			if !s.advance(&campaign) {
+				return
+			}
+			continue
+		}
+
+		pushService.deliverPushLog(pushManager, pushLog)
+
+		interval := 50 * time.Millisecond
+		if campaign.RatePerMinute > 0 {
+			interval = time.Minute / time.Duration(campaign.RatePerMinute)
+		}
+		if leaseToken != "" {
+			renewed, err := renewCampaignLease(context.Background(), campaignRedis, campaignID, leaseToken, campaignLeaseTTL+interval)
+			if err != nil || !renewed {
+				logger.Warn("分批发送任务租约已失去，停止发送", "campaign_id", campaignID, "error", err)
+				return
+			}
+		}
+		time.Sleep(interval)
+	}
+}
+
+// advance 当前阶段发送完毕后推进任务：灰度阶段按指标自动全量或等待手动全量，全量阶段标记完成。
+// 返回 true 表示有新的待发送推送
+func (s *CampaignService) advance(campaign *models.PushCampaign) bool {
+	var held, staged int64
+	database.DB.Model(&models.PushLog{}).Where("campaign_id = ? AND status = ?", campaign.ID, "held").Count(&held)
+	if held > 0 {
+		// 等待静默时段结束
+		return false
+	}
+	database.DB.Model(&models.PushLog{}).Where("campaign_id = ? AND status = ?", campaign.ID, "staged").Count(&staged)
+
+	if campaign.Stage != "canary" || staged == 0 {
+		database.DB.Model(&models.PushCampaign{}).
+			Where("id = ? AND status = ?", campaign.ID, "running").
+			Updates(map[string]interface{}{"status": "completed", "stage": "full"})
+		return false
+	}
+
+	now := utils.TimeNow()
+	if campaign.CanaryDoneAt == nil {
+		campaign.CanaryDoneAt = &now
+		database.DB.Model(campaign).Update("canary_done_at", now)
+	}
+	if campaign.PromoteAfter == 0 || now.Before(campaign.CanaryDoneAt.Add(time.Duration(campaign.PromoteAfter)*time.Second)) {
+		// 等待手动全量或自动全量时间到期
+		return false
+	}
+
+	if reason := s.canaryHealth(campaign); reason != "" {
+		database.DB.Model(&models.PushCampaign{}).
+			Where("id = ? AND status = ?", campaign.ID, "running").
+			Updates(map[string]interface{}{"status": "paused", "paused_reason": reason})
+		logger.Warn("分批发送任务灰度指标未达标，已暂停", "app_id", campaign.AppID, "campaign_id", campaign.ID, "reason", reason)
+		return false
+	}
+
+	// A/B 测试按指标选出胜出变体发送给其余设备
+	winner := ""
+	if campaign.WinnerMetric != "" {
+		stats, err := variantStats(campaign.ID)
+		if err != nil {
+			return false
+		}
+		winner = chooseWinner(stats, campaign.WinnerMetric)
+	}
+
+	promoted, err := s.promote(campaign.ID, []string{"running"}, winner)
+	return err == nil && promoted
+}
+
+// canaryHealth 检查灰度阶段的失败率和点击率，未达标时返回暂停原因
+func (s *CampaignService) canaryHealth(campaign *models.PushCampaign) string {
+	var sent, failed, clicked int64
+	base := database.DB.Model(&models.PushLog{}).Where("campaign_id = ?", campaign.ID)
+	base.Session(&gorm.Session{}).Where("status = ?", "sent").Count(&sent)
+	base.Session(&gorm.Session{}).Where("status = ?", "failed").Count(&failed)
+	base.Session(&gorm.Session{}).Where("clicked_at IS NOT NULL").Count(&clicked)
+	return evaluateCanary(sent, failed, clicked, campaign.MaxFailureRate, campaign.MinClickRate)
+}
+
+// evaluateCanary 按阈值判断灰度指标，阈值为 0 时不检查该项
+func evaluateCanary(sent, failed, clicked int64, maxFailureRate, minClickRate float64) string {
+	if maxFailureRate > 0 && sent+failed > 0 && float64(failed)/float64(sent+failed) > maxFailureRate {
+		return PauseFailureRateExceeded
+	}
+	if minClickRate > 0 && (sent == 0 || float64(clicked)/float64(sent) < minClickRate) {
+		return PauseClickRateTooLow
+	}
+	return ""
+}
+
+// promote 将灰度任务切换为全量：剩余推送转为待发送，指定胜出变体时改用该变体的内容。返回是否有推送被释放
+func (s *CampaignService) promote(campaignID uint, fromStatus []string, winner string) (bool, error) {
+	var released int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{"stage": "full", "status": "running", "paused_reason": ""}
+		logUpdates := map[string]interface{}{"status": "pending"}
+		if winner != "" {
+			var variant models.PushVariant
+			if err := tx.Where("campaign_id = ? AND `key` = ?", campaignID, winner).First(&variant).Error; err != nil {
+				return fmt.Errorf("变体不存在: %s", winner)
+			}
+			// 全量发送的日志不标记变体，避免计入测试阶段的变体统计；发送的变体记录在任务的 winner_variant
+			updates["winner_variant"] = variant.Key
+			logUpdates["title"] = variant.Title
+			logUpdates["content"] = variant.Content
+		}
+
+		result := tx.Model(&models.PushCampaign{}).
+			Where("id = ? AND stage = ? AND status IN ?", campaignID, "canary", fromStatus).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("只有灰度阶段的任务可以全量发送")
+		}
+		result = tx.Model(&models.PushLog{}).
+			Where("campaign_id = ? AND status = ?", campaignID, "staged").
+			Updates(logUpdates)
+		released = result.RowsAffected
+		return result.Error
+	})
+	return released > 0, err
+}
+
+// getCampaign 获取应用下的分批发送任务并检查权限
+func (s *CampaignService) getCampaign(appID, userID, campaignID uint, role string) (*models.PushCampaign, error) {
+	hasPermission, err := NewUserService().CheckAppPermission(userID, appID, role)
+	if err != nil {
+		return nil, errors.New("权限检查失败")
+	}
+	if !hasPermission {
+		return nil, errors.New("无权限操作分批发送任务")
+	}
+
+	var campaign models.PushCampaign
+	if err := database.DB.Where("app_id = ? AND id = ?", appID, campaignID).First(&campaign).Error; err != nil {
+		return nil, errors.New("分批发送任务不存在")
+	}
+	return &campaign, nil
+}
+
+// GetCampaign 获取分批发送任务及进度
+func (s *CampaignService) GetCampaign(appID, userID, campaignID uint) (*CampaignProgress, error) {
+	campaign, err := s.getCampaign(appID, userID, campaignID, "viewer")
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := database.DB.Model(&models.PushLog{}).
+		Select("status, COUNT(*) AS count").
+		Where("campaign_id = ?", campaign.ID).
+		Group("status").Scan(&rows).Error; err != nil {
+		return nil, errors.New("获取任务进度失败")
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	stats, err := variantStats(campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &CampaignProgress{PushCampaign: *campaign, Counts: counts, Variants: stats}, nil
+}
+
+// PauseCampaign 暂停进行中的任务，已发出的推送不受影响
+func (s *CampaignService) PauseCampaign(appID, userID, campaignID uint) (*models.PushCampaign, error) {
+	campaign, err := s.getCampaign(appID, userID, campaignID, "developer")
+	if err != nil {
+		return nil, err
+	}
+	result := database.DB.Model(campaign).Where("status = ?", "running").
+		Updates(map[string]interface{}{"status": "paused", "paused_reason": "manual"})
+	if result.Error != nil {
+		return nil, fmt.Errorf("暂停任务失败: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("只有进行中的任务可以暂停")
+	}
+	return s.reload(campaign)
+}
+
+// campaignTargetsAll 任务是否面向全部设备。升级前创建的任务未记录目标类型，按面向全部设备处理
+func campaignTargetsAll(campaign *models.PushCampaign) bool {
+	return campaign.TargetType == "all" || campaign.TargetType == ""
+}
+
+// ResumeCampaign 恢复已暂停的任务。因灰度指标未达标暂停的任务恢复后不再自动全量，需手动全量。
+// allowBroadcast 为 false 时（API 密钥缺少 push:broadcast）不能恢复面向全部设备的任务
+func (s *CampaignService) ResumeCampaign(appID, userID, campaignID uint, allowBroadcast bool) (*models.PushCampaign, error) {
+	campaign, err := s.getCampaign(appID, userID, campaignID, "developer")
+	if err != nil {
+		return nil, err
+	}
+	if !allowBroadcast && campaignTargetsAll(campaign) {
+		return nil, ErrCampaignBroadcastScope
+	}
+	updates := map[string]interface{}{"status": "running", "paused_reason": ""}
+	if campaign.PausedReason == PauseFailureRateExceeded || campaign.PausedReason == PauseClickRateTooLow {
+		updates["promote_after"] = 0
+	}
+	result := database.DB.Model(campaign).Where("status = ?", "paused").Updates(updates)
+	if result.Error != nil {
+		return nil, fmt.Errorf("恢复任务失败: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("只有已暂停的任务可以恢复")
+	}
+
+	s.startCampaign(campaign.ID)
+	return s.reload(campaign)
+}
+
+// PromoteCampaign 手动将灰度任务切换为全量发送。A/B 测试可指定胜出变体，未指定时按胜出指标选出。
+// allowBroadcast 与 ResumeCampaign 相同
+func (s *CampaignService) PromoteCampaign(appID, userID, campaignID uint, variant string, allowBroadcast bool) (*models.PushCampaign, error) {
+	campaign, err := s.getCampaign(appID, userID, campaignID, "developer")
+	if err != nil {
+		return nil, err
+	}
+	if !allowBroadcast && campaignTargetsAll(campaign) {
+		return nil, ErrCampaignBroadcastScope
+	}
+	if campaign.WinnerMetric == "" && variant != "" {
+		return nil, errors.New("该任务不是 A/B 测试")
+	}
+	if campaign.WinnerMetric != "" && variant == "" {
+		stats, err := variantStats(campaign.ID)
+		if err != nil {
+			return nil, err
+		}
+		variant = chooseWinner(stats, campaign.WinnerMetric)
+	}
+	if _, err := s.promote(campaign.ID, []string{"running", "paused"}, variant); err != nil {
+		return nil, err
+	}
+
+	s.startCampaign(campaign.ID)
+	return s.reload(campaign)
+}
+
+// CancelCampaign 取消任务，尚未发出的推送标记为 cancelled
+func (s *CampaignService) CancelCampaign(appID, userID, campaignID uint) (*models.PushCampaign, error) {
+	campaign, err := s.getCampaign(appID, userID, campaignID, "developer")
+	if err != nil {
+		return nil, err
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(campaign).Where("status IN ?", []string{"running", "paused"}).Update("status", "cancelled")
+		if result.Error != nil {
+			return fmt.Errorf("取消任务失败: %v", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("任务已结束，无法取消")
+		}
+		return tx.Model(&models.PushLog{}).
+			Where("campaign_id = ? AND status IN ?", campaign.ID, []string{"pending", "staged", "held"}).
+			Update("status", "cancelled").Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	NewFrequencyCapService().releaseCapReservations("campaign_id", campaign.ID)
+	return s.reload(campaign)
+}
+
+// reload 重新读取任务
+func (s *CampaignService) reload(campaign *models.PushCampaign) (*models.PushCampaign, error) {
+	if err := database.DB.First(campaign, campaign.ID).Error; err != nil {
+		return nil, errors.New("分批发送任务不存在")
+	}
+	return campaign, nil
+}
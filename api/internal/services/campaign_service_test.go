@@ -0,0 +1,58 @@
+package services
+
+import "testing"
+
+func TestCanarySize(t *testing.T) {
+	tests := []struct {
+		total, percent, want int
+	}{
+		{1000, 5, 50},
+		{10, 5, 1},
+		{1, 5, 1},
+		{0, 5, 0},
+		{101, 10, 11},
+		{200, 100, 200},
+	}
+	for _, tt := range tests {
+		if got := canarySize(tt.total, tt.percent); got != tt.want {
+			t.Errorf("canarySize(%d, %d) = %d, want %d", tt.total, tt.percent, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateCanary(t *testing.T) {
+	tests := []struct {
+		name                  string
+		sent, failed, clicked int64
+		maxFailure, minClick  float64
+		want                  string
+	}{
+		{"no thresholds", 10, 90, 0, 0, 0, ""},
+		{"healthy", 95, 5, 10, 0.1, 0.05, ""},
+		{"too many failures", 80, 20, 10, 0.1, 0, PauseFailureRateExceeded},
+		{"too few clicks", 100, 0, 1, 0, 0.05, PauseClickRateTooLow},
+		{"nothing sent with click threshold", 0, 0, 0, 0, 0.01, PauseClickRateTooLow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := evaluateCanary(tt.sent, tt.failed, tt.clicked, tt.maxFailure, tt.minClick); got != tt.want {
+				t.Errorf("evaluateCanary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRollout(t *testing.T) {
+	if err := validateRollout(&RolloutOptions{}); err == nil {
+		t.Error("empty rollout should be rejected")
+	}
+	if err := validateRollout(&RolloutOptions{Percent: 100}); err == nil {
+		t.Error("100% rollout without rate should be rejected")
+	}
+	if err := validateRollout(&RolloutOptions{RatePerMinute: 600}); err != nil {
+		t.Errorf("rate only: unexpected error %v", err)
+	}
+	if err := validateRollout(&RolloutOptions{Percent: 5, MaxFailureRate: 1.5}); err == nil {
+		t.Error("failure rate above 1 should be rejected")
+	}
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/doopush/doopush/api/internal/config"
+	"github.com/doopush/doopush/api/internal/database"
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/internal/push"
+	"github.com/doopush/doopush/api/pkg/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrAppConfigNotFound = errors.New("推送配置不存在")
+)
+
+const (
+	// configHealthRunInterval 调度器每轮都会调用 CheckDue，实际检查间隔
+	configHealthRunInterval = time.Minute
+	// configHealthBatchSize 每轮最多检查的配置数，避免厂商接口变慢时阻塞过久
+	configHealthBatchSize = 20
+	// configHealthTimeout 单个配置的检查超时
+	configHealthTimeout = 30 * time.Second
+)
+
+var (
+	configHealthRunning     atomic.Bool
+	configHealthLastChecked atomic.Int64
+)
+
+// ConfigHealthService 推送配置凭据健康检查服务
+type ConfigHealthService struct{}
+
+// NewConfigHealthService 创建推送配置凭据健康检查服务
+func NewConfigHealthService() *ConfigHealthService {
+	return &ConfigHealthService{}
+}
+
+// CheckConfig 立即检查指定推送配置的凭据并保存结果
+func (s *ConfigHealthService) CheckConfig(appID, configID uint) (*models.AppConfigHealth, error) {
+	var cfg models.AppConfig
+	if err := database.DB.Where("id = ? AND app_id = ?", configID, appID).First(&cfg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAppConfigNotFound
+		}
+		return nil, errors.New("获取推送配置失败")
+	}
+	return s.check(&cfg)
+}
+
+// Reset 清除配置的检查结果，配置修改后由下一轮后台检查重新检查
+func (s *ConfigHealthService) Reset(configID uint) {
+	if err := database.DB.Where("config_id = ?", configID).Delete(&models.AppConfigHealth{}).Error; err != nil {
+		logger.Error("清除推送配置检查结果失败", "config_id", configID, "error", err)
+	}
+}
+
+// CheckDue 由调度器定期调用，检查从未检查过或距上次检查超过 CONFIG_HEALTH_CHECK_INTERVAL 小时的启用配置。
+// 厂商接口可能较慢，调用方应在单独的 goroutine 中执行
+func (s *ConfigHealthService) CheckDue() {
+	interval := config.GetInt("CONFIG_HEALTH_CHECK_INTERVAL", 6)
+	if interval <= 0 {
+		return
+	}
+	now := time.Now()
+	if now.Unix()-configHealthLastChecked.Load() < int64(configHealthRunInterval/time.Second) {
+		return
+	}
+	if !configHealthRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer configHealthRunning.Store(false)
+	configHealthLastChecked.Store(now.Unix())
+
+	var configs []models.AppConfig
+	err := database.DB.Select("app_configs.*").
+		Joins("LEFT JOIN app_config_healths ON app_config_healths.config_id = app_configs.id").
+		Where("app_configs.status = 1").
+		Where("app_config_healths.id IS NULL OR app_config_healths.checked_at < ?", now.Add(-time.Duration(interval)*time.Hour)).
+		Order("app_config_healths.checked_at ASC").
+		Limit(configHealthBatchSize).
+		Find(&configs).Error
+	if err != nil {
+		logger.Error("获取待检查的推送配置失败", "error", err)
+		return
+	}
+	for i := range configs {
+		if _, err := s.check(&configs[i]); err != nil {
+			logger.Error("保存推送配置检查结果失败", "app_id", configs[i].AppID, "config_id", configs[i].ID, "error", err)
+		}
+	}
+}
+
+// check 向厂商鉴权接口检查凭据，保存结果并在状态变化时记录日志
+func (s *ConfigHealthService) check(cfg *models.AppConfig) (*models.AppConfigHealth, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), configHealthTimeout)
+	defer cancel()
+	result := push.NewPushManager().CheckCredentials(ctx, cfg.Platform, cfg.Channel, cfg.Config)
+
+	var previous models.AppConfigHealth
+	hadPrevious := database.DB.Where("config_id = ?", cfg.ID).First(&previous).Error == nil
+
+	now := time.Now()
+	health := models.AppConfigHealth{
+		AppID:     cfg.AppID,
+		ConfigID:  cfg.ID,
+		Status:    result.Status,
+		Message:   truncateRunes(result.Message, 500),
+		CheckedAt: now,
+		ExpiresAt: result.ExpiresAt,
+	}
+	updates := map[string]interface{}{
+		"status":     health.Status,
+		"message":    health.Message,
+		"checked_at": now,
+		"expires_at": health.ExpiresAt,
+		"updated_at": now,
+	}
+	if result.Status == push.CredentialHealthy {
+		health.LastSuccessAt = &now
+		updates["last_success_at"] = now
+	} else if hadPrevious {
+		health.LastSuccessAt = previous.LastSuccessAt
+	}
+
+	err := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "config_id"}},
+		DoUpdates: clause.Assignments(updates),
+	}).Create(&health).Error
+	if err != nil {
+		return nil, err
+	}
+	// 冲突更新时不会回填主键等字段，重新读取
+	database.DB.Where("config_id = ?", cfg.ID).First(&health)
+
+	if result.Status == push.CredentialUnhealthy && (!hadPrevious || previous.Status != push.CredentialUnhealthy) {
+		logger.Warn("推送配置凭据检查失败", "app_id", cfg.AppID, "config_id", cfg.ID, "channel", cfg.Channel, "error", result.Message)
+	} else if result.Status == push.CredentialHealthy && hadPrevious && previous.Status == push.CredentialUnhealthy {
+		logger.Info("推送配置凭据检查恢复正常", "app_id", cfg.AppID, "config_id", cfg.ID, "channel", cfg.Channel)
+	}
+	return &health, nil
+}
+
+// truncateRunes 按字符截断，厂商错误响应可能很长
+func truncateRunes(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max])
+}
@@ -3,14 +3,20 @@ package services
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/doopush/doopush/api/internal/database"
 	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/internal/push"
 	"github.com/doopush/doopush/api/pkg/utils"
 	"gorm.io/gorm"
 )
 
+// ErrDeviceModeMismatch 已注册的设备与本次注册所用密钥的沙箱/正式模式不一致
+var ErrDeviceModeMismatch = errors.New("设备已用另一种模式的密钥注册，沙箱设备和正式设备不能互相切换，请删除设备后重新注册")
+
 // DeviceService 设备服务
 type DeviceService struct{}
 
@@ -19,8 +25,9 @@ func NewDeviceService() *DeviceService {
 	return &DeviceService{}
 }
 
-// RegisterDevice 注册设备
-func (s *DeviceService) RegisterDevice(appID uint, token, bundleID, platform, channel, pushEnv, brand, model, systemVer, appVersion, userAgent string) (*models.Device, error) {
+// RegisterDevice 注册设备。sandbox 表示由测试密钥注册；已有设备的沙箱标记不会改变，
+// 用另一种模式的密钥重新注册时返回 ErrDeviceModeMismatch，避免测试密钥把正式设备拉进沙箱
+func (s *DeviceService) RegisterDevice(appID uint, token, bundleID, platform, channel, pushEnv, brand, model, systemVer, appVersion, userAgent string, sandbox bool) (*models.Device, error) {
 	// 1. 验证应用是否存在并获取应用信息
 	var app models.App
 	if err := database.DB.Where("id = ? AND status = 1", appID).First(&app).Error; err != nil {
@@ -38,6 +45,9 @@ func (s *DeviceService) RegisterDevice(appID uint, token, bundleID, platform, ch
 	var existingDevice models.Device
 
 	if err := database.DB.Where("app_id = ? AND token_hash = ?", appID, tokenHash).First(&existingDevice).Error; err == nil {
+		if existingDevice.Sandbox != sandbox {
+			return nil, ErrDeviceModeMismatch
+		}
 		// 设备已存在，更新信息
 		updates := map[string]interface{}{
 			"brand":            brand,
@@ -74,6 +84,7 @@ func (s *DeviceService) RegisterDevice(appID uint, token, bundleID, platform, ch
 		AppVersion: appVersion,
 		UserAgent:  userAgent,
 		Status:     1,
+		Sandbox:    sandbox,
 		LastSeen:   &[]time.Time{utils.TimeNow()}[0],
 	}
 
@@ -81,6 +92,9 @@ func (s *DeviceService) RegisterDevice(appID uint, token, bundleID, platform, ch
 		if errors.Is(err, gorm.ErrDuplicatedKey) {
 			// 并发场景下可能同时写入，捕获唯一索引冲突后回退到更新逻辑
 			if err := database.DB.Where("app_id = ? AND token_hash = ?", appID, tokenHash).First(&existingDevice).Error; err == nil {
+				if existingDevice.Sandbox != sandbox {
+					return nil, ErrDeviceModeMismatch
+				}
 				updates := map[string]interface{}{
 					"token":            token,
 					"brand":            brand,
@@ -104,6 +118,8 @@ func (s *DeviceService) RegisterDevice(appID uint, token, bundleID, platform, ch
 		return nil, errors.New("设备注册失败")
 	}
 
+	NewWebhookService().Emit(DeviceEvent(WebhookEventDeviceRegistered, device))
+
 	// 刚插入的设备缺少关联的App信息，这里补充加载一次
 	if err := database.DB.Preload("App").First(device, device.ID).Error; err != nil {
 		// 如果预加载失败不影响主流程，记录错误即可
@@ -150,14 +166,145 @@ func (s *DeviceService) UpdateDeviceTags(appID uint, deviceToken string, tags []
 	return tagService.BatchAddDeviceTags(appID, deviceTags)
 }
 
+// GetDeviceByTokenHash 根据Token定位应用内的设备，供客户端SDK接口识别调用方设备
+func (s *DeviceService) GetDeviceByTokenHash(appID uint, token string) (*models.Device, error) {
+	var device models.Device
+	if err := database.DB.Where("app_id = ? AND token_hash = ?", appID, utils.HashString(token)).First(&device).Error; err != nil {
+		return nil, errors.New("设备不存在")
+	}
+	return &device, nil
+}
+
+// UpdateBadge 更新设备的服务端角标计数（reset=清零，decrement=减少，set=设置），返回新的计数
+func (s *DeviceService) UpdateBadge(device *models.Device, action string, count int) (int, error) {
+	badge := device.BadgeCount
+	switch action {
+	case "reset":
+		badge = 0
+	case "decrement":
+		badge = max(device.BadgeCount-count, 0)
+	case "set":
+		badge = count
+	default:
+		return 0, errors.New("无效的角标操作")
+	}
+
+	// 减少操作在数据库中原子计算，避免与并发推送的累加互相覆盖
+	expr := gorm.Expr("?", badge)
+	if action == "decrement" {
+		expr = gorm.Expr("CASE WHEN badge_count > ? THEN badge_count - ? ELSE 0 END", count, count)
+	}
+	if err := database.DB.Model(device).Update("badge_count", expr).Error; err != nil {
+		return 0, errors.New("更新角标计数失败")
+	}
+	return badge, nil
+}
+
+// BindAlias 将外部用户ID绑定到设备，设备已绑定其他用户时直接改绑（退出登录后换号登录）
+func (s *DeviceService) BindAlias(device *models.Device, externalUserID string) error {
+	if err := database.DB.Model(device).Update("external_user_id", externalUserID).Error; err != nil {
+		return errors.New("绑定用户失败")
+	}
+	return nil
+}
+
+// UnbindAlias 解除设备与外部用户ID的绑定
+func (s *DeviceService) UnbindAlias(device *models.Device) error {
+	if err := database.DB.Model(device).Update("external_user_id", "").Error; err != nil {
+		return errors.New("解除绑定失败")
+	}
+	return nil
+}
+
+// UnregisterDevice 客户端注销设备：停用设备并解除用户绑定，重新注册后恢复
+func (s *DeviceService) UnregisterDevice(device *models.Device) error {
+	updates := map[string]interface{}{
+		"status":           0,
+		"external_user_id": "",
+	}
+	if err := database.DB.Model(device).Updates(updates).Error; err != nil {
+		return errors.New("注销设备失败")
+	}
+	return nil
+}
+
+// UpdatePreferences 更新设备的语言和时区，空值表示不修改
+func (s *DeviceService) UpdatePreferences(device *models.Device, locale, timezone string) error {
+	updates := make(map[string]interface{})
+	if locale != "" {
+		updates["locale"] = locale
+	}
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return errors.New("无效的时区")
+		}
+		updates["timezone"] = timezone
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	if err := database.DB.Model(device).Updates(updates).Error; err != nil {
+		return errors.New("更新设备偏好失败")
+	}
+	return nil
+}
+
+// UpdateCategoryPreferences 更新设备对各消息分类的订阅状态（true=接收，false=退订），返回退订的分类列表
+func (s *DeviceService) UpdateCategoryPreferences(device *models.Device, preferences map[string]bool) ([]string, error) {
+	optOut := make(map[string]bool)
+	for _, category := range splitCategories(device.OptOutCats) {
+		optOut[category] = true
+	}
+	for category, enabled := range preferences {
+		if !push.IsValidCategory(category) {
+			return nil, fmt.Errorf("不支持的消息分类: %s", category)
+		}
+		optOut[category] = !enabled
+	}
+
+	var categories []string
+	for category, out := range optOut {
+		if out {
+			categories = append(categories, category)
+		}
+	}
+	sort.Strings(categories)
+
+	if err := database.DB.Model(device).Update("opt_out_categories", strings.Join(categories, ",")).Error; err != nil {
+		return nil, errors.New("更新消息分类订阅失败")
+	}
+	return categories, nil
+}
+
+// splitCategories 解析逗号分隔的分类列表
+func splitCategories(value string) []string {
+	var categories []string
+	for _, category := range strings.Split(value, ",") {
+		if category = strings.TrimSpace(category); category != "" {
+			categories = append(categories, category)
+		}
+	}
+	return categories
+}
+
+// deviceOptedOut 设备是否已退订指定消息分类
+func deviceOptedOut(device models.Device, category string) bool {
+	for _, c := range splitCategories(device.OptOutCats) {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
 // DeviceTagItem 设备标签项（与控制器中的结构保持一致）
 type DeviceTagItem struct {
 	TagName  string `json:"tag_name"`
 	TagValue string `json:"tag_value"`
 }
 
-// GetDevices 获取设备列表
-func (s *DeviceService) GetDevices(appID uint, userID uint, page, pageSize int, platform, status, pushEnv string) ([]models.Device, int64, error) {
+// GetDevices 获取设备列表，sandbox 为 true/false 时只看沙箱或正式设备
+func (s *DeviceService) GetDevices(appID uint, userID uint, page, pageSize int, platform, status, pushEnv, sandbox string) ([]models.Device, int64, error) {
 	// 检查用户权限
 	userService := NewUserService()
 	hasPermission, err := userService.CheckAppPermission(userID, appID, "viewer")
@@ -180,6 +327,9 @@ func (s *DeviceService) GetDevices(appID uint, userID uint, page, pageSize int,
 	if pushEnv != "" {
 		query = query.Where("push_environment = ?", pushEnv)
 	}
+	if sandbox == "true" || sandbox == "false" {
+		query = query.Where("sandbox = ?", sandbox == "true")
+	}
 
 	// 获取总数
 	var total int64
@@ -195,10 +345,10 @@ func (s *DeviceService) GetDevices(appID uint, userID uint, page, pageSize int,
 	return devices, total, nil
 }
 
-// GetDeviceByID 根据ID获取设备
-func (s *DeviceService) GetDeviceByID(deviceID uint, userID uint) (*models.Device, error) {
+// GetDeviceByID 根据ID获取应用下的设备，设备不属于该应用时按不存在处理
+func (s *DeviceService) GetDeviceByID(appID, deviceID uint, userID uint) (*models.Device, error) {
 	var device models.Device
-	if err := database.DB.Preload("App").Where("id = ?", deviceID).First(&device).Error; err != nil {
+	if err := database.DB.Preload("App").Where("app_id = ? AND id = ?", appID, deviceID).First(&device).Error; err != nil {
 		return nil, errors.New("设备不存在")
 	}
 
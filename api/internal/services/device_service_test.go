@@ -0,0 +1,79 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/doopush/doopush/api/internal/testutil"
+)
+
+func TestBindAliasUsersTarget(t *testing.T) {
+	testutil.SetupDB(t)
+	app := testutil.CreateApp(t, 1, "a")
+	other := testutil.CreateApp(t, 1, "b")
+	phone := testutil.CreateDevice(t, app.ID, "token-phone", false)
+	tablet := testutil.CreateDevice(t, app.ID, "token-tablet", false)
+	foreign := testutil.CreateDevice(t, other.ID, "token-foreign", false)
+	sandbox := testutil.CreateDevice(t, app.ID, "token-sandbox", true)
+	devices := NewDeviceService()
+
+	// usersTarget 按外部用户ID解析本应用的目标设备
+	usersTarget := func(userIDs ...string) []uint {
+		t.Helper()
+		targets, err := NewPushService().getTargetDevices(app.ID, PushTarget{Type: "users", UserIDs: userIDs}, false)
+		if err != nil {
+			t.Fatalf("users %v: %v", userIDs, err)
+		}
+		var ids []uint
+		for _, device := range targets {
+			ids = append(ids, device.ID)
+		}
+		slices.Sort(ids)
+		return ids
+	}
+
+	// 一个用户可以绑定多台设备，其他应用和沙箱中的同名用户不受影响
+	if err := devices.BindAlias(phone, "user-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := devices.BindAlias(tablet, "user-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := devices.BindAlias(foreign, "user-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := devices.BindAlias(sandbox, "user-1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := usersTarget("user-1"); !slices.Equal(got, []uint{phone.ID, tablet.ID}) {
+		t.Fatalf("user-1 devices = %v, want %v", got, []uint{phone.ID, tablet.ID})
+	}
+
+	// 设备改绑到其他用户后不再属于原用户
+	if err := devices.BindAlias(tablet, "user-2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := usersTarget("user-1"); !slices.Equal(got, []uint{phone.ID}) {
+		t.Fatalf("user-1 devices after rebind = %v, want %v", got, []uint{phone.ID})
+	}
+	if got := usersTarget("user-2"); !slices.Equal(got, []uint{tablet.ID}) {
+		t.Fatalf("user-2 devices = %v, want %v", got, []uint{tablet.ID})
+	}
+	if got := usersTarget("user-1", "user-2"); !slices.Equal(got, []uint{phone.ID, tablet.ID}) {
+		t.Fatalf("user-1,user-2 devices = %v", got)
+	}
+
+	// 解除绑定或注销后按用户推送不再送达该设备
+	if err := devices.UnbindAlias(phone); err != nil {
+		t.Fatal(err)
+	}
+	if got := usersTarget("user-1"); len(got) != 0 {
+		t.Fatalf("user-1 devices after unbind = %v, want none", got)
+	}
+	if err := devices.UnregisterDevice(tablet); err != nil {
+		t.Fatal(err)
+	}
+	if got := usersTarget("user-2"); len(got) != 0 {
+		t.Fatalf("user-2 devices after unregister = %v, want none", got)
+	}
+}
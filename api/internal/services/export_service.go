@@ -10,6 +10,7 @@ import (
 
 	"github.com/doopush/doopush/api/internal/database"
 	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/pkg/logger"
 	"github.com/xuri/excelize/v2"
 	"gorm.io/gorm"
 )
@@ -370,7 +371,7 @@ func (s *ExportService) generatePushLogsExcel(pushLogs []models.PushLog, filenam
 	f := excelize.NewFile()
 	defer func() {
 		if err := f.Close(); err != nil {
-			fmt.Printf("关闭Excel文件失败: %v\n", err)
+			logger.Warn("关闭Excel文件失败", "error", err)
 		}
 	}()
 
@@ -482,7 +483,7 @@ func (s *ExportService) generateStatisticsExcel(appID uint, startDate, endDate t
 	f := excelize.NewFile()
 	defer func() {
 		if err := f.Close(); err != nil {
-			fmt.Printf("关闭Excel文件失败: %v\n", err)
+			logger.Warn("关闭Excel文件失败", "error", err)
 		}
 	}()
 
@@ -780,7 +781,7 @@ func (s *ExportService) generatePushLogDetailsExcel(pushLog models.PushLog, resu
 	f := excelize.NewFile()
 	defer func() {
 		if err := f.Close(); err != nil {
-			fmt.Printf("关闭Excel文件失败: %v\n", err)
+			logger.Warn("关闭Excel文件失败", "error", err)
 		}
 	}()
 
@@ -1151,7 +1152,7 @@ func (s *ExportService) StartCleanupScheduler() {
 
 		for range ticker.C {
 			if err := s.CleanupExpiredFiles(); err != nil {
-				fmt.Printf("清理过期文件失败: %v\n", err)
+				logger.Error("清理过期导出文件失败", "error", err)
 			}
 		}
 	}()
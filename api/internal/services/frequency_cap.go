@@ -0,0 +1,361 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/doopush/doopush/api/internal/database"
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/internal/push"
+	"github.com/doopush/doopush/api/pkg/logger"
+	"github.com/doopush/doopush/api/pkg/utils"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 频控导致的跳过原因
+const (
+	SkipDeviceDailyCap  = "device_daily_cap"
+	SkipDeviceWeeklyCap = "device_weekly_cap"
+	SkipAppDailyQuota   = "app_daily_quota"
+)
+
+// frequencyRedis 频控计数使用的 Redis 客户端，为 nil 时不做频控
+var frequencyRedis *redis.Client
+
+// SetFrequencyRedis 注入频控计数使用的 Redis 客户端
+func SetFrequencyRedis(rdb *redis.Client) {
+	frequencyRedis = rdb
+}
+
+// deviceCapScript 检查并累加单设备的日/周计数，超限时不累加。
+// 返回 0=通过，1=超出日上限，2=超出周上限
+var deviceCapScript = redis.NewScript(`
+local daily = tonumber(redis.call('GET', KEYS[1]) or '0')
+local weekly = tonumber(redis.call('GET', KEYS[2]) or '0')
+if tonumber(ARGV[1]) > 0 and daily >= tonumber(ARGV[1]) then return 1 end
+if tonumber(ARGV[2]) > 0 and weekly >= tonumber(ARGV[2]) then return 2 end
+redis.call('INCR', KEYS[1])
+redis.call('EXPIRE', KEYS[1], ARGV[3])
+redis.call('INCR', KEYS[2])
+redis.call('EXPIRE', KEYS[2], ARGV[4])
+return 0
+`)
+
+// appQuotaScript 从应用每日配额中预占至多 ARGV[2] 条，返回实际预占数量
+var appQuotaScript = redis.NewScript(`
+local used = tonumber(redis.call('GET', KEYS[1]) or '0')
+local allow = tonumber(ARGV[1]) - used
+if allow < 0 then allow = 0 end
+if allow > tonumber(ARGV[2]) then allow = tonumber(ARGV[2]) end
+if allow > 0 then
+  redis.call('INCRBY', KEYS[1], allow)
+  redis.call('EXPIRE', KEYS[1], ARGV[3])
+end
+return allow
+`)
+
+// capReleaseScript 归还预占的计数，ARGV[i] 为 KEYS[i] 需要归还的数量，计数不会减到 0 以下
+var capReleaseScript = redis.NewScript(`
+for i, key in ipairs(KEYS) do
+  local used = tonumber(redis.call('GET', key) or '0')
+  local n = math.min(used, tonumber(ARGV[i]))
+  if n > 0 then redis.call('DECRBY', key, n) end
+end
+return 0
+`)
+
+// 推送日志频控占用标记中的计数类型
+const (
+	capReservedQuota  = "a" // 应用每日配额
+	capReservedDevice = "d" // 单设备日/周计数
+)
+
+// FrequencyCapService 推送频控服务
+type FrequencyCapService struct{}
+
+// NewFrequencyCapService 创建推送频控服务
+func NewFrequencyCapService() *FrequencyCapService {
+	return &FrequencyCapService{}
+}
+
+// FrequencyCapUsage 频控配置及当天用量
+type FrequencyCapUsage struct {
+	models.AppFrequencyCap
+	EffectiveDailyQuota int   `json:"effective_daily_quota" example:"100000"` // 生效的每日配额，应用未配置时取系统配置 max_push_per_day
+	UsedToday           int64 `json:"used_today" example:"1200"`              // 当天已占用的配额
+}
+
+// GetFrequencyCap 获取应用频控配置，未配置时返回全 0（不限制）
+func (s *FrequencyCapService) GetFrequencyCap(appID uint) (*models.AppFrequencyCap, error) {
+	var limits models.AppFrequencyCap
+	err := database.DB.Where("app_id = ?", appID).First(&limits).Error
+	if err != nil {
+		return &models.AppFrequencyCap{AppID: appID}, nil
+	}
+	return &limits, nil
+}
+
+// GetUsage 获取应用频控配置及当天配额用量
+func (s *FrequencyCapService) GetUsage(appID uint) (*FrequencyCapUsage, error) {
+	limits, err := s.GetFrequencyCap(appID)
+	if err != nil {
+		return nil, err
+	}
+	usage := &FrequencyCapUsage{AppFrequencyCap: *limits, EffectiveDailyQuota: s.dailyQuota(limits)}
+	if frequencyRedis != nil {
+		used, err := frequencyRedis.Get(context.Background(), appQuotaKey(appID, utils.TimeNow())).Int64()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, errors.New("获取配额用量失败")
+		}
+		usage.UsedToday = used
+	}
+	return usage, nil
+}
+
+// UpdateFrequencyCap 更新应用频控配置
+func (s *FrequencyCapService) UpdateFrequencyCap(appID uint, maxPushPerDay, deviceDailyCap, deviceWeeklyCap int) (*models.AppFrequencyCap, error) {
+	if maxPushPerDay < 0 || deviceDailyCap < 0 || deviceWeeklyCap < 0 {
+		return nil, errors.New("频控上限不能为负数")
+	}
+
+	limits, _ := s.GetFrequencyCap(appID)
+	limits.MaxPushPerDay = maxPushPerDay
+	limits.DeviceDailyCap = deviceDailyCap
+	limits.DeviceWeeklyCap = deviceWeeklyCap
+	if err := database.DB.Save(limits).Error; err != nil {
+		return nil, fmt.Errorf("更新频控配置失败: %v", err)
+	}
+	return limits, nil
+}
+
+// dailyQuota 生效的应用每日配额：应用配置优先，其次为系统配置 max_push_per_day，0 表示不限制
+func (s *FrequencyCapService) dailyQuota(limits *models.AppFrequencyCap) int {
+	if limits.MaxPushPerDay > 0 {
+		return limits.MaxPushPerDay
+	}
+	var config models.SystemConfig
+	if err := database.DB.Where("`key` = ?", "max_push_per_day").First(&config).Error; err != nil {
+		return 0
+	}
+	quota, err := strconv.Atoi(config.Value)
+	if err != nil || quota < 0 {
+		return 0
+	}
+	return quota
+}
+
+// capBypassed 交易类、即时通讯和账号类消息不受频控限制
+func capBypassed(category string) bool {
+	return category == push.CategoryTransactional || category == push.CategoryIM || category == push.CategoryAccount
+}
+
+// applyCaps 按应用每日配额和单设备日/周上限筛选设备，返回放行的设备、被抑制的设备，
+// 以及放行设备的频控占用标记（记录到推送日志，未投递时据此归还）。
+// Redis 不可用时放行全部设备，避免频控故障阻断推送
+func (s *FrequencyCapService) applyCaps(appID uint, category string, devices []models.Device) ([]models.Device, []skippedDevice, string, error) {
+	if frequencyRedis == nil || capBypassed(category) || len(devices) == 0 {
+		return devices, nil, "", nil
+	}
+
+	limits, err := s.GetFrequencyCap(appID)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	quota := s.dailyQuota(limits)
+	if quota == 0 && limits.DeviceDailyCap == 0 && limits.DeviceWeeklyCap == 0 {
+		return devices, nil, "", nil
+	}
+
+	ctx := context.Background()
+	now := utils.TimeNow()
+	var suppressed []skippedDevice
+	reserved := ""
+
+	// 先预占应用配额，超出部分直接抑制
+	if quota > 0 {
+		allowed, err := reserveAppQuota(ctx, frequencyRedis, appID, quota, len(devices), now)
+		if err != nil {
+			logger.Warn("应用配额检查失败，跳过频控", "app_id", appID, "error", err)
+			return devices, nil, "", nil
+		}
+		for _, device := range devices[allowed:] {
+			suppressed = append(suppressed, skippedDevice{Device: device, status: "suppressed", reason: SkipAppDailyQuota})
+		}
+		devices = devices[:allowed]
+		reserved += capReservedQuota
+	}
+
+	// 再检查单设备上限，被抑制的设备归还预占的配额
+	if limits.DeviceDailyCap > 0 || limits.DeviceWeeklyCap > 0 {
+		reasons, err := checkDeviceCaps(ctx, frequencyRedis, devices, limits.DeviceDailyCap, limits.DeviceWeeklyCap, now)
+		if err != nil {
+			logger.Warn("设备频控检查失败，跳过设备频控", "app_id", appID, "error", err)
+			return devices, suppressed, capReservation(now, reserved), nil
+		}
+		var passed []models.Device
+		for _, device := range devices {
+			if reason, capped := reasons[device.ID]; capped {
+				suppressed = append(suppressed, skippedDevice{Device: device, status: "suppressed", reason: reason})
+			} else {
+				passed = append(passed, device)
+			}
+		}
+		if released := len(devices) - len(passed); quota > 0 && released > 0 {
+			frequencyRedis.DecrBy(ctx, appQuotaKey(appID, now), int64(released))
+		}
+		devices = passed
+		reserved += capReservedDevice
+	}
+
+	return devices, suppressed, capReservation(now, reserved), nil
+}
+
+// capReservation 生成频控占用标记："日期:计数类型"，未占用时为空
+func capReservation(now time.Time, reserved string) string {
+	if reserved == "" {
+		return ""
+	}
+	return now.Format("20060102") + ":" + reserved
+}
+
+// releaseCapReservations 归还未投递的推送占用的频控计数，在请求失败、审批驳回或取消后调用。
+// 只处理 column = value 范围内已取消或驳回、仍带占用标记的日志，标记在同一事务中清空，避免重复归还
+func (s *FrequencyCapService) releaseCapReservations(column string, value interface{}) {
+	if frequencyRedis == nil {
+		return
+	}
+	var pushLogs []models.PushLog
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "app_id", "device_id", "cap_reserved").
+			Where(column+" = ? AND status IN ? AND cap_reserved <> ''", value, []string{"cancelled", "rejected"}).
+			Find(&pushLogs).Error; err != nil {
+			return err
+		}
+		if len(pushLogs) == 0 {
+			return nil
+		}
+		ids := make([]uint, 0, len(pushLogs))
+		for _, pushLog := range pushLogs {
+			ids = append(ids, pushLog.ID)
+		}
+		return tx.Model(&models.PushLog{}).Where("id IN ?", ids).Update("cap_reserved", "").Error
+	})
+	if err != nil {
+		logger.Warn("读取频控占用失败，未归还", column, value, "error", err)
+		return
+	}
+	if err := releaseCaps(context.Background(), frequencyRedis, pushLogs); err != nil {
+		logger.Warn("归还频控计数失败", column, value, "error", err)
+	}
+}
+
+// releaseDeviceCaps 归还已预占、但没有创建推送日志的设备计数（在线设备跳过或日志写入失败）
+func (s *FrequencyCapService) releaseDeviceCaps(appID uint, capReserved string, devices []models.Device) {
+	if frequencyRedis == nil || capReserved == "" || len(devices) == 0 {
+		return
+	}
+	pushLogs := make([]models.PushLog, 0, len(devices))
+	for _, device := range devices {
+		pushLogs = append(pushLogs, models.PushLog{AppID: appID, DeviceID: device.ID, CapReserved: capReserved})
+	}
+	if err := releaseCaps(context.Background(), frequencyRedis, pushLogs); err != nil {
+		logger.Warn("归还频控计数失败", "app_id", appID, "devices", len(devices), "error", err)
+	}
+}
+
+// releaseCaps 按推送日志的占用标记归还应用配额和设备日/周计数
+func releaseCaps(ctx context.Context, rdb *redis.Client, pushLogs []models.PushLog) error {
+	counts := make(map[string]int)
+	var keys []string
+	add := func(key string) {
+		if counts[key] == 0 {
+			keys = append(keys, key)
+		}
+		counts[key]++
+	}
+	for _, pushLog := range pushLogs {
+		day, reserved, ok := strings.Cut(pushLog.CapReserved, ":")
+		if !ok {
+			continue
+		}
+		at, err := time.ParseInLocation("20060102", day, time.Local)
+		if err != nil {
+			continue
+		}
+		if strings.Contains(reserved, capReservedQuota) {
+			add(appQuotaKey(pushLog.AppID, at))
+		}
+		if strings.Contains(reserved, capReservedDevice) {
+			add(deviceDailyKey(pushLog.DeviceID, at))
+			add(deviceWeeklyKey(pushLog.DeviceID, at))
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = counts[key]
+	}
+	return capReleaseScript.Run(ctx, rdb, keys, args...).Err()
+}
+
+// appQuotaKey 应用每日配额计数键
+func appQuotaKey(appID uint, now time.Time) string {
+	return fmt.Sprintf("freq:app:%d:%s", appID, now.Format("20060102"))
+}
+
+// deviceDailyKey 单设备每日计数键
+func deviceDailyKey(deviceID uint, now time.Time) string {
+	return fmt.Sprintf("freq:dev:%d:d:%s", deviceID, now.Format("20060102"))
+}
+
+// deviceWeeklyKey 单设备每周（ISO 周）计数键
+func deviceWeeklyKey(deviceID uint, now time.Time) string {
+	year, week := now.ISOWeek()
+	return fmt.Sprintf("freq:dev:%d:w:%d%02d", deviceID, year, week)
+}
+
+// reserveAppQuota 从应用当天配额中预占至多 want 条，返回实际预占数量
+func reserveAppQuota(ctx context.Context, rdb *redis.Client, appID uint, quota, want int, now time.Time) (int, error) {
+	allowed, err := appQuotaScript.Run(ctx, rdb, []string{appQuotaKey(appID, now)},
+		quota, want, int((48 * time.Hour).Seconds())).Int()
+	if err != nil {
+		return 0, err
+	}
+	return allowed, nil
+}
+
+// checkDeviceCaps 检查并累加设备的日/周计数，返回超限设备及原因
+func checkDeviceCaps(ctx context.Context, rdb *redis.Client, devices []models.Device, dailyCap, weeklyCap int, now time.Time) (map[uint]string, error) {
+	dayTTL := int((48 * time.Hour).Seconds())
+	weekTTL := int((8 * 24 * time.Hour).Seconds())
+
+	pipe := rdb.Pipeline()
+	cmds := make([]*redis.Cmd, len(devices))
+	for i, device := range devices {
+		keys := []string{deviceDailyKey(device.ID, now), deviceWeeklyKey(device.ID, now)}
+		cmds[i] = deviceCapScript.Eval(ctx, pipe, keys, dailyCap, weeklyCap, dayTTL, weekTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	reasons := make(map[uint]string)
+	for i, cmd := range cmds {
+		switch code, _ := cmd.Int(); code {
+		case 1:
+			reasons[devices[i].ID] = SkipDeviceDailyCap
+		case 2:
+			reasons[devices[i].ID] = SkipDeviceWeeklyCap
+		}
+	}
+	return reasons, nil
+}
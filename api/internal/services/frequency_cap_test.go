@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/internal/testutil"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+func newCapRedis(t *testing.T) *redis.Client {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis: %v", err)
+	}
+	t.Cleanup(mr.Close)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return rdb
+}
+
+func TestReserveAppQuota(t *testing.T) {
+	rdb := newCapRedis(t)
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		want, expect int
+	}{
+		{3, 3},
+		{4, 2},
+		{1, 0},
+	}
+	for i, step := range steps {
+		got, err := reserveAppQuota(ctx, rdb, 1, 5, step.want, now)
+		if err != nil {
+			t.Fatalf("step %d: unexpected error: %v", i, err)
+		}
+		if got != step.expect {
+			t.Errorf("step %d: reserved %d, want %d", i, got, step.expect)
+		}
+	}
+
+	// 次日配额重新计算
+	got, _ := reserveAppQuota(ctx, rdb, 1, 5, 2, now.Add(24*time.Hour))
+	if got != 2 {
+		t.Errorf("next day reserved %d, want 2", got)
+	}
+}
+
+func TestCheckDeviceCaps(t *testing.T) {
+	rdb := newCapRedis(t)
+	ctx := context.Background()
+	monday := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	devices := []models.Device{{ID: 1}, {ID: 2}}
+
+	// 日上限 2、周上限 3
+	for i := 0; i < 2; i++ {
+		reasons, err := checkDeviceCaps(ctx, rdb, devices[:1], 2, 3, monday)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(reasons) != 0 {
+			t.Fatalf("push %d should pass, got %v", i, reasons)
+		}
+	}
+
+	reasons, _ := checkDeviceCaps(ctx, rdb, devices, 2, 3, monday)
+	if reasons[1] != SkipDeviceDailyCap {
+		t.Errorf("device 1 reason = %q, want %q", reasons[1], SkipDeviceDailyCap)
+	}
+	if _, capped := reasons[2]; capped {
+		t.Errorf("device 2 should pass")
+	}
+
+	// 次日日计数清零，但周计数已到 3
+	tuesday := monday.Add(24 * time.Hour)
+	if reasons, _ := checkDeviceCaps(ctx, rdb, devices[:1], 2, 3, tuesday); len(reasons) != 0 {
+		t.Fatalf("tuesday first push should pass, got %v", reasons)
+	}
+	reasons, _ = checkDeviceCaps(ctx, rdb, devices[:1], 2, 3, tuesday)
+	if reasons[1] != SkipDeviceWeeklyCap {
+		t.Errorf("device 1 reason = %q, want %q", reasons[1], SkipDeviceWeeklyCap)
+	}
+}
+
+func TestReleaseCaps(t *testing.T) {
+	rdb := newCapRedis(t)
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	devices := []models.Device{{ID: 1}, {ID: 2}}
+
+	if _, err := reserveAppQuota(ctx, rdb, 1, 5, 2, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := checkDeviceCaps(ctx, rdb, devices, 1, 1, now); err != nil {
+		t.Fatal(err)
+	}
+
+	// 两条推送被取消，归还配额和设备计数；未占用的日志不归还
+	reserved := capReservation(now, capReservedQuota+capReservedDevice)
+	if err := releaseCaps(ctx, rdb, []models.PushLog{
+		{AppID: 1, DeviceID: 1, CapReserved: reserved},
+		{AppID: 1, DeviceID: 2, CapReserved: reserved},
+		{AppID: 1, DeviceID: 3},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if used, _ := rdb.Get(ctx, appQuotaKey(1, now)).Int(); used != 0 {
+		t.Errorf("app quota used = %d, want 0", used)
+	}
+	if reasons, _ := checkDeviceCaps(ctx, rdb, devices, 1, 1, now); len(reasons) != 0 {
+		t.Errorf("released devices should pass again, got %v", reasons)
+	}
+
+	// 重复归还不会把计数减到 0 以下
+	releaseCaps(ctx, rdb, []models.PushLog{{AppID: 1, DeviceID: 9, CapReserved: reserved}})
+	if used, _ := rdb.Get(ctx, deviceDailyKey(9, now)).Int(); used != 0 {
+		t.Errorf("device 9 daily count = %d, want 0", used)
+	}
+}
+
+func TestSendPushReleasesCapsWhenLogCreateFails(t *testing.T) {
+	db := testutil.SetupDB(t)
+	rdb := newCapRedis(t)
+	SetFrequencyRedis(rdb)
+	t.Cleanup(func() { SetFrequencyRedis(nil) })
+
+	app := testutil.CreateApp(t, 1, "a")
+	device := testutil.CreateDevice(t, app.ID, "token-a", false)
+	if _, err := NewFrequencyCapService().UpdateFrequencyCap(app.ID, 10, 3, 0); err != nil {
+		t.Fatalf("UpdateFrequencyCap: %v", err)
+	}
+
+	// 推送日志写入失败
+	db.Callback().Create().Before("gorm:create").Register("test:fail_push_logs", func(tx *gorm.DB) {
+		if tx.Statement.Table == "push_logs" {
+			tx.AddError(errors.New("insert failed"))
+		}
+	})
+
+	_, _ = NewPushService().SendPush(context.Background(), app.ID, 1, PushRequest{
+		Title:   "t",
+		Content: "c",
+		Target:  PushTarget{Type: "devices", DeviceIDs: []uint{device.ID}},
+	})
+
+	ctx := context.Background()
+	now := time.Now()
+	for _, key := range []string{appQuotaKey(app.ID, now), deviceDailyKey(device.ID, now)} {
+		if used, _ := rdb.Get(ctx, key).Int(); used != 0 {
+			t.Errorf("%s = %d after failed log insert, want 0", key, used)
+		}
+	}
+}
@@ -45,7 +45,7 @@ func (s *InvitationService) LookupCandidate(appID, operatorID uint, email string
 	if count > 0 {
 		state = "member"
 	} else if err := database.DB.Model(&models.AppInvitation{}).
-		Where("app_id = ? AND invitee_id = ? AND status = ?", appID, user.ID, "pending").Count(&count).Error; err != nil {
+		Where("app_id = ? AND invitee_id = ? AND type = ? AND status = ?", appID, user.ID, InboxTypeInvitation, "pending").Count(&count).Error; err != nil {
 		return nil, errors.New("查询邀请状态失败")
 	} else if count > 0 {
 		state = "pending"
@@ -91,7 +91,7 @@ func (s *InvitationService) CreateInvitation(appID, inviterID, inviteeID uint, r
 
 		pendingKey := fmt.Sprintf("%d:%d", appID, inviteeID)
 		invitation = models.AppInvitation{
-			AppID: appID, InviterID: inviterID, InviteeID: inviteeID,
+			Type: InboxTypeInvitation, AppID: appID, InviterID: inviterID, InviteeID: inviteeID,
 			Role: role, Status: "pending", PendingKey: &pendingKey,
 			AppNameSnapshot: app.Name, AppIconSnapshot: app.AppIcon,
 			InviterNameSnapshot: displayUserName(inviter),
@@ -116,7 +116,7 @@ func (s *InvitationService) GetPendingInvitations(appID, operatorID uint) ([]mod
 		return nil, err
 	}
 	var invitations []models.AppInvitation
-	if err := database.DB.Where("app_id = ? AND status = ?", appID, "pending").
+	if err := database.DB.Where("app_id = ? AND type = ? AND status = ?", appID, InboxTypeInvitation, "pending").
 		Order("created_at DESC").Find(&invitations).Error; err != nil {
 		return nil, errors.New("获取待处理邀请失败")
 	}
@@ -130,7 +130,7 @@ func (s *InvitationService) CancelInvitation(appID, operatorID, invitationID uin
 		}
 		var invitation models.AppInvitation
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-			Where("id = ? AND app_id = ?", invitationID, appID).First(&invitation).Error; err != nil {
+			Where("id = ? AND app_id = ? AND type = ?", invitationID, appID, InboxTypeInvitation).First(&invitation).Error; err != nil {
 			return ErrInvitationNotFound
 		}
 		if invitation.Status != "pending" {
@@ -176,6 +176,13 @@ func (s *InvitationService) MarkAllRead(userID uint) error {
 }
 
 func (s *InvitationService) RespondInvitation(userID, invitationID uint, accept bool) (*models.AppInvitation, error) {
+	// 推送审批条目：接受即批准推送，拒绝即驳回推送
+	var item models.AppInvitation
+	if err := database.DB.Where("id = ? AND invitee_id = ?", invitationID, userID).First(&item).Error; err == nil &&
+		item.Type == InboxTypePushApproval {
+		return NewPushApprovalService().respondInbox(userID, item, accept)
+	}
+
 	var invitation models.AppInvitation
 	err := database.DB.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
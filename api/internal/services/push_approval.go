@@ -0,0 +1,402 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/doopush/doopush/api/internal/database"
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/pkg/logger"
+	"github.com/doopush/doopush/api/pkg/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrApprovalNotFound     = errors.New("推送审批不存在")
+	ErrApprovalNotPending   = errors.New("推送审批已处理，无法重复操作")
+	ErrCannotReviewOwnPush  = errors.New("不能审批自己发起的推送")
+	ErrNoApprovalPermission = errors.New("无权限审批推送")
+)
+
+// 收件箱条目类型
+const (
+	InboxTypeInvitation   = "invitation"
+	InboxTypePushApproval = "push_approval"
+)
+
+// 需要审批的原因
+const (
+	ApprovalReasonBroadcast       = "broadcast"
+	ApprovalReasonDeviceThreshold = "device_threshold"
+)
+
+// PushApprovalService 推送审批服务
+type PushApprovalService struct{}
+
+// NewPushApprovalService 创建推送审批服务
+func NewPushApprovalService() *PushApprovalService {
+	return &PushApprovalService{}
+}
+
+// GetPolicy 获取应用审批策略，未配置时返回不需要审批的默认策略
+func (s *PushApprovalService) GetPolicy(appID uint) (*models.AppApprovalPolicy, error) {
+	var policy models.AppApprovalPolicy
+	if err := database.DB.Where("app_id = ?", appID).First(&policy).Error; err != nil {
+		return &models.AppApprovalPolicy{AppID: appID}, nil
+	}
+	return &policy, nil
+}
+
+// UpdatePolicy 更新应用审批策略
+func (s *PushApprovalService) UpdatePolicy(appID uint, deviceThreshold int, requireForBroadcast, allowDeveloperApproval bool) (*models.AppApprovalPolicy, error) {
+	if deviceThreshold < 0 {
+		return nil, errors.New("设备数阈值不能为负数")
+	}
+
+	policy, _ := s.GetPolicy(appID)
+	policy.DeviceThreshold = deviceThreshold
+	policy.RequireForBroadcast = requireForBroadcast
+	policy.AllowDeveloperApproval = allowDeveloperApproval
+	if err := database.DB.Save(policy).Error; err != nil {
+		return nil, fmt.Errorf("更新审批策略失败: %v", err)
+	}
+	return policy, nil
+}
+
+// approvalReason 按审批策略判断推送是否需要审批，返回需要审批的原因，不需要时返回空
+func approvalReason(policy *models.AppApprovalPolicy, targetType string, deviceCount int) string {
+	if policy.RequireForBroadcast && targetType == "all" {
+		return ApprovalReasonBroadcast
+	}
+	if policy.DeviceThreshold > 0 && deviceCount > policy.DeviceThreshold {
+		return ApprovalReasonDeviceThreshold
+	}
+	return ""
+}
+
+// canReview 判断该角色能否审批推送：owner 始终可以，策略允许时其他开发者也可以
+func canReview(role string, policy *models.AppApprovalPolicy) bool {
+	return role == "owner" || (role == "developer" && policy.AllowDeveloperApproval)
+}
+
+// appRole 获取用户在应用中的角色，不是成员时返回空
+func appRole(db *gorm.DB, appID, userID uint) string {
+	var permission models.UserAppPermission
+	if err := db.Where("app_id = ? AND user_id = ?", appID, userID).First(&permission).Error; err != nil {
+		return ""
+	}
+	return permission.Role
+}
+
+// requiresApproval 判断推送是否需要审批。owner 在控制台或定时任务中发起的推送视为已审批
+func (s *PushApprovalService) requiresApproval(appID, userID uint, viaAPIKey bool, targetType string, deviceCount int) (string, error) {
+	if !viaAPIKey && appRole(database.DB, appID, userID) == "owner" {
+		return "", nil
+	}
+	policy, err := s.GetPolicy(appID)
+	if err != nil {
+		return "", err
+	}
+	return approvalReason(policy, targetType, deviceCount), nil
+}
+
+// requestApproval 创建推送审批，并向可审批的成员发送收件箱条目
+func (s *PushApprovalService) requestApproval(appID, userID uint, batchID, reason string, req PushRequest, deviceCount int) (*models.PushApproval, error) {
+	requestJSON, err := json.Marshal(req)
+	if err != nil {
+		return nil, errors.New("序列化推送请求失败")
+	}
+	approval := &models.PushApproval{
+		AppID:        appID,
+		BatchID:      batchID,
+		RequesterID:  userID,
+		ViaAPIKey:    req.ViaAPIKey,
+		Status:       "pending",
+		Reason:       reason,
+		Title:        req.Title,
+		TargetType:   req.Target.Type,
+		DeviceCount:  deviceCount,
+		ScheduleTime: req.Schedule,
+		Request:      string(requestJSON),
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(approval).Error; err != nil {
+			return err
+		}
+		return s.notifyReviewers(tx, approval)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建推送审批失败: %v", err)
+	}
+
+	s.audit(userID, approval, "request_approval", "")
+	return approval, nil
+}
+
+// notifyReviewers 向发起人以外的可审批成员发送收件箱条目。API Key 发起的推送没有真实发起人，所有可审批成员都会收到
+func (s *PushApprovalService) notifyReviewers(tx *gorm.DB, approval *models.PushApproval) error {
+	policy, err := s.GetPolicy(approval.AppID)
+	if err != nil {
+		return err
+	}
+	roles := []string{"owner"}
+	if policy.AllowDeveloperApproval {
+		roles = append(roles, "developer")
+	}
+
+	var app models.App
+	if err := tx.First(&app, approval.AppID).Error; err != nil {
+		return errors.New("应用不存在")
+	}
+	var requester models.User
+	if err := tx.First(&requester, approval.RequesterID).Error; err != nil {
+		return errors.New("发起人不存在")
+	}
+	query := tx.Preload("User").Where("app_id = ? AND role IN ?", approval.AppID, roles)
+	if !approval.ViaAPIKey {
+		query = query.Where("user_id <> ?", approval.RequesterID)
+	}
+	var permissions []models.UserAppPermission
+	if err := query.Find(&permissions).Error; err != nil {
+		return err
+	}
+
+	for _, permission := range permissions {
+		item := models.AppInvitation{
+			Type: InboxTypePushApproval, PushApprovalID: &approval.ID,
+			AppID: approval.AppID, InviterID: approval.RequesterID, InviteeID: permission.UserID,
+			Role: permission.Role, Status: "pending",
+			AppNameSnapshot: app.Name, AppIconSnapshot: app.AppIcon,
+			InviterNameSnapshot: displayUserName(requester),
+			InviteeNameSnapshot: displayUserName(permission.User), InviteeEmailSnapshot: permission.User.Email,
+		}
+		if err := tx.Create(&item).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetApprovals 获取应用的推送审批列表
+func (s *PushApprovalService) GetApprovals(appID uint, status string, page, pageSize int) ([]models.PushApproval, int64, error) {
+	query := database.DB.Model(&models.PushApproval{}).Where("app_id = ?", appID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.New("获取推送审批失败")
+	}
+	var approvals []models.PushApproval
+	if err := query.Order("created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&approvals).Error; err != nil {
+		return nil, 0, errors.New("获取推送审批失败")
+	}
+	return approvals, total, nil
+}
+
+// GetApproval 获取推送审批
+func (s *PushApprovalService) GetApproval(appID, approvalID uint) (*models.PushApproval, error) {
+	var approval models.PushApproval
+	if err := database.DB.Where("id = ? AND app_id = ?", approvalID, appID).First(&approval).Error; err != nil {
+		return nil, ErrApprovalNotFound
+	}
+	return &approval, nil
+}
+
+// Review 批准或拒绝推送。批准后推送按原计划投递，拒绝后尚未投递的推送标记为 rejected
+func (s *PushApprovalService) Review(appID, reviewerID, approvalID uint, approve bool, note string) (*models.PushApproval, error) {
+	var approval models.PushApproval
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND app_id = ?", approvalID, appID).First(&approval).Error; err != nil {
+			return ErrApprovalNotFound
+		}
+		if approval.Status != "pending" {
+			return ErrApprovalNotPending
+		}
+		if !approval.ViaAPIKey && approval.RequesterID == reviewerID {
+			return ErrCannotReviewOwnPush
+		}
+		policy, err := s.GetPolicy(appID)
+		if err != nil {
+			return err
+		}
+		if !canReview(appRole(tx, appID, reviewerID), policy) {
+			return ErrNoApprovalPermission
+		}
+
+		now := utils.TimeNow()
+		approval.Status = "rejected"
+		if approve {
+			approval.Status = "approved"
+		}
+		approval.ReviewerID = &reviewerID
+		approval.ReviewNote = note
+		approval.ReviewedAt = &now
+		if err := tx.Model(&approval).Updates(map[string]interface{}{
+			"status": approval.Status, "reviewer_id": reviewerID, "review_note": note, "reviewed_at": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		if approve {
+			if err := releaseApprovedLogs(tx, &approval); err != nil {
+				return err
+			}
+		} else if err := rejectLogs(tx, &approval); err != nil {
+			return err
+		}
+
+		// 审批人自己的收件箱条目标记为已读，其他审批人的条目同步为审批结果
+		if err := closeInboxItems(tx, approval.ID, approval.Status, now); err != nil {
+			return err
+		}
+		return tx.Model(&models.AppInvitation{}).
+			Where("push_approval_id = ? AND invitee_id = ?", approval.ID, reviewerID).
+			Update("read_at", now).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrApprovalNotFound) || errors.Is(err, ErrApprovalNotPending) ||
+			errors.Is(err, ErrCannotReviewOwnPush) || errors.Is(err, ErrNoApprovalPermission) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("处理推送审批失败: %v", err)
+	}
+
+	action := "reject_push"
+	if approve {
+		action = "approve_push"
+		s.dispatch(&approval)
+	} else {
+		NewFrequencyCapService().releaseCapReservations("batch_id", approval.BatchID)
+	}
+	s.audit(reviewerID, &approval, action, note)
+	return &approval, nil
+}
+
+// releaseApprovedLogs 将等待审批的推送恢复为原计划状态：静默时段内的暂存，定时推送等待计划时间，其余待发送
+func releaseApprovedLogs(tx *gorm.DB, approval *models.PushApproval) error {
+	logs := tx.Model(&models.PushLog{}).Where("batch_id = ? AND status = ?", approval.BatchID, "pending_approval")
+	if err := logs.Session(&gorm.Session{}).Where("hold_until IS NOT NULL").Update("status", "held").Error; err != nil {
+		return err
+	}
+	status := "pending"
+	if approval.ScheduleTime != nil {
+		status = "scheduled"
+	}
+	if err := logs.Session(&gorm.Session{}).Update("status", status).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.PushCampaign{}).
+		Where("id IN (?) AND status = ?", batchCampaignIDs(tx, approval.BatchID), "pending_approval").
+		Update("status", "running").Error
+}
+
+// rejectLogs 将被拒绝的推送中尚未投递的日志标记为 rejected，并取消分批发送任务
+func rejectLogs(tx *gorm.DB, approval *models.PushApproval) error {
+	if err := tx.Model(&models.PushLog{}).
+		Where("batch_id = ? AND status IN ?", approval.BatchID, []string{"pending_approval", "staged"}).
+		Update("status", "rejected").Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.PushCampaign{}).
+		Where("id IN (?) AND status = ?", batchCampaignIDs(tx, approval.BatchID), "pending_approval").
+		Update("status", "cancelled").Error
+}
+
+// batchCampaignIDs 批次所属分批发送任务ID的子查询
+func batchCampaignIDs(tx *gorm.DB, batchID string) *gorm.DB {
+	return tx.Model(&models.PushLog{}).Select("campaign_id").Where("batch_id = ? AND campaign_id > 0", batchID)
+}
+
+// closeInboxItems 将审批对应的收件箱条目同步为审批结果
+func closeInboxItems(tx *gorm.DB, approvalID uint, status string, now time.Time) error {
+	return tx.Model(&models.AppInvitation{}).
+		Where("push_approval_id = ? AND status = ?", approvalID, "pending").
+		Updates(map[string]interface{}{"status": status, "responded_at": now}).Error
+}
+
+// cancelApproval 推送被取消时一并取消待处理的审批
+func cancelApproval(tx *gorm.DB, appID uint, batchID string) error {
+	var approval models.PushApproval
+	if err := tx.Where("app_id = ? AND batch_id = ? AND status = ?", appID, batchID, "pending").First(&approval).Error; err != nil {
+		return nil
+	}
+	now := utils.TimeNow()
+	if err := tx.Model(&approval).Updates(map[string]interface{}{"status": "cancelled", "reviewed_at": now}).Error; err != nil {
+		return err
+	}
+	return closeInboxItems(tx, approval.ID, "cancelled", now)
+}
+
+// dispatch 审批通过后开始投递：分批发送由任务协程发送，定时推送加入定时队列，其余立即发送
+func (s *PushApprovalService) dispatch(approval *models.PushApproval) {
+	pushService := NewPushService()
+
+	var campaignIDs []uint
+	database.DB.Model(&models.PushCampaign{}).
+		Where("id IN (?) AND status = ?", batchCampaignIDs(database.DB, approval.BatchID), "running").
+		Pluck("id", &campaignIDs)
+	for _, id := range campaignIDs {
+		NewCampaignService().startCampaign(id)
+	}
+
+	if approval.ScheduleTime != nil {
+		var req PushRequest
+		if err := json.Unmarshal([]byte(approval.Request), &req); err != nil {
+			logger.Error("解析推送审批的请求失败", "app_id", approval.AppID, "approval_id", approval.ID, "error", err)
+			return
+		}
+		go pushService.scheduleQueuePush(approval.AppID, req, *approval.ScheduleTime)
+		return
+	}
+
+	var pendingLogs []models.PushLog
+	if err := database.DB.Where("batch_id = ? AND campaign_id = 0 AND status = ?", approval.BatchID, "pending").
+		Find(&pendingLogs).Error; err != nil {
+		logger.Error("获取推送审批的待发送推送失败", "app_id", approval.AppID, "approval_id", approval.ID, "error", err)
+		return
+	}
+	go pushService.processPushLogs(pendingLogs)
+}
+
+// respondInbox 通过收件箱条目批准或拒绝推送
+func (s *PushApprovalService) respondInbox(userID uint, item models.AppInvitation, approve bool) (*models.AppInvitation, error) {
+	if item.PushApprovalID == nil {
+		return nil, ErrInvitationNotFound
+	}
+	if _, err := s.Review(item.AppID, userID, *item.PushApprovalID, approve, ""); err != nil {
+		return nil, err
+	}
+	if err := database.DB.First(&item, item.ID).Error; err != nil {
+		return nil, ErrInvitationNotFound
+	}
+	return &item, nil
+}
+
+// audit 记录审批相关的审计日志
+func (s *PushApprovalService) audit(userID uint, approval *models.PushApproval, action, note string) {
+	details := map[string]interface{}{
+		"batch_id":     approval.BatchID,
+		"reason":       approval.Reason,
+		"title":        approval.Title,
+		"target_type":  approval.TargetType,
+		"device_count": approval.DeviceCount,
+		"requester_id": approval.RequesterID,
+		"status":       approval.Status,
+	}
+	if note != "" {
+		details["review_note"] = note
+	}
+	appID := approval.AppID
+	if err := NewAuditService().LogActionWithContext(userID, &appID, action, "push_approval",
+		strconv.FormatUint(uint64(approval.ID), 10), "", "", nil, details); err != nil {
+		logger.Error("记录推送审批审计日志失败", "app_id", approval.AppID, "approval_id", approval.ID, "error", err)
+	}
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/doopush/doopush/api/internal/models"
+)
+
+func TestApprovalReason(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      models.AppApprovalPolicy
+		targetType  string
+		deviceCount int
+		want        string
+	}{
+		{"no policy", models.AppApprovalPolicy{}, "all", 100000, ""},
+		{"broadcast", models.AppApprovalPolicy{RequireForBroadcast: true}, "all", 1, ApprovalReasonBroadcast},
+		{"targeted send below threshold", models.AppApprovalPolicy{RequireForBroadcast: true, DeviceThreshold: 1000}, "tags", 1000, ""},
+		{"targeted send above threshold", models.AppApprovalPolicy{DeviceThreshold: 1000}, "tags", 1001, ApprovalReasonDeviceThreshold},
+		{"broadcast above threshold", models.AppApprovalPolicy{DeviceThreshold: 1000}, "all", 5000, ApprovalReasonDeviceThreshold},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := approvalReason(&tt.policy, tt.targetType, tt.deviceCount); got != tt.want {
+				t.Errorf("approvalReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanReview(t *testing.T) {
+	ownersOnly := &models.AppApprovalPolicy{}
+	withDevelopers := &models.AppApprovalPolicy{AllowDeveloperApproval: true}
+
+	if !canReview("owner", ownersOnly) {
+		t.Error("owner should be able to review")
+	}
+	if canReview("developer", ownersOnly) {
+		t.Error("developer should not review when the policy requires an owner")
+	}
+	if !canReview("developer", withDevelopers) {
+		t.Error("developer should review when the policy allows it")
+	}
+	if canReview("viewer", withDevelopers) || canReview("", withDevelopers) {
+		t.Error("viewers and non-members should never review")
+	}
+}
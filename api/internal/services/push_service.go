@@ -1,16 +1,21 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"math/rand"
 	"time"
 
 	"github.com/doopush/doopush/api/internal/database"
+	"github.com/doopush/doopush/api/internal/metrics"
 	"github.com/doopush/doopush/api/internal/models"
 	"github.com/doopush/doopush/api/internal/push"
+	"github.com/doopush/doopush/api/internal/tracing"
+	"github.com/doopush/doopush/api/pkg/logger"
 	"github.com/doopush/doopush/api/pkg/utils"
+	"go.opentelemetry.io/otel/attribute"
 	"gorm.io/gorm"
 )
 
@@ -24,17 +29,28 @@ func NewPushService() *PushService {
 
 // PushRequest 推送请求结构
 type PushRequest struct {
-	Title    string                 `json:"title" binding:"required"`
-	Content  string                 `json:"content" binding:"required"`
-	Payload  map[string]interface{} `json:"payload,omitempty"`
-	Target   PushTarget             `json:"target" binding:"required"`
-	Schedule *time.Time             `json:"schedule_time,omitempty"`
-	Badge    *int                   `json:"badge,omitempty"` // 新增角标字段
+	Title       string                 `json:"title" binding:"required_unless=MessageType data"`
+	Content     string                 `json:"content" binding:"required_unless=MessageType data"`
+	Payload     map[string]interface{} `json:"payload,omitempty"`
+	Target      PushTarget             `json:"target" binding:"required"`
+	Schedule    *time.Time             `json:"schedule_time,omitempty"`
+	Badge       *BadgeValue            `json:"badge,omitempty"`        // 角标：数字、"+N" 或 "reset"，未设置时通知消息按 +1 累加
+	MessageType string                 `json:"message_type,omitempty"` // notification（默认）或 data（静默/透传消息）
+	Category    string                 `json:"category,omitempty"`     // 统一消息分类：transactional/marketing/im/account
+	TTLSeconds  int                    `json:"ttl_seconds,omitempty"`  // 消息存活时长（秒），超时未送达则丢弃
+	Priority    string                 `json:"priority,omitempty"`     // 投递优先级：high（默认）/normal
+	CollapseKey string                 `json:"collapse_key,omitempty"` // 合并键，相同合并键的新消息覆盖旧消息
+	Topic       string                 `json:"topic,omitempty"`        // 订阅主题，未订阅该主题的设备将被排除
+	Rollout     *RolloutOptions        `json:"rollout,omitempty"`      // 分批发送：按速率节流或按比例灰度
+	Variants    []PushVariantInput     `json:"variants,omitempty"`     // A/B 测试内容变体，设备按哈希分配到各变体
+	ABTest      *ABTestOptions         `json:"ab_test,omitempty"`      // A/B 测试参数
+	ViaAPIKey   bool                   `json:"-"`                      // 通过 API Key 发起：API Key 以应用 owner 身份认证，不享受 owner 免审批
+	Sandbox     bool                   `json:"-"`                      // 通过测试密钥发起：只推送沙箱设备，由沙箱记录而不发往厂商
 }
 
 // PushTarget 推送目标
 type PushTarget struct {
-	Type      string      `json:"type" binding:"required,oneof=all devices tags groups" example:"devices"`
+	Type      string      `json:"type" binding:"required,oneof=all devices tags groups users topics" example:"devices"`
 	DeviceIDs []uint      `json:"device_ids,omitempty"`
 	TagIDs    []uint      `json:"tag_ids,omitempty"` // 保留旧的TagIDs用于兼容
 	GroupIDs  []uint      `json:"group_ids,omitempty"`
@@ -42,6 +58,9 @@ type PushTarget struct {
 	Platform  string      `json:"platform,omitempty" example:"ios"`
 	Channel   string      `json:"channel,omitempty" example:"fcm"`                 // 推送通道筛选
 	PushEnv   string      `json:"push_environment,omitempty" example:"production"` // APNs development/production
+	UserIDs   []string    `json:"user_ids,omitempty" example:"12345"`              // 外部用户ID，推送到用户绑定的所有设备
+	Topics    []string    `json:"topics,omitempty" example:"order_updates"`        // 订阅主题，推送到订阅了任一主题的设备
+	Timezones []string    `json:"timezones,omitempty" example:"Asia/Shanghai"`     // 设备时区筛选，空字符串匹配未上报时区的设备
 }
 
 // TagFilter 标签筛选条件
@@ -51,7 +70,18 @@ type TagFilter struct {
 }
 
 // SendPush 发送推送
-func (s *PushService) SendPush(appID uint, userID uint, req PushRequest) ([]models.PushLog, error) {
+func (s *PushService) SendPush(ctx context.Context, appID uint, userID uint, req PushRequest) ([]models.PushLog, error) {
+	ctx, span := tracing.Start(ctx, "PushService.SendPush",
+		attribute.Int("doopush.app_id", int(appID)),
+		attribute.String("doopush.target_type", req.Target.Type))
+	pushLogs, err := s.sendPush(ctx, appID, userID, req)
+	span.SetAttributes(attribute.Int("doopush.push_logs", len(pushLogs)))
+	tracing.End(span, err)
+	return pushLogs, err
+}
+
+// sendPush 创建推送日志并开始投递，ctx 中的链路随推送日志保存，供异步投递接续
+func (s *PushService) sendPush(ctx context.Context, appID uint, userID uint, req PushRequest) ([]models.PushLog, error) {
 	// 检查用户权限
 	userService := NewUserService()
 	hasPermission, err := userService.CheckAppPermission(userID, appID, "developer")
@@ -62,16 +92,67 @@ func (s *PushService) SendPush(appID uint, userID uint, req PushRequest) ([]mode
 		return nil, errors.New("无权限发送推送")
 	}
 
+	// 校验消息类型和投递参数
+	messageType := req.MessageType
+	if messageType == "" {
+		messageType = "notification"
+	}
+	if err := validateDeliveryOptions(req.Title, req.Content, messageType, req.Category, req.TTLSeconds, req.Priority); err != nil {
+		return nil, err
+	}
+	if req.Rollout != nil {
+		if req.Schedule != nil {
+			return nil, errors.New("分批发送不支持定时推送")
+		}
+		if err := validateRollout(req.Rollout); err != nil {
+			return nil, err
+		}
+	}
+	if len(req.Variants) > 0 {
+		if err := validateVariants(req); err != nil {
+			return nil, err
+		}
+	}
+
+	// 计算过期时间：定时推送从计划时间开始计算存活时长
+	var expiresAt *time.Time
+	if req.TTLSeconds > 0 {
+		base := utils.TimeNow()
+		if req.Schedule != nil {
+			base = *req.Schedule
+		}
+		expireTime := base.Add(time.Duration(req.TTLSeconds) * time.Second)
+		expiresAt = &expireTime
+	}
+
 	// 获取目标设备
-	devices, err := s.getTargetDevices(appID, req.Target)
+	_, querySpan := tracing.Start(ctx, "PushService.getTargetDevices")
+	devices, err := s.getTargetDevices(appID, req.Target, req.Sandbox)
+	querySpan.SetAttributes(attribute.Int("doopush.devices", len(devices)))
+	tracing.End(querySpan, err)
 	if err != nil {
 		return nil, err
 	}
 
+	devices = filterByTimezone(devices, req.Target.Timezones)
+
 	if len(devices) == 0 {
 		return nil, errors.New("没有找到目标设备")
 	}
 
+	// 命中应用审批策略的推送在批准前不投递
+	approvalReason, err := NewPushApprovalService().requiresApproval(appID, userID, req.ViaAPIKey, req.Target.Type, len(devices))
+	if err != nil {
+		return nil, err
+	}
+	targetCount := len(devices)
+
+	// 排除已退订该消息分类或主题的设备，排除结果记录为 excluded 推送日志
+	devices, skipped, err := s.excludeUnsubscribed(appID, req, devices)
+	if err != nil {
+		return nil, err
+	}
+
 	// 准备推送载荷
 	payloadJSON := "{}"
 	if req.Payload != nil {
@@ -80,61 +161,360 @@ func (s *PushService) SendPush(appID uint, userID uint, req PushRequest) ([]mode
 		}
 	}
 
-	// 创建推送日志
+	// 按通道翻译消息分类，配置缺失或与显式厂商参数冲突时整体拒绝，不创建任何推送日志
+	channelPayloads := make(map[string]string)
+	if req.Category != "" {
+		channelPayloads, err = s.buildCategoryPayloads(appID, req.Category, req.Payload, devices)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 角标取值：未指定时通知消息累加1，透传消息保持当前计数。服务端计数在投递时才更新
+	badge := BadgeValue{Op: BadgeIncrement, Amount: 1}
+	if req.Badge != nil {
+		badge = *req.Badge
+	} else if messageType == "data" {
+		badge = BadgeValue{Op: BadgeIncrement, Amount: 0}
+	}
+	badgeSpec := badge.String()
+	if badge.Op == BadgeIncrement && badge.Amount == 0 {
+		badgeSpec = "" // 计数不变
+	}
+
+	// 普通优先级的通知消息在设备本地的静默时段内暂存，时段结束后再投递；未设置优先级按 high 处理
+	var app models.App
+	quietHours := false
+	if req.Schedule == nil && messageType == "notification" && req.Priority == "normal" {
+		if err := database.DB.First(&app, appID).Error; err == nil && app.QuietStart != "" && app.QuietEnd != "" {
+			quietHours = true
+		}
+	}
+	now := utils.TimeNow()
+
+	// 分批发送：创建任务，灰度时随机选取设备，超出灰度数量的推送暂不发送（staged）。
+	// A/B 测试同样以任务执行，设备按哈希分配变体，测试组之外的设备等待胜出变体
+	var campaign *models.PushCampaign
+	var variants []models.PushVariant
+	if req.Rollout != nil || len(req.Variants) > 0 {
+		campaign, variants, err = NewCampaignService().createCampaign(appID, userID, req, approvalReason != "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 频控：超出应用每日配额或单设备日/周上限的设备记录为 suppressed 推送日志。沙箱推送不占用正式配额。
+	// 放在所有可能失败的校验之后，请求失败时不占用配额；日志记录占用标记，取消或驳回时归还
+	capReserved := ""
+	if !req.Sandbox {
+		var suppressed []skippedDevice
+		devices, suppressed, capReserved, err = NewFrequencyCapService().applyCaps(appID, req.Category, devices)
+		if err != nil {
+			return nil, err
+		}
+		skipped = append(skipped, suppressed...)
+	}
+
+	canaryLimit := len(devices)
+	if campaign != nil && campaign.Stage == "canary" && len(variants) == 0 {
+		rand.Shuffle(len(devices), func(i, j int) { devices[i], devices[j] = devices[j], devices[i] })
+		canaryLimit = canarySize(len(devices), campaign.RolloutPercent)
+	}
+
+	// 同一次请求创建的日志共享批次ID，用于整体取消、撤回或审批
+	batchID := utils.GenerateAPIKey()
+	traceID, traceParent := tracing.TraceID(ctx), tracing.TraceParent(ctx)
+
+	// 创建推送日志，没有创建日志的设备归还预占的频控计数
 	var pushLogs []models.PushLog
+	var pendingLogs []models.PushLog
+	var unlogged []models.Device
+	defer func() { NewFrequencyCapService().releaseDeviceCaps(appID, capReserved, unlogged) }()
 	for _, device := range devices {
 		// 在线设备走网关直推，跳过通知栏消息（多数路径 SQL 已过滤，此处兜底）
 		if device.IsOnline {
+			unlogged = append(unlogged, device)
 			continue
 		}
 		// 生成去重键
+		dedupContent := req.Content
+		if messageType == "data" {
+			dedupContent = payloadJSON // 透传消息没有正文，按载荷去重
+		}
 		dedupKey := utils.HashString(fmt.Sprintf("%d_%s_%s_%d",
-			appID, req.Title, req.Content, device.ID))
+			appID, req.Title, dedupContent, device.ID))
 
 		pushLog := models.PushLog{
-			AppID:    appID,
-			DeviceID: device.ID,
-			Title:    req.Title,
-			Content:  req.Content,
-			Payload:  payloadJSON,
-			Channel:  device.Channel,
-			Status:   "pending",
-			DedupKey: dedupKey,
-		}
-
-		// 设置角标数量
-		if req.Badge != nil {
-			pushLog.Badge = *req.Badge
-		} else {
-			pushLog.Badge = 1 // 默认角标数量为1
+			AppID:       appID,
+			DeviceID:    device.ID,
+			Title:       req.Title,
+			Content:     req.Content,
+			Payload:     payloadJSON,
+			Channel:     device.Channel,
+			Status:      "pending",
+			DedupKey:    dedupKey,
+			MessageType: messageType,
+			Category:    req.Category,
+			TTLSeconds:  req.TTLSeconds,
+			Priority:    req.Priority,
+			CollapseKey: req.CollapseKey,
+			ExpiresAt:   expiresAt,
+			CapReserved: capReserved,
+			BatchID:     batchID,
+			TraceID:     traceID,
+			TraceParent: traceParent,
+			Sandbox:     req.Sandbox,
+		}
+		if channelPayload, ok := channelPayloads[device.Channel]; ok {
+			pushLog.Payload = channelPayload
+		}
+
+		// 按当前计数预估角标，投递时再按 BadgeSpec 更新计数并写入实际值
+		pushLog.Badge = badge.Apply(device.BadgeCount)
+		pushLog.BadgeSpec = badgeSpec
+
+		variantIndex := -1
+		if campaign != nil {
+			pushLog.CampaignID = campaign.ID
+			if len(variants) > 0 {
+				variantIndex = assignVariant(campaign.ID, device.ID, campaign.RolloutPercent, len(variants))
+			}
+			if variantIndex >= 0 {
+				pushLog.Variant = variants[variantIndex].Key
+				pushLog.Title = variants[variantIndex].Title
+				pushLog.Content = variants[variantIndex].Content
+			}
 		}
 
 		// 如果是定时推送，添加到队列
 		if req.Schedule != nil {
 			pushLog.Status = "scheduled"
+		} else if quietHours {
+			if release, ok := quietHoursRelease(now, app.QuietStart, app.QuietEnd, deviceLocation(device)); ok {
+				pushLog.Status = "held"
+				pushLog.HoldUntil = &release
+			}
+		}
+		if len(variants) > 0 && variantIndex < 0 {
+			// 测试组之外的设备在选出胜出变体后发送
+			pushLog.Status = "staged"
+			pushLog.HoldUntil = nil
+		} else if pushLog.Status == "pending" && campaign != nil && len(variants) == 0 {
+			if canaryLimit > 0 {
+				canaryLimit--
+			} else {
+				pushLog.Status = "staged"
+			}
+		}
+		if approvalReason != "" && pushLog.Status != "staged" {
+			// 批准后按 hold_until 和定时设置恢复为原计划状态
+			pushLog.Status = "pending_approval"
 		}
 
+		if err := database.DB.Create(&pushLog).Error; err != nil {
+			logger.WarnContext(ctx, "创建推送日志失败", "app_id", appID, "device_id", device.ID, "error", err)
+			unlogged = append(unlogged, device)
+			continue
+		}
+		pushLogs = append(pushLogs, pushLog)
+		if pushLog.Status == "pending" {
+			pendingLogs = append(pendingLogs, pushLog)
+		}
+	}
+
+	// 记录被排除或抑制的设备，不参与投递
+	var skippedLogs []models.PushLog
+	for _, device := range skipped {
+		pushLog := models.PushLog{
+			AppID:       appID,
+			DeviceID:    device.ID,
+			Title:       req.Title,
+			Content:     req.Content,
+			Payload:     payloadJSON,
+			Channel:     device.Channel,
+			Status:      device.status,
+			MessageType: messageType,
+			Category:    req.Category,
+			Badge:       device.BadgeCount,
+			SkipReason:  device.reason,
+			BatchID:     batchID,
+			TraceID:     traceID,
+			Sandbox:     req.Sandbox,
+		}
 		if err := database.DB.Create(&pushLog).Error; err == nil {
-			pushLogs = append(pushLogs, pushLog)
+			skippedLogs = append(skippedLogs, pushLog)
 		}
 	}
 
+	// 日志全部创建后再发起审批，避免审批通过时仍有日志未创建
+	if approvalReason != "" {
+		if campaign != nil {
+			database.DB.Model(campaign).Update("total", len(pushLogs))
+		}
+		if _, err := NewPushApprovalService().requestApproval(appID, userID, batchID, approvalReason, req, targetCount); err != nil {
+			// 审批创建失败时取消本次推送，避免日志一直等待审批
+			database.DB.Model(&models.PushCampaign{}).
+				Where("id IN (?)", batchCampaignIDs(database.DB, batchID)).
+				Update("status", "cancelled")
+			database.DB.Model(&models.PushLog{}).
+				Where("batch_id = ? AND status IN ?", batchID, []string{"pending_approval", "staged"}).
+				Update("status", "cancelled")
+			NewFrequencyCapService().releaseCapReservations("batch_id", batchID)
+			return nil, err
+		}
+		return append(pushLogs, skippedLogs...), nil
+	}
+
 	// 立即推送或加入队列
-	if req.Schedule == nil {
-		// 立即推送
-		go s.processPushLogs(pushLogs)
+	if campaign != nil {
+		// 分批发送由任务协程按速率发送，并响应暂停、取消
+		database.DB.Model(campaign).Update("total", len(pushLogs))
+		NewCampaignService().startCampaign(campaign.ID)
+	} else if req.Schedule == nil {
+		// 立即推送（静默时段内暂存的日志由调度器释放）
+		go s.processPushLogs(pendingLogs)
 	} else {
 		// 加入定时队列
 		go s.scheduleQueuePush(appID, req, *req.Schedule)
 	}
 
-	return pushLogs, nil
+	return append(pushLogs, skippedLogs...), nil
+}
+
+// validateDeliveryOptions 校验消息类型、分类、存活时长和优先级，定时推送在创建时使用同样的校验
+func validateDeliveryOptions(title, content, messageType, category string, ttlSeconds int, priority string) error {
+	if messageType != "notification" && messageType != "data" {
+		return errors.New("无效的消息类型")
+	}
+	if messageType == "notification" && (title == "" || content == "") {
+		return errors.New("通知消息必须包含标题和内容")
+	}
+	if category != "" && !push.IsValidCategory(category) {
+		return errors.New("无效的消息分类")
+	}
+	if ttlSeconds < 0 {
+		return errors.New("无效的消息存活时长")
+	}
+	if priority != "" && priority != "high" && priority != "normal" {
+		return errors.New("无效的推送优先级")
+	}
+	return nil
+}
+
+// filterByTimezone 按设备时区筛选，timezones 为空时不筛选
+func filterByTimezone(devices []models.Device, timezones []string) []models.Device {
+	if len(timezones) == 0 {
+		return devices
+	}
+	zoneSet := make(map[string]bool, len(timezones))
+	for _, zone := range timezones {
+		zoneSet[zone] = true
+	}
+	var filtered []models.Device
+	for _, device := range devices {
+		if zoneSet[device.Timezone] {
+			filtered = append(filtered, device)
+		}
+	}
+	return filtered
+}
+
+// skippedDevice 未投递的设备：因订阅偏好被排除（excluded）或被频控抑制（suppressed）
+type skippedDevice struct {
+	models.Device
+	status string
+	reason string
+}
+
+// excludeUnsubscribed 按消息分类和订阅主题过滤目标设备，返回仍需投递的设备和被排除的设备
+func (s *PushService) excludeUnsubscribed(appID uint, req PushRequest, devices []models.Device) ([]models.Device, []skippedDevice, error) {
+	if req.Category == "" && req.Topic == "" {
+		return devices, nil, nil
+	}
+
+	var unsubscribed map[uint]bool
+	if req.Topic != "" {
+		var err error
+		unsubscribed, err = NewTopicService().unsubscribedDevices(appID, req.Topic, devices)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var remaining []models.Device
+	var excluded []skippedDevice
+	for _, device := range devices {
+		switch {
+		case req.Category != "" && deviceOptedOut(device, req.Category):
+			excluded = append(excluded, skippedDevice{Device: device, status: "excluded", reason: "category_opt_out"})
+		case unsubscribed[device.ID]:
+			excluded = append(excluded, skippedDevice{Device: device, status: "excluded", reason: "topic_unsubscribed"})
+		default:
+			remaining = append(remaining, device)
+		}
+	}
+	return remaining, excluded, nil
+}
+
+// buildCategoryPayloads 将统一消息分类翻译为各通道的厂商参数，返回 通道 -> 载荷JSON
+func (s *PushService) buildCategoryPayloads(appID uint, category string, payload map[string]interface{}, devices []models.Device) (map[string]string, error) {
+	channelPayloads := make(map[string]string)
+	for _, device := range devices {
+		channel := device.Channel
+		if _, done := channelPayloads[channel]; done {
+			continue
+		}
+
+		// 读取应用为该通道配置的分类映射
+		appMapping := push.CategoryMapping{}
+		var appConfig models.AppConfig
+		if err := database.DB.Where("app_id = ? AND channel = ? AND status = 1", appID, channel).First(&appConfig).Error; err == nil {
+			mapping, err := push.ParseCategoryMapping(appConfig.CategoryMap)
+			if err != nil {
+				return nil, fmt.Errorf("%s 通道%v", channel, err)
+			}
+			appMapping = mapping
+		}
+
+		fields, err := push.ResolveCategoryFields(channel, category, appMapping)
+		if err != nil {
+			return nil, err
+		}
+
+		channelPayload := make(map[string]interface{}, len(payload)+1)
+		for k, v := range payload {
+			channelPayload[k] = v
+		}
+		if len(fields) > 0 {
+			// 厂商参数可能是结构体（如 APNs 选项），统一转为 map 再合并
+			vendorParams := make(map[string]interface{})
+			if raw, ok := channelPayload[channel]; ok && raw != nil {
+				data, _ := json.Marshal(raw)
+				if err := json.Unmarshal(data, &vendorParams); err != nil {
+					return nil, fmt.Errorf("%s 厂商参数格式错误", channel)
+				}
+			}
+			merged, err := push.MergeCategoryFields(channel, vendorParams, fields)
+			if err != nil {
+				return nil, err
+			}
+			channelPayload[channel] = merged
+		}
+
+		data, err := json.Marshal(channelPayload)
+		if err != nil {
+			return nil, errors.New("推送载荷序列化失败")
+		}
+		channelPayloads[channel] = string(data)
+	}
+	return channelPayloads, nil
 }
 
 // getTargetDevices 获取目标设备。
 // 在线设备由网关 TCP 通道直接送达，因此推送目标只取离线设备，避免下游再扫一遍 IsOnline。
-func (s *PushService) getTargetDevices(appID uint, target PushTarget) ([]models.Device, error) {
-	query := database.DB.Preload("App").Where("app_id = ? AND status = 1 AND is_online = ?", appID, false)
+// sandbox 为 true 时只取测试密钥注册的沙箱设备，否则只取正式设备。
+func (s *PushService) getTargetDevices(appID uint, target PushTarget, sandbox bool) ([]models.Device, error) {
+	query := database.DB.Preload("App").Where("app_id = ? AND status = 1 AND is_online = ? AND sandbox = ?", appID, false, sandbox)
 
 	// 平台筛选
 	if target.Platform != "" {
@@ -198,7 +578,7 @@ func (s *PushService) getTargetDevices(appID uint, target PushTarget) ([]models.
 			// 兼容旧的TagIDs方式（预加载App关联）
 			tagQuery := database.DB.Preload("App").Table("devices").
 				Joins("JOIN device_tag_maps ON devices.id = device_tag_maps.device_id").
-				Where("devices.app_id = ? AND devices.status = 1 AND devices.is_online = ? AND device_tag_maps.tag_id IN ?", appID, false, target.TagIDs)
+				Where("devices.app_id = ? AND devices.status = 1 AND devices.is_online = ? AND devices.sandbox = ? AND device_tag_maps.tag_id IN ?", appID, false, sandbox, target.TagIDs)
 			if target.Platform != "" {
 				tagQuery = tagQuery.Where("devices.platform = ?", target.Platform)
 			}
@@ -215,6 +595,32 @@ func (s *PushService) getTargetDevices(appID uint, target PushTarget) ([]models.
 		}
 		return devices, nil
 
+	case "users":
+		// 外部用户ID - 推送到用户绑定的所有设备
+		if len(target.UserIDs) == 0 {
+			return nil, errors.New("未指定目标用户")
+		}
+		var devices []models.Device
+		if err := query.Where("external_user_id IN ?", target.UserIDs).Find(&devices).Error; err != nil {
+			return nil, errors.New("获取用户设备失败")
+		}
+		return devices, nil
+
+	case "topics":
+		// 订阅主题 - 推送到订阅了任一主题的设备
+		if len(target.Topics) == 0 {
+			return nil, errors.New("未指定目标主题")
+		}
+		cond, args, err := NewTopicService().subscribedCondition(appID, target.Topics)
+		if err != nil {
+			return nil, err
+		}
+		var devices []models.Device
+		if err := query.Where(cond, args...).Find(&devices).Error; err != nil {
+			return nil, errors.New("获取主题订阅设备失败")
+		}
+		return devices, nil
+
 	case "groups":
 		// 分组设备 - 通过分组条件查询
 		if len(target.GroupIDs) == 0 {
@@ -228,7 +634,7 @@ func (s *PushService) getTargetDevices(appID uint, target PushTarget) ([]models.
 			// 获取分组信息
 			group, err := s.getGroupByID(appID, groupID)
 			if err != nil {
-				log.Printf("获取分组 %d 失败: %v", groupID, err)
+				logger.Warn("获取分组失败", "app_id", appID, "group_id", groupID, "error", err)
 				continue
 			}
 
@@ -236,13 +642,13 @@ func (s *PushService) getTargetDevices(appID uint, target PushTarget) ([]models.
 			var filterRules []FilterRule
 			if group.Conditions != "" {
 				if err := json.Unmarshal([]byte(group.Conditions), &filterRules); err != nil {
-					log.Printf("解析分组 %d 条件失败: %v", groupID, err)
+					logger.Warn("解析分组条件失败", "app_id", appID, "group_id", groupID, "error", err)
 					continue
 				}
 			}
 
 			// 应用筛选规则查询设备（预加载App关联）
-			groupQuery := database.DB.Preload("App").Where("app_id = ? AND status = 1 AND is_online = ?", appID, false)
+			groupQuery := database.DB.Preload("App").Where("app_id = ? AND status = 1 AND is_online = ? AND sandbox = ?", appID, false, sandbox)
 			if target.Platform != "" {
 				groupQuery = groupQuery.Where("platform = ?", target.Platform)
 			}
@@ -256,7 +662,7 @@ func (s *PushService) getTargetDevices(appID uint, target PushTarget) ([]models.
 
 			var groupDevices []models.Device
 			if err := groupQuery.Find(&groupDevices).Error; err != nil {
-				log.Printf("查询分组 %d 设备失败: %v", groupID, err)
+				logger.Warn("查询分组设备失败", "app_id", appID, "group_id", groupID, "error", err)
 				continue
 			}
 
@@ -285,44 +691,275 @@ func (s *PushService) processPushLogs(pushLogs []models.PushLog) {
 	pushManager := push.NewPushManager()
 
 	for _, pushLog := range pushLogs {
-		// 获取设备信息（预加载App关联）
+		if !s.deliverPushLog(pushManager, pushLog) {
+			continue
+		}
+
+		// 避免推送过快
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// deliverPushLog 投递单条推送日志并记录结果，返回是否调用了推送通道
+func (s *PushService) deliverPushLog(pushManager *push.PushManager, pushLog models.PushLog) bool {
+	// 投递在发送请求返回后异步执行，不能沿用请求的 ctx，通过日志上的 traceparent 接续原链路
+	ctx, span := tracing.Start(tracing.ContextWithTraceParent(context.Background(), pushLog.TraceParent), "PushService.deliverPushLog",
+		attribute.Int("doopush.push_log_id", int(pushLog.ID)),
+		attribute.String("doopush.channel", pushLog.Channel))
+	defer span.End()
+
+	// 超过存活时长仍未发出的消息不再投递
+	if pushLog.ExpiresAt != nil && pushLog.ExpiresAt.Before(utils.TimeNow()) {
+		database.DB.Model(&pushLog).Where("status = ?", "pending").Update("status", "expired")
+		span.SetAttributes(attribute.String("doopush.status", "expired"))
+		return false
+	}
+
+	// 认领待发送的日志，已取消的日志不再投递
+	claim := database.DB.Model(&models.PushLog{}).
+		Where("id = ? AND status = ?", pushLog.ID, "pending").
+		Update("status", "sending")
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		return false
+	}
+
+	// 获取设备信息（预加载App关联）
+	var device models.Device
+	if err := database.DB.Preload("App").First(&device, pushLog.DeviceID).Error; err != nil {
+		// 设备不存在，标记失败
+		result := models.PushResult{
+			AppID:        pushLog.AppID,
+			PushLogID:    pushLog.ID,
+			Success:      false,
+			ErrorCode:    "DEVICE_NOT_FOUND",
+			ErrorMessage: "设备不存在",
+			ResponseData: "{}", // 初始化为空 JSON 对象
+		}
+
+		database.DB.Create(&result)
+		database.DB.Model(&pushLog).Updates(map[string]interface{}{
+			"status":  "failed",
+			"send_at": utils.TimeNow(),
+		})
+		metrics.PushesTotal.WithLabelValues(pushLog.Channel, "failed", result.ErrorCode).Inc()
+		tracing.Fail(span, result.ErrorCode, result.ErrorMessage)
+		return false
+	}
+
+	// 投递时才更新服务端角标计数，下发计算后的值
+	if pushLog.BadgeSpec != "" {
+		if badge, err := applyDeviceBadge(device.ID, pushLog.BadgeSpec); err == nil {
+			pushLog.Badge = badge
+			database.DB.Model(&pushLog).Update("badge", badge)
+		} else {
+			logger.WarnContext(ctx, "更新角标计数失败，按预估值下发", "app_id", pushLog.AppID, "push_log_id", pushLog.ID, "device_id", device.ID, "error", err)
+		}
+	}
+
+	// 发送推送
+	result := pushManager.SendPush(ctx, &device, &pushLog)
+
+	// 更新推送状态
+	status := "failed"
+	if result.Success {
+		status = "sent"
+	}
+	// 沙箱推送单独计数，不混入真实通道的成功率
+	channel := device.Channel
+	if pushLog.Sandbox || device.Sandbox {
+		channel = "sandbox"
+	}
+	metrics.PushesTotal.WithLabelValues(channel, status, result.ErrorCode).Inc()
+	span.SetAttributes(attribute.String("doopush.status", status))
+
+	// 保存结果到数据库
+	_, writeSpan := tracing.Start(ctx, "PushService.saveResult")
+	database.DB.Create(result)
+	database.DB.Model(&pushLog).Updates(map[string]interface{}{
+		"status":  status,
+		"send_at": utils.TimeNow(),
+	})
+	writeSpan.End()
+
+	// 厂商报告 token 无效时通知订阅了 device.token_invalid 的回调
+	if push.IsInvalidToken(result) {
+		NewWebhookService().Emit(DeviceEvent(WebhookEventDeviceTokenInvalid, &device))
+	}
+
+	// 发送期间推送被取消，发出后立即撤回
+	if result.Success {
+		var cancelling int64
+		database.DB.Model(&models.PushLog{}).Where("id = ? AND cancelling = ?", pushLog.ID, true).Count(&cancelling)
+		if cancelling > 0 {
+			go s.recallPushLogs([]models.PushLog{pushLog})
+		}
+	}
+	return true
+}
+
+// ExpireStalePushLogs 将超过存活时长仍在排队的推送日志标记为 expired
+func (s *PushService) ExpireStalePushLogs() (int64, error) {
+	result := database.DB.Model(&models.PushLog{}).
+		Where("status IN ? AND expires_at IS NOT NULL AND expires_at < ?", []string{"pending", "scheduled", "held", "staged", "pending_approval"}, utils.TimeNow()).
+		Update("status", "expired")
+	return result.RowsAffected, result.Error
+}
+
+// queuedStatuses 尚未发出的推送状态
+var queuedStatuses = []string{"pending", "sending", "scheduled", "held", "staged", "pending_approval"}
+
+// RecordQueueDepth 统计各状态下尚未发出的推送数，更新队列深度指标
+func (s *PushService) RecordQueueDepth() {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := database.DB.Model(&models.PushLog{}).
+		Select("status, COUNT(*) AS count").
+		Where("status IN ?", queuedStatuses).
+		Group("status").Scan(&rows).Error; err != nil {
+		logger.Error("统计推送队列深度失败", "error", err)
+		return
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	for _, status := range queuedStatuses {
+		metrics.PushQueueDepth.WithLabelValues(status).Set(float64(counts[status]))
+	}
+}
+
+// ReleaseHeldPushLogs 释放静默时段已结束的暂存推送并开始投递
+func (s *PushService) ReleaseHeldPushLogs() (int, error) {
+	var pushLogs []models.PushLog
+	if err := database.DB.Where("status = ? AND hold_until <= ?", "held", utils.TimeNow()).
+		Limit(1000).Find(&pushLogs).Error; err != nil {
+		return 0, err
+	}
+	if len(pushLogs) == 0 {
+		return 0, nil
+	}
+
+	// 仅释放仍处于暂存状态的日志，避免并发调度重复投递
+	var released []models.PushLog
+	for _, pushLog := range pushLogs {
+		result := database.DB.Model(&models.PushLog{}).
+			Where("id = ? AND status = ?", pushLog.ID, "held").
+			Update("status", "pending")
+		if result.Error == nil && result.RowsAffected == 1 {
+			pushLog.Status = "pending"
+			released = append(released, pushLog)
+		}
+	}
+
+	// 分批发送的推送由任务协程继续发送
+	var direct []models.PushLog
+	for _, pushLog := range released {
+		if pushLog.CampaignID == 0 {
+			direct = append(direct, pushLog)
+		}
+	}
+	go s.processPushLogs(direct)
+	return len(released), nil
+}
+
+// CancelPushResult 取消推送的结果
+type CancelPushResult struct {
+	BatchID   string `json:"batch_id"`
+	Cancelled int64  `json:"cancelled" example:"1200"` // 尚未发出、已标记为 cancelled 的推送数
+	Recalling int    `json:"recalling" example:"300"`  // 已发出或正在发送、将在后台撤回的推送数
+}
+
+// CancelPushBatch 取消一次推送：未发出的日志标记为 cancelled，已发出的日志通过推送通道撤回
+func (s *PushService) CancelPushBatch(appID, userID uint, batchID string) (*CancelPushResult, error) {
+	hasPermission, err := NewUserService().CheckAppPermission(userID, appID, "developer")
+	if err != nil {
+		return nil, errors.New("权限检查失败")
+	}
+	if !hasPermission {
+		return nil, errors.New("无权限取消推送")
+	}
+
+	var total int64
+	database.DB.Model(&models.PushLog{}).Where("app_id = ? AND batch_id = ?", appID, batchID).Count(&total)
+	if total == 0 {
+		return nil, errors.New("推送不存在")
+	}
+
+	result := &CancelPushResult{BatchID: batchID}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		cancelled := tx.Model(&models.PushLog{}).
+			Where("app_id = ? AND batch_id = ? AND status IN ?", appID, batchID, []string{"pending", "scheduled", "held", "staged", "pending_approval"}).
+			Update("status", "cancelled")
+		if cancelled.Error != nil {
+			return cancelled.Error
+		}
+		result.Cancelled = cancelled.RowsAffected
+
+		// 正在调用厂商接口的日志无法拦截，标记后由投递协程在发出后撤回
+		inFlight := tx.Model(&models.PushLog{}).
+			Where("app_id = ? AND batch_id = ? AND status = ?", appID, batchID, "sending").
+			Update("cancelling", true)
+		if inFlight.Error != nil {
+			return inFlight.Error
+		}
+		result.Recalling = int(inFlight.RowsAffected)
+
+		// 分批发送的任务一并取消，停止任务协程
+		if err := tx.Model(&models.PushCampaign{}).
+			Where("id IN (?) AND status IN ?", batchCampaignIDs(tx, batchID), []string{"running", "paused", "pending_approval"}).
+			Update("status", "cancelled").Error; err != nil {
+			return err
+		}
+		return cancelApproval(tx, appID, batchID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("取消推送失败: %v", err)
+	}
+	NewFrequencyCapService().releaseCapReservations("batch_id", batchID)
+
+	// 已发出且尚未撤回的日志，发送中被取消的日志由投递协程撤回
+	var sentLogs []models.PushLog
+	if err := database.DB.Where("app_id = ? AND batch_id = ? AND status = ? AND cancelling = ?", appID, batchID, "sent", false).
+		Where("id NOT IN (?)", database.DB.Model(&models.PushRecall{}).Select("push_log_id").Where("app_id = ?", appID)).
+		Find(&sentLogs).Error; err != nil {
+		return nil, errors.New("获取已发送推送失败")
+	}
+	result.Recalling += len(sentLogs)
+	if len(sentLogs) > 0 {
+		go s.recallPushLogs(sentLogs)
+	}
+	return result, nil
+}
+
+// recallPushLogs 逐条撤回已发送的推送并记录结果
+func (s *PushService) recallPushLogs(pushLogs []models.PushLog) {
+	pushManager := push.NewPushManager()
+
+	for _, pushLog := range pushLogs {
+		var recall *models.PushRecall
 		var device models.Device
 		if err := database.DB.Preload("App").First(&device, pushLog.DeviceID).Error; err != nil {
-			// 设备不存在，标记失败
-			result := models.PushResult{
+			recall = &models.PushRecall{
 				AppID:        pushLog.AppID,
 				PushLogID:    pushLog.ID,
-				Success:      false,
+				Status:       push.RecallStatusFailed,
 				ErrorCode:    "DEVICE_NOT_FOUND",
 				ErrorMessage: "设备不存在",
-				ResponseData: "{}", // 初始化为空 JSON 对象
+				ResponseData: "{}",
 			}
-
-			database.DB.Create(&result)
-			database.DB.Model(&pushLog).Updates(map[string]interface{}{
-				"status":  "failed",
-				"send_at": utils.TimeNow(),
-			})
-			continue
+		} else {
+			var sendResult models.PushResult
+			database.DB.Where("push_log_id = ?", pushLog.ID).First(&sendResult)
+			recall = pushManager.RecallPush(&device, &pushLog, &sendResult)
 		}
 
-		// 发送推送
-		result := pushManager.SendPush(&device, &pushLog)
-
-		// 更新推送状态
-		status := "failed"
-		if result.Success {
-			status = "sent"
+		if err := database.DB.Create(recall).Error; err != nil {
+			logger.Error("保存推送撤回结果失败", "app_id", pushLog.AppID, "push_log_id", pushLog.ID, "error", err)
 		}
 
-		// 保存结果到数据库
-		database.DB.Create(result)
-		database.DB.Model(&pushLog).Updates(map[string]interface{}{
-			"status":  status,
-			"send_at": utils.TimeNow(),
-		})
-
-		// 避免推送过快
+		// 避免请求过快
 		time.Sleep(50 * time.Millisecond)
 	}
 }
@@ -343,6 +980,12 @@ func (s *PushService) scheduleQueuePush(appID uint, req PushRequest, scheduleTim
 		Content:      req.Content,
 		Payload:      string(payloadJSON),
 		Target:       string(targetJSON),
+		MessageType:  req.MessageType,
+		Category:     req.Category,
+		TTLSeconds:   req.TTLSeconds,
+		PushPriority: req.Priority,
+		CollapseKey:  req.CollapseKey,
+		Topic:        req.Topic,
 		ScheduleTime: &scheduleTime,
 		Status:       "scheduled",
 		Priority:     5,
@@ -354,11 +997,11 @@ func (s *PushService) scheduleQueuePush(appID uint, req PushRequest, scheduleTim
 
 // GetPushLogs 获取推送日志（兼容旧接口）
 func (s *PushService) GetPushLogs(appID uint, userID uint, page, pageSize int) ([]models.PushLog, int64, error) {
-	return s.GetPushLogsWithFilters(appID, userID, page, pageSize, "", "")
+	return s.GetPushLogsWithFilters(appID, userID, page, pageSize, "", "", "")
 }
 
-// GetPushLogsWithFilters 获取推送日志（支持筛选）
-func (s *PushService) GetPushLogsWithFilters(appID uint, userID uint, page, pageSize int, status, platform string) ([]models.PushLog, int64, error) {
+// GetPushLogsWithFilters 获取推送日志（支持筛选），sandbox 为 true/false 时只看沙箱或正式推送
+func (s *PushService) GetPushLogsWithFilters(appID uint, userID uint, page, pageSize int, status, platform, sandbox string) ([]models.PushLog, int64, error) {
 	// 检查用户权限
 	userService := NewUserService()
 	hasPermission, err := userService.CheckAppPermission(userID, appID, "viewer")
@@ -376,6 +1019,9 @@ func (s *PushService) GetPushLogsWithFilters(appID uint, userID uint, page, page
 	if status != "" && status != "all" {
 		query = query.Where("push_logs.status = ?", status)
 	}
+	if sandbox == "true" || sandbox == "false" {
+		query = query.Where("push_logs.sandbox = ?", sandbox == "true")
+	}
 
 	// 对于platform筛选，我们需要通过关联的Device表进行筛选
 	if platform != "" && platform != "all" {
@@ -393,6 +1039,7 @@ func (s *PushService) GetPushLogsWithFilters(appID uint, userID uint, page, page
 	err = query.
 		Preload("Device").     // 预加载设备信息
 		Preload("PushResult"). // 预加载推送结果
+		Preload("Recall").     // 预加载撤回结果
 		Offset(offset).
 		Limit(pageSize).
 		Order("push_logs.created_at DESC").
@@ -454,22 +1101,22 @@ func (s *PushService) GetPushStatistics(appID uint, days int) (*PushStatisticsRe
 
 	// 统计推送总数（半开区间，包含今日）
 	database.DB.Model(&models.PushLog{}).
-		Where("app_id = ? AND created_at >= ? AND created_at < ?", appID, startDate, endDate).
+		Where("app_id = ? AND sandbox = ? AND created_at >= ? AND created_at < ?", appID, false, startDate, endDate).
 		Count(&totalPushes)
 
 	// 统计成功推送
 	database.DB.Model(&models.PushLog{}).
-		Where("app_id = ? AND status = 'sent' AND created_at >= ? AND created_at < ?", appID, startDate, endDate).
+		Where("app_id = ? AND sandbox = ? AND status = 'sent' AND created_at >= ? AND created_at < ?", appID, false, startDate, endDate).
 		Count(&successPushes)
 
 	// 统计失败推送
 	database.DB.Model(&models.PushLog{}).
-		Where("app_id = ? AND status = 'failed' AND created_at >= ? AND created_at < ?", appID, startDate, endDate).
+		Where("app_id = ? AND sandbox = ? AND status = 'failed' AND created_at >= ? AND created_at < ?", appID, false, startDate, endDate).
 		Count(&failedPushes)
 
 	// 统计设备总数
 	database.DB.Model(&models.Device{}).
-		Where("app_id = ? AND status = 1", appID).
+		Where("app_id = ? AND status = 1 AND sandbox = ?", appID, false).
 		Count(&totalDevices)
 
 	// 获取每日统计数据（按自然日）
@@ -515,15 +1162,15 @@ func (s *PushService) getDailyStats(appID uint, startDate, endDate time.Time) []
 		// 查询当日推送统计（半开区间）
 		var totalPushes, successPushes, failedPushes int64
 		database.DB.Model(&models.PushLog{}).
-			Where("app_id = ? AND created_at >= ? AND created_at < ?", appID, dayStart, nextDay).
+			Where("app_id = ? AND sandbox = ? AND created_at >= ? AND created_at < ?", appID, false, dayStart, nextDay).
 			Count(&totalPushes)
 
 		database.DB.Model(&models.PushLog{}).
-			Where("app_id = ? AND status = 'sent' AND created_at >= ? AND created_at < ?", appID, dayStart, nextDay).
+			Where("app_id = ? AND sandbox = ? AND status = 'sent' AND created_at >= ? AND created_at < ?", appID, false, dayStart, nextDay).
 			Count(&successPushes)
 
 		database.DB.Model(&models.PushLog{}).
-			Where("app_id = ? AND status = 'failed' AND created_at >= ? AND created_at < ?", appID, dayStart, nextDay).
+			Where("app_id = ? AND sandbox = ? AND status = 'failed' AND created_at >= ? AND created_at < ?", appID, false, dayStart, nextDay).
 			Count(&failedPushes)
 
 		// 查询点击和打开数据（从统计表，使用自然日匹配）
@@ -556,34 +1203,34 @@ func (s *PushService) getPlatformStats(appID uint, startDate time.Time) []Platfo
 	var iosTotal, iosSuccess, iosFailed int64
 	database.DB.Model(&models.PushLog{}).
 		Joins("JOIN devices ON push_logs.device_id = devices.id").
-		Where("push_logs.app_id = ? AND devices.platform = 'ios' AND push_logs.created_at >= ?", appID, startDate).
+		Where("push_logs.app_id = ? AND push_logs.sandbox = ? AND devices.platform = 'ios' AND push_logs.created_at >= ?", appID, false, startDate).
 		Count(&iosTotal)
 
 	database.DB.Model(&models.PushLog{}).
 		Joins("JOIN devices ON push_logs.device_id = devices.id").
-		Where("push_logs.app_id = ? AND devices.platform = 'ios' AND push_logs.status = 'sent' AND push_logs.created_at >= ?", appID, startDate).
+		Where("push_logs.app_id = ? AND push_logs.sandbox = ? AND devices.platform = 'ios' AND push_logs.status = 'sent' AND push_logs.created_at >= ?", appID, false, startDate).
 		Count(&iosSuccess)
 
 	database.DB.Model(&models.PushLog{}).
 		Joins("JOIN devices ON push_logs.device_id = devices.id").
-		Where("push_logs.app_id = ? AND devices.platform = 'ios' AND push_logs.status = 'failed' AND push_logs.created_at >= ?", appID, startDate).
+		Where("push_logs.app_id = ? AND push_logs.sandbox = ? AND devices.platform = 'ios' AND push_logs.status = 'failed' AND push_logs.created_at >= ?", appID, false, startDate).
 		Count(&iosFailed)
 
 	// 查询Android平台统计
 	var androidTotal, androidSuccess, androidFailed int64
 	database.DB.Model(&models.PushLog{}).
 		Joins("JOIN devices ON push_logs.device_id = devices.id").
-		Where("push_logs.app_id = ? AND devices.platform = 'android' AND push_logs.created_at >= ?", appID, startDate).
+		Where("push_logs.app_id = ? AND push_logs.sandbox = ? AND devices.platform = 'android' AND push_logs.created_at >= ?", appID, false, startDate).
 		Count(&androidTotal)
 
 	database.DB.Model(&models.PushLog{}).
 		Joins("JOIN devices ON push_logs.device_id = devices.id").
-		Where("push_logs.app_id = ? AND devices.platform = 'android' AND push_logs.status = 'sent' AND push_logs.created_at >= ?", appID, startDate).
+		Where("push_logs.app_id = ? AND push_logs.sandbox = ? AND devices.platform = 'android' AND push_logs.status = 'sent' AND push_logs.created_at >= ?", appID, false, startDate).
 		Count(&androidSuccess)
 
 	database.DB.Model(&models.PushLog{}).
 		Joins("JOIN devices ON push_logs.device_id = devices.id").
-		Where("push_logs.app_id = ? AND devices.platform = 'android' AND push_logs.status = 'failed' AND push_logs.created_at >= ?", appID, startDate).
+		Where("push_logs.app_id = ? AND push_logs.sandbox = ? AND devices.platform = 'android' AND push_logs.status = 'failed' AND push_logs.created_at >= ?", appID, false, startDate).
 		Count(&androidFailed)
 
 	if iosTotal > 0 {
@@ -625,6 +1272,7 @@ func (s *PushService) ReportPushStatistics(appID uint, deviceToken string, repor
 
 	// 按日期分组统计，用于批量更新 PushStatistics 表
 	dateStatsMap := make(map[string]*models.PushStatistics)
+	events := make([]WebhookEvent, 0, len(reports))
 
 	// 处理每个统计事件
 	for _, report := range reports {
@@ -647,7 +1295,7 @@ func (s *PushService) ReportPushStatistics(appID uint, deviceToken string, repor
 		}
 
 		if !found {
-			log.Printf("推送记录不存在: push_log_id=%d, dedup_key=%s", report.PushLogID, report.DedupKey)
+			logger.Warn("推送记录不存在", "app_id", appID, "push_log_id", report.PushLogID, "dedup_key", report.DedupKey)
 			continue // 跳过找不到的推送记录
 		}
 
@@ -655,52 +1303,63 @@ func (s *PushService) ReportPushStatistics(appID uint, deviceToken string, repor
 		eventTime := time.Unix(report.Timestamp, 0)
 		dateStr := eventTime.Format("2006-01-02")
 
-		// 获取或创建日期统计记录
-		if _, exists := dateStatsMap[dateStr]; !exists {
-			var stat models.PushStatistics
-			err := database.DB.Where("app_id = ? AND date = ?", appID, eventTime.Truncate(24*time.Hour)).First(&stat).Error
-			if err != nil {
-				// 如果不存在，创建新记录
-				stat = models.PushStatistics{
-					AppID:         appID,
-					Date:          eventTime.Truncate(24 * time.Hour),
-					TotalPushes:   0,
-					SuccessPushes: 0,
-					FailedPushes:  0,
-					ClickCount:    0,
-					OpenCount:     0,
+		// 获取或创建日期统计记录。沙箱推送只记录打开和点击时间，计数写入不保存的临时记录，不计入统计
+		stat := &models.PushStatistics{}
+		if !pushLog.Sandbox {
+			if _, exists := dateStatsMap[dateStr]; !exists {
+				var dayStat models.PushStatistics
+				err := database.DB.Where("app_id = ? AND date = ?", appID, eventTime.Truncate(24*time.Hour)).First(&dayStat).Error
+				if err != nil {
+					// 如果不存在，创建新记录
+					dayStat = models.PushStatistics{
+						AppID:         appID,
+						Date:          eventTime.Truncate(24 * time.Hour),
+						TotalPushes:   0,
+						SuccessPushes: 0,
+						FailedPushes:  0,
+						ClickCount:    0,
+						OpenCount:     0,
+					}
 				}
+				dateStatsMap[dateStr] = &dayStat
 			}
-			dateStatsMap[dateStr] = &stat
+			stat = dateStatsMap[dateStr]
 		}
 
 		// 更新对应的统计数据
 		switch report.Event {
 		case "click":
-			dateStatsMap[dateStr].ClickCount++
+			stat.ClickCount++
+			// 记录首次点击，用于分批发送的灰度点击率和 A/B 测试的点击率
+			database.DB.Model(&models.PushLog{}).Where("id = ? AND clicked_at IS NULL", pushLog.ID).Update("clicked_at", eventTime)
+			events = append(events, pushEvent(WebhookEventPushClicked, &pushLog))
 		case "open":
-			dateStatsMap[dateStr].OpenCount++
+			stat.OpenCount++
+			// 记录首次打开，用于 A/B 测试的打开率
+			database.DB.Model(&models.PushLog{}).Where("id = ? AND opened_at IS NULL", pushLog.ID).Update("opened_at", eventTime)
+			events = append(events, pushEvent(WebhookEventPushOpened, &pushLog))
 		}
 
-		log.Printf("统计事件记录: 设备=%s, 事件=%s, 推送=%d", deviceToken, report.Event, pushLog.ID)
+		logger.Debug("统计事件记录", "app_id", appID, "device_id", device.ID, "event", report.Event, "push_log_id", pushLog.ID)
 	}
+	NewWebhookService().EmitEvents(events)
 
 	// 批量更新或创建统计记录
 	for dateStr, stat := range dateStatsMap {
 		if stat.ID == 0 {
 			// 新建记录
 			if err := database.DB.Create(stat).Error; err != nil {
-				log.Printf("创建统计记录失败: %s - %v", dateStr, err)
+				logger.Error("创建统计记录失败", "app_id", appID, "date", dateStr, "error", err)
 				return errors.New("统计数据保存失败")
 			}
 		} else {
 			// 更新现有记录
 			if err := database.DB.Save(stat).Error; err != nil {
-				log.Printf("更新统计记录失败: %s - %v", dateStr, err)
+				logger.Error("更新统计记录失败", "app_id", appID, "date", dateStr, "error", err)
 				return errors.New("统计数据更新失败")
 			}
 		}
-		log.Printf("统计数据已更新: %s - 点击:%d, 打开:%d", dateStr, stat.ClickCount, stat.OpenCount)
+		logger.Debug("统计数据已更新", "app_id", appID, "date", dateStr, "clicks", stat.ClickCount, "opens", stat.OpenCount)
 	}
 
 	return nil
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/doopush/doopush/api/internal/database"
+	"github.com/doopush/doopush/api/internal/models"
+)
+
+// PushVariantInput A/B 测试的内容变体
+type PushVariantInput struct {
+	Key     string `json:"key" binding:"required,max=16" example:"A"`
+	Title   string `json:"title" binding:"required,max=200" example:"限时优惠"`
+	Content string `json:"content" binding:"required" example:"全场八折，仅限今天"`
+}
+
+// ABTestOptions A/B 测试参数
+type ABTestOptions struct {
+	TestPercent      int    `json:"test_percent,omitempty" binding:"omitempty,min=1,max=100" example:"20"`        // 参与测试的设备比例，其余设备等待胜出变体；默认 100，即全部设备参与测试
+	WinnerAfterHours int    `json:"winner_after_hours,omitempty" binding:"omitempty,min=1,max=168" example:"4"`   // 测试发送完成后等待的小时数，到期按指标自动发送胜出变体；0=手动全量
+	WinnerMetric     string `json:"winner_metric,omitempty" binding:"omitempty,oneof=open click" example:"click"` // 胜出指标：open=打开率，click=点击率（默认）
+}
+
+// VariantStats 变体的发送与互动统计
+type VariantStats struct {
+	Key       string  `json:"key" example:"A"`
+	Title     string  `json:"title" example:"限时优惠"`
+	Content   string  `json:"content" example:"全场八折，仅限今天"`
+	Total     int64   `json:"total" example:"1000"`
+	Sent      int64   `json:"sent" example:"980"`
+	Opened    int64   `json:"opened" example:"120"`
+	Clicked   int64   `json:"clicked" example:"45"`
+	OpenRate  float64 `json:"open_rate" example:"0.1224"`
+	ClickRate float64 `json:"click_rate" example:"0.0459"`
+}
+
+// validateVariants 校验 A/B 测试变体及参数
+func validateVariants(req PushRequest) error {
+	if len(req.Variants) < 2 || len(req.Variants) > 3 {
+		return errors.New("A/B 测试需要 2 至 3 个变体")
+	}
+	if req.MessageType == "data" {
+		return errors.New("A/B 测试仅支持通知消息")
+	}
+	if req.Schedule != nil {
+		return errors.New("A/B 测试不支持定时推送")
+	}
+	if req.Rollout != nil && req.Rollout.Percent > 0 && req.Rollout.Percent < 100 {
+		return errors.New("A/B 测试请使用 test_percent 设置测试比例")
+	}
+
+	keys := make(map[string]bool, len(req.Variants))
+	for _, variant := range req.Variants {
+		if variant.Key == "" || len(variant.Key) > 16 || variant.Title == "" || variant.Content == "" {
+			return errors.New("变体必须包含标识、标题和内容")
+		}
+		if keys[variant.Key] {
+			return fmt.Errorf("变体标识重复: %s", variant.Key)
+		}
+		keys[variant.Key] = true
+	}
+
+	if ab := req.ABTest; ab != nil {
+		if ab.TestPercent < 0 || ab.TestPercent > 100 || ab.WinnerAfterHours < 0 {
+			return errors.New("无效的 A/B 测试参数")
+		}
+		if ab.WinnerMetric != "" && ab.WinnerMetric != "open" && ab.WinnerMetric != "click" {
+			return errors.New("无效的胜出指标")
+		}
+		if (ab.TestPercent == 0 || ab.TestPercent == 100) && ab.WinnerAfterHours > 0 {
+			return errors.New("全部设备参与测试时无需发送胜出变体")
+		}
+	}
+	return nil
+}
+
+// assignVariant 按任务ID和设备ID哈希分配变体：同一设备在同一任务中的分配结果固定。
+// 不在测试比例内的设备返回 -1，等待胜出变体
+func assignVariant(campaignID, deviceID uint, testPercent, variantCount int) int {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%d:%d", campaignID, deviceID)
+	sum := h.Sum32()
+	if testPercent > 0 && testPercent < 100 && int(sum%100) >= testPercent {
+		return -1
+	}
+	return int((sum / 100) % uint32(variantCount))
+}
+
+// chooseWinner 按指标选出比率最高的变体，比率相同时取靠前的变体
+func chooseWinner(stats []VariantStats, metric string) string {
+	winner := ""
+	best := -1.0
+	for _, stat := range stats {
+		rate := stat.ClickRate
+		if metric == "open" {
+			rate = stat.OpenRate
+		}
+		if rate > best {
+			winner, best = stat.Key, rate
+		}
+	}
+	return winner
+}
+
+// variantStats 统计任务各变体的发送、打开和点击
+func variantStats(campaignID uint) ([]VariantStats, error) {
+	var variants []models.PushVariant
+	if err := database.DB.Where("campaign_id = ?", campaignID).Order("id ASC").Find(&variants).Error; err != nil {
+		return nil, errors.New("获取变体失败")
+	}
+	if len(variants) == 0 {
+		return nil, nil
+	}
+
+	var rows []struct {
+		Variant string
+		Total   int64
+		Sent    int64
+		Opened  int64
+		Clicked int64
+	}
+	if err := database.DB.Model(&models.PushLog{}).
+		Select("variant, COUNT(*) AS total, SUM(CASE WHEN status = 'sent' THEN 1 ELSE 0 END) AS sent, COUNT(opened_at) AS opened, COUNT(clicked_at) AS clicked").
+		Where("campaign_id = ? AND variant <> ''", campaignID).
+		Group("variant").Scan(&rows).Error; err != nil {
+		return nil, errors.New("获取变体统计失败")
+	}
+	counts := make(map[string]int, len(rows))
+	for i, row := range rows {
+		counts[row.Variant] = i
+	}
+
+	stats := make([]VariantStats, 0, len(variants))
+	for _, variant := range variants {
+		stat := VariantStats{Key: variant.Key, Title: variant.Title, Content: variant.Content}
+		if i, ok := counts[variant.Key]; ok {
+			stat.Total, stat.Sent, stat.Opened, stat.Clicked = rows[i].Total, rows[i].Sent, rows[i].Opened, rows[i].Clicked
+			if stat.Sent > 0 {
+				stat.OpenRate = float64(stat.Opened) / float64(stat.Sent)
+				stat.ClickRate = float64(stat.Clicked) / float64(stat.Sent)
+			}
+		}
+		stats = append(stats, stat)
+	}
+	return stats, nil
+}
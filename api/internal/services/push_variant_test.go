@@ -0,0 +1,71 @@
+package services
+
+import "testing"
+
+func TestAssignVariant(t *testing.T) {
+	counts := make([]int, 3)
+	outside := 0
+	for deviceID := uint(1); deviceID <= 3000; deviceID++ {
+		index := assignVariant(7, deviceID, 100, 3)
+		if index != assignVariant(7, deviceID, 100, 3) {
+			t.Fatalf("assignVariant is not deterministic for device %d", deviceID)
+		}
+		counts[index]++
+		if assignVariant(7, deviceID, 20, 3) == -1 {
+			outside++
+		}
+	}
+	for i, count := range counts {
+		if count < 800 || count > 1200 {
+			t.Errorf("variant %d got %d devices, want about 1000", i, count)
+		}
+	}
+	if outside < 2200 || outside > 2600 {
+		t.Errorf("got %d devices outside a 20%% test, want about 2400", outside)
+	}
+}
+
+func TestChooseWinner(t *testing.T) {
+	stats := []VariantStats{
+		{Key: "A", OpenRate: 0.2, ClickRate: 0.05},
+		{Key: "B", OpenRate: 0.1, ClickRate: 0.08},
+		{Key: "C", OpenRate: 0.2, ClickRate: 0.01},
+	}
+	if got := chooseWinner(stats, "click"); got != "B" {
+		t.Errorf("chooseWinner(click) = %q, want B", got)
+	}
+	if got := chooseWinner(stats, "open"); got != "A" {
+		t.Errorf("chooseWinner(open) = %q, want A", got)
+	}
+	if got := chooseWinner(nil, "click"); got != "" {
+		t.Errorf("chooseWinner(nil) = %q, want empty", got)
+	}
+}
+
+func TestValidateVariants(t *testing.T) {
+	variants := []PushVariantInput{
+		{Key: "A", Title: "标题A", Content: "内容A"},
+		{Key: "B", Title: "标题B", Content: "内容B"},
+	}
+	tests := []struct {
+		name    string
+		req     PushRequest
+		wantErr bool
+	}{
+		{"valid", PushRequest{Variants: variants}, false},
+		{"valid with winner", PushRequest{Variants: variants, ABTest: &ABTestOptions{TestPercent: 20, WinnerAfterHours: 4}}, false},
+		{"single variant", PushRequest{Variants: variants[:1]}, true},
+		{"duplicate key", PushRequest{Variants: []PushVariantInput{variants[0], variants[0]}}, true},
+		{"data message", PushRequest{Variants: variants, MessageType: "data"}, true},
+		{"rollout percent", PushRequest{Variants: variants, Rollout: &RolloutOptions{Percent: 10}}, true},
+		{"winner without holdout", PushRequest{Variants: variants, ABTest: &ABTestOptions{WinnerAfterHours: 4}}, true},
+		{"invalid metric", PushRequest{Variants: variants, ABTest: &ABTestOptions{WinnerMetric: "sent"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateVariants(tt.req); (err != nil) != tt.wantErr {
+				t.Errorf("validateVariants() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/pkg/utils"
+)
+
+// parseClock 解析 HH:MM 格式的时刻，返回当天的分钟数
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, errors.New("时刻格式必须为 HH:MM")
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// ValidateQuietHours 校验静默时段，开始和结束需同时设置或同时为空
+func ValidateQuietHours(start, end string) error {
+	if start == "" && end == "" {
+		return nil
+	}
+	if start == "" || end == "" {
+		return errors.New("静默时段的开始和结束时间需同时设置")
+	}
+	startMin, err := parseClock(start)
+	if err != nil {
+		return errors.New("无效的静默时段开始时间")
+	}
+	endMin, err := parseClock(end)
+	if err != nil {
+		return errors.New("无效的静默时段结束时间")
+	}
+	if startMin == endMin {
+		return errors.New("静默时段的开始和结束时间不能相同")
+	}
+	return nil
+}
+
+// quietHoursRelease 判断 now 在 loc 时区是否处于静默时段，处于时返回时段结束的时刻。
+// 开始晚于结束（如 22:00-08:00）表示跨午夜的时段
+func quietHoursRelease(now time.Time, start, end string, loc *time.Location) (time.Time, bool) {
+	startMin, err1 := parseClock(start)
+	endMin, err2 := parseClock(end)
+	if err1 != nil || err2 != nil || startMin == endMin {
+		return time.Time{}, false
+	}
+
+	local := now.In(loc)
+	current := local.Hour()*60 + local.Minute()
+	release := time.Date(local.Year(), local.Month(), local.Day(), endMin/60, endMin%60, 0, 0, loc)
+
+	if startMin < endMin {
+		return release, current >= startMin && current < endMin
+	}
+	if current >= startMin {
+		return release.AddDate(0, 0, 1), true
+	}
+	return release, current < endMin
+}
+
+// deviceLocation 返回设备时区，未上报或无效时使用服务器时区
+func deviceLocation(device models.Device) *time.Location {
+	if device.Timezone != "" {
+		if loc, err := time.LoadLocation(device.Timezone); err == nil {
+			return loc
+		}
+	}
+	return utils.TimeNow().Location()
+}
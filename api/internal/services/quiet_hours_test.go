@@ -0,0 +1,74 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuietHoursRelease(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip("缺少时区数据")
+	}
+
+	tests := []struct {
+		name       string
+		now        time.Time
+		start, end string
+		wantQuiet  bool
+		wantAt     time.Time
+	}{
+		{
+			name: "跨午夜时段的夜间", now: time.Date(2026, 10, 18, 23, 30, 0, 0, shanghai),
+			start: "22:00", end: "08:00", wantQuiet: true,
+			wantAt: time.Date(2026, 10, 19, 8, 0, 0, 0, shanghai),
+		},
+		{
+			name: "跨午夜时段的凌晨", now: time.Date(2026, 10, 18, 6, 0, 0, 0, shanghai),
+			start: "22:00", end: "08:00", wantQuiet: true,
+			wantAt: time.Date(2026, 10, 18, 8, 0, 0, 0, shanghai),
+		},
+		{
+			name: "跨午夜时段之外", now: time.Date(2026, 10, 18, 8, 0, 0, 0, shanghai),
+			start: "22:00", end: "08:00", wantQuiet: false,
+		},
+		{
+			name: "当天时段", now: time.Date(2026, 10, 18, 13, 0, 0, 0, shanghai),
+			start: "12:00", end: "14:00", wantQuiet: true,
+			wantAt: time.Date(2026, 10, 18, 14, 0, 0, 0, shanghai),
+		},
+		{
+			name: "按设备时区判断", now: time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC),
+			start: "22:00", end: "08:00", wantQuiet: true,
+			wantAt: time.Date(2026, 10, 19, 8, 0, 0, 0, shanghai),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			at, quiet := quietHoursRelease(test.now, test.start, test.end, shanghai)
+			if quiet != test.wantQuiet {
+				t.Fatalf("quiet = %v, want %v", quiet, test.wantQuiet)
+			}
+			if quiet && !at.Equal(test.wantAt) {
+				t.Fatalf("release = %v, want %v", at, test.wantAt)
+			}
+		})
+	}
+}
+
+func TestValidateQuietHours(t *testing.T) {
+	valid := [][2]string{{"", ""}, {"22:00", "08:00"}, {"12:00", "14:30"}}
+	for _, v := range valid {
+		if err := ValidateQuietHours(v[0], v[1]); err != nil {
+			t.Fatalf("ValidateQuietHours(%q, %q) unexpected error: %v", v[0], v[1], err)
+		}
+	}
+
+	invalid := [][2]string{{"22:00", ""}, {"25:00", "08:00"}, {"22:00", "22:00"}, {"10pm", "08:00"}}
+	for _, v := range invalid {
+		if err := ValidateQuietHours(v[0], v[1]); err == nil {
+			t.Fatalf("ValidateQuietHours(%q, %q) expected error, got nil", v[0], v[1])
+		}
+	}
+}
@@ -1,47 +1,87 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/doopush/doopush/api/internal/database"
+	"github.com/doopush/doopush/api/internal/metrics"
 	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/internal/tracing"
+	"github.com/doopush/doopush/api/pkg/logger"
 	"github.com/doopush/doopush/api/pkg/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // SchedulerService 定时推送服务
 type SchedulerService struct {
-	stopChan chan bool
+	stopChan    chan bool
+	localRuns   chan models.ScheduledPush // 本地时间投递的任务，由单个协程依次执行
+	localQueued sync.Map                  // 已在 localRuns 中或正在执行的任务ID，避免每次检查重复加入
 }
 
+// 本地时间投递失败的时区批次的重试间隔和最多执行次数
+const (
+	localRunRetryDelay  = 5 * time.Minute
+	localRunMaxAttempts = 3
+)
+
 // NewSchedulerService 创建定时推送服务实例
 func NewSchedulerService() *SchedulerService {
 	service := &SchedulerService{
-		stopChan: make(chan bool),
+		stopChan:  make(chan bool),
+		localRuns: make(chan models.ScheduledPush, 64),
 	}
 
-	// 启动定时任务调度器
+	// 启动定时任务调度器和本地时间投递协程
 	go service.startScheduler()
+	go service.runLocalDeliveries()
 
 	return service
 }
 
+// DeliveryOptions 定时推送的投递参数，与 PushRequest 中的同名参数相同，每次执行时使用
+type DeliveryOptions struct {
+	MessageType string // notification（默认）或 data
+	Category    string
+	TTLSeconds  int
+	Priority    string
+	CollapseKey string
+	Topic       string
+}
+
+// validate 补全默认消息类型并按即时推送的规则校验，无效参数在创建时拒绝而不是在执行时失败
+func (o *DeliveryOptions) validate(title, content string) error {
+	if o.MessageType == "" {
+		o.MessageType = "notification"
+	}
+	return validateDeliveryOptions(title, content, o.MessageType, o.Category, o.TTLSeconds, o.Priority)
+}
+
 // CreateScheduledPush 创建定时推送
-func (s *SchedulerService) CreateScheduledPush(appID uint, userID uint, name string, templateID *uint, targetType, targetValue string, scheduleTime time.Time, timezone, repeatType, cronExpr string) (*models.ScheduledPush, error) {
-	badge := 1
-	return s.CreateScheduledPushWithContent(appID, userID, name, "", "", "", "", targetType, targetValue, scheduleTime, timezone, repeatType, "", cronExpr, &badge)
+func (s *SchedulerService) CreateScheduledPush(ctx context.Context, appID uint, userID uint, name string, templateID *uint, targetType, targetValue string, scheduleTime time.Time, timezone, repeatType, cronExpr string) (*models.ScheduledPush, error) {
+	return s.CreateScheduledPushWithContent(ctx, appID, userID, name, "", "", "", "", targetType, targetValue, scheduleTime, timezone, repeatType, "", cronExpr, nil, false, DeliveryOptions{})
 }
 
 // CreateScheduledPushWithContent 创建包含推送内容的定时推送
-func (s *SchedulerService) CreateScheduledPushWithContent(appID uint, userID uint, name, title, content, payload, pushType, targetType, targetValue string, scheduleTime time.Time, timezone, repeatType, repeatConfig, cronExpr string, badge *int) (*models.ScheduledPush, error) {
+func (s *SchedulerService) CreateScheduledPushWithContent(ctx context.Context, appID uint, userID uint, name, title, content, payload, pushType, targetType, targetValue string, scheduleTime time.Time, timezone, repeatType, repeatConfig, cronExpr string, badge *BadgeValue, localDelivery bool, opts DeliveryOptions) (*models.ScheduledPush, error) {
 	// 检查任务名是否重复
 	var existingPush models.ScheduledPush
 	err := database.DB.Where("app_id = ? AND name = ?", appID, name).First(&existingPush).Error
 	if err == nil {
 		return nil, fmt.Errorf("任务名称已存在")
 	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return nil, fmt.Errorf("无效的时区")
+	}
+	if err := opts.validate(title, content); err != nil {
+		return nil, err
+	}
 
 	// 处理和验证 payload
 	if payload == "" {
@@ -51,15 +91,12 @@ func (s *SchedulerService) CreateScheduledPushWithContent(appID uint, userID uin
 		return nil, fmt.Errorf("payload必须是有效的JSON格式")
 	}
 
-	// 计算下次执行时间
-	nextRunAt := s.calculateNextRunTime(scheduleTime, repeatType, cronExpr)
-
 	// 如果没有明确指定 push_type，则根据 targetType 推断
 	if pushType == "" {
 		switch targetType {
 		case "devices":
 			pushType = "single"
-		case "groups", "tags":
+		case "groups", "tags", "users", "topics":
 			pushType = "batch"
 		case "all":
 			pushType = "broadcast"
@@ -68,10 +105,10 @@ func (s *SchedulerService) CreateScheduledPushWithContent(appID uint, userID uin
 		}
 	}
 
-	// 设置badge数量
-	badgeValue := 1 // 默认值
+	// 保存角标取值，未设置时每次执行与普通推送一样按 +1 累加
+	badgeSpec := ""
 	if badge != nil {
-		badgeValue = *badge
+		badgeSpec = badge.String()
 	}
 
 	scheduledPush := &models.ScheduledPush{
@@ -80,7 +117,13 @@ func (s *SchedulerService) CreateScheduledPushWithContent(appID uint, userID uin
 		Title:        title,   // 推送标题
 		Content:      content, // 推送内容
 		Payload:      payload, // 推送载荷
-		Badge:        badgeValue,
+		Badge:        badgeSpec,
+		MessageType:  opts.MessageType,
+		Category:     opts.Category,
+		TTLSeconds:   opts.TTLSeconds,
+		Priority:     opts.Priority,
+		CollapseKey:  opts.CollapseKey,
+		Topic:        opts.Topic,
 		PushType:     pushType,
 		TargetType:   targetType,
 		TargetValue:  targetValue,
@@ -89,11 +132,16 @@ func (s *SchedulerService) CreateScheduledPushWithContent(appID uint, userID uin
 		RepeatType:   repeatType,
 		RepeatConfig: repeatConfig,
 		CronExpr:     cronExpr,
-		NextRunAt:    &nextRunAt,
+		LocalTime:    localDelivery,
 		Status:       "pending", // 新创建的任务默认为pending状态，等待调度器执行
 		CreatedBy:    userID,
+		TraceParent:  tracing.TraceParent(ctx),
 	}
 
+	// 计算下次执行时间
+	nextRunAt := s.nextRunTime(scheduledPush)
+	scheduledPush.NextRunAt = &nextRunAt
+
 	if err := database.DB.Create(scheduledPush).Error; err != nil {
 		return nil, fmt.Errorf("创建定时推送失败: %v", err)
 	}
@@ -222,7 +270,7 @@ func (s *SchedulerService) UpdateScheduledPush(appID uint, pushID uint, name str
 	push.CronExpr = cronExpr
 
 	// 重新计算下次执行时间
-	nextRunAt := s.calculateNextRunTime(scheduleTime, repeatType, cronExpr)
+	nextRunAt := s.nextRunTime(&push)
 	push.NextRunAt = &nextRunAt
 
 	if err := database.DB.Save(&push).Error; err != nil {
@@ -233,12 +281,18 @@ func (s *SchedulerService) UpdateScheduledPush(appID uint, pushID uint, name str
 }
 
 // UpdateScheduledPushWithContent 更新包含推送内容的定时推送
-func (s *SchedulerService) UpdateScheduledPushWithContent(appID uint, pushID uint, name, title, content, payload, pushType, targetType, targetValue string, scheduleTime time.Time, timezone, repeatType, repeatConfig, cronExpr, status string, badge *int) (*models.ScheduledPush, error) {
+func (s *SchedulerService) UpdateScheduledPushWithContent(appID uint, pushID uint, name, title, content, payload, pushType, targetType, targetValue string, scheduleTime time.Time, timezone, repeatType, repeatConfig, cronExpr, status string, badge *BadgeValue, localDelivery bool, opts DeliveryOptions) (*models.ScheduledPush, error) {
 	var push models.ScheduledPush
 	err := database.DB.Where("app_id = ? AND id = ?", appID, pushID).First(&push).Error
 	if err != nil {
 		return nil, fmt.Errorf("定时推送不存在")
 	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return nil, fmt.Errorf("无效的时区")
+	}
+	if err := opts.validate(title, content); err != nil {
+		return nil, err
+	}
 
 	// 检查名称是否与其他任务冲突
 	if name != push.Name {
@@ -265,7 +319,7 @@ func (s *SchedulerService) UpdateScheduledPushWithContent(appID uint, pushID uin
 			switch targetType {
 			case "devices":
 				pushType = "single"
-			case "groups", "tags":
+			case "groups", "tags", "users", "topics":
 				pushType = "batch"
 			case "all":
 				pushType = "broadcast"
@@ -275,10 +329,10 @@ func (s *SchedulerService) UpdateScheduledPushWithContent(appID uint, pushID uin
 		}
 	}
 
-	// 设置badge数量
-	badgeValue := 1 // 默认值
+	// 保存角标取值，未设置时每次执行与普通推送一样按 +1 累加
+	badgeSpec := ""
 	if badge != nil {
-		badgeValue = *badge
+		badgeSpec = badge.String()
 	}
 
 	// 更新任务信息（包含推送内容）
@@ -286,7 +340,13 @@ func (s *SchedulerService) UpdateScheduledPushWithContent(appID uint, pushID uin
 	push.Title = title
 	push.Content = content
 	push.Payload = payload
-	push.Badge = badgeValue
+	push.Badge = badgeSpec
+	push.MessageType = opts.MessageType
+	push.Category = opts.Category
+	push.TTLSeconds = opts.TTLSeconds
+	push.Priority = opts.Priority
+	push.CollapseKey = opts.CollapseKey
+	push.Topic = opts.Topic
 	push.PushType = pushType
 	push.TargetType = targetType
 	push.TargetValue = targetValue
@@ -295,6 +355,7 @@ func (s *SchedulerService) UpdateScheduledPushWithContent(appID uint, pushID uin
 	push.RepeatType = repeatType
 	push.RepeatConfig = repeatConfig
 	push.CronExpr = cronExpr
+	push.LocalTime = localDelivery
 
 	// 更新状态（如果提供了）
 	if status != "" {
@@ -302,7 +363,7 @@ func (s *SchedulerService) UpdateScheduledPushWithContent(appID uint, pushID uin
 	}
 
 	// 重新计算下次执行时间
-	nextRunAt := s.calculateNextRunTime(scheduleTime, repeatType, cronExpr)
+	nextRunAt := s.nextRunTime(&push)
 	push.NextRunAt = &nextRunAt
 
 	if err := database.DB.Save(&push).Error; err != nil {
@@ -348,7 +409,7 @@ func (s *SchedulerService) ResumeScheduledPush(appID uint, pushID uint) error {
 	}
 
 	// 重新计算下次执行时间
-	nextRunAt := s.calculateNextRunTime(push.ScheduleTime, push.RepeatType, push.CronExpr)
+	nextRunAt := s.nextRunTime(&push)
 	push.NextRunAt = &nextRunAt
 	push.Status = "pending" // 恢复后设置为等待中，让调度器重新检查并执行
 
@@ -360,7 +421,7 @@ func (s *SchedulerService) ResumeScheduledPush(appID uint, pushID uint) error {
 }
 
 // ExecuteScheduledPush 执行定时推送
-func (s *SchedulerService) ExecuteScheduledPush(appID uint, pushID uint) error {
+func (s *SchedulerService) ExecuteScheduledPush(ctx context.Context, appID uint, pushID uint) error {
 	var push models.ScheduledPush
 	err := database.DB.Where("app_id = ? AND id = ? AND status IN ?", appID, pushID, []string{"pending", "running"}).First(&push).Error
 	if err != nil {
@@ -368,7 +429,7 @@ func (s *SchedulerService) ExecuteScheduledPush(appID uint, pushID uint) error {
 	}
 
 	// 先执行实际推送
-	err = s.executeActualPush(push)
+	err = s.executeActualPush(ctx, push, nil)
 	if err != nil {
 		// 推送失败时更新状态
 		push.Status = "failed"
@@ -384,7 +445,7 @@ func (s *SchedulerService) ExecuteScheduledPush(appID uint, pushID uint) error {
 
 	// 计算下次执行时间
 	if push.RepeatType != "once" {
-		nextRunAt := s.calculateNextRunTime(push.ScheduleTime, push.RepeatType, push.CronExpr)
+		nextRunAt := s.nextRunTime(&push)
 		push.NextRunAt = &nextRunAt
 		// 保持状态为 pending，等待下次执行
 	} else {
@@ -401,6 +462,117 @@ func (s *SchedulerService) ExecuteScheduledPush(appID uint, pushID uint) error {
 	return nil
 }
 
+// executeLocalRun 按设备本地时间分时区执行定时推送。
+// 每个时区到达本地执行时刻后执行一次，失败的时区间隔 localRunRetryDelay 后重试，最多执行 localRunMaxAttempts 次；
+// 全部时区完成（或放弃重试）且最晚时区也已到达后结束本次执行
+func (s *SchedulerService) executeLocalRun(ctx context.Context, push models.ScheduledPush) error {
+	if push.NextRunAt == nil {
+		return fmt.Errorf("定时推送缺少执行时间")
+	}
+	occurrence := push.NextRunAt.In(earliestZone) // 本次执行的本地日期和时刻
+	runDate := occurrence.Format("2006-01-02")
+	now := utils.TimeNow()
+
+	// 按设备时区分桶，未上报或无效时区的设备归入任务时区
+	var zones []string
+	if err := database.DB.Model(&models.Device{}).Where("app_id = ? AND status = 1", push.AppID).
+		Distinct().Pluck("timezone", &zones).Error; err != nil {
+		return fmt.Errorf("获取设备时区失败: %v", err)
+	}
+	buckets := make(map[string][]string)
+	for _, zone := range zones {
+		bucket := zone
+		if _, err := time.LoadLocation(zone); zone == "" || err != nil {
+			bucket = push.Timezone
+		}
+		buckets[bucket] = append(buckets[bucket], zone)
+	}
+
+	waiting := false
+	for bucket, deviceZones := range buckets {
+		loc, err := time.LoadLocation(bucket)
+		if err != nil {
+			loc = scheduleLocation(&push)
+		}
+		if wallClockIn(occurrence, loc).After(now) {
+			waiting = true
+			continue
+		}
+
+		// 先写入批次记录占位，唯一索引保证每个时区只执行一次；已有记录时只重试失败的批次
+		run := models.ScheduledPushRun{ScheduledPushID: push.ID, RunDate: runDate, Timezone: bucket, Status: "running", Attempts: 1}
+		if err := database.DB.Create(&run).Error; err != nil {
+			claimed, retryLater := claimFailedRun(&run, now)
+			if retryLater {
+				waiting = true
+			}
+			if !claimed {
+				continue
+			}
+		}
+
+		updates := map[string]interface{}{"status": "completed", "error": ""}
+		if err := s.executeActualPush(ctx, push, deviceZones); err != nil {
+			updates = map[string]interface{}{"status": "failed", "error": err.Error()}
+			if run.Attempts < localRunMaxAttempts {
+				waiting = true
+			} else {
+				logger.Error("本地时间投递的时区批次多次执行失败，已放弃", "app_id", push.AppID, "scheduled_push_id", push.ID,
+					"run_date", runDate, "timezone", bucket, "attempts", run.Attempts, "error", err)
+			}
+		}
+		database.DB.Model(&run).Updates(updates)
+	}
+
+	now = utils.TimeNow()
+	push.LastRunAt = &now
+	switch {
+	case waiting || !now.After(wallClockIn(occurrence, latestZone)):
+		// 仍有时区未到执行时刻或等待重试
+		push.Status = "running"
+	case push.RepeatType != "once":
+		nextRunAt := s.nextRunTime(&push)
+		push.NextRunAt = &nextRunAt
+		push.Status = "pending"
+	default:
+		push.Status = "completed"
+		push.NextRunAt = nil
+	}
+
+	if err := database.DB.Model(&push).Select("status", "last_run_at", "next_run_at").Updates(&push).Error; err != nil {
+		return fmt.Errorf("更新定时推送状态失败: %v", err)
+	}
+	return nil
+}
+
+// claimFailedRun 占用执行失败、尚未达到重试次数的时区批次，run 更新为占用后的记录。
+// claimed 表示本次重试该批次，retryLater 表示批次失败但未到重试时间
+func claimFailedRun(run *models.ScheduledPushRun, now time.Time) (claimed, retryLater bool) {
+	var existing models.ScheduledPushRun
+	if err := database.DB.Where("scheduled_push_id = ? AND run_date = ? AND timezone = ?",
+		run.ScheduledPushID, run.RunDate, run.Timezone).First(&existing).Error; err != nil {
+		return false, false
+	}
+	if existing.Status != "failed" || existing.Attempts >= localRunMaxAttempts {
+		return false, false
+	}
+	if existing.UpdatedAt.After(now.Add(-localRunRetryDelay)) {
+		return false, true
+	}
+
+	// 按执行次数做乐观锁，多实例同时检查时只有一个实例重试
+	result := database.DB.Model(&models.ScheduledPushRun{}).
+		Where("id = ? AND status = ? AND attempts = ?", existing.ID, "failed", existing.Attempts).
+		Updates(map[string]interface{}{"status": "running", "attempts": existing.Attempts + 1})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, false
+	}
+	existing.Status = "running"
+	existing.Attempts++
+	*run = existing
+	return true, false
+}
+
 // GetPendingSchedules 获取待执行的定时任务
 func (s *SchedulerService) GetPendingSchedules() ([]models.ScheduledPush, error) {
 	now := utils.TimeNow()
@@ -412,7 +584,37 @@ func (s *SchedulerService) GetPendingSchedules() ([]models.ScheduledPush, error)
 	return pushes, err
 }
 
-// calculateNextRunTime 计算下次执行时间
+// 本地时间投递覆盖的时区范围：同一时刻最早在 UTC+14 到达，最晚在 UTC-12 到达
+var (
+	earliestZone = time.FixedZone("UTC+14", 14*3600)
+	latestZone   = time.FixedZone("UTC-12", -12*3600)
+)
+
+// scheduleLocation 返回任务时区，无效时沿用调度时间自带的时区
+func scheduleLocation(push *models.ScheduledPush) *time.Location {
+	if loc, err := time.LoadLocation(push.Timezone); err == nil && push.Timezone != "" {
+		return loc
+	}
+	return push.ScheduleTime.Location()
+}
+
+// wallClockIn 保持日期和时刻不变，将时间放到 loc 时区
+func wallClockIn(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc)
+}
+
+// nextRunTime 按任务时区计算下次执行时间。
+// 本地时间投递的任务以最晚时区判断本次是否已结束，返回下次执行在最早时区的开始时间
+func (s *SchedulerService) nextRunTime(push *models.ScheduledPush) time.Time {
+	start := push.ScheduleTime.In(scheduleLocation(push))
+	if !push.LocalTime {
+		return s.calculateNextRunTime(start, push.RepeatType, push.CronExpr)
+	}
+	next := s.calculateNextRunTime(wallClockIn(start, latestZone), push.RepeatType, push.CronExpr)
+	return wallClockIn(next, earliestZone)
+}
+
+// calculateNextRunTime 计算下次执行时间，按 scheduleTime 所在时区的日历累加
 func (s *SchedulerService) calculateNextRunTime(scheduleTime time.Time, repeatType, cronExpr string) time.Time {
 	now := utils.TimeNow()
 
@@ -444,8 +646,19 @@ func (s *SchedulerService) calculateNextRunTime(scheduleTime time.Time, repeatTy
 	}
 }
 
-// executeActualPush 执行实际的推送操作
-func (s *SchedulerService) executeActualPush(push models.ScheduledPush) error {
+// executeActualPush 执行实际的推送操作。
+// 每次执行开始新的链路（手动执行时为请求链路的子 span），并链接到创建任务时的链路
+func (s *SchedulerService) executeActualPush(ctx context.Context, push models.ScheduledPush, timezones []string) (err error) {
+	opts := []trace.SpanStartOption{trace.WithAttributes(
+		attribute.Int("doopush.app_id", int(push.AppID)),
+		attribute.Int("doopush.scheduled_push_id", int(push.ID)),
+	)}
+	if link, ok := tracing.LinkTraceParent(push.TraceParent); ok {
+		opts = append(opts, trace.WithLinks(link))
+	}
+	ctx, span := tracing.Tracer().Start(ctx, "SchedulerService.executeActualPush", opts...)
+	defer func() { tracing.End(span, err) }()
+
 	pushService := NewPushService()
 
 	// 解析payload
@@ -461,19 +674,36 @@ func (s *SchedulerService) executeActualPush(push models.ScheduledPush) error {
 	if err != nil {
 		return fmt.Errorf("构建推送目标失败: %v", err)
 	}
+	target.Timezones = timezones
+
+	// 角标按任务保存的取值更新设备计数，未设置时由 SendPush 按 +1 累加
+	var badge *BadgeValue
+	if push.Badge != "" {
+		value, err := ParseBadgeValue(push.Badge)
+		if err != nil {
+			return fmt.Errorf("角标配置无效: %v", err)
+		}
+		badge = &value
+	}
 
 	// 构建推送请求
 	pushRequest := PushRequest{
-		Title:   push.Title,
-		Content: push.Content,
-		Badge:   &push.Badge,
-		Payload: payloadMap,
-		Target:  target,
-		// Schedule 为 nil 表示立即推送
+		Title:       push.Title,
+		Content:     push.Content,
+		Badge:       badge,
+		Payload:     payloadMap,
+		Target:      target,
+		MessageType: push.MessageType,
+		Category:    push.Category,
+		TTLSeconds:  push.TTLSeconds,
+		Priority:    push.Priority,
+		CollapseKey: push.CollapseKey,
+		Topic:       push.Topic,
+		// Schedule 为 nil 表示立即推送，存活时长从本次执行开始计算
 	}
 
 	// 执行推送（使用创建者ID作为用户ID）
-	_, err = pushService.SendPush(push.AppID, push.CreatedBy, pushRequest)
+	_, err = pushService.SendPush(ctx, push.AppID, push.CreatedBy, pushRequest)
 	if err != nil {
 		return fmt.Errorf("推送发送失败: %v", err)
 	}
@@ -599,6 +829,20 @@ func (s *SchedulerService) buildPushTarget(push models.ScheduledPush) (PushTarge
 			}
 		}
 		target.TagIDs = tagIDs
+	case "users":
+		target.Type = "users"
+		// target_config 为外部用户ID的JSON数组或逗号分隔列表
+		target.UserIDs = splitTargetList(push.TargetValue)
+		if len(target.UserIDs) == 0 {
+			return target, fmt.Errorf("users目标配置为空")
+		}
+	case "topics":
+		target.Type = "topics"
+		// target_config 为主题标识的JSON数组或逗号分隔列表
+		target.Topics = splitTargetList(push.TargetValue)
+		if len(target.Topics) == 0 {
+			return target, fmt.Errorf("topics目标配置为空")
+		}
 	default:
 		return target, fmt.Errorf("不支持的目标类型: %s", push.TargetType)
 	}
@@ -606,6 +850,21 @@ func (s *SchedulerService) buildPushTarget(push models.ScheduledPush) (PushTarge
 	return target, nil
 }
 
+// splitTargetList 解析JSON数组或逗号分隔的目标列表
+func splitTargetList(value string) []string {
+	var items []string
+	if err := json.Unmarshal([]byte(value), &items); err != nil {
+		items = strings.Split(value, ",")
+	}
+	var result []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
 // startScheduler 启动定时任务调度器
 func (s *SchedulerService) startScheduler() {
 	ticker := time.NewTicker(30 * time.Second) // 每30秒检查一次
@@ -614,8 +873,31 @@ func (s *SchedulerService) startScheduler() {
 	for {
 		select {
 		case <-ticker.C:
+			metrics.SchedulerLastTick.SetToCurrentTime()
 			// 检查并执行到期的定时推送任务
 			s.checkAndExecuteScheduledPushes()
+			// 释放静默时段已结束的暂存推送
+			if count, err := NewPushService().ReleaseHeldPushLogs(); err != nil {
+				logger.Error("释放静默时段推送失败", "error", err)
+			} else if count > 0 {
+				logger.Info("已释放静默时段暂存的推送", "count", count)
+			}
+			// 继续发送进行中的分批发送任务（服务重启、静默时段释放、灰度等待到期）
+			NewCampaignService().ResumeCampaigns()
+			// 清理超过存活时长仍未发出的推送
+			if count, err := NewPushService().ExpireStalePushLogs(); err != nil {
+				logger.Error("标记过期推送失败", "error", err)
+			} else if count > 0 {
+				logger.Info("已将超时未发出的推送标记为过期", "count", count)
+			}
+			// 重试到期的事件回调投递
+			NewWebhookService().RetryDueDeliveries()
+			// 评估告警规则
+			NewAlertService().EvaluateDue()
+			// 检查推送配置凭据，厂商接口可能较慢，不阻塞调度
+			go NewConfigHealthService().CheckDue()
+			// 更新推送队列深度指标
+			NewPushService().RecordQueueDepth()
 		case <-s.stopChan:
 			// 停止调度器
 			return
@@ -629,35 +911,62 @@ func (s *SchedulerService) checkAndExecuteScheduledPushes() {
 	pendingPushes, err := s.GetPendingSchedules()
 	if err != nil {
 		// 记录错误日志，但不中断调度器
-		fmt.Printf("获取待执行任务失败: %v\n", err)
+		logger.Error("获取待执行的定时推送失败", "error", err)
 		return
 	}
 
 	// 记录检查到的任务数量
 	if len(pendingPushes) > 0 {
-		fmt.Printf("发现 %d 个待执行的定时推送任务\n", len(pendingPushes))
+		logger.Debug("发现待执行的定时推送任务", "count", len(pendingPushes))
 	}
 
 	// 执行到期的任务
 	for _, push := range pendingPushes {
 		// 检查任务是否真的到期了
 		if push.NextRunAt != nil && push.NextRunAt.Before(utils.TimeNow()) {
-			fmt.Printf("执行定时推送任务: ID=%d, 名称=%s, 下次执行时间=%v\n",
-				push.ID, push.Name, push.NextRunAt)
+			logger.Info("执行定时推送任务", "app_id", push.AppID, "scheduled_push_id", push.ID, "name", push.Name, "next_run_at", *push.NextRunAt)
+			metrics.SchedulerLag.Observe(utils.TimeNow().Sub(*push.NextRunAt).Seconds())
+
+			// 本地时间投递的任务按时区滚动执行，交给本地时间投递协程；上次还未执行完的任务不重复加入
+			if push.LocalTime {
+				if _, queued := s.localQueued.LoadOrStore(push.ID, true); !queued {
+					select {
+					case s.localRuns <- push:
+					default:
+						// 队列已满，下次检查时再加入
+						s.localQueued.Delete(push.ID)
+					}
+				}
+				continue
+			}
 
 			// 异步执行推送任务，避免阻塞调度器
 			go func(pushID uint, appID uint, taskName string) {
-				fmt.Printf("开始执行定时推送任务: ID=%d, 名称=%s\n", pushID, taskName)
-				if err := s.ExecuteScheduledPush(appID, pushID); err != nil {
-					fmt.Printf("执行定时推送任务失败 (ID: %d, 名称: %s): %v\n", pushID, taskName, err)
+				if err := s.ExecuteScheduledPush(context.Background(), appID, pushID); err != nil {
+					logger.Error("执行定时推送任务失败", "app_id", appID, "scheduled_push_id", pushID, "name", taskName, "error", err)
 				} else {
-					fmt.Printf("定时推送任务执行成功: ID=%d, 名称=%s\n", pushID, taskName)
+					logger.Info("定时推送任务执行成功", "app_id", appID, "scheduled_push_id", pushID, "name", taskName)
 				}
 			}(push.ID, push.AppID, push.Name)
 		}
 	}
 }
 
+// runLocalDeliveries 依次执行本地时间投递的任务，每次检查不再为每个任务单独启动协程
+func (s *SchedulerService) runLocalDeliveries() {
+	for {
+		select {
+		case push := <-s.localRuns:
+			if err := s.executeLocalRun(context.Background(), push); err != nil {
+				logger.Error("执行本地时间定时推送失败", "app_id", push.AppID, "scheduled_push_id", push.ID, "name", push.Name, "error", err)
+			}
+			s.localQueued.Delete(push.ID)
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
 // StopScheduler 停止定时任务调度器
 func (s *SchedulerService) StopScheduler() {
 	close(s.stopChan)
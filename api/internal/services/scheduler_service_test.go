@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/doopush/doopush/api/internal/database"
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/internal/testutil"
+)
+
+func TestExecuteLocalRunRetriesFailedZone(t *testing.T) {
+	testutil.SetupDB(t)
+	app := testutil.CreateApp(t, 1, "a")
+	device := testutil.CreateDevice(t, app.ID, "token-a", false)
+	database.DB.Model(device).Update("timezone", "Asia/Shanghai")
+
+	// 两天前的执行，所有时区都已到达；创建者没有应用权限，每次发送都会失败
+	nextRunAt := time.Now().Add(-48 * time.Hour)
+	push := models.ScheduledPush{
+		AppID: app.ID, Name: "daily", Title: "t", Content: "c", PushType: "broadcast", TargetType: "all",
+		ScheduleTime: nextRunAt, Timezone: "UTC", LocalTime: true, RepeatType: "once",
+		NextRunAt: &nextRunAt, Status: "pending", CreatedBy: 99,
+	}
+	if err := database.DB.Create(&push).Error; err != nil {
+		t.Fatalf("create scheduled push: %v", err)
+	}
+	s := &SchedulerService{}
+
+	run := func() (models.ScheduledPush, models.ScheduledPushRun) {
+		t.Helper()
+		var current models.ScheduledPush
+		database.DB.First(&current, push.ID)
+		if err := s.executeLocalRun(context.Background(), current); err != nil {
+			t.Fatalf("executeLocalRun: %v", err)
+		}
+		var zoneRun models.ScheduledPushRun
+		database.DB.Where("scheduled_push_id = ? AND timezone = ?", push.ID, "Asia/Shanghai").First(&zoneRun)
+		database.DB.First(&current, push.ID)
+		return current, zoneRun
+	}
+	backdate := func() {
+		database.DB.Model(&models.ScheduledPushRun{}).Where("scheduled_push_id = ?", push.ID).
+			UpdateColumn("updated_at", time.Now().Add(-2*localRunRetryDelay))
+	}
+
+	current, zoneRun := run()
+	if zoneRun.Status != "failed" || zoneRun.Attempts != 1 || current.Status != "running" {
+		t.Fatalf("first run: zone %s/%d, push %s", zoneRun.Status, zoneRun.Attempts, current.Status)
+	}
+
+	// 未到重试间隔不重复执行
+	current, zoneRun = run()
+	if zoneRun.Attempts != 1 || current.Status != "running" {
+		t.Fatalf("before retry delay: zone %s/%d, push %s", zoneRun.Status, zoneRun.Attempts, current.Status)
+	}
+
+	backdate()
+	current, zoneRun = run()
+	if zoneRun.Status != "failed" || zoneRun.Attempts != 2 || current.Status != "running" {
+		t.Fatalf("second attempt: zone %s/%d, push %s", zoneRun.Status, zoneRun.Attempts, current.Status)
+	}
+
+	// 达到次数上限后放弃该时区，本次执行结束
+	backdate()
+	current, zoneRun = run()
+	if zoneRun.Attempts != localRunMaxAttempts || current.Status != "completed" {
+		t.Fatalf("last attempt: zone %s/%d, push %s", zoneRun.Status, zoneRun.Attempts, current.Status)
+	}
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/doopush/doopush/api/internal/database"
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/pkg/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TopicService 订阅主题服务
+type TopicService struct{}
+
+// NewTopicService 创建订阅主题服务
+func NewTopicService() *TopicService {
+	return &TopicService{}
+}
+
+// topicKeyPattern 主题标识格式
+var topicKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// DeviceTopicStatus 设备在某个主题上的订阅状态
+type DeviceTopicStatus struct {
+	Key        string `json:"key" example:"order_updates"`
+	Name       string `json:"name" example:"订单更新"`
+	Subscribed bool   `json:"subscribed" example:"true"`
+}
+
+// CreateTopic 创建订阅主题
+func (s *TopicService) CreateTopic(appID uint, key, name, description string, defaultSubscribed bool) (*models.Topic, error) {
+	if !topicKeyPattern.MatchString(key) {
+		return nil, errors.New("主题标识只能包含字母、数字、下划线、中划线和点")
+	}
+
+	var existing models.Topic
+	if err := database.DB.Where("app_id = ? AND `key` = ?", appID, key).First(&existing).Error; err == nil {
+		return nil, errors.New("主题标识已存在")
+	}
+
+	topic := &models.Topic{
+		AppID:             appID,
+		Key:               key,
+		Name:              name,
+		Description:       description,
+		DefaultSubscribed: defaultSubscribed,
+	}
+	if err := database.DB.Create(topic).Error; err != nil {
+		return nil, fmt.Errorf("创建主题失败: %v", err)
+	}
+	return topic, nil
+}
+
+// GetTopics 获取应用的订阅主题列表
+func (s *TopicService) GetTopics(appID uint) ([]models.Topic, error) {
+	var topics []models.Topic
+	if err := database.DB.Where("app_id = ?", appID).Order("id ASC").Find(&topics).Error; err != nil {
+		return nil, errors.New("获取主题列表失败")
+	}
+	return topics, nil
+}
+
+// UpdateTopic 更新订阅主题（主题标识创建后不可修改）
+func (s *TopicService) UpdateTopic(appID, topicID uint, name, description string, defaultSubscribed bool) (*models.Topic, error) {
+	var topic models.Topic
+	if err := database.DB.Where("app_id = ? AND id = ?", appID, topicID).First(&topic).Error; err != nil {
+		return nil, errors.New("主题不存在")
+	}
+
+	topic.Name = name
+	topic.Description = description
+	topic.DefaultSubscribed = defaultSubscribed
+	if err := database.DB.Save(&topic).Error; err != nil {
+		return nil, fmt.Errorf("更新主题失败: %v", err)
+	}
+	return &topic, nil
+}
+
+// DeleteTopic 删除订阅主题及设备的订阅记录
+func (s *TopicService) DeleteTopic(appID, topicID uint) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("app_id = ? AND id = ?", appID, topicID).Delete(&models.Topic{})
+		if result.Error != nil {
+			return fmt.Errorf("删除主题失败: %v", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("主题不存在")
+		}
+		if err := tx.Where("topic_id = ?", topicID).Delete(&models.TopicSubscription{}).Error; err != nil {
+			return fmt.Errorf("删除主题订阅失败: %v", err)
+		}
+		return nil
+	})
+}
+
+// GetDeviceByID 获取应用下的设备（管理后台按设备ID设置订阅时使用）
+func (s *TopicService) GetDeviceByID(appID, deviceID uint) (*models.Device, error) {
+	var device models.Device
+	if err := database.DB.Where("app_id = ? AND id = ?", appID, deviceID).First(&device).Error; err != nil {
+		return nil, errors.New("设备不存在")
+	}
+	return &device, nil
+}
+
+// GetDeviceTopics 获取设备在应用所有主题上的订阅状态，未显式设置的主题取主题默认值
+func (s *TopicService) GetDeviceTopics(device *models.Device) ([]DeviceTopicStatus, error) {
+	topics, err := s.GetTopics(device.AppID)
+	if err != nil {
+		return nil, err
+	}
+
+	var subscriptions []models.TopicSubscription
+	if err := database.DB.Where("device_id = ?", device.ID).Find(&subscriptions).Error; err != nil {
+		return nil, errors.New("获取设备订阅失败")
+	}
+	explicit := make(map[uint]bool, len(subscriptions))
+	for _, sub := range subscriptions {
+		explicit[sub.TopicID] = sub.Subscribed
+	}
+
+	statuses := make([]DeviceTopicStatus, 0, len(topics))
+	for _, topic := range topics {
+		subscribed, ok := explicit[topic.ID]
+		if !ok {
+			subscribed = topic.DefaultSubscribed
+		}
+		statuses = append(statuses, DeviceTopicStatus{Key: topic.Key, Name: topic.Name, Subscribed: subscribed})
+	}
+	return statuses, nil
+}
+
+// UpdateDeviceTopics 订阅或退订设备的主题，topics 为 主题标识 -> 是否订阅
+func (s *TopicService) UpdateDeviceTopics(device *models.Device, topics map[string]bool) ([]DeviceTopicStatus, error) {
+	keys := make([]string, 0, len(topics))
+	for key := range topics {
+		keys = append(keys, key)
+	}
+	topicMap, err := s.getTopicsByKeys(device.AppID, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, subscribed := range topics {
+		sub := models.TopicSubscription{
+			AppID:      device.AppID,
+			DeviceID:   device.ID,
+			TopicID:    topicMap[key].ID,
+			Subscribed: subscribed,
+		}
+		err := database.DB.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "device_id"}, {Name: "topic_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"subscribed": subscribed,
+				"updated_at": utils.TimeNow(),
+			}),
+		}).Create(&sub).Error
+		if err != nil {
+			return nil, errors.New("更新主题订阅失败")
+		}
+	}
+
+	return s.GetDeviceTopics(device)
+}
+
+// getTopicsByKeys 按主题标识批量获取主题，任一标识不存在时返回错误
+func (s *TopicService) getTopicsByKeys(appID uint, keys []string) (map[string]models.Topic, error) {
+	var topics []models.Topic
+	if len(keys) > 0 {
+		if err := database.DB.Where("app_id = ? AND `key` IN ?", appID, keys).Find(&topics).Error; err != nil {
+			return nil, errors.New("获取主题失败")
+		}
+	}
+	topicMap := make(map[string]models.Topic, len(topics))
+	for _, topic := range topics {
+		topicMap[topic.Key] = topic
+	}
+	for _, key := range keys {
+		if _, ok := topicMap[key]; !ok {
+			return nil, fmt.Errorf("主题不存在: %s", key)
+		}
+	}
+	return topicMap, nil
+}
+
+// subscribedCondition 构造“设备订阅了任一主题”的查询条件：
+// 默认订阅的主题取未显式退订的设备，默认不订阅的主题取显式订阅的设备
+func (s *TopicService) subscribedCondition(appID uint, keys []string) (string, []interface{}, error) {
+	topicMap, err := s.getTopicsByKeys(appID, keys)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var conds []string
+	var args []interface{}
+	for _, topic := range topicMap {
+		if topic.DefaultSubscribed {
+			conds = append(conds, "id NOT IN (SELECT device_id FROM topic_subscriptions WHERE topic_id = ? AND subscribed = ?)")
+			args = append(args, topic.ID, false)
+		} else {
+			conds = append(conds, "id IN (SELECT device_id FROM topic_subscriptions WHERE topic_id = ? AND subscribed = ?)")
+			args = append(args, topic.ID, true)
+		}
+	}
+	return "(" + strings.Join(conds, " OR ") + ")", args, nil
+}
+
+// unsubscribedDevices 返回给定设备中未订阅该主题的设备ID集合。
+// 只读取与主题默认值相反的显式订阅记录，避免按全部目标设备回表
+func (s *TopicService) unsubscribedDevices(appID uint, key string, devices []models.Device) (map[uint]bool, error) {
+	topicMap, err := s.getTopicsByKeys(appID, []string{key})
+	if err != nil {
+		return nil, err
+	}
+	topic := topicMap[key]
+
+	var overridden []uint
+	if err := database.DB.Model(&models.TopicSubscription{}).
+		Where("topic_id = ? AND subscribed = ?", topic.ID, !topic.DefaultSubscribed).
+		Pluck("device_id", &overridden).Error; err != nil {
+		return nil, errors.New("获取主题订阅失败")
+	}
+	overriddenSet := make(map[uint]bool, len(overridden))
+	for _, id := range overridden {
+		overriddenSet[id] = true
+	}
+
+	unsubscribed := make(map[uint]bool)
+	for _, device := range devices {
+		// 默认订阅时显式记录即为退订；默认不订阅时没有显式订阅即为未订阅
+		if overriddenSet[device.ID] == topic.DefaultSubscribed {
+			unsubscribed[device.ID] = true
+		}
+	}
+	return unsubscribed, nil
+}
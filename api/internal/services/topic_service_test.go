@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/doopush/doopush/api/internal/database"
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/internal/testutil"
+)
+
+func TestTopicsTarget(t *testing.T) {
+	testutil.SetupDB(t)
+	app := testutil.CreateApp(t, 1, "a")
+	topics := NewTopicService()
+	if _, err := topics.CreateTopic(app.ID, "news", "新闻", "", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := topics.CreateTopic(app.ID, "promo", "促销", "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	defaults := testutil.CreateDevice(t, app.ID, "token-default", false)
+	optedOut := testutil.CreateDevice(t, app.ID, "token-opted-out", false)
+	optedIn := testutil.CreateDevice(t, app.ID, "token-opted-in", false)
+	sandbox := testutil.CreateDevice(t, app.ID, "token-sandbox", true)
+	for device, subscriptions := range map[*models.Device]map[string]bool{
+		optedOut: {"news": false},
+		optedIn:  {"promo": true},
+		sandbox:  {"promo": true},
+	} {
+		if _, err := topics.UpdateDeviceTopics(device, subscriptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		topics []string
+		want   []uint
+	}{
+		{[]string{"news"}, []uint{defaults.ID, optedIn.ID}},
+		{[]string{"promo"}, []uint{optedIn.ID}},
+		{[]string{"news", "promo"}, []uint{defaults.ID, optedIn.ID}},
+	} {
+		devices, err := NewPushService().getTargetDevices(app.ID, PushTarget{Type: "topics", Topics: tc.topics}, false)
+		if err != nil {
+			t.Fatalf("%v: %v", tc.topics, err)
+		}
+		var got []uint
+		for _, device := range devices {
+			got = append(got, device.ID)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("topics %v: devices = %v, want %v", tc.topics, got, tc.want)
+		}
+	}
+
+	if _, err := NewPushService().getTargetDevices(app.ID, PushTarget{Type: "topics", Topics: []string{"missing"}}, false); err == nil {
+		t.Fatal("unknown topic: expected error")
+	}
+}
+
+func TestSendPushExcludesUnsubscribedDevices(t *testing.T) {
+	testutil.SetupDB(t)
+	app := testutil.CreateApp(t, 1, "a")
+	if _, err := NewTopicService().CreateTopic(app.ID, "news", "新闻", "", true); err != nil {
+		t.Fatal(err)
+	}
+
+	subscribed := testutil.CreateDevice(t, app.ID, "token-subscribed", false)
+	categoryOptOut := testutil.CreateDevice(t, app.ID, "token-category-opt-out", false)
+	topicOptOut := testutil.CreateDevice(t, app.ID, "token-topic-opt-out", false)
+	if _, err := NewDeviceService().UpdateCategoryPreferences(categoryOptOut, map[string]bool{"marketing": false}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTopicService().UpdateDeviceTopics(topicOptOut, map[string]bool{"news": false}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := NewPushService().SendPush(context.Background(), app.ID, 1, PushRequest{
+		Title:    "t",
+		Content:  "c",
+		Category: "marketing",
+		Topic:    "news",
+		Target:   PushTarget{Type: "devices", DeviceIDs: []uint{subscribed.ID, categoryOptOut.ID, topicOptOut.ID}},
+	})
+	if err != nil {
+		t.Fatalf("SendPush: %v", err)
+	}
+
+	var logs []models.PushLog
+	database.DB.Where("app_id = ?", app.ID).Find(&logs)
+	byDevice := make(map[uint]models.PushLog, len(logs))
+	for _, log := range logs {
+		byDevice[log.DeviceID] = log
+	}
+	if len(logs) != 3 {
+		t.Fatalf("push logs = %d, want 3", len(logs))
+	}
+	if log := byDevice[subscribed.ID]; log.Status == "excluded" {
+		t.Errorf("subscribed device excluded: %s", log.SkipReason)
+	}
+	for id, reason := range map[uint]string{categoryOptOut.ID: "category_opt_out", topicOptOut.ID: "topic_unsubscribed"} {
+		if log := byDevice[id]; log.Status != "excluded" || log.SkipReason != reason {
+			t.Errorf("device %d: status = %s, reason = %s, want excluded %s", id, log.Status, log.SkipReason, reason)
+		}
+	}
+}
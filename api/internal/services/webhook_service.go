@@ -0,0 +1,669 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/doopush/doopush/api/internal/database"
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/pkg/logger"
+	"github.com/doopush/doopush/api/pkg/utils"
+)
+
+var (
+	ErrWebhookNotFound         = errors.New("回调订阅不存在")
+	ErrWebhookDeliveryNotFound = errors.New("投递记录不存在")
+	ErrWebhookDeliveryRunning  = errors.New("投递尚未结束，无法重放")
+)
+
+// 事件类型
+const (
+	WebhookEventPushDelivered      = "push.delivered"
+	WebhookEventPushClicked        = "push.clicked"
+	WebhookEventPushOpened         = "push.opened"
+	WebhookEventDeviceRegistered   = "device.registered"
+	WebhookEventDeviceTokenInvalid = "device.token_invalid"
+	WebhookEventDeviceOnline       = "device.online"
+	WebhookEventDeviceOffline      = "device.offline"
+	WebhookEventAlertFiring        = "alert.firing"
+	WebhookEventAlertResolved      = "alert.resolved"
+	// WebhookEventTest 测试事件，仅由测试接口发送，不参与订阅过滤
+	WebhookEventTest = "webhook.test"
+)
+
+// WebhookEvents 可订阅的事件类型
+var WebhookEvents = []string{
+	WebhookEventPushDelivered,
+	WebhookEventPushClicked,
+	WebhookEventPushOpened,
+	WebhookEventDeviceRegistered,
+	WebhookEventDeviceTokenInvalid,
+	WebhookEventDeviceOnline,
+	WebhookEventDeviceOffline,
+	WebhookEventAlertFiring,
+	WebhookEventAlertResolved,
+}
+
+const (
+	// WebhookSignatureHeader 签名请求头，格式 t=<unix 秒>,v1=<hex(HMAC-SHA256(secret, "<t>.<body>"))>
+	WebhookSignatureHeader = "X-DooPush-Signature"
+	webhookSecretPrefix    = "whsec_"
+	webhookMaxAttempts     = 6
+	webhookTimeout         = 10 * time.Second
+	// webhookStuckAfter 投递中超过该时长视为进程中断，重新放回待投递
+	webhookStuckAfter = 5 * time.Minute
+	// webhookRetryBatch 每轮重试最多处理的投递数
+	webhookRetryBatch  = 500
+	webhookMaxRespBody = 1024
+)
+
+var (
+	webhookHTTPClient   = newWebhookHTTPClient()
+	webhookRetryRunning atomic.Bool
+)
+
+// WebhookEvent 待发送的事件
+type WebhookEvent struct {
+	AppID uint
+	Type  string
+	Data  interface{}
+}
+
+// WebhookPayload 事件请求体
+type WebhookPayload struct {
+	ID        string      `json:"id" example:"a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p6"` // 事件ID，重放时不变，可用于去重
+	Type      string      `json:"type" example:"push.delivered"`
+	AppID     uint        `json:"app_id" example:"1"`
+	CreatedAt int64       `json:"created_at" example:"1760745600"` // 事件发生时间（unix 秒）
+	Data      interface{} `json:"data"`
+}
+
+// WebhookPushEventData 推送事件内容
+type WebhookPushEventData struct {
+	PushLogID uint   `json:"push_log_id" example:"1001"`
+	DeviceID  uint   `json:"device_id" example:"42"`
+	Channel   string `json:"channel" example:"xiaomi"`
+	BatchID   string `json:"batch_id,omitempty"`
+	DedupKey  string `json:"dedup_key,omitempty"`
+}
+
+// WebhookDeviceEventData 设备事件内容
+type WebhookDeviceEventData struct {
+	DeviceID uint   `json:"device_id" example:"42"`
+	Token    string `json:"token"`
+	Platform string `json:"platform" example:"android"`
+	Channel  string `json:"channel" example:"xiaomi"`
+}
+
+// WebhookService 事件回调服务
+type WebhookService struct{}
+
+// NewWebhookService 创建事件回调服务
+func NewWebhookService() *WebhookService {
+	return &WebhookService{}
+}
+
+// pushEvent 由推送日志构造推送事件
+func pushEvent(eventType string, pushLog *models.PushLog) WebhookEvent {
+	return WebhookEvent{
+		AppID: pushLog.AppID,
+		Type:  eventType,
+		Data: WebhookPushEventData{
+			PushLogID: pushLog.ID,
+			DeviceID:  pushLog.DeviceID,
+			Channel:   pushLog.Channel,
+			BatchID:   pushLog.BatchID,
+			DedupKey:  pushLog.DedupKey,
+		},
+	}
+}
+
+// DeviceEvent 由设备构造设备事件
+func DeviceEvent(eventType string, device *models.Device) WebhookEvent {
+	return WebhookEvent{
+		AppID: device.AppID,
+		Type:  eventType,
+		Data: WebhookDeviceEventData{
+			DeviceID: device.ID,
+			Token:    device.Token,
+			Platform: device.Platform,
+			Channel:  device.Channel,
+		},
+	}
+}
+
+// GetWebhooks 获取应用的回调订阅
+func (s *WebhookService) GetWebhooks(appID uint) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	if err := database.DB.Where("app_id = ?", appID).Order("id ASC").Find(&webhooks).Error; err != nil {
+		return nil, errors.New("获取回调订阅失败")
+	}
+	return webhooks, nil
+}
+
+// GetWebhook 获取回调订阅
+func (s *WebhookService) GetWebhook(appID, webhookID uint) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := database.DB.Where("id = ? AND app_id = ?", webhookID, appID).First(&webhook).Error; err != nil {
+		return nil, ErrWebhookNotFound
+	}
+	return &webhook, nil
+}
+
+// CreateWebhook 创建回调订阅，签名密钥仅在创建时返回
+func (s *WebhookService) CreateWebhook(appID uint, name, rawURL string, events []string) (*models.Webhook, string, error) {
+	if err := validateWebhookURL(rawURL); err != nil {
+		return nil, "", err
+	}
+	if err := validateWebhookEvents(events); err != nil {
+		return nil, "", err
+	}
+
+	secret := webhookSecretPrefix + utils.GenerateAPIKey()
+	webhook := &models.Webhook{
+		AppID:  appID,
+		Name:   name,
+		URL:    rawURL,
+		Secret: secret,
+		Events: strings.Join(events, ","),
+		Status: 1,
+	}
+	if err := database.DB.Create(webhook).Error; err != nil {
+		return nil, "", errors.New("回调订阅创建失败")
+	}
+	return webhook, secret, nil
+}
+
+// UpdateWebhook 更新回调订阅，参数为 nil 时保持不变
+func (s *WebhookService) UpdateWebhook(appID, webhookID uint, name, rawURL *string, events []string, status *int) (*models.Webhook, error) {
+	webhook, err := s.GetWebhook(appID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if name != nil {
+		updates["name"] = *name
+	}
+	if rawURL != nil {
+		if err := validateWebhookURL(*rawURL); err != nil {
+			return nil, err
+		}
+		updates["url"] = *rawURL
+	}
+	if events != nil {
+		if err := validateWebhookEvents(events); err != nil {
+			return nil, err
+		}
+		updates["events"] = strings.Join(events, ",")
+	}
+	if status != nil {
+		updates["status"] = *status
+	}
+	if len(updates) == 0 {
+		return webhook, nil
+	}
+
+	if err := database.DB.Model(webhook).Updates(updates).Error; err != nil {
+		return nil, errors.New("回调订阅更新失败")
+	}
+	return s.GetWebhook(appID, webhookID)
+}
+
+// RotateSecret 重新生成签名密钥
+func (s *WebhookService) RotateSecret(appID, webhookID uint) (*models.Webhook, string, error) {
+	webhook, err := s.GetWebhook(appID, webhookID)
+	if err != nil {
+		return nil, "", err
+	}
+	secret := webhookSecretPrefix + utils.GenerateAPIKey()
+	if err := database.DB.Model(webhook).Update("secret", secret).Error; err != nil {
+		return nil, "", errors.New("签名密钥更新失败")
+	}
+	return webhook, secret, nil
+}
+
+// DeleteWebhook 删除回调订阅，未完成的投递不再重试
+func (s *WebhookService) DeleteWebhook(appID, webhookID uint) error {
+	webhook, err := s.GetWebhook(appID, webhookID)
+	if err != nil {
+		return err
+	}
+	if err := database.DB.Delete(webhook).Error; err != nil {
+		return errors.New("删除回调订阅失败")
+	}
+	database.DB.Model(&models.WebhookDelivery{}).
+		Where("webhook_id = ? AND status = ?", webhook.ID, "pending").
+		Updates(map[string]interface{}{"status": "failed", "error": "回调订阅已删除", "next_attempt_at": nil})
+	return nil
+}
+
+// GetDeliveries 获取回调订阅的投递记录
+func (s *WebhookService) GetDeliveries(appID, webhookID uint, status, event string, page, pageSize int) ([]models.WebhookDelivery, int64, error) {
+	if _, err := s.GetWebhook(appID, webhookID); err != nil {
+		return nil, 0, err
+	}
+
+	query := database.DB.Model(&models.WebhookDelivery{}).Where("app_id = ? AND webhook_id = ?", appID, webhookID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if event != "" {
+		query = query.Where("event = ?", event)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.New("获取投递记录失败")
+	}
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&deliveries).Error; err != nil {
+		return nil, 0, errors.New("获取投递记录失败")
+	}
+	return deliveries, total, nil
+}
+
+// GetDelivery 获取投递记录
+func (s *WebhookService) GetDelivery(appID, webhookID, deliveryID uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := database.DB.Where("id = ? AND app_id = ? AND webhook_id = ?", deliveryID, appID, webhookID).First(&delivery).Error; err != nil {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+	return &delivery, nil
+}
+
+// Replay 重放投递：以相同事件ID和内容创建新的投递记录并立即发送
+func (s *WebhookService) Replay(appID, webhookID, deliveryID uint) (*models.WebhookDelivery, error) {
+	webhook, err := s.GetWebhook(appID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	original, err := s.GetDelivery(appID, webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if original.Status == "pending" || original.Status == "delivering" {
+		return nil, ErrWebhookDeliveryRunning
+	}
+
+	now := time.Now()
+	delivery := &models.WebhookDelivery{
+		AppID:         appID,
+		WebhookID:     webhook.ID,
+		EventID:       original.EventID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        "pending",
+		NextAttemptAt: &now,
+	}
+	if err := database.DB.Create(delivery).Error; err != nil {
+		return nil, errors.New("创建投递记录失败")
+	}
+	s.attempt(delivery.ID)
+	return s.GetDelivery(appID, webhookID, delivery.ID)
+}
+
+// Test 向回调地址发送一条 webhook.test 测试事件并返回投递结果，失败不重试
+func (s *WebhookService) Test(appID, webhookID uint) (*models.WebhookDelivery, error) {
+	webhook, err := s.GetWebhook(appID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := s.createDeliveries([]WebhookEvent{{
+		AppID: appID,
+		Type:  WebhookEventTest,
+		Data:  map[string]interface{}{"webhook_id": webhook.ID, "message": "DooPush webhook 测试事件"},
+	}}, map[uint][]models.Webhook{appID: {*webhook}})
+	if err != nil {
+		return nil, err
+	}
+	s.attempt(deliveries[0].ID)
+	return s.GetDelivery(appID, webhookID, deliveries[0].ID)
+}
+
+// Emit 发送单个事件
+func (s *WebhookService) Emit(event WebhookEvent) {
+	s.EmitEvents([]WebhookEvent{event})
+}
+
+// EmitEvents 为订阅了事件的回调创建投递记录并异步发送；不影响调用方主流程
+func (s *WebhookService) EmitEvents(events []WebhookEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	appIDs := make([]uint, 0)
+	seen := make(map[uint]bool)
+	for _, event := range events {
+		if !seen[event.AppID] {
+			seen[event.AppID] = true
+			appIDs = append(appIDs, event.AppID)
+		}
+	}
+	var webhooks []models.Webhook
+	if err := database.DB.Where("app_id IN ? AND status = 1", appIDs).Find(&webhooks).Error; err != nil {
+		logger.Error("查询回调订阅失败", "error", err)
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+	byApp := make(map[uint][]models.Webhook)
+	for _, webhook := range webhooks {
+		byApp[webhook.AppID] = append(byApp[webhook.AppID], webhook)
+	}
+
+	deliveries, err := s.createDeliveries(events, byApp)
+	if err != nil {
+		logger.Error("创建回调投递失败", "error", err)
+		return
+	}
+	if len(deliveries) == 0 {
+		return
+	}
+	go func() {
+		for _, delivery := range deliveries {
+			s.attempt(delivery.ID)
+		}
+	}()
+}
+
+// createDeliveries 为每个事件匹配的回调订阅创建待投递记录
+func (s *WebhookService) createDeliveries(events []WebhookEvent, byApp map[uint][]models.Webhook) ([]models.WebhookDelivery, error) {
+	now := time.Now()
+	deliveries := make([]models.WebhookDelivery, 0)
+	for _, event := range events {
+		hooks := byApp[event.AppID]
+		if len(hooks) == 0 {
+			continue
+		}
+		payload := WebhookPayload{
+			ID:        utils.GenerateAPIKey(),
+			Type:      event.Type,
+			AppID:     event.AppID,
+			CreatedAt: now.Unix(),
+			Data:      event.Data,
+		}
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("事件序列化失败: %v", err)
+		}
+		for _, webhook := range hooks {
+			if event.Type != WebhookEventTest && !webhookSubscribes(webhook.Events, event.Type) {
+				continue
+			}
+			deliveries = append(deliveries, models.WebhookDelivery{
+				AppID:         event.AppID,
+				WebhookID:     webhook.ID,
+				EventID:       payload.ID,
+				Event:         event.Type,
+				Payload:       string(body),
+				Status:        "pending",
+				NextAttemptAt: &now,
+			})
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil, nil
+	}
+	if err := database.DB.CreateInBatches(deliveries, 200).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// RetryDueDeliveries 重新发送到期的失败投递，并恢复因进程中断停留在投递中的记录。
+// 由调度器定期调用，上一轮未结束时直接返回
+func (s *WebhookService) RetryDueDeliveries() {
+	if !webhookRetryRunning.CompareAndSwap(false, true) {
+		return
+	}
+
+	now := time.Now()
+	database.DB.Model(&models.WebhookDelivery{}).
+		Where("status = ? AND updated_at < ?", "delivering", now.Add(-webhookStuckAfter)).
+		Updates(map[string]interface{}{"status": "pending", "next_attempt_at": now})
+
+	var ids []uint
+	if err := database.DB.Model(&models.WebhookDelivery{}).
+		Where("status = ? AND next_attempt_at <= ?", "pending", now).
+		Order("next_attempt_at ASC").Limit(webhookRetryBatch).
+		Pluck("id", &ids).Error; err != nil {
+		logger.Error("获取待重试的回调投递失败", "error", err)
+		webhookRetryRunning.Store(false)
+		return
+	}
+	if len(ids) == 0 {
+		webhookRetryRunning.Store(false)
+		return
+	}
+
+	go func() {
+		defer webhookRetryRunning.Store(false)
+		for _, id := range ids {
+			s.attempt(id)
+		}
+	}()
+}
+
+// attempt 抢占一条待投递记录并发送一次，根据结果标记成功、安排重试或标记失败
+func (s *WebhookService) attempt(deliveryID uint) {
+	claim := database.DB.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ?", deliveryID, "pending").
+		Update("status", "delivering")
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		return
+	}
+
+	var delivery models.WebhookDelivery
+	if err := database.DB.First(&delivery, deliveryID).Error; err != nil {
+		return
+	}
+
+	var webhook models.Webhook
+	if err := database.DB.Where("id = ?", delivery.WebhookID).First(&webhook).Error; err != nil {
+		s.finish(&delivery, 0, "", "回调订阅已删除", false)
+		return
+	}
+	if webhook.Status != 1 && delivery.Event != WebhookEventTest {
+		s.finish(&delivery, 0, "", "回调订阅已禁用", false)
+		return
+	}
+
+	statusCode, body, err := postWebhook(webhook.URL, webhook.Secret, delivery.Event, delivery.EventID, []byte(delivery.Payload), time.Now())
+	switch {
+	case err != nil:
+		s.finish(&delivery, statusCode, body, err.Error(), true)
+	case statusCode < 200 || statusCode >= 300:
+		s.finish(&delivery, statusCode, body, fmt.Sprintf("回调地址返回状态码 %d", statusCode), true)
+	default:
+		s.finish(&delivery, statusCode, body, "", true)
+	}
+}
+
+// finish 记录一次投递结果。errMsg 为空表示成功；retry 为 false 时失败不再重试
+func (s *WebhookService) finish(delivery *models.WebhookDelivery, statusCode int, body, errMsg string, retry bool) {
+	now := time.Now()
+	attempts := delivery.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts":        attempts,
+		"response_status": statusCode,
+		"response_body":   body,
+		"error":           errMsg,
+		"next_attempt_at": nil,
+	}
+	switch {
+	case errMsg == "":
+		updates["status"] = "success"
+		updates["delivered_at"] = &now
+	case retry && delivery.Event != WebhookEventTest && attempts < webhookMaxAttempts:
+		next := now.Add(webhookRetryDelay(attempts))
+		updates["status"] = "pending"
+		updates["next_attempt_at"] = &next
+	default:
+		updates["status"] = "failed"
+	}
+	if err := database.DB.Model(delivery).Updates(updates).Error; err != nil {
+		logger.Error("更新回调投递状态失败", "app_id", delivery.AppID, "delivery_id", delivery.ID, "error", err)
+	}
+}
+
+// postWebhook 签名并发送事件，返回响应状态码和截断后的响应内容
+func postWebhook(rawURL, secret, event, eventID string, body []byte, now time.Time) (int, string, error) {
+	req, err := http.NewRequest(http.MethodPost, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", fmt.Errorf("构造请求失败: %v", err)
+	}
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "DooPush-Webhook/1.0")
+	req.Header.Set("X-DooPush-Event", event)
+	req.Header.Set("X-DooPush-Event-Id", eventID)
+	req.Header.Set(WebhookSignatureHeader, fmt.Sprintf("t=%d,v1=%s", timestamp, signWebhookPayload(secret, timestamp, body)))
+
+	resp, err := webhookHTTPClient.Do(req)
+	if err != nil {
+		return 0, "", fmt.Errorf("请求回调地址失败: %v", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, webhookMaxRespBody))
+	return resp.StatusCode, string(respBody), nil
+}
+
+// signWebhookPayload 计算签名：hex(HMAC-SHA256(secret, "<timestamp>.<body>"))
+func signWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookRetryDelay 第 attempts 次失败后的重试间隔：1m、4m、16m、64m，之后固定 4h
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := time.Minute
+	for i := 1; i < attempts; i++ {
+		delay *= 4
+		if delay >= 4*time.Hour {
+			return 4 * time.Hour
+		}
+	}
+	return delay
+}
+
+// webhookSubscribes 判断订阅的事件列表（逗号分隔，为空表示全部）是否包含事件
+func webhookSubscribes(events, event string) bool {
+	if strings.TrimSpace(events) == "" {
+		return true
+	}
+	for _, e := range strings.Split(events, ",") {
+		if strings.TrimSpace(e) == event {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookBlockedNets 回调不允许访问的网段，IsLoopback/IsPrivate 等未覆盖的部分：
+// 0.0.0.0/8 和运营商级 NAT 网段（部分云厂商的元数据服务位于 100.100.100.200）
+var webhookBlockedNets = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+}
+
+// mustParseCIDR 解析固定的网段常量
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return ipNet
+}
+
+// webhookIPAllowed 回调只能访问公网地址，拒绝回环、内网、链路本地和未指定地址
+func webhookIPAllowed(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, ipNet := range webhookBlockedNets {
+		if ipNet.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// webhookDialControl 在 DNS 解析之后、建立连接之前检查目标 IP，防止通过 DNS 重绑定访问内网
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !webhookIPAllowed(ip) {
+		return fmt.Errorf("回调地址不能指向内网地址: %s", host)
+	}
+	return nil
+}
+
+// newWebhookHTTPClient 发送回调的 HTTP 客户端：只连接公网地址，不使用代理，不跟随重定向
+func newWebhookHTTPClient() *http.Client {
+	dialer := &net.Dialer{Timeout: webhookTimeout, Control: webhookDialControl}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// 经代理转发时检查的是代理地址而不是回调地址
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   webhookTimeout,
+		Transport: transport,
+		// 重定向目标未经校验，直接把 3xx 作为投递结果
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// validateWebhookURL 回调地址必须是 http 或 https 绝对地址，且不能直接指向内网。
+// 域名在每次投递建立连接时再检查解析结果
+func validateWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("回调地址必须是 http 或 https 地址")
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); (ip != nil && !webhookIPAllowed(ip)) || strings.EqualFold(host, "localhost") {
+		return errors.New("回调地址不能指向内网地址")
+	}
+	return nil
+}
+
+// validateWebhookEvents 校验订阅的事件类型
+func validateWebhookEvents(events []string) error {
+	for _, event := range events {
+		valid := false
+		for _, e := range WebhookEvents {
+			if e == event {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("不支持的事件类型: %s", event)
+		}
+	}
+	return nil
+}
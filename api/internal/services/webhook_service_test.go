@@ -0,0 +1,163 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	body := []byte(`{"id":"evt","type":"push.delivered"}`)
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte("1700000000." + string(body)))
+	want := hex.EncodeToString(mac.Sum(nil))
+
+	if got := signWebhookPayload("whsec_test", 1700000000, body); got != want {
+		t.Fatalf("signature = %s, want %s", got, want)
+	}
+	if signWebhookPayload("whsec_other", 1700000000, body) == want {
+		t.Fatal("different secret must produce different signature")
+	}
+	if signWebhookPayload("whsec_test", 1700000001, body) == want {
+		t.Fatal("different timestamp must produce different signature")
+	}
+}
+
+func TestPostWebhookHeaders(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"evt1"}`)
+
+	var gotSig, gotEvent, gotEventID, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSig = r.Header.Get(WebhookSignatureHeader)
+		gotEvent = r.Header.Get("X-DooPush-Event")
+		gotEventID = r.Header.Get("X-DooPush-Event-Id")
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	// 测试服务器监听在回环地址，跳过内网地址检查
+	defaultClient := webhookHTTPClient
+	webhookHTTPClient = server.Client()
+	defer func() { webhookHTTPClient = defaultClient }()
+
+	status, respBody, err := postWebhook(server.URL, "whsec_test", WebhookEventPushClicked, "evt1", body, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status != http.StatusAccepted || respBody != "ok" {
+		t.Fatalf("got status=%d body=%q", status, respBody)
+	}
+	wantSig := fmt.Sprintf("t=1700000000,v1=%s", signWebhookPayload("whsec_test", 1700000000, body))
+	if gotSig != wantSig {
+		t.Fatalf("signature header = %q, want %q", gotSig, wantSig)
+	}
+	if gotEvent != WebhookEventPushClicked || gotEventID != "evt1" || gotBody != string(body) {
+		t.Fatalf("got event=%q id=%q body=%q", gotEvent, gotEventID, gotBody)
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	cases := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 4 * time.Minute},
+		{3, 16 * time.Minute},
+		{4, 64 * time.Minute},
+		{5, 4 * time.Hour},
+		{10, 4 * time.Hour},
+	}
+	for _, c := range cases {
+		if got := webhookRetryDelay(c.attempts); got != c.want {
+			t.Errorf("webhookRetryDelay(%d) = %v, want %v", c.attempts, got, c.want)
+		}
+	}
+}
+
+func TestWebhookSubscribes(t *testing.T) {
+	cases := []struct {
+		events, event string
+		want          bool
+	}{
+		{"", WebhookEventDeviceOnline, true},
+		{"push.delivered,push.clicked", WebhookEventPushClicked, true},
+		{"push.delivered, device.online", WebhookEventDeviceOnline, true},
+		{"push.delivered", WebhookEventPushOpened, false},
+		{"push.delivered", "push", false},
+	}
+	for _, c := range cases {
+		if got := webhookSubscribes(c.events, c.event); got != c.want {
+			t.Errorf("webhookSubscribes(%q, %q) = %v, want %v", c.events, c.event, got, c.want)
+		}
+	}
+}
+
+func TestValidateWebhook(t *testing.T) {
+	for _, u := range []string{"https://example.com/hook", "http://203.0.113.7:8080/cb"} {
+		if err := validateWebhookURL(u); err != nil {
+			t.Errorf("validateWebhookURL(%q) unexpected error: %v", u, err)
+		}
+	}
+	for _, u := range []string{"", "example.com/hook", "ftp://example.com", "https://",
+		"http://10.0.0.1:8080/cb", "http://127.0.0.1/cb", "http://localhost:8080/cb", "http://169.254.169.254/latest", "http://[::1]/cb"} {
+		if err := validateWebhookURL(u); err == nil {
+			t.Errorf("validateWebhookURL(%q) expected error", u)
+		}
+	}
+
+	if err := validateWebhookEvents([]string{WebhookEventPushDelivered, WebhookEventDeviceOffline}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := validateWebhookEvents([]string{WebhookEventTest}); err == nil {
+		t.Error("webhook.test must not be subscribable")
+	}
+}
+
+func TestWebhookIPAllowed(t *testing.T) {
+	for _, ip := range []string{"8.8.8.8", "203.0.113.7", "2001:4860:4860::8888"} {
+		if !webhookIPAllowed(net.ParseIP(ip)) {
+			t.Errorf("%s should be allowed", ip)
+		}
+	}
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254",
+		"100.100.100.200", "0.0.0.0", "::1", "fe80::1", "fd00::1", "::ffff:127.0.0.1"} {
+		if webhookIPAllowed(net.ParseIP(ip)) {
+			t.Errorf("%s should be rejected", ip)
+		}
+	}
+}
+
+func TestWebhookClientRejectsLoopback(t *testing.T) {
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	// 投递时不再校验地址字面值，解析到回环地址的连接在拨号阶段被拒绝
+	if _, _, err := postWebhook(server.URL, "whsec_test", WebhookEventTest, "evt1", []byte(`{}`), time.Now()); err == nil {
+		t.Fatal("expected loopback address to be rejected")
+	}
+	if called {
+		t.Fatal("request must not reach the loopback server")
+	}
+}
+
+func TestWebhookClientDoesNotFollowRedirects(t *testing.T) {
+	client := newWebhookHTTPClient()
+	req := httptest.NewRequest(http.MethodPost, "https://example.com/hook", nil)
+	if err := client.CheckRedirect(req, []*http.Request{req}); err != http.ErrUseLastResponse {
+		t.Fatalf("CheckRedirect = %v, want ErrUseLastResponse", err)
+	}
+}
@@ -0,0 +1,89 @@
+// Package testutil 测试辅助工具，只在 _test.go 中引用
+package testutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/doopush/doopush/api/internal/database"
+	"github.com/doopush/doopush/api/internal/models"
+	"github.com/doopush/doopush/api/pkg/utils"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// SetupDB 创建内存 SQLite 数据库并迁移全部模型，替换 database.DB。
+// 只保留一个连接，保证同一测试内的查询和后台协程看到同一个内存库
+func SetupDB(t testing.TB) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("sqlite handle: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(models.AllModels()...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	database.DB = db
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// CreateApp 创建应用并设置所有者
+func CreateApp(t testing.TB, ownerID uint, name string) *models.App {
+	t.Helper()
+	app := &models.App{Name: name, PackageName: "com.example." + name, Platform: "both", Status: 1}
+	if err := database.DB.Create(app).Error; err != nil {
+		t.Fatalf("create app: %v", err)
+	}
+	permission := &models.UserAppPermission{UserID: ownerID, AppID: app.ID, Role: "owner"}
+	if err := database.DB.Create(permission).Error; err != nil {
+		t.Fatalf("create app permission: %v", err)
+	}
+	return app
+}
+
+// CreateAPIKey 为应用创建明文为 key 的API密钥，key 以 dp_test_ 开头时为测试密钥
+func CreateAPIKey(t testing.TB, appID uint, key string, scopes ...string) *models.AppAPIKey {
+	t.Helper()
+	prefix := models.APIKeyPrefixLive
+	if strings.HasPrefix(key, models.APIKeyPrefixTest) {
+		prefix = models.APIKeyPrefixTest
+	}
+	apiKey := &models.AppAPIKey{
+		AppID:     appID,
+		Name:      "test",
+		KeyHash:   utils.HashString(key),
+		KeyPrefix: prefix,
+		KeySuffix: key[len(key)-4:],
+		Status:    1,
+		Scopes:    strings.Join(scopes, ","),
+	}
+	if err := database.DB.Create(apiKey).Error; err != nil {
+		t.Fatalf("create api key: %v", err)
+	}
+	return apiKey
+}
+
+// CreateDevice 在应用下创建设备，token 同时用于计算 token_hash
+func CreateDevice(t testing.TB, appID uint, token string, sandbox bool) *models.Device {
+	t.Helper()
+	device := &models.Device{
+		AppID:     appID,
+		Token:     token,
+		TokenHash: utils.HashString(token),
+		Platform:  "android",
+		Channel:   "fcm",
+		Status:    1,
+		Sandbox:   sandbox,
+	}
+	if err := database.DB.Create(device).Error; err != nil {
+		t.Fatalf("create device: %v", err)
+	}
+	return device
+}
@@ -0,0 +1,167 @@
+// Package tracing 基于 OpenTelemetry 的链路追踪。
+// 一次推送从 API 请求开始，经目标设备查询、推送通道调用、厂商 HTTP 请求到结果写入构成一条链路；
+// 异步投递（静默时段暂存、分批发送、审批）通过推送日志上保存的 traceparent 接续原链路，
+// 定时推送每次执行开始新链路，并链接（Link）到创建任务时的链路。
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/doopush/doopush/api/internal/config"
+	"github.com/doopush/doopush/api/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/doopush/doopush/api"
+
+// propagator W3C Trace Context，用于 HTTP 头和推送日志上的 traceparent
+var propagator = propagation.TraceContext{}
+
+// Init 按配置初始化链路追踪，返回进程退出时调用的 shutdown。
+// 未配置 OTEL_EXPORTER_OTLP_ENDPOINT 时不导出任何数据，span 仍会创建但开销可忽略
+func Init(serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagator)
+
+	endpoint := config.GetString("OTEL_EXPORTER_OTLP_ENDPOINT")
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpointURL(strings.TrimRight(endpoint, "/")+"/v1/traces"))
+	if err != nil {
+		return nil, fmt.Errorf("创建 OTLP 导出器失败: %v", err)
+	}
+
+	ratio := 1.0
+	if value := config.GetString("OTEL_TRACES_SAMPLER_RATIO"); value != "" {
+		if ratio, err = strconv.ParseFloat(value, 64); err != nil || ratio < 0 || ratio > 1 {
+			return nil, fmt.Errorf("无效的采样比例: %s", value)
+		}
+	}
+
+	res := resource.NewSchemaless(
+		attribute.String("service.name", config.GetString("OTEL_SERVICE_NAME", serviceName)),
+		attribute.String("deployment.environment", config.GetString("APP_ENV", "development")),
+	)
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(provider)
+	logger.Info("链路追踪已启用", "endpoint", endpoint, "sample_ratio", ratio)
+	return provider.Shutdown, nil
+}
+
+// Tracer 返回全局 TracerProvider 下的 Tracer，测试中替换 TracerProvider 后立即生效
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Start 开始一个内部 span
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End 结束 span，err 非空时记录错误并将 span 状态置为 Error
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Fail 将 span 标记为失败，用于不以 error 返回的失败结果（如厂商错误码）
+func Fail(span trace.Span, code, message string) {
+	span.SetAttributes(attribute.String("doopush.error_code", code))
+	span.SetStatus(codes.Error, message)
+}
+
+// TraceID 返回 ctx 中 span 的 trace ID，没有有效 span 时返回空字符串
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// TraceParent 将 ctx 中的 span 编码为 W3C traceparent，用于随推送日志、定时任务持久化
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// ContextWithTraceParent 将持久化的 traceparent 还原为父 span，traceparent 为空或无效时原样返回 ctx
+func ContextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier{"traceparent": traceParent})
+}
+
+// LinkTraceParent 返回指向 traceparent 所在链路的 Link，traceparent 为空或无效时返回 false
+func LinkTraceParent(traceParent string) (trace.Link, bool) {
+	sc := trace.SpanContextFromContext(ContextWithTraceParent(context.Background(), traceParent))
+	if !sc.IsValid() {
+		return trace.Link{}, false
+	}
+	return trace.Link{SpanContext: sc}, true
+}
+
+// Transport 包装厂商请求使用的 Transport，为每个请求创建 client span。
+// 不向厂商注入 traceparent 请求头。next 为 nil 时使用 http.DefaultTransport
+func Transport(channel string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &tracingTransport{channel: channel, next: next}
+}
+
+type tracingTransport struct {
+	channel string
+	next    http.RoundTripper
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := Tracer().Start(req.Context(), t.channel+" "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("doopush.channel", t.channel),
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Host),
+			attribute.String("url.path", req.URL.Path),
+		))
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		End(span, err)
+		return resp, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	span.End()
+	return resp, nil
+}
+
+// CloseIdleConnections 透传给底层 Transport，保持 http.Client.CloseIdleConnections 的行为
+func (t *tracingTransport) CloseIdleConnections() {
+	if c, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,108 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// useInMemoryExporter 将全局 TracerProvider 替换为同步导出到内存的实现，测试结束后恢复
+func useInMemoryExporter(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previous)
+	})
+	return exporter
+}
+
+func TestTraceParentRoundTrip(t *testing.T) {
+	useInMemoryExporter(t)
+
+	ctx, span := Start(context.Background(), "send")
+	defer span.End()
+
+	traceParent := TraceParent(ctx)
+	if traceParent == "" {
+		t.Fatal("expected traceparent")
+	}
+
+	// 异步投递从 traceparent 还原父 span，子 span 属于同一链路
+	restored := ContextWithTraceParent(context.Background(), traceParent)
+	_, child := Start(restored, "deliver")
+	defer child.End()
+	if got, want := child.SpanContext().TraceID().String(), TraceID(ctx); got != want {
+		t.Fatalf("child trace id = %s, want %s", got, want)
+	}
+
+	link, ok := LinkTraceParent(traceParent)
+	if !ok || link.SpanContext.SpanID() != span.SpanContext().SpanID() {
+		t.Fatalf("link = %+v, ok = %v", link, ok)
+	}
+
+	if TraceParent(context.Background()) != "" || TraceID(context.Background()) != "" {
+		t.Fatal("context without span must yield empty values")
+	}
+	if _, ok := LinkTraceParent("invalid"); ok {
+		t.Fatal("invalid traceparent must not produce a link")
+	}
+}
+
+func TestTransport(t *testing.T) {
+	exporter := useInMemoryExporter(t)
+
+	var gotTraceParent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTraceParent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, parent := Start(context.Background(), "PushProvider.SendPush")
+	req, _ := http.NewRequestWithContext(ctx, "POST", server.URL+"/v3/message/regid?token=secret", nil)
+	client := &http.Client{Transport: Transport("xiaomi", nil)}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	if gotTraceParent != "" {
+		t.Fatalf("traceparent must not be sent to vendors, got %q", gotTraceParent)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("spans = %d, want 2", len(spans))
+	}
+	vendor := spans[0]
+	if vendor.Name != "xiaomi POST" || vendor.SpanKind != trace.SpanKindClient {
+		t.Fatalf("got span %q kind %v", vendor.Name, vendor.SpanKind)
+	}
+	if vendor.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Fatal("vendor span must be a child of the provider span")
+	}
+	if vendor.Status.Code != codes.Error {
+		t.Fatalf("status = %v, want error", vendor.Status.Code)
+	}
+	attrs := attribute.NewSet(vendor.Attributes...)
+	if v, _ := attrs.Value("http.response.status_code"); v.AsInt64() != http.StatusServiceUnavailable {
+		t.Fatalf("status code attribute = %v", v.AsInt64())
+	}
+	if v, _ := attrs.Value("url.path"); v.AsString() != "/v3/message/regid" {
+		t.Fatalf("url.path = %q, query string must not be recorded", v.AsString())
+	}
+}
@@ -1,28 +1,204 @@
+// Package logger 基于 slog 的结构化日志，API 服务与 WebSocket 网关共用。
+//
+// 统一字段名：app_id、push_log_id、device_id、channel、request_id、trace_id、error。
+// 所有输出（包括经标准库 log 打印的内容）都会经过脱敏：设备 Token、API Key、密钥类字段按字段名掩码，
+// 字符串中出现的 access_token、Bearer 凭据、API Key、私钥等在输出前替换。
 package logger
 
 import (
-	"log"
+	"context"
+	"io"
+	"log/slog"
 	"os"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
-// Logger 统一日志管理
-var Logger *log.Logger
+// level 全局日志级别，Init 后可通过 SetLevel 调整
+var level = new(slog.LevelVar)
 
 func init() {
-	Logger = log.New(os.Stdout, "[API] ", log.LstdFlags|log.Lshortfile)
+	slog.SetDefault(slog.New(newHandler(os.Stdout, "json")))
+}
+
+// Init 按服务名、级别（debug/info/warn/error）和格式（json/text）初始化默认日志，
+// 同时接管标准库 log 的输出。无效级别按 info 处理
+func Init(service, levelName, format string) {
+	SetLevel(levelName)
+	slog.SetDefault(slog.New(newHandler(os.Stdout, format)).With("service", service))
+}
+
+// SetLevel 设置日志级别，无效级别按 info 处理
+func SetLevel(levelName string) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(levelName)); err != nil {
+		l = slog.LevelInfo
+	}
+	level.Set(l)
+}
+
+func newHandler(w io.Writer, format string) slog.Handler {
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redactAttr}
+	if format == "text" {
+		return &contextHandler{slog.NewTextHandler(w, opts)}
+	}
+	return &contextHandler{slog.NewJSONHandler(w, opts)}
+}
+
+// contextHandler 从 ctx 中补充 request_id 和 trace_id
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{h.Handler.WithGroup(name)}
+}
+
+type requestIDKey struct{}
+
+// ContextWithRequestID 将请求 ID 放入 ctx，之后以该 ctx 记录的日志都带 request_id
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID 返回 ctx 中的请求 ID
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// With 返回附带固定字段的 Logger，如 logger.With("app_id", appID, "channel", channel)
+func With(args ...any) *slog.Logger {
+	return slog.Default().With(args...)
+}
+
+// Debug 调试日志
+func Debug(msg string, args ...any) {
+	slog.Default().Debug(msg, args...)
 }
 
 // Info 信息日志
-func Info(v ...interface{}) {
-	Logger.Println("[INFO]", v)
+func Info(msg string, args ...any) {
+	slog.Default().Info(msg, args...)
+}
+
+// Warn 警告日志
+func Warn(msg string, args ...any) {
+	slog.Default().Warn(msg, args...)
 }
 
 // Error 错误日志
-func Error(v ...interface{}) {
-	Logger.Println("[ERROR]", v)
+func Error(msg string, args ...any) {
+	slog.Default().Error(msg, args...)
+}
+
+// DebugContext 带请求上下文的调试日志
+func DebugContext(ctx context.Context, msg string, args ...any) {
+	slog.Default().DebugContext(ctx, msg, args...)
+}
+
+// InfoContext 带请求上下文的信息日志
+func InfoContext(ctx context.Context, msg string, args ...any) {
+	slog.Default().InfoContext(ctx, msg, args...)
+}
+
+// WarnContext 带请求上下文的警告日志
+func WarnContext(ctx context.Context, msg string, args ...any) {
+	slog.Default().WarnContext(ctx, msg, args...)
+}
+
+// ErrorContext 带请求上下文的错误日志
+func ErrorContext(ctx context.Context, msg string, args ...any) {
+	slog.Default().ErrorContext(ctx, msg, args...)
+}
+
+// Fatal 记录错误日志后退出进程
+func Fatal(msg string, args ...any) {
+	slog.Default().Error(msg, args...)
+	os.Exit(1)
+}
+
+// sensitiveKeys 按字段名整体掩码的字段（不区分大小写）
+var sensitiveKeys = map[string]bool{
+	"token":         true,
+	"device_token":  true,
+	"push_token":    true,
+	"api_key":       true,
+	"apikey":        true,
+	"secret":        true,
+	"app_secret":    true,
+	"client_secret": true,
+	"master_secret": true,
+	"password":      true,
+	"private_key":   true,
+	"key_p8":        true,
+	"certificate":   true,
+	"authorization": true,
+	"access_token":  true,
+	"auth_token":    true,
+}
+
+// redactAttr 作为 slog ReplaceAttr 对每个字段脱敏
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, Mask(a.Value.String()))
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, Redact(err.Error()))
+		}
+	}
+	return a
+}
+
+// Mask 掩码凭据，仅保留前 4 个字符用于排查，长度不足 12 时全部掩码
+func Mask(s string) string {
+	if s == "" {
+		return ""
+	}
+	if len(s) < 12 {
+		return "***"
+	}
+	return s[:4] + "***"
+}
+
+var redactPatterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// PEM 私钥（APNs p8、证书私钥等）
+	{regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`), "[REDACTED PRIVATE KEY]"},
+	// Authorization: Bearer xxx
+	{regexp.MustCompile(`(?i)\b(Bearer)\s+[A-Za-z0-9._~+/=-]+`), "${1} ***"},
+	// DooPush API Key、回调签名密钥
+	{regexp.MustCompile(`\b(dp_(?:live|test)_|whsec_)[A-Za-z0-9]+`), "${1}***"},
+	// JSON、表单、请求头中的凭据字段
+	{regexp.MustCompile(`(?i)(["']?\b(?:access_?token|auth_?token|refresh_?token|id_?token|client_?secret|app_?secret|master_?secret|app_?key|api_?key|token|secret|password|private_?key|authorization)["']?\s*[:=]\s*\[?\s*["']?)([^"'&,\s}\]]+)`), "${1}***"},
 }
 
-// Fatal 致命错误日志
-func Fatal(v ...interface{}) {
-	Logger.Fatal("[FATAL]", v)
+// Redact 替换字符串中出现的凭据，用于厂商响应、错误信息等自由文本
+func Redact(s string) string {
+	for _, p := range redactPatterns {
+		s = p.re.ReplaceAllString(s, p.repl)
+	}
+	return s
 }
@@ -50,7 +50,9 @@ export default withMermaid(
               { text: 'API 认证', link: '/api/authentication' },
               { text: '推送接口', link: '/api/push-apis' },
               { text: '设备注册', link: '/api/device-apis' },
-              { text: '统计上报', link: '/api/data-apis' }
+              { text: '统计上报', link: '/api/data-apis' },
+              { text: '事件回调', link: '/api/webhooks' },
+              { text: '告警', link: '/api/alerts' }
             ]
           }
         ],
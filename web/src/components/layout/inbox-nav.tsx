@@ -28,6 +28,9 @@ const statusLabels: Record<InvitationStatus, string> = {
   accepted: '已接受',
   rejected: '已拒绝',
   cancelled: '已撤回',
+  approved: '已批准',
+  firing: '告警中',
+  resolved: '已恢复',
 }
 
 export function InboxButton() {
@@ -101,7 +104,9 @@ export function InboxButton() {
       setProcessingId(item.id)
       const updated = accept ? await InboxService.accept(item.id) : await InboxService.reject(item.id)
       replaceItem(updated, !item.read_at)
-      if (accept) {
+      if (item.type === 'push_approval') {
+        toast.success(accept ? '已批准推送' : '已驳回推送')
+      } else if (accept) {
         try {
           setUserApps(await AppService.getApps())
         } catch {
@@ -199,11 +204,15 @@ export function InboxButton() {
                     <div className='pe-5'>
                       <div className='text-sm font-medium'>{item.app_name}</div>
                       <p className='mt-1 text-sm text-muted-foreground'>
-                        {item.inviter_name} 邀请你以{roleLabels[item.role]}身份管理该应用
+                        {item.type === 'alert'
+                          ? item.message
+                          : item.type === 'push_approval'
+                            ? `${item.inviter_name} 发起了一条需要审批的推送`
+                            : `${item.inviter_name} 邀请你以${roleLabels[item.role]}身份管理该应用`}
                       </p>
                     </div>
                     <div className='flex flex-wrap items-center gap-2'>
-                      <Badge variant={item.status === 'pending' ? 'default' : 'secondary'}>
+                      <Badge variant={item.status === 'firing' ? 'destructive' : item.status === 'pending' ? 'default' : 'secondary'}>
                         {statusLabels[item.status]}
                       </Badge>
                       <span className='text-xs text-muted-foreground'>
@@ -214,11 +223,11 @@ export function InboxButton() {
                       <div className='flex gap-2 justify-end'>
                         <Button size='sm' onClick={() => void respond(item, true)} disabled={processing}>
                           {processing ? <Loader2 className='h-4 w-4 animate-spin' /> : <Check className='h-4 w-4' />}
-                          接受
+                          {item.type === 'push_approval' ? '批准' : '接受'}
                         </Button>
                         <Button size='sm' variant='outline' onClick={() => void respond(item, false)} disabled={processing}>
                           <X className='h-4 w-4' />
-                          拒绝
+                          {item.type === 'push_approval' ? '驳回' : '拒绝'}
                         </Button>
                       </div>
                     )}
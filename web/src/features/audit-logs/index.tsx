@@ -225,6 +225,7 @@ export function AuditLogs() {
       case 'update': return 'secondary'
       case 'delete': return 'destructive'
       case 'push': return 'outline'
+      case 'reveal': return 'destructive'
       default: return 'secondary'
     }
   }
@@ -282,6 +283,7 @@ export function AuditLogs() {
       update: '更新',
       delete: '删除',
       push: '推送',
+      reveal: '查看明文',
     }
     return labels[log.action] || log.action
   }
@@ -429,6 +431,7 @@ export function AuditLogs() {
                       <SelectItem value='update'>更新</SelectItem>
                       <SelectItem value='delete'>删除</SelectItem>
                       <SelectItem value='push'>推送</SelectItem>
+                      <SelectItem value='reveal'>查看明文</SelectItem>
                     </SelectContent>
                   </Select>
                 </div>
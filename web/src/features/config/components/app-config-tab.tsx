@@ -4,9 +4,11 @@ import {
   Plus,
   Copy,
   Trash2,
-  MoreHorizontal
+  MoreHorizontal,
+  ShieldCheck
 } from 'lucide-react'
 import { Button } from '@/components/ui/button'
+import { Badge } from '@/components/ui/badge'
 import {
   Table,
   TableBody,
@@ -33,8 +35,9 @@ import { Label } from '@/components/ui/label'
 
 import { useAuthStore } from '@/stores/auth-store'
 import { AppService } from '@/services/app-service'
-import { CreateApiKeyDialog } from './create-api-key-dialog'
+import { API_KEY_SCOPE_OPTIONS, CreateApiKeyDialog } from './create-api-key-dialog'
 import { DeleteApiKeyDialog } from './delete-api-key-dialog'
+import { EditApiKeyDialog } from './edit-api-key-dialog'
 import type { AppAPIKey } from '@/types/api'
 import { toast } from 'sonner'
 
@@ -46,6 +49,7 @@ export function AppConfigTab() {
   // 对话框状态
   const [createDialogOpen, setCreateDialogOpen] = useState(false)
   const [deleteDialogOpen, setDeleteDialogOpen] = useState(false)
+  const [editDialogOpen, setEditDialogOpen] = useState(false)
   const [selectedApiKey, setSelectedApiKey] = useState<AppAPIKey | null>(null)
   
 
@@ -96,6 +100,12 @@ export function AppConfigTab() {
 
 
 
+  // 处理编辑API密钥权限
+  const handleEditApiKey = (apiKey: AppAPIKey) => {
+    setSelectedApiKey(apiKey)
+    setEditDialogOpen(true)
+  }
+
   // 处理删除API密钥
   const handleDeleteApiKey = (apiKey: AppAPIKey) => {
     setSelectedApiKey(apiKey)
@@ -209,6 +219,7 @@ export function AppConfigTab() {
                 <TableRow>
                   <TableHead>密钥名称</TableHead>
                   <TableHead>API密钥</TableHead>
+                  <TableHead>权限范围</TableHead>
                   <TableHead>创建时间</TableHead>
                   <TableHead className="text-right">操作</TableHead>
                 </TableRow>
@@ -225,6 +236,26 @@ export function AppConfigTab() {
                           {apiKey.key_prefix}****************{apiKey.key_suffix}
                         </code>
                       </TableCell>
+                      <TableCell>
+                        <div className="flex flex-wrap gap-1">
+                          {apiKey.scopes.split(',').filter(Boolean).map((scope) => (
+                            <Badge
+                              key={scope}
+                              variant={scope.startsWith('push:') ? 'default' : 'secondary'}
+                              className="text-xs"
+                            >
+                              {API_KEY_SCOPE_OPTIONS.find((option) => option.value === scope)?.label ?? scope}
+                            </Badge>
+                          ))}
+                        </div>
+                        {(apiKey.allowed_cidrs || apiKey.rate_limit > 0) && (
+                          <div className="text-xs text-muted-foreground mt-1">
+                            {apiKey.allowed_cidrs && 'IP白名单'}
+                            {apiKey.allowed_cidrs && apiKey.rate_limit > 0 && ' · '}
+                            {apiKey.rate_limit > 0 && `${apiKey.rate_limit} 次/分钟`}
+                          </div>
+                        )}
+                      </TableCell>
                       <TableCell className="text-muted-foreground text-sm">
                         {new Date(apiKey.created_at).toLocaleString('zh-CN', {
                           year: 'numeric',
@@ -242,7 +273,10 @@ export function AppConfigTab() {
                             </Button>
                           </DropdownMenuTrigger>
                           <DropdownMenuContent align="end">
-
+                            <DropdownMenuItem onClick={() => handleEditApiKey(apiKey)}>
+                              <ShieldCheck className="mr-2 h-4 w-4" />
+                              编辑权限
+                            </DropdownMenuItem>
                             <DropdownMenuItem
                               onClick={() => handleDeleteApiKey(apiKey)}
                               className="text-red-600"
@@ -269,6 +303,13 @@ export function AppConfigTab() {
         onSuccess={handleApiKeyCreated}
       />
       
+      <EditApiKeyDialog
+        apiKey={selectedApiKey}
+        open={editDialogOpen}
+        onOpenChange={setEditDialogOpen}
+        onSuccess={loadApiKeys}
+      />
+
       <DeleteApiKeyDialog
         apiKey={selectedApiKey}
         open={deleteDialogOpen}
@@ -0,0 +1,82 @@
+import { CheckCircle, AlertCircle, HelpCircle, Clock } from 'lucide-react'
+import { Badge } from '@/components/ui/badge'
+import { Tooltip, TooltipContent, TooltipTrigger } from '@/components/ui/tooltip'
+import type { AppConfigHealth } from '@/types/api'
+
+// 证书剩余天数低于该值时提示即将过期
+const EXPIRY_WARNING_DAYS = 30
+
+const formatTime = (value: string) =>
+  new Date(value).toLocaleString('zh-CN', {
+    year: 'numeric',
+    month: '2-digit',
+    day: '2-digit',
+    hour: '2-digit',
+    minute: '2-digit'
+  })
+
+interface ConfigHealthBadgeProps {
+  health?: AppConfigHealth
+}
+
+export function ConfigHealthBadge({ health }: ConfigHealthBadgeProps) {
+  if (!health) {
+    return (
+      <Badge variant="secondary">
+        <Clock className="mr-1 h-3 w-3" />
+        待检查
+      </Badge>
+    )
+  }
+
+  const daysLeft = health.expires_at
+    ? Math.floor((new Date(health.expires_at).getTime() - Date.now()) / 86400000)
+    : null
+
+  let badge
+  if (health.status === 'unhealthy') {
+    badge = (
+      <Badge variant="destructive">
+        <AlertCircle className="mr-1 h-3 w-3" />
+        凭据异常
+      </Badge>
+    )
+  } else if (health.status === 'unsupported') {
+    badge = (
+      <Badge variant="secondary">
+        <HelpCircle className="mr-1 h-3 w-3" />
+        未校验凭据
+      </Badge>
+    )
+  } else if (daysLeft !== null && daysLeft < EXPIRY_WARNING_DAYS) {
+    badge = (
+      <Badge className="bg-yellow-100 text-yellow-800">
+        <AlertCircle className="mr-1 h-3 w-3" />
+        {daysLeft} 天后过期
+      </Badge>
+    )
+  } else {
+    badge = (
+      <Badge className="bg-green-100 text-green-800">
+        <CheckCircle className="mr-1 h-3 w-3" />
+        凭据正常
+      </Badge>
+    )
+  }
+
+  return (
+    <Tooltip>
+      <TooltipTrigger className="cursor-help">{badge}</TooltipTrigger>
+      <TooltipContent side="top" className="max-w-sm">
+        <div className="space-y-1">
+          <div className="break-all">{health.message}</div>
+          <div>检查时间：{formatTime(health.checked_at)}</div>
+          {health.last_success_at && health.status !== 'healthy' && (
+            <div>最近正常：{formatTime(health.last_success_at)}</div>
+          )}
+          {health.expires_at && <div>证书过期时间：{formatTime(health.expires_at)}</div>}
+        </div>
+      </TooltipContent>
+    </Tooltip>
+  )
+}
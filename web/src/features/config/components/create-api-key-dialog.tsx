@@ -16,21 +16,44 @@ import {
 import {
   Form,
   FormControl,
+  FormDescription,
   FormField,
   FormItem,
   FormLabel,
   FormMessage,
 } from '@/components/ui/form'
 import { Input } from '@/components/ui/input'
+import { Textarea } from '@/components/ui/textarea'
+import { Checkbox } from '@/components/ui/checkbox'
+import {
+  Select,
+  SelectContent,
+  SelectItem,
+  SelectTrigger,
+  SelectValue,
+} from '@/components/ui/select'
 import { useAuthStore } from '@/stores/auth-store'
 import { AppService } from '@/services/app-service'
 import { requireApp } from '@/utils/app-utils'
 import { toast } from 'sonner'
-import type { App } from '@/types/api'
+import type { App, APIKeyScope } from '@/types/api'
+
+// 可选的权限范围，默认只勾选SDK需要的权限
+export const API_KEY_SCOPE_OPTIONS: { value: APIKeyScope; label: string; description: string }[] = [
+  { value: 'device:register', label: '注册设备', description: '设备注册、SDK设备接口' },
+  { value: 'stats:report', label: '上报统计', description: '上报推送到达、点击等统计' },
+  { value: 'push:send', label: '发送推送', description: '单推、批量推送及发送任务管理' },
+  { value: 'push:broadcast', label: '广播推送', description: '向应用全部设备发送' },
+  { value: 'devices:read', label: '读取设备', description: '查询设备列表和详情' },
+]
 
 // 表单验证规则
 const createApiKeySchema = z.object({
   name: z.string().min(1, '请输入密钥名称').max(100, '密钥名称不能超过100个字符'),
+  mode: z.enum(['live', 'test']),
+  scopes: z.array(z.enum(['device:register', 'stats:report', 'push:send', 'push:broadcast', 'devices:read'])).min(1, '请至少选择一个权限'),
+  allowed_cidrs: z.string().max(1000, 'IP白名单过长'),
+  rate_limit: z.number().int().min(0, '频率限制不能为负数'),
 })
 
 type CreateApiKeyFormData = z.infer<typeof createApiKeySchema>
@@ -54,6 +77,10 @@ export function CreateApiKeyDialog({ open, onOpenChange, onSuccess, app }: Creat
     resolver: zodResolver(createApiKeySchema),
     defaultValues: {
       name: '',
+      mode: 'live',
+      scopes: ['device:register', 'stats:report'],
+      allowed_cidrs: '',
+      rate_limit: 0,
     },
   })
 
@@ -64,7 +91,10 @@ export function CreateApiKeyDialog({ open, onOpenChange, onSuccess, app }: Creat
 
     try {
       setLoading(true)
-      const result = await AppService.createAPIKey(targetApp.id, data)
+      const result = await AppService.createAPIKey(targetApp.id, {
+        ...data,
+        allowed_cidrs: data.allowed_cidrs.split(/[\s,]+/).filter(Boolean),
+      })
       
       // 设置创建成功的密钥信息
       setCreatedKey({
@@ -141,6 +171,107 @@ export function CreateApiKeyDialog({ open, onOpenChange, onSuccess, app }: Creat
                     </FormItem>
                   )}
                 />
+                <FormField
+                  control={form.control}
+                  name="mode"
+                  render={({ field }) => (
+                    <FormItem>
+                      <FormLabel>密钥类型</FormLabel>
+                      <Select onValueChange={field.onChange} value={field.value}>
+                        <FormControl>
+                          <SelectTrigger className="w-full min-w-0">
+                            <SelectValue placeholder="选择密钥类型" />
+                          </SelectTrigger>
+                        </FormControl>
+                        <SelectContent>
+                          <SelectItem value="live">正式密钥（dp_live_）</SelectItem>
+                          <SelectItem value="test">测试密钥（dp_test_）</SelectItem>
+                        </SelectContent>
+                      </Select>
+                      <FormDescription>
+                        测试密钥注册的设备与正式设备隔离，发起的推送只在沙箱中记录，不会发往厂商
+                      </FormDescription>
+                      <FormMessage />
+                    </FormItem>
+                  )}
+                />
+                <FormField
+                  control={form.control}
+                  name="scopes"
+                  render={({ field }) => (
+                    <FormItem>
+                      <FormLabel>权限范围 *</FormLabel>
+                      <div className="space-y-2">
+                        {API_KEY_SCOPE_OPTIONS.map((option) => (
+                          <label key={option.value} className="flex items-start gap-2 text-sm">
+                            <Checkbox
+                              checked={field.value.includes(option.value)}
+                              onCheckedChange={(checked) => {
+                                field.onChange(checked
+                                  ? [...field.value, option.value]
+                                  : field.value.filter((scope) => scope !== option.value))
+                              }}
+                            />
+                            <span className="leading-none">
+                              {option.label}
+                              <span className="text-muted-foreground ml-1 text-xs">{option.description}</span>
+                            </span>
+                          </label>
+                        ))}
+                      </div>
+                      <FormDescription>
+                        内置在App中的SDK密钥只需要注册设备和上报统计，不要授予推送权限
+                      </FormDescription>
+                      <FormMessage />
+                    </FormItem>
+                  )}
+                />
+                <FormField
+                  control={form.control}
+                  name="allowed_cidrs"
+                  render={({ field }) => (
+                    <FormItem>
+                      <FormLabel>IP白名单</FormLabel>
+                      <FormControl>
+                        <Textarea
+                          placeholder={`例如：203.0.113.0/24\n10.0.0.8`}
+                          className="resize-none font-mono text-sm"
+                          rows={3}
+                          {...field}
+                        />
+                      </FormControl>
+                      <FormDescription>
+                        每行一个IP或CIDR，留空不限制。SDK密钥通常留空
+                      </FormDescription>
+                      <FormMessage />
+                    </FormItem>
+                  )}
+                />
+                <FormField
+                  control={form.control}
+                  name="rate_limit"
+                  render={({ field }) => (
+                    <FormItem>
+                      <FormLabel>频率限制（次/分钟）</FormLabel>
+                      <FormControl>
+                        <Input
+                          type="number"
+                          min="0"
+                          step="1"
+                          {...field}
+                          onChange={(e) => {
+                            const value = parseInt(e.target.value) || 0
+                            field.onChange(value >= 0 ? value : 0)
+                          }}
+                        />
+                      </FormControl>
+                      <FormDescription>
+                        超过上限的请求返回 429，0 表示不限制
+                      </FormDescription>
+                      <FormMessage />
+                    </FormItem>
+                  )}
+                />
               </form>
             </Form>
           </DialogScrollBody>
@@ -0,0 +1,215 @@
+import { useEffect, useState } from 'react'
+import { useForm } from 'react-hook-form'
+import { zodResolver } from '@hookform/resolvers/zod'
+import { z } from 'zod'
+import { Loader2, ShieldCheck } from 'lucide-react'
+import { Button } from '@/components/ui/button'
+import {
+  Dialog,
+  DialogContent,
+  DialogDescription,
+  DialogFooter,
+  DialogHeader,
+  DialogScrollBody,
+  DialogTitle,
+} from '@/components/ui/dialog'
+import {
+  Form,
+  FormControl,
+  FormDescription,
+  FormField,
+  FormItem,
+  FormLabel,
+  FormMessage,
+} from '@/components/ui/form'
+import { Input } from '@/components/ui/input'
+import { Textarea } from '@/components/ui/textarea'
+import { Checkbox } from '@/components/ui/checkbox'
+import { useAuthStore } from '@/stores/auth-store'
+import { AppService } from '@/services/app-service'
+import { requireApp } from '@/utils/app-utils'
+import { toast } from 'sonner'
+import type { AppAPIKey, APIKeyScope } from '@/types/api'
+import { API_KEY_SCOPE_OPTIONS } from './create-api-key-dialog'
+
+// 表单验证规则
+const editApiKeySchema = z.object({
+  scopes: z.array(z.enum(['device:register', 'stats:report', 'push:send', 'push:broadcast', 'devices:read'])).min(1, '请至少选择一个权限'),
+  allowed_cidrs: z.string().max(1000, 'IP白名单过长'),
+  rate_limit: z.number().int().min(0, '频率限制不能为负数'),
+})
+
+type EditApiKeyFormData = z.infer<typeof editApiKeySchema>
+
+interface EditApiKeyDialogProps {
+  apiKey: AppAPIKey | null
+  open: boolean
+  onOpenChange: (open: boolean) => void
+  onSuccess: () => void
+}
+
+export function EditApiKeyDialog({ apiKey, open, onOpenChange, onSuccess }: EditApiKeyDialogProps) {
+  const { currentApp } = useAuthStore()
+  const [loading, setLoading] = useState(false)
+
+  const form = useForm<EditApiKeyFormData>({
+    resolver: zodResolver(editApiKeySchema),
+    defaultValues: {
+      scopes: [],
+      allowed_cidrs: '',
+      rate_limit: 0,
+    },
+  })
+
+  // 打开时填入密钥当前的设置
+  useEffect(() => {
+    if (apiKey && open) {
+      form.reset({
+        scopes: apiKey.scopes.split(',').filter(Boolean) as APIKeyScope[],
+        allowed_cidrs: apiKey.allowed_cidrs.split(',').filter(Boolean).join('\n'),
+        rate_limit: apiKey.rate_limit,
+      })
+    }
+  }, [apiKey, open, form])
+
+  const onSubmit = async (data: EditApiKeyFormData) => {
+    if (!requireApp(currentApp) || !apiKey) {
+      return
+    }
+
+    try {
+      setLoading(true)
+      await AppService.updateAPIKey(currentApp.id, apiKey.id, {
+        ...data,
+        allowed_cidrs: data.allowed_cidrs.split(/[\s,]+/).filter(Boolean),
+      })
+      toast.success('API密钥权限已更新')
+      onSuccess()
+      onOpenChange(false)
+    } catch (error) {
+      toast.error((error as Error).message || '更新API密钥失败')
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  const handleClose = () => {
+    if (!loading) {
+      onOpenChange(false)
+    }
+  }
+
+  if (!apiKey) {
+    return null
+  }
+
+  return (
+    <Dialog open={open} onOpenChange={handleClose}>
+      <DialogContent className="sm:max-w-[480px] max-h-[90vh] overflow-hidden flex flex-col">
+        <DialogHeader>
+          <DialogTitle className="flex items-center gap-2">
+            <ShieldCheck className="h-5 w-5" />
+            编辑密钥权限
+          </DialogTitle>
+          <DialogDescription>
+            修改「{apiKey.name}」的权限范围、IP白名单和频率限制，保存后立即生效。
+          </DialogDescription>
+        </DialogHeader>
+
+        <DialogScrollBody>
+          <Form {...form}>
+            <form onSubmit={form.handleSubmit(onSubmit)} className="space-y-4">
+              <FormField
+                control={form.control}
+                name="scopes"
+                render={({ field }) => (
+                  <FormItem>
+                    <FormLabel>权限范围 *</FormLabel>
+                    <div className="space-y-2">
+                      {API_KEY_SCOPE_OPTIONS.map((option) => (
+                        <label key={option.value} className="flex items-start gap-2 text-sm">
+                          <Checkbox
+                            checked={field.value.includes(option.value)}
+                            onCheckedChange={(checked) => {
+                              field.onChange(checked
+                                ? [...field.value, option.value]
+                                : field.value.filter((scope) => scope !== option.value))
+                            }}
+                          />
+                          <span className="leading-none">
+                            {option.label}
+                            <span className="text-muted-foreground ml-1 text-xs">{option.description}</span>
+                          </span>
+                        </label>
+                      ))}
+                    </div>
+                    <FormDescription>
+                      内置在App中的SDK密钥只需要注册设备和上报统计，不要授予推送权限
+                    </FormDescription>
+                    <FormMessage />
+                  </FormItem>
+                )}
+              />
+              <FormField
+                control={form.control}
+                name="allowed_cidrs"
+                render={({ field }) => (
+                  <FormItem>
+                    <FormLabel>IP白名单</FormLabel>
+                    <FormControl>
+                      <Textarea
+                        placeholder={`例如：203.0.113.0/24\n10.0.0.8`}
+                        className="resize-none font-mono text-sm"
+                        rows={3}
+                        {...field}
+                      />
+                    </FormControl>
+                    <FormDescription>
+                      每行一个IP或CIDR，留空不限制
+                    </FormDescription>
+                    <FormMessage />
+                  </FormItem>
+                )}
+              />
+              <FormField
+                control={form.control}
+                name="rate_limit"
+                render={({ field }) => (
+                  <FormItem>
+                    <FormLabel>频率限制（次/分钟）</FormLabel>
+                    <FormControl>
+                      <Input
+                        type="number"
+                        min="0"
+                        step="1"
+                        {...field}
+                        onChange={(e) => {
+                          const value = parseInt(e.target.value) || 0
+                          field.onChange(value >= 0 ? value : 0)
+                        }}
+                      />
+                    </FormControl>
+                    <FormDescription>
+                      超过上限的请求返回 429，0 表示不限制
+                    </FormDescription>
+                    <FormMessage />
+                  </FormItem>
+                )}
+              />
+            </form>
+          </Form>
+        </DialogScrollBody>
+
+        <DialogFooter>
+          <Button type="button" variant="outline" onClick={handleClose} disabled={loading}>
+            取消
+          </Button>
+          <Button onClick={form.handleSubmit(onSubmit)} disabled={loading}>
+            {loading && <Loader2 className="mr-2 h-4 w-4 animate-spin" />}
+            保存
+          </Button>
+        </DialogFooter>
+      </DialogContent>
+    </Dialog>
+  )
+}
@@ -7,8 +7,8 @@ import {
   MoreHorizontal,
   Play,
   Shield,
+  ShieldCheck,
   RefreshCw,
-  CheckCircle,
   AlertCircle,
   Cog
 } from 'lucide-react'
@@ -53,6 +53,7 @@ import { EditConfigDialog } from './components/edit-config-dialog'
 import { DeleteConfigDialog } from './components/delete-config-dialog'
 import { TestConfigDialog } from './components/test-config-dialog'
 import { AppConfigTab } from './components/app-config-tab'
+import { ConfigHealthBadge } from './components/config-health-badge'
 import { Apple, Android } from '@/components/platform-icon'
 import type { AppConfig } from '@/types/api'
 import { toast } from 'sonner'
@@ -120,6 +121,22 @@ export function PushConfig() {
     setTestDialogOpen(true)
   }
 
+  const handleCheckHealth = async (config: AppConfig) => {
+    if (!currentApp) return
+    try {
+      const health = await ConfigService.checkHealth(currentApp.id, config.id)
+      setConfigs(prev => prev.map(c => (c.id === config.id ? { ...c, health } : c)))
+      if (health.status === 'unhealthy') {
+        toast.error('凭据检查未通过: ' + health.message)
+      } else {
+        toast.success(health.status === 'healthy' ? '凭据检查通过' : health.message)
+      }
+    } catch (error) {
+      console.error('凭据检查失败:', error)
+      toast.error('凭据检查失败')
+    }
+  }
+
   const handleConfigCreated = (createdPlatform?: string) => {
     setCreateDialogOpen(false)
     loadConfigs()
@@ -271,7 +288,7 @@ export function PushConfig() {
                         <TableHeader>
                           <TableRow>
                             <TableHead>推送通道</TableHead>
-                            <TableHead>配置状态</TableHead>
+                            <TableHead>凭据状态</TableHead>
                             <TableHead>推送环境</TableHead>
                             <TableHead>创建时间</TableHead>
                             <TableHead className="text-right">操作</TableHead>
@@ -290,10 +307,7 @@ export function PushConfig() {
                                 </div>
                               </TableCell>
                               <TableCell>
-                                <Badge className="bg-green-100 text-green-800">
-                                  <CheckCircle className="mr-1 h-3 w-3" />
-                                  已配置
-                                </Badge>
+                                <ConfigHealthBadge health={config.health} />
                               </TableCell>
                               <TableCell>
                                 {(() => {
@@ -336,6 +350,10 @@ export function PushConfig() {
                                       <Play className="mr-2 h-4 w-4" />
                                       测试配置
                                     </DropdownMenuItem>
+                                    <DropdownMenuItem onClick={() => handleCheckHealth(config)}>
+                                      <ShieldCheck className="mr-2 h-4 w-4" />
+                                      检查凭据
+                                    </DropdownMenuItem>
                                     <DropdownMenuItem onClick={() => handleEditConfig(config)}>
                                       <Edit className="mr-2 h-4 w-4" />
                                       编辑配置
@@ -382,7 +400,7 @@ export function PushConfig() {
                         <TableHeader>
                           <TableRow>
                             <TableHead>推送通道</TableHead>
-                            <TableHead>配置状态</TableHead>
+                            <TableHead>凭据状态</TableHead>
                             <TableHead>创建时间</TableHead>
                             <TableHead className="text-right">操作</TableHead>
                           </TableRow>
@@ -400,10 +418,7 @@ export function PushConfig() {
                                 </div>
                               </TableCell>
                               <TableCell>
-                                <Badge className="bg-green-100 text-green-800">
-                                  <CheckCircle className="mr-1 h-3 w-3" />
-                                  已配置
-                                </Badge>
+                                <ConfigHealthBadge health={config.health} />
                               </TableCell>
                               <TableCell className="text-muted-foreground text-sm">
                                 {new Date(config.created_at).toLocaleString('zh-CN', {
@@ -426,6 +441,10 @@ export function PushConfig() {
                                       <Play className="mr-2 h-4 w-4" />
                                       测试配置
                                     </DropdownMenuItem>
+                                    <DropdownMenuItem onClick={() => handleCheckHealth(config)}>
+                                      <ShieldCheck className="mr-2 h-4 w-4" />
+                                      检查凭据
+                                    </DropdownMenuItem>
                                     <DropdownMenuItem onClick={() => handleEditConfig(config)}>
                                       <Edit className="mr-2 h-4 w-4" />
                                       编辑配置
@@ -446,9 +446,14 @@ export function Devices() {
                           )}
                         </TableCell>
                         <TableCell>
-                          <Badge className={getStatusBadge(device.status).className}>
-                            {getStatusBadge(device.status).label}
-                          </Badge>
+                          <div className="flex items-center gap-2">
+                            <Badge className={getStatusBadge(device.status).className}>
+                              {getStatusBadge(device.status).label}
+                            </Badge>
+                            {device.sandbox && (
+                              <Badge variant="outline" title="测试密钥注册，只接收沙箱推送">沙箱</Badge>
+                            )}
+                          </div>
                         </TableCell>
                         <TableCell className="text-muted-foreground text-sm">
                           {device.last_seen ? formatDistanceToNow(new Date(device.last_seen), { 
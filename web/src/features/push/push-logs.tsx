@@ -65,6 +65,7 @@ export default function PushLogs() {
   const [searchTerm, setSearchTerm] = useState('')
   const [statusFilter, setStatusFilter] = useState<string>('all')
   const [platformFilter, setPlatformFilter] = useState<string>('all')
+  const [sandboxFilter, setSandboxFilter] = useState<string>('all')
   
   // 对话框状态
   const [logDetailsOpen, setLogDetailsOpen] = useState(false)
@@ -106,6 +107,7 @@ export default function PushLogs() {
         page_size: pageSize,
         ...(statusFilter !== 'all' && { status: statusFilter }),
         ...(platformFilter !== 'all' && { platform: platformFilter }),
+        ...(sandboxFilter !== 'all' && { sandbox: sandboxFilter }),
       }
       const resp = await PushService.getPushLogs(currentApp.id, params)
       setLogs(resp.data.items)
@@ -119,7 +121,7 @@ export default function PushLogs() {
       loadingRef.current = false
       setLoading(false)
     }
-  }, [currentApp, statusFilter, platformFilter, currentPage, pageSize])
+  }, [currentApp, statusFilter, platformFilter, sandboxFilter, currentPage, pageSize])
 
   useEffect(() => {
     if (currentApp) {
@@ -274,6 +276,17 @@ export default function PushLogs() {
                   <SelectItem value="android">Android</SelectItem>
                 </SelectContent>
               </Select>
+
+              <Select value={sandboxFilter} onValueChange={setSandboxFilter}>
+                <SelectTrigger className="w-32">
+                  <SelectValue />
+                </SelectTrigger>
+                <SelectContent>
+                  <SelectItem value="all">全部推送</SelectItem>
+                  <SelectItem value="false">正式推送</SelectItem>
+                  <SelectItem value="true">沙箱推送</SelectItem>
+                </SelectContent>
+              </Select>
             </div>
 
             {/* 推送日志表格 */}
@@ -343,6 +356,9 @@ export default function PushLogs() {
                             <Badge className={getStatusBadge(log.status).className}>
                               {getStatusBadge(log.status).label}
                             </Badge>
+                            {log.sandbox && (
+                              <Badge variant="outline" title="测试密钥发起，未发往厂商">沙箱</Badge>
+                            )}
                           </div>
                         </TableCell>
                         <TableCell>
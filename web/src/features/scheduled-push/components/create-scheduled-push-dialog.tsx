@@ -118,7 +118,7 @@ const createScheduledPushSchema = z.object({
       callback_type: z.enum(['1', '2', '3']).optional(),
     }).optional(),
   }).optional(),
-  badge: z.number().int('角标必须是整数').min(1, '角标数量必须大于等于1').optional(),
+  badge: z.string().regex(/^(\+[1-9]\d*|reset|\d+)?$/, '角标必须为数字、+N 或 reset').optional(),
   scheduled_at: z.string().min(1, '请选择执行时间').refine((val) => {
     const scheduledTime = new Date(val);
     const now = new Date();
@@ -174,7 +174,7 @@ export function CreateScheduledPushDialog({ open, onOpenChange, onSuccess }: Cre
           category: undefined,
         },
       },
-      badge: 1,
+      badge: '+1',
       scheduled_at: '',
       repeat_type: 'none',
       repeat_config: '',
@@ -282,6 +282,7 @@ export function CreateScheduledPushDialog({ open, onOpenChange, onSuccess }: Cre
       // 创建请求数据
       const requestData = {
         ...data,
+        badge: data.badge || undefined, // 留空按 +1 累加
         payload: finalPayload,
         target_config: formattedTargetConfig
       }
@@ -396,27 +397,20 @@ export function CreateScheduledPushDialog({ open, onOpenChange, onSuccess }: Cre
                         render={({ field }) => (
                           <FormItem>
                             <FormLabel className='flex items-center gap-1'>
-                              角标数量
+                              角标
                               <Tooltip>
                                 <TooltipTrigger>
                                   <HelpCircle className="h-3.5 w-3.5 text-muted-foreground cursor-help" />
                                 </TooltipTrigger>
                                 <TooltipContent side="top">
-                                  设置推送消息的角标数量，iOS平台原生支持，Android平台支持情况因厂商而异，必须为大于等于1的整数
+                                  每次执行时的角标：数字为固定值，+N 在设备当前计数上累加，reset 清零。iOS平台原生支持，Android平台支持情况因厂商而异
                                 </TooltipContent>
                               </Tooltip>
                             </FormLabel>
                             <FormControl>
-                              <Input 
-                                type="number"
-                                min="1"
-                                step="1"
-                                placeholder="输入角标数量"
+                              <Input
+                                placeholder="+1、5 或 reset"
                                 {...field}
-                                onChange={(e) => {
-                                  const value = parseInt(e.target.value) || 1
-                                  field.onChange(value >= 1 ? value : 1)
-                                }}
                               />
                             </FormControl>
                             <FormMessage />
@@ -1,5 +1,5 @@
 import apiClient from './api-client'
-import type { App, AppAPIKey, AppConfig, AppInvitation, AppInviteCandidate, AppMember, AppRole, PaginationRequest } from '@/types/api'
+import type { APIKeyScope, App, AppAPIKey, AppConfig, AppInvitation, AppInviteCandidate, AppMember, AppRole, PaginationRequest } from '@/types/api'
 
 export class AppService {
   /**
@@ -90,10 +90,25 @@ export class AppService {
    */
   static async createAPIKey(appId: number, data: {
     name: string
+    mode?: 'live' | 'test'
+    scopes?: APIKeyScope[]
+    allowed_cidrs?: string[]
+    rate_limit?: number
   }): Promise<{ api_key: string; key_info: AppAPIKey; warning?: string }> {
     return apiClient.post(`/apps/${appId}/api-keys`, data)
   }
 
+  /**
+   * 更新API密钥的权限范围、IP白名单和频率限制
+   */
+  static async updateAPIKey(appId: number, keyId: number, data: {
+    scopes: APIKeyScope[]
+    allowed_cidrs?: string[]
+    rate_limit?: number
+  }): Promise<AppAPIKey> {
+    return apiClient.put(`/apps/${appId}/api-keys/${keyId}`, data)
+  }
+
   /**
    * 删除API密钥
    */
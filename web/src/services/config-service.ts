@@ -1,5 +1,5 @@
 import apiClient from './api-client'
-import type { AppConfig } from '@/types/api'
+import type { AppConfig, AppConfigHealth } from '@/types/api'
 
 export class ConfigService {
   /**
@@ -36,6 +36,13 @@ export class ConfigService {
     return apiClient.delete(`/apps/${appId}/config/${configId}`)
   }
 
+  /**
+   * 检查推送配置凭据
+   */
+  static async checkHealth(appId: number, configId: number): Promise<AppConfigHealth> {
+    return apiClient.post(`/apps/${appId}/config/${configId}/health-check`)
+  }
+
   /**
    * 测试推送配置
    */
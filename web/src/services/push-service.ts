@@ -97,7 +97,7 @@ export class PushService {
   /**
    * 获取推送日志（统一分页响应）
    */
-  static async getPushLogs(appId: number, params?: PaginationRequest<{ status?: string; platform?: string; start_time?: string; end_time?: string }>): Promise<PaginationEnvelope<PushLog>> {
+  static async getPushLogs(appId: number, params?: PaginationRequest<{ status?: string; platform?: string; sandbox?: string; start_time?: string; end_time?: string }>): Promise<PaginationEnvelope<PushLog>> {
     return apiClient.get(`/apps/${appId}/push/logs`, { params })
   }
 
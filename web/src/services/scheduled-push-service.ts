@@ -5,7 +5,7 @@ export interface CreateScheduledPushRequest {
   title: string
   content: string
   payload?: string
-  badge?: number
+  badge?: string  // 数字、+N 或 reset，不传按 +1 累加
   scheduled_at: string
   repeat_type: 'none' | 'daily' | 'weekly' | 'monthly'
   repeat_config?: string
@@ -20,7 +20,7 @@ export interface UpdateScheduledPushRequest {
   title?: string
   content?: string
   payload?: string
-  badge?: number
+  badge?: string  // 数字、+N 或 reset，不传按 +1 累加
   scheduled_at?: string
   repeat_type?: 'none' | 'daily' | 'weekly' | 'monthly'
   repeat_config?: string
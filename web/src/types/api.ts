@@ -35,10 +35,16 @@ export interface AppMember {
   created_at: string
 }
 
-export type InvitationStatus = 'pending' | 'accepted' | 'rejected' | 'cancelled'
+export type InvitationStatus = 'pending' | 'accepted' | 'rejected' | 'cancelled' | 'approved' | 'firing' | 'resolved'
+
+// 收件箱条目类型：成员邀请、推送审批或告警
+export type InboxItemType = 'invitation' | 'push_approval' | 'alert'
 
 export interface AppInvitation {
   id: number
+  type: InboxItemType
+  push_approval_id?: number
+  alert_id?: number
   app_id: number
   inviter_id: number
   invitee_id: number
@@ -49,6 +55,7 @@ export interface AppInvitation {
   inviter_name: string
   invitee_name: string
   invitee_email: string
+  message?: string
   read_at: string | null
   responded_at: string | null
   created_at: string
@@ -79,6 +86,9 @@ export interface App {
   updated_at: string
 }
 
+// API密钥权限范围
+export type APIKeyScope = 'device:register' | 'stats:report' | 'push:send' | 'push:broadcast' | 'devices:read'
+
 export interface AppAPIKey {
   id: number
   app_id: number
@@ -87,6 +97,9 @@ export interface AppAPIKey {
   key_prefix: string
   key_suffix: string
   status: number
+  scopes: string  // 逗号分隔的权限范围
+  allowed_cidrs: string  // 逗号分隔的IP白名单，为空不限制
+  rate_limit: number  // 每分钟请求上限，0=不限制
   expires_at: string | null
   last_used: string | null
   created_at: string
@@ -101,10 +114,23 @@ export interface AppConfig {
   platform: 'ios' | 'android'
   channel: string
   config: string
+  health?: AppConfigHealth
   created_at: string
   updated_at: string
 }
 
+// 推送配置凭据健康状态
+export interface AppConfigHealth {
+  id: number
+  app_id: number
+  config_id: number
+  status: 'healthy' | 'unhealthy' | 'unsupported'
+  message: string
+  checked_at: string
+  last_success_at?: string
+  expires_at?: string
+}
+
 // ===== 设备相关 =====
 export interface Device {
   id: number
@@ -120,6 +146,7 @@ export interface Device {
   user_agent: string
   status: number
   is_online: boolean
+  sandbox?: boolean // 测试密钥注册的沙箱设备
   last_seen: string
   created_at: string
   updated_at: string
@@ -159,6 +186,7 @@ export interface PushLog {
   dedup_key?: string
   send_at: string | null
   badge?: number
+  sandbox?: boolean // 测试密钥发起的沙箱推送，未发往厂商
   created_at: string
   updated_at: string
 }
@@ -442,7 +470,7 @@ export interface ScheduledPush {
   title: string
   content: string
   payload?: string
-  badge?: number
+  badge?: string  // 数字、+N 或 reset，空按 +1 累加
   template_id: number | null
   push_type: 'single' | 'batch' | 'broadcast' | 'groups'
   target_type: string